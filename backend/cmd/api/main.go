@@ -10,6 +10,7 @@ import (
 	"stock-in-order/backend/internal/database"
 	"stock-in-order/backend/internal/rabbitmq"
 	"stock-in-order/backend/internal/router"
+	"stock-in-order/backend/internal/storage"
 
 	"github.com/getsentry/sentry-go"
 	"github.com/joho/godotenv"
@@ -85,8 +86,17 @@ func main() {
 	defer rabbitClient.Close()
 	logger.Info("Conexión a RabbitMQ establecida")
 
+	// Initialize file storage for product attachments
+	store, err := storage.New(cfg.StorageBackend, cfg.StorageDir)
+	if err != nil {
+		logger.Error("Error inicializando el storage de archivos", "error", err)
+		sentry.CaptureException(err)
+		os.Exit(1)
+	}
+	logger.Info("Storage de archivos inicializado", "backend", cfg.StorageBackend, "dir", cfg.StorageDir)
+
 	// Initialize router with routes
-	r := router.SetupRouter(pool, rabbitClient, cfg, logger)
+	r := router.SetupRouter(pool, rabbitClient, store, cfg, logger)
 
 	// Start HTTP server
 	srv := &http.Server{
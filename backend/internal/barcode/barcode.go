@@ -0,0 +1,235 @@
+package barcode
+
+import (
+	"errors"
+	"strings"
+)
+
+// Simbologías soportadas
+const (
+	EAN13   = "ean13"
+	UPCA    = "upca"
+	Code128 = "code128"
+)
+
+// MaxCode128Length limita el largo de un Code128 para que entre en una etiqueta.
+const MaxCode128Length = 48
+
+var (
+	// ErrUnknownSymbology se lanza cuando la simbología no es ean13, upca ni code128
+	ErrUnknownSymbology = errors.New("unknown barcode symbology")
+	// ErrInvalidBarcode se lanza cuando el código no tiene el formato de su simbología
+	ErrInvalidBarcode = errors.New("invalid barcode")
+	// ErrInvalidCheckDigit se lanza cuando el dígito verificador de un EAN-13 / UPC-A no coincide
+	ErrInvalidCheckDigit = errors.New("invalid barcode check digit")
+	// ErrNoLabels se lanza cuando se pide imprimir sin etiquetas
+	ErrNoLabels = errors.New("no labels to print")
+)
+
+// Detect determina la simbología de un código: 13 dígitos es EAN-13, 12 dígitos es UPC-A y
+// cualquier otro texto ASCII imprimible es Code128. Valida el dígito verificador de EAN/UPC.
+func Detect(code string) (string, error) {
+	symbology := Code128
+	if isDigits(code) {
+		switch len(code) {
+		case 13:
+			symbology = EAN13
+		case 12:
+			symbology = UPCA
+		}
+	}
+	return symbology, Validate(code, symbology)
+}
+
+// Canonical devuelve la forma con la que se guarda y se busca un código: un UPC-A válido pasa a su
+// EAN-13 equivalente (el mismo código con un 0 adelante), así ambas lecturas son el mismo código.
+// El resto de los códigos no cambia.
+func Canonical(code string) string {
+	if symbology, err := Detect(code); err == nil && symbology == UPCA {
+		return "0" + code
+	}
+	return code
+}
+
+// Validate verifica que el código sea válido para la simbología indicada.
+func Validate(code string, symbology string) error {
+	switch symbology {
+	case EAN13, UPCA:
+		length := 13
+		if symbology == UPCA {
+			length = 12
+		}
+		if len(code) != length || !isDigits(code) {
+			return ErrInvalidBarcode
+		}
+		// Un UPC-A es un EAN-13 con un 0 adelante: mismo cálculo del dígito verificador
+		full := strings.Repeat("0", 13-length) + code
+		if CheckDigit(full[:12]) != full[12] {
+			return ErrInvalidCheckDigit
+		}
+		return nil
+	case Code128:
+		if code == "" || len(code) > MaxCode128Length {
+			return ErrInvalidBarcode
+		}
+		for i := 0; i < len(code); i++ {
+			if code[i] < 32 || code[i] > 126 {
+				return ErrInvalidBarcode
+			}
+		}
+		return nil
+	default:
+		return ErrUnknownSymbology
+	}
+}
+
+// CheckDigit calcula el dígito verificador EAN de los primeros 12 dígitos (pesos 1 y 3 alternados).
+func CheckDigit(digits12 string) byte {
+	sum := 0
+	for i := 0; i < len(digits12); i++ {
+		d := int(digits12[i] - '0')
+		if i%2 == 1 {
+			d *= 3
+		}
+		sum += d
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Modules codifica un código válido en módulos de igual ancho: true es barra y false espacio.
+// No incluye la zona silenciosa.
+func Modules(code string, symbology string) ([]bool, error) {
+	if err := Validate(code, symbology); err != nil {
+		return nil, err
+	}
+	switch symbology {
+	case UPCA:
+		return ean13Modules("0" + code), nil
+	case EAN13:
+		return ean13Modules(code), nil
+	default:
+		return code128Modules(code), nil
+	}
+}
+
+// Patrones EAN de cada dígito (conjunto L); R es su complemento y G el espejo de R.
+var eanL = [10]string{"0001101", "0011001", "0010011", "0111101", "0100011", "0110001", "0101111", "0111011", "0110111", "0001011"}
+
+// eanParity indica para el primer dígito si cada dígito de la mitad izquierda usa L o G.
+var eanParity = [10]string{"LLLLLL", "LLGLGG", "LLGGLG", "LLGGGL", "LGLLGG", "LGGLLG", "LGGGLL", "LGLGLG", "LGLGGL", "LGGLGL"}
+
+func ean13Modules(code string) []bool {
+	var sb strings.Builder
+	sb.WriteString("101")
+	parity := eanParity[code[0]-'0']
+	for i := 1; i <= 6; i++ {
+		l := eanL[code[i]-'0']
+		if parity[i-1] == 'G' {
+			sb.WriteString(reverse(complement(l)))
+		} else {
+			sb.WriteString(l)
+		}
+	}
+	sb.WriteString("01010")
+	for i := 7; i <= 12; i++ {
+		sb.WriteString(complement(eanL[code[i]-'0']))
+	}
+	sb.WriteString("101")
+	return bitsToModules(sb.String())
+}
+
+func complement(bits string) string {
+	b := []byte(bits)
+	for i := range b {
+		if b[i] == '0' {
+			b[i] = '1'
+		} else {
+			b[i] = '0'
+		}
+	}
+	return string(b)
+}
+
+func reverse(s string) string {
+	b := []byte(s)
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return string(b)
+}
+
+func bitsToModules(bits string) []bool {
+	out := make([]bool, len(bits))
+	for i := range bits {
+		out[i] = bits[i] == '1'
+	}
+	return out
+}
+
+// code128Widths son los anchos barra/espacio de cada símbolo Code128 (0-105) y del stop (106).
+var code128Widths = [107]string{
+	"212222", "222122", "222221", "121223", "121322", "131222", "122213", "122312", "132212", "221213",
+	"221312", "231212", "112232", "122132", "122231", "113222", "123122", "123221", "223211", "221132",
+	"221231", "213212", "223112", "312131", "311222", "321122", "321221", "312212", "322112", "322211",
+	"212123", "212321", "232121", "111323", "131123", "131321", "112313", "132113", "132311", "211313",
+	"231113", "231311", "112133", "112331", "132131", "113123", "113321", "133121", "313121", "211331",
+	"231131", "213113", "213311", "213131", "311123", "311321", "331121", "312113", "312311", "332111",
+	"314111", "221411", "431111", "111224", "111422", "121124", "121421", "141122", "141221", "112214",
+	"112412", "122114", "122411", "142112", "142211", "241211", "221114", "413111", "241112", "134111",
+	"111242", "121142", "121241", "114212", "124112", "124211", "411212", "421112", "421211", "212141",
+	"214121", "412121", "111143", "111341", "131141", "114113", "114311", "411113", "411311", "113141",
+	"114131", "311141", "411131", "211412", "211214", "211232", "2331112",
+}
+
+const (
+	code128StartB = 104
+	code128StartC = 105
+	code128Stop   = 106
+)
+
+// code128Modules usa el set C (pares de dígitos) para códigos numéricos de largo par y el set B
+// para el resto. El símbolo de control es (start + Σ valor·posición) mod 103.
+func code128Modules(code string) []bool {
+	var values []int
+	if isDigits(code) && len(code)%2 == 0 {
+		values = append(values, code128StartC)
+		for i := 0; i < len(code); i += 2 {
+			values = append(values, int(code[i]-'0')*10+int(code[i+1]-'0'))
+		}
+	} else {
+		values = append(values, code128StartB)
+		for i := 0; i < len(code); i++ {
+			values = append(values, int(code[i])-32)
+		}
+	}
+
+	checksum := values[0]
+	for i := 1; i < len(values); i++ {
+		checksum += values[i] * i
+	}
+	values = append(values, checksum%103, code128Stop)
+
+	var out []bool
+	for _, v := range values {
+		bar := true
+		for _, w := range code128Widths[v] {
+			for n := 0; n < int(w-'0'); n++ {
+				out = append(out, bar)
+			}
+			bar = !bar
+		}
+	}
+	return out
+}
@@ -0,0 +1,109 @@
+package barcode
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	cases := []struct {
+		code      string
+		symbology string
+		err       error
+	}{
+		{"4006381333931", EAN13, nil},
+		{"4006381333932", EAN13, ErrInvalidCheckDigit},
+		{"036000291452", UPCA, nil},
+		{"036000291453", UPCA, ErrInvalidCheckDigit},
+		{"SKU-001", Code128, nil},
+		{"12345", Code128, nil},
+		{"", Code128, ErrInvalidBarcode},
+		{"caño", Code128, ErrInvalidBarcode},
+	}
+	for _, c := range cases {
+		symbology, err := Detect(c.code)
+		if symbology != c.symbology || err != c.err {
+			t.Errorf("Detect(%q) = %q, %v; want %q, %v", c.code, symbology, err, c.symbology, c.err)
+		}
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	cases := map[string]string{
+		"036000291452":  "0036000291452", // UPC-A pasa a su EAN-13
+		"0036000291452": "0036000291452",
+		"4006381333931": "4006381333931",
+		"036000291453":  "036000291453", // dígito verificador inválido: no es un UPC-A
+		"SKU-001":       "SKU-001",
+	}
+	for code, want := range cases {
+		if got := Canonical(code); got != want {
+			t.Errorf("Canonical(%q) = %q; want %q", code, got, want)
+		}
+	}
+
+	// Las barras de un UPC-A y de su EAN-13 son las mismas
+	upc, _ := Modules("036000291452", UPCA)
+	ean, _ := Modules(Canonical("036000291452"), EAN13)
+	if fmt.Sprint(upc) != fmt.Sprint(ean) {
+		t.Fatal("UPC-A and its EAN-13 form should encode the same bars")
+	}
+}
+
+func TestModules(t *testing.T) {
+	ean, err := Modules("4006381333931", EAN13)
+	if err != nil || len(ean) != 95 {
+		t.Fatalf("EAN-13 should have 95 modules, got %d (%v)", len(ean), err)
+	}
+	upc, _ := Modules("036000291452", UPCA)
+	if len(upc) != 95 {
+		t.Fatalf("UPC-A should have 95 modules, got %d", len(upc))
+	}
+
+	// Set C: start C, 12, 34, 56, control 44, stop
+	want := "11010011100" + "10110011100" + "10001011000" + "11100010110" + "10001101110" + "1100011101011"
+	got, _ := Modules("123456", Code128)
+	var sb bytes.Buffer
+	for _, bar := range got {
+		if bar {
+			sb.WriteByte('1')
+		} else {
+			sb.WriteByte('0')
+		}
+	}
+	if sb.String() != want {
+		t.Fatalf("unexpected Code128 modules:\n got %s\nwant %s", sb.String(), want)
+	}
+}
+
+func TestWriteLabelsPDF(t *testing.T) {
+	labels := make([]Label, 30) // dos páginas
+	for i := range labels {
+		labels[i] = Label{Title: fmt.Sprintf("Producto (%d) ñandú", i), Code: "4006381333931", Symbology: EAN13}
+	}
+
+	var buf bytes.Buffer
+	if err := WriteLabelsPDF(&buf, labels); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pdf := buf.Bytes()
+
+	if !bytes.Contains(pdf, []byte("/Count 2")) {
+		t.Fatalf("expected two pages")
+	}
+	// Cada entrada de la tabla xref apunta al inicio de su objeto
+	offsets := regexp.MustCompile(`(\d{10}) 00000 n`).FindAllSubmatch(pdf, -1)
+	for i, m := range offsets {
+		off, _ := strconv.Atoi(string(m[1]))
+		if !bytes.HasPrefix(pdf[off:], []byte(fmt.Sprintf("%d 0 obj", i+1))) {
+			t.Fatalf("xref entry %d does not point to its object", i+1)
+		}
+	}
+
+	if err := WriteLabelsPDF(&buf, nil); err != ErrNoLabels {
+		t.Fatalf("expected ErrNoLabels, got %v", err)
+	}
+}
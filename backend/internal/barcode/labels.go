@@ -0,0 +1,209 @@
+package barcode
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+)
+
+// Label es una etiqueta a imprimir: un título (nombre del producto), el código y su leyenda.
+type Label struct {
+	Title     string
+	Code      string
+	Symbology string
+	Caption   string // texto bajo las barras; vacío = el código
+}
+
+// Grilla de etiquetas: hojas A4 de 3 x 8 etiquetas de 70 x 37 mm
+const (
+	labelColumns  = 3
+	labelRows     = 8
+	quietModules  = 10
+	pageWidthPt   = 595.0
+	pageHeightPt  = 842.0
+	labelWidthPt  = pageWidthPt / labelColumns
+	labelHeightPt = pageHeightPt / labelRows
+	maxTitleChars = 40
+)
+
+// Tamaño mínimo de cada etiqueta en la imagen PNG (px)
+const (
+	pngLabelWidth  = 420
+	pngLabelHeight = 200
+	pngBarHeight   = 140
+)
+
+// WriteLabelsPNG dibuja las etiquetas en una imagen PNG con la misma grilla de 3 columnas.
+// La imagen lleva sólo las barras (sin texto); para etiquetas con nombre y leyenda usar PDF.
+func WriteLabelsPNG(w io.Writer, labels []Label) error {
+	encoded, err := encodeLabels(labels)
+	if err != nil {
+		return err
+	}
+
+	// Los Code128 largos ensanchan la celda para no bajar de un píxel por módulo
+	cellWidth := pngLabelWidth
+	for _, modules := range encoded {
+		cellWidth = max(cellWidth, len(modules)+2*quietModules)
+	}
+
+	rows := (len(labels) + labelColumns - 1) / labelColumns
+	cols := min(len(labels), labelColumns)
+	img := image.NewGray(image.Rect(0, 0, cols*cellWidth, rows*pngLabelHeight))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+
+	for i, modules := range encoded {
+		x0 := (i % labelColumns) * cellWidth
+		y0 := (i/labelColumns)*pngLabelHeight + (pngLabelHeight-pngBarHeight)/2
+
+		total := len(modules) + 2*quietModules
+		scale := cellWidth / total
+		start := x0 + (cellWidth-len(modules)*scale)/2
+		for m, bar := range modules {
+			if !bar {
+				continue
+			}
+			for x := start + m*scale; x < start+(m+1)*scale; x++ {
+				for y := y0; y < y0+pngBarHeight; y++ {
+					img.SetGray(x, y, color.Gray{Y: 0})
+				}
+			}
+		}
+	}
+	return png.Encode(w, img)
+}
+
+// WriteLabelsPDF genera un PDF A4 con las etiquetas en grilla de 3 x 8 por página: título,
+// barras y leyenda. Usa la fuente Helvetica estándar, sin fuentes embebidas.
+func WriteLabelsPDF(w io.Writer, labels []Label) error {
+	encoded, err := encodeLabels(labels)
+	if err != nil {
+		return err
+	}
+
+	perPage := labelColumns * labelRows
+	var pages []string
+	for first := 0; first < len(labels); first += perPage {
+		var content strings.Builder
+		content.WriteString("0 g\n")
+		for i := first; i < min(first+perPage, len(labels)); i++ {
+			slot := i - first
+			x0 := float64(slot%labelColumns) * labelWidthPt
+			top := pageHeightPt - float64(slot/labelColumns)*labelHeightPt
+			writePDFLabel(&content, labels[i], encoded[i], x0, top)
+		}
+		pages = append(pages, content.String())
+	}
+
+	// Objetos: 1 catálogo, 2 árbol de páginas, 3 fuente, luego contenido y página de cada hoja
+	objects := make([]string, 3, 3+2*len(pages))
+	kids := make([]string, len(pages))
+	for i, content := range pages {
+		contentID := len(objects) + 1
+		objects = append(objects, fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(content), content))
+		pageID := len(objects) + 1
+		objects = append(objects, fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			pageWidthPt, pageHeightPt, contentID))
+		kids[i] = fmt.Sprintf("%d 0 R", pageID)
+	}
+	objects[0] = "<< /Type /Catalog /Pages 2 0 R >>"
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages))
+	objects[2] = "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>"
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// writePDFLabel escribe una etiqueta con su esquina superior izquierda en (x0, top).
+func writePDFLabel(sb *strings.Builder, l Label, modules []bool, x0, top float64) {
+	const barHeight = 48.0
+
+	mw := min((labelWidthPt-20)/float64(len(modules)+2*quietModules), 1.5)
+	width := mw * float64(len(modules))
+	left := x0 + (labelWidthPt-width)/2
+
+	title := l.Title
+	if r := []rune(title); len(r) > maxTitleChars {
+		title = string(r[:maxTitleChars-1]) + "…"
+	}
+	fmt.Fprintf(sb, "BT /F1 8 Tf %.2f %.2f Td (%s) Tj ET\n", x0+12, top-16, pdfString(title))
+
+	// Cada tramo de barras contiguas es un rectángulo
+	barsBottom := top - 24 - barHeight
+	for m := 0; m < len(modules); {
+		if !modules[m] {
+			m++
+			continue
+		}
+		run := m
+		for run < len(modules) && modules[run] {
+			run++
+		}
+		fmt.Fprintf(sb, "%.3f %.2f %.3f %.2f re\n", left+float64(m)*mw, barsBottom, float64(run-m)*mw, barHeight)
+		m = run
+	}
+	sb.WriteString("f\n")
+
+	caption := l.Caption
+	if caption == "" {
+		caption = l.Code
+	}
+	fmt.Fprintf(sb, "BT /F1 9 Tf %.2f %.2f Td (%s) Tj ET\n", left, barsBottom-11, pdfString(caption))
+}
+
+// pdfString escapa un texto para un string literal PDF en WinAnsiEncoding; los caracteres
+// fuera de Latin-1 se reemplazan por '?'.
+func pdfString(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			sb.WriteByte('\\')
+			sb.WriteByte(byte(r))
+		case r == '…':
+			sb.WriteByte(0x85)
+		case r >= 32 && r < 127, r >= 160 && r <= 255:
+			sb.WriteByte(byte(r))
+		default:
+			sb.WriteByte('?')
+		}
+	}
+	return sb.String()
+}
+
+// encodeLabels codifica las barras de cada etiqueta.
+func encodeLabels(labels []Label) ([][]bool, error) {
+	if len(labels) == 0 {
+		return nil, ErrNoLabels
+	}
+	out := make([][]bool, len(labels))
+	for i, l := range labels {
+		modules, err := Modules(l.Code, l.Symbology)
+		if err != nil {
+			return nil, fmt.Errorf("label %d (%s): %w", i+1, l.Code, err)
+		}
+		out[i] = modules
+	}
+	return out, nil
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"strconv"
 )
 
 // Config holds application configuration.
@@ -20,6 +21,15 @@ type Config struct {
 	MLClientID     string
 	MLClientSecret string
 	MLRedirectURI  string
+
+	// Storage de adjuntos: backend ("local") y directorio raíz del backend local
+	StorageBackend string
+	StorageDir     string
+
+	// Umbrales de los ajustes manuales de stock que requieren aprobación de un admin:
+	// cantidad absoluta (unidad base) y valor al costo promedio. 0 = sin límite.
+	AdjustmentApprovalQuantity float64
+	AdjustmentApprovalValue    float64
 }
 
 // LoadConfig reads configuration from environment variables with sensible defaults.
@@ -61,6 +71,19 @@ func LoadConfig() Config {
 		mlRedirectURI = "http://localhost:8080/api/v1/integrations/mercadolibre/callback"
 	}
 
+	storageBackend := os.Getenv("STORAGE_BACKEND")
+	if storageBackend == "" {
+		storageBackend = "local"
+	}
+
+	storageDir := os.Getenv("STORAGE_DIR")
+	if storageDir == "" {
+		storageDir = "./uploads"
+	}
+
+	adjustmentApprovalQuantity, _ := strconv.ParseFloat(os.Getenv("ADJUSTMENT_APPROVAL_QUANTITY"), 64)
+	adjustmentApprovalValue, _ := strconv.ParseFloat(os.Getenv("ADJUSTMENT_APPROVAL_VALUE"), 64)
+
 	return Config{
 		Port:          port,
 		DB_DSN:        dsn,
@@ -70,5 +93,11 @@ func LoadConfig() Config {
 		MLClientID:     mlClientID,
 		MLClientSecret: mlClientSecret,
 		MLRedirectURI:  mlRedirectURI,
+
+		StorageBackend: storageBackend,
+		StorageDir:     storageDir,
+
+		AdjustmentApprovalQuantity: adjustmentApprovalQuantity,
+		AdjustmentApprovalValue:    adjustmentApprovalValue,
 	}
 }
@@ -0,0 +1,132 @@
+package forecast
+
+import (
+	"math"
+)
+
+// Model es un suavizado exponencial simple con estacionalidad aditiva (Holt-Winters sin tendencia)
+// ajustado a una serie de demanda semanal. Period es el largo de la temporada en semanas; 0 si la
+// serie no alcanza dos temporadas completas y el modelo es un suavizado simple.
+type Model struct {
+	Alpha    float64   // suavizado del nivel
+	Gamma    float64   // suavizado de la estacionalidad
+	Period   int       // largo de la temporada (0 = sin estacionalidad)
+	Level    float64   // nivel al final de la serie
+	Seasonal []float64 // índice estacional de cada posición de la temporada
+	RMSE     float64   // error de los pronósticos a un paso sobre la historia
+
+	n int // largo de la serie ajustada
+}
+
+// smoothingGrid son los valores de Alpha y Gamma que prueba Fit.
+var smoothingGrid = []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9}
+
+// Fit ajusta el modelo a history (la demanda de cada semana, la más antigua primero) eligiendo
+// Alpha y Gamma con el menor error cuadrático de los pronósticos a un paso.
+func Fit(history []float64, period int) Model {
+	if period < 2 || len(history) < 2*period {
+		period = 0
+	}
+	gammas := []float64{0}
+	if period > 0 {
+		gammas = smoothingGrid
+	}
+
+	best := Model{n: len(history)}
+	bestSSE := math.Inf(1)
+	for _, alpha := range smoothingGrid {
+		for _, gamma := range gammas {
+			m, sse, count := run(history, period, alpha, gamma)
+			if sse < bestSSE {
+				bestSSE = sse
+				best = m
+				if count > 0 {
+					best.RMSE = math.Sqrt(sse / float64(count))
+				}
+			}
+		}
+	}
+	return best
+}
+
+// run aplica el suavizado con los parámetros dados y devuelve el modelo final junto con la suma
+// de errores cuadráticos a un paso y la cantidad de pronósticos evaluados.
+func run(y []float64, period int, alpha, gamma float64) (Model, float64, int) {
+	m := Model{Alpha: alpha, Gamma: gamma, Period: period, n: len(y)}
+	if len(y) == 0 {
+		return m, 0, 0
+	}
+
+	// Inicialización: el nivel es el promedio de la primera temporada (o la primera semana)
+	start := 1
+	if period > 0 {
+		start = period
+		for _, v := range y[:period] {
+			m.Level += v
+		}
+		m.Level /= float64(period)
+		m.Seasonal = make([]float64, period)
+		for i := 0; i < period; i++ {
+			m.Seasonal[i] = y[i] - m.Level
+		}
+	} else {
+		m.Level = y[0]
+	}
+
+	var sse float64
+	for t := start; t < len(y); t++ {
+		season := 0.0
+		if period > 0 {
+			season = m.Seasonal[t%period]
+		}
+		err := y[t] - (m.Level + season)
+		sse += err * err
+
+		level := alpha*(y[t]-season) + (1-alpha)*m.Level
+		if period > 0 {
+			m.Seasonal[t%period] = gamma*(y[t]-level) + (1-gamma)*season
+		}
+		m.Level = level
+	}
+	return m, sse, len(y) - start
+}
+
+// Forecast devuelve la demanda pronosticada para las próximas weeks semanas (nunca negativa).
+func (m Model) Forecast(weeks int) []float64 {
+	out := make([]float64, weeks)
+	for h := 0; h < weeks; h++ {
+		v := m.Level
+		if m.Period > 0 {
+			v += m.Seasonal[(m.n+h)%m.Period]
+		}
+		out[h] = math.Max(v, 0)
+	}
+	return out
+}
+
+// DaysUntilStockout recorre el pronóstico semanal (repartido en partes iguales entre los días de
+// cada semana) y devuelve en cuántos días la demanda acumulada alcanza el stock disponible.
+// ok es false si el stock alcanza para todo el horizonte pronosticado.
+func DaysUntilStockout(available float64, weekly []float64) (days float64, ok bool) {
+	if available <= 0 {
+		return 0, true
+	}
+	remaining := available
+	for i, demand := range weekly {
+		if demand <= 0 {
+			continue
+		}
+		if demand >= remaining {
+			return float64(i*7) + remaining/(demand/7), true
+		}
+		remaining -= demand
+	}
+	return 0, false
+}
+
+// SuggestedMinimum calcula el stock mínimo sugerido: la demanda esperada durante el plazo de
+// reposición más un stock de seguridad de z desvíos del error semanal del pronóstico.
+func SuggestedMinimum(avgWeekly float64, rmse float64, leadTimeDays int, z float64) float64 {
+	weeks := float64(leadTimeDays) / 7
+	return avgWeekly*weeks + z*rmse*math.Sqrt(weeks)
+}
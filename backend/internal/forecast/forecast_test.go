@@ -0,0 +1,76 @@
+package forecast
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFitConstantDemand(t *testing.T) {
+	history := []float64{10, 10, 10, 10, 10, 10}
+	m := Fit(history, 52)
+	if m.Period != 0 {
+		t.Fatalf("expected no seasonality with less than two seasons, got period %d", m.Period)
+	}
+	for i, v := range m.Forecast(4) {
+		if math.Abs(v-10) > 1e-9 {
+			t.Fatalf("week %d: expected 10, got %v", i, v)
+		}
+	}
+	if m.RMSE != 0 {
+		t.Fatalf("expected zero error, got %v", m.RMSE)
+	}
+}
+
+func TestFitSeasonalDemand(t *testing.T) {
+	// Tres temporadas de 4 semanas con un pico en la tercera semana
+	season := []float64{5, 5, 20, 10}
+	var history []float64
+	for i := 0; i < 3; i++ {
+		history = append(history, season...)
+	}
+
+	m := Fit(history, 4)
+	if m.Period != 4 {
+		t.Fatalf("expected period 4, got %d", m.Period)
+	}
+	got := m.Forecast(6)
+	want := []float64{5, 5, 20, 10, 5, 5}
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > 1e-6 {
+			t.Fatalf("week %d: expected %v, got %v (forecast %v)", i, want[i], got[i], got)
+		}
+	}
+}
+
+func TestForecastNeverNegative(t *testing.T) {
+	m := Model{Level: 1, Period: 2, Seasonal: []float64{-5, 0}, n: 2}
+	if got := m.Forecast(2); got[0] != 0 || got[1] != 1 {
+		t.Fatalf("expected [0 1], got %v", got)
+	}
+}
+
+func TestDaysUntilStockout(t *testing.T) {
+	weekly := []float64{7, 14, 14}
+
+	// La primera semana consume 7 (quedan 3) y la segunda 2 por día: 10 unidades duran 7 + 1,5 días
+	days, ok := DaysUntilStockout(10, weekly)
+	if !ok || math.Abs(days-8.5) > 1e-9 {
+		t.Fatalf("expected stockout in 8.5 days, got %v (%t)", days, ok)
+	}
+
+	if _, ok := DaysUntilStockout(100, weekly); ok {
+		t.Fatal("expected no stockout within the horizon")
+	}
+	if days, ok := DaysUntilStockout(0, weekly); !ok || days != 0 {
+		t.Fatalf("expected immediate stockout, got %v (%t)", days, ok)
+	}
+}
+
+func TestSuggestedMinimum(t *testing.T) {
+	// Dos semanas de plazo: 2 x 10 de demanda + 2 desvíos de 3 x raíz de 2
+	got := SuggestedMinimum(10, 3, 14, 2)
+	want := 20 + 2*3*math.Sqrt(2)
+	if math.Abs(got-want) > 1e-9 {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"stock-in-order/backend/internal/models"
+)
+
+// archiveFilterFromRequest lee ?archived= de la query string: vacío (sólo activos), only o include.
+func archiveFilterFromRequest(r *http.Request) (models.ArchiveFilter, bool) {
+	f := models.ArchiveFilter(r.URL.Query().Get("archived"))
+	return f, f.Valid()
+}
+
+// writeArchivedError responde 400 cuando una orden nueva usa un producto, cliente o proveedor
+// archivado o inexistente. Devuelve false si err no es uno de esos casos.
+func writeArchivedError(w http.ResponseWriter, err error) bool {
+	var msg string
+	switch err {
+	case models.ErrProductArchived:
+		msg = "product is archived"
+	case models.ErrCustomerArchived:
+		msg = "customer is archived"
+	case models.ErrSupplierArchived:
+		msg = "supplier is archived"
+	case models.ErrCustomerNotFound:
+		msg = "customer not found"
+	case models.ErrSupplierNotFound:
+		msg = "supplier not found"
+	default:
+		return false
+	}
+	w.WriteHeader(http.StatusBadRequest)
+	_ = json.NewEncoder(w).Encode(map[string]any{"error": msg})
+	return true
+}
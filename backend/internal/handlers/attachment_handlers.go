@@ -0,0 +1,390 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"stock-in-order/backend/internal/middleware"
+	"stock-in-order/backend/internal/models"
+	"stock-in-order/backend/internal/storage"
+)
+
+// MaxAttachmentBytes es el tamaño máximo de un archivo adjunto.
+const MaxAttachmentBytes = 20 << 20
+
+// Tipos de contenido aceptados, detectados a partir de los primeros bytes del archivo (no del
+// Content-Type que manda el cliente). Las imágenes pueden subirse también como documento.
+var (
+	imageContentTypes = map[string]string{
+		"image/jpeg": ".jpg",
+		"image/png":  ".png",
+		"image/gif":  ".gif",
+	}
+	documentContentTypes = map[string]string{
+		"application/pdf": ".pdf",
+	}
+)
+
+// attachmentExtension devuelve la extensión con la que se guarda un archivo del tipo detectado,
+// o "" si el tipo no se acepta para ese kind.
+func attachmentExtension(kind, contentType string) string {
+	if ext, ok := imageContentTypes[contentType]; ok {
+		return ext
+	}
+	if kind != models.AttachmentImage {
+		return documentContentTypes[contentType]
+	}
+	return ""
+}
+
+// newAttachmentKey genera una clave de storage aleatoria para un archivo del producto.
+func newAttachmentKey(productID int64) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("products/%d/%s", productID, hex.EncodeToString(b)), nil
+}
+
+// deleteStoredFiles borra archivos del storage; los errores sólo se registran, el registro en la base ya no existe.
+func deleteStoredFiles(r *http.Request, store storage.Storage, keys []string) {
+	for _, key := range keys {
+		if err := store.Delete(r.Context(), key); err != nil {
+			slog.Error("could not delete stored file", "error", err, "key", key)
+		}
+	}
+}
+
+// GetProductAttachments handles GET /api/v1/products/{id}/attachments
+func GetProductAttachments(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+
+		am := &models.AttachmentModel{DB: db}
+		attachments, err := am.GetForProduct(id, userID)
+		if err != nil {
+			if err == models.ErrNotFound {
+				http.NotFound(w, r)
+				return
+			}
+			http.Error(w, "could not fetch attachments", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(attachments)
+	}
+}
+
+// UploadProductAttachment handles POST /api/v1/products/{id}/attachments
+// Formulario multipart con los campos file, kind (image, spec_sheet, safety_data_sheet o document;
+// por defecto image) e is_primary. De las imágenes se genera una miniatura JPEG.
+func UploadProductAttachment(db *pgxpool.Pool, store storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+
+		// Margen de 1 MB para los demás campos y los encabezados multipart
+		r.Body = http.MaxBytesReader(w, r.Body, MaxAttachmentBytes+1<<20)
+		if err := r.ParseMultipartForm(8 << 20); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(w, fmt.Sprintf("file too large (max %d MB)", MaxAttachmentBytes>>20), http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, "invalid multipart form", http.StatusBadRequest)
+			return
+		}
+		defer func() { _ = r.MultipartForm.RemoveAll() }()
+
+		kind := strings.ToLower(strings.TrimSpace(r.FormValue("kind")))
+		if kind == "" {
+			kind = models.AttachmentImage
+		}
+		if !models.ValidAttachmentKind(kind) {
+			http.Error(w, "kind must be image, spec_sheet, safety_data_sheet or document", http.StatusBadRequest)
+			return
+		}
+		isPrimary, _ := strconv.ParseBool(r.FormValue("is_primary"))
+
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, "file required", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		if header.Size > MaxAttachmentBytes {
+			http.Error(w, fmt.Sprintf("file too large (max %d MB)", MaxAttachmentBytes>>20), http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		sniff := make([]byte, 512)
+		n, err := io.ReadFull(file, sniff)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			http.Error(w, "could not read file", http.StatusBadRequest)
+			return
+		}
+		contentType, _, _ := mime.ParseMediaType(http.DetectContentType(sniff[:n]))
+		ext := attachmentExtension(kind, contentType)
+		if ext == "" {
+			if kind == models.AttachmentImage {
+				http.Error(w, "images must be JPEG, PNG or GIF", http.StatusUnsupportedMediaType)
+			} else {
+				http.Error(w, "documents must be PDF, JPEG, PNG or GIF", http.StatusUnsupportedMediaType)
+			}
+			return
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			http.Error(w, "could not read file", http.StatusInternalServerError)
+			return
+		}
+
+		am := &models.AttachmentModel{DB: db}
+		exists, err := am.ProductExists(id, userID)
+		if err != nil {
+			http.Error(w, "could not fetch product", http.StatusInternalServerError)
+			return
+		}
+		if !exists {
+			http.NotFound(w, r)
+			return
+		}
+
+		// La miniatura se genera antes de guardar nada: si la imagen no se puede decodificar, no se guarda.
+		var thumb bytes.Buffer
+		if kind == models.AttachmentImage {
+			if err := storage.WriteThumbnail(&thumb, file); err != nil {
+				if errors.Is(err, storage.ErrImageTooLarge) {
+					http.Error(w, "image dimensions too large", http.StatusBadRequest)
+					return
+				}
+				http.Error(w, "invalid image", http.StatusBadRequest)
+				return
+			}
+			if _, err := file.Seek(0, io.SeekStart); err != nil {
+				http.Error(w, "could not read file", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		key, err := newAttachmentKey(id)
+		if err != nil {
+			http.Error(w, "could not store file", http.StatusInternalServerError)
+			return
+		}
+		a := &models.ProductAttachment{
+			ProductID:   id,
+			Kind:        kind,
+			FileName:    filepath.Base(header.Filename),
+			ContentType: contentType,
+			IsPrimary:   isPrimary,
+			StorageKey:  key + ext,
+		}
+
+		stored := []string{}
+		if a.SizeBytes, err = store.Save(r.Context(), a.StorageKey, file); err != nil {
+			slog.Error("UploadProductAttachment: save failed", "error", err, "productID", id, "userID", userID)
+			http.Error(w, "could not store file", http.StatusInternalServerError)
+			return
+		}
+		stored = append(stored, a.StorageKey)
+		if thumb.Len() > 0 {
+			thumbKey := key + "_thumb.jpg"
+			if _, err := store.Save(r.Context(), thumbKey, &thumb); err != nil {
+				deleteStoredFiles(r, store, stored)
+				slog.Error("UploadProductAttachment: thumbnail save failed", "error", err, "productID", id, "userID", userID)
+				http.Error(w, "could not store file", http.StatusInternalServerError)
+				return
+			}
+			a.ThumbnailKey = &thumbKey
+			stored = append(stored, thumbKey)
+		}
+
+		if err := am.Insert(userID, a); err != nil {
+			deleteStoredFiles(r, store, stored)
+			if err == models.ErrNotFound {
+				http.NotFound(w, r)
+				return
+			}
+			slog.Error("UploadProductAttachment: insert failed", "error", err, "productID", id, "userID", userID)
+			http.Error(w, "could not save attachment", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(a)
+	}
+}
+
+// serveAttachment copia un archivo del storage a la respuesta.
+func serveAttachment(w http.ResponseWriter, r *http.Request, store storage.Storage, key, contentType, disposition string) {
+	f, err := store.Open(r.Context(), key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		http.Error(w, "could not read file", http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", disposition)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	_, _ = io.Copy(w, f)
+}
+
+// GetProductAttachmentFile handles GET /api/v1/products/{id}/attachments/{attachmentId}/file
+func GetProductAttachmentFile(db *pgxpool.Pool, store storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+		attachmentID, _ := strconv.ParseInt(vars["attachmentId"], 10, 64)
+
+		am := &models.AttachmentModel{DB: db}
+		a, err := am.Get(id, attachmentID, userID)
+		if err != nil {
+			if err == models.ErrNotFound {
+				http.NotFound(w, r)
+				return
+			}
+			http.Error(w, "could not fetch attachment", http.StatusInternalServerError)
+			return
+		}
+
+		disposition := mime.FormatMediaType("inline", map[string]string{"filename": a.FileName})
+		serveAttachment(w, r, store, a.StorageKey, a.ContentType, disposition)
+	}
+}
+
+// GetProductAttachmentThumbnail handles GET /api/v1/products/{id}/attachments/{attachmentId}/thumbnail
+func GetProductAttachmentThumbnail(db *pgxpool.Pool, store storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+		attachmentID, _ := strconv.ParseInt(vars["attachmentId"], 10, 64)
+
+		am := &models.AttachmentModel{DB: db}
+		a, err := am.Get(id, attachmentID, userID)
+		if err != nil {
+			if err == models.ErrNotFound {
+				http.NotFound(w, r)
+				return
+			}
+			http.Error(w, "could not fetch attachment", http.StatusInternalServerError)
+			return
+		}
+		if a.ThumbnailKey == nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		serveAttachment(w, r, store, *a.ThumbnailKey, "image/jpeg", "inline")
+	}
+}
+
+// SetPrimaryProductAttachment handles PUT /api/v1/products/{id}/attachments/{attachmentId}/primary
+func SetPrimaryProductAttachment(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+		attachmentID, _ := strconv.ParseInt(vars["attachmentId"], 10, 64)
+
+		am := &models.AttachmentModel{DB: db}
+		if err := am.SetPrimary(id, attachmentID, userID); err != nil {
+			if err == models.ErrNotFound {
+				http.NotFound(w, r)
+				return
+			}
+			if err == models.ErrNotAnImage {
+				http.Error(w, "only images can be the primary attachment", http.StatusBadRequest)
+				return
+			}
+			http.Error(w, "could not update attachment", http.StatusInternalServerError)
+			return
+		}
+
+		attachments, err := am.GetForProduct(id, userID)
+		if err != nil {
+			http.Error(w, "could not fetch attachments", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(attachments)
+	}
+}
+
+// DeleteProductAttachment handles DELETE /api/v1/products/{id}/attachments/{attachmentId}
+func DeleteProductAttachment(db *pgxpool.Pool, store storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+		attachmentID, _ := strconv.ParseInt(vars["attachmentId"], 10, 64)
+
+		am := &models.AttachmentModel{DB: db}
+		a, err := am.Delete(id, attachmentID, userID)
+		if err != nil {
+			if err == models.ErrNotFound {
+				http.NotFound(w, r)
+				return
+			}
+			http.Error(w, "could not delete attachment", http.StatusInternalServerError)
+			return
+		}
+		deleteStoredFiles(r, store, a.StorageKeys())
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
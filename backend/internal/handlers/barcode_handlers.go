@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"stock-in-order/backend/internal/barcode"
+	"stock-in-order/backend/internal/middleware"
+	"stock-in-order/backend/internal/models"
+)
+
+// Límites de etiquetas por impresión; la PNG es una sola imagen, así que admite menos.
+const (
+	MaxLabelsPerRequest = 1000
+	MaxPNGLabels        = 48
+)
+
+// DTOs for barcodes and labels
+
+type CreateBarcodeInput struct {
+	Code      string `json:"code"`
+	Symbology string `json:"symbology"` // vacío = se deduce del código
+}
+
+type LabelItemInput struct {
+	ProductID int64  `json:"product_id"`
+	Quantity  int    `json:"quantity"`
+	Barcode   string `json:"barcode"` // vacío = primer código del producto (o el SKU)
+}
+
+type PrintLabelsInput struct {
+	Format string           `json:"format"` // "pdf" (por defecto) o "png"
+	Items  []LabelItemInput `json:"items"`
+}
+
+// writeBarcodeError traduce los errores de validación de códigos a un 400 con el motivo.
+func writeBarcodeError(w http.ResponseWriter, err error) bool {
+	var msg string
+	switch {
+	case errors.Is(err, barcode.ErrInvalidCheckDigit):
+		msg = "invalid check digit"
+	case errors.Is(err, barcode.ErrInvalidBarcode):
+		msg = "invalid barcode: EAN-13 needs 13 digits, UPC-A 12 digits and Code128 up to 48 printable ASCII characters"
+	case errors.Is(err, barcode.ErrUnknownSymbology):
+		msg = "symbology must be ean13, upca or code128"
+	default:
+		return false
+	}
+	w.WriteHeader(http.StatusBadRequest)
+	_ = json.NewEncoder(w).Encode(map[string]any{"error": msg})
+	return true
+}
+
+// GetProductBarcodes handles GET /api/v1/products/{id}/barcodes
+func GetProductBarcodes(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+
+		bm := &models.BarcodeModel{DB: db}
+		barcodes, err := bm.GetForProduct(id, userID)
+		if err != nil {
+			if err == models.ErrNotFound {
+				http.NotFound(w, r)
+				return
+			}
+			http.Error(w, "could not fetch barcodes", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(barcodes)
+	}
+}
+
+// CreateProductBarcode handles POST /api/v1/products/{id}/barcodes
+func CreateProductBarcode(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+
+		var in CreateBarcodeInput
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		b := &models.ProductBarcode{
+			ProductID: id,
+			Code:      strings.TrimSpace(in.Code),
+			Symbology: strings.ToLower(in.Symbology),
+		}
+		bm := &models.BarcodeModel{DB: db}
+		if err := bm.Insert(userID, b); err != nil {
+			if writeBarcodeError(w, err) {
+				return
+			}
+			if err == models.ErrNotFound {
+				http.NotFound(w, r)
+				return
+			}
+			if err == models.ErrDuplicateBarcode {
+				w.WriteHeader(http.StatusConflict)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "barcode already assigned to a product"})
+				return
+			}
+			http.Error(w, "could not create barcode", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(b)
+	}
+}
+
+// DeleteProductBarcode handles DELETE /api/v1/products/{id}/barcodes/{barcodeId}
+func DeleteProductBarcode(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+		barcodeID, _ := strconv.ParseInt(vars["barcodeId"], 10, 64)
+
+		bm := &models.BarcodeModel{DB: db}
+		if err := bm.Delete(id, barcodeID, userID); err != nil {
+			if err == models.ErrNotFound {
+				http.NotFound(w, r)
+				return
+			}
+			http.Error(w, "could not delete barcode", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// GetProductByBarcode handles GET /api/v1/products/by-barcode/{code}
+// Búsqueda para lectores de mostrador y recepción: código de barras o, en su defecto, SKU.
+func GetProductByBarcode(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		code := strings.TrimSpace(mux.Vars(r)["code"])
+
+		bm := &models.BarcodeModel{DB: db}
+		productID, err := bm.FindProductID(code, userID)
+		if err != nil {
+			if err == models.ErrNotFound {
+				http.NotFound(w, r)
+				return
+			}
+			http.Error(w, "could not look up barcode", http.StatusInternalServerError)
+			return
+		}
+
+		pm := &models.ProductModel{DB: db}
+		p, err := pm.GetByID(productID, userID)
+		if err != nil {
+			http.Error(w, "could not fetch product", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(p)
+	}
+}
+
+// PrintBarcodeLabels handles POST /api/v1/products/labels
+// Genera las etiquetas pedidas (quantity por producto) como PDF A4 o como imagen PNG.
+func PrintBarcodeLabels(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var in PrintLabelsInput
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if in.Format == "" {
+			in.Format = "pdf"
+		}
+		if in.Format != "pdf" && in.Format != "png" {
+			http.Error(w, "format must be pdf or png", http.StatusBadRequest)
+			return
+		}
+		if len(in.Items) == 0 {
+			http.Error(w, "items required", http.StatusBadRequest)
+			return
+		}
+
+		total := 0
+		for _, it := range in.Items {
+			if it.Quantity <= 0 {
+				http.Error(w, "quantity must be > 0", http.StatusBadRequest)
+				return
+			}
+			total += it.Quantity
+		}
+		if total > MaxLabelsPerRequest || (in.Format == "png" && total > MaxPNGLabels) {
+			http.Error(w, "too many labels in one request", http.StatusBadRequest)
+			return
+		}
+
+		pm := &models.ProductModel{DB: db}
+		labels := make([]barcode.Label, 0, total)
+		for _, it := range in.Items {
+			p, err := pm.GetByID(it.ProductID, userID)
+			if err != nil {
+				if err == models.ErrNotFound {
+					w.WriteHeader(http.StatusBadRequest)
+					_ = json.NewEncoder(w).Encode(map[string]any{"error": "product not found", "product_id": it.ProductID})
+					return
+				}
+				http.Error(w, "could not fetch product", http.StatusInternalServerError)
+				return
+			}
+
+			label, err := models.LabelFor(p, strings.TrimSpace(it.Barcode))
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]any{
+					"error":      "product has no printable barcode (barcode not assigned, or SKU not valid as Code128)",
+					"product_id": it.ProductID,
+				})
+				return
+			}
+			for i := 0; i < it.Quantity; i++ {
+				labels = append(labels, label)
+			}
+		}
+
+		var buf bytes.Buffer
+		var err error
+		if in.Format == "png" {
+			err = barcode.WriteLabelsPNG(&buf, labels)
+		} else {
+			err = barcode.WriteLabelsPDF(&buf, labels)
+		}
+		if err != nil {
+			http.Error(w, "could not render labels", http.StatusInternalServerError)
+			return
+		}
+
+		if in.Format == "png" {
+			w.Header().Set("Content-Type", "image/png")
+			w.Header().Set("Content-Disposition", "attachment; filename=\"etiquetas.png\"")
+		} else {
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Header().Set("Content-Disposition", "attachment; filename=\"etiquetas.pdf\"")
+		}
+		_, _ = w.Write(buf.Bytes())
+	}
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"stock-in-order/backend/internal/middleware"
+	"stock-in-order/backend/internal/models"
+)
+
+// DTO for category create/update
+type CategoryInput struct {
+	Name     string `json:"name"`
+	ParentID *int64 `json:"parent_id"` // nil o 0 = categoría raíz
+}
+
+// writeCategoryError traduce los errores del modelo de categorías a respuestas HTTP.
+func writeCategoryError(w http.ResponseWriter, r *http.Request, err error) {
+	switch err {
+	case models.ErrCategoryNotFound:
+		http.NotFound(w, r)
+	case models.ErrDuplicateCategory:
+		w.WriteHeader(http.StatusConflict)
+		_ = json.NewEncoder(w).Encode(map[string]any{"error": "a category with that name already exists under the same parent"})
+	case models.ErrInvalidCategoryRef:
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]any{"error": "parent category not found or is the category itself or one of its subcategories"})
+	case models.ErrHasReferences:
+		w.WriteHeader(http.StatusConflict)
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"error": "No se puede eliminar la categoría porque tiene subcategorías o productos asignados",
+		})
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// categoryFromInput valida el nombre y normaliza parent_id 0 como raíz.
+func categoryFromInput(in CategoryInput) (*models.Category, bool) {
+	name := strings.TrimSpace(in.Name)
+	if name == "" {
+		return nil, false
+	}
+	c := &models.Category{Name: name, ParentID: in.ParentID}
+	if c.ParentID != nil && *c.ParentID == 0 {
+		c.ParentID = nil
+	}
+	return c, true
+}
+
+// ListCategories handles GET /api/v1/categories
+// Devuelve el árbol anidado; con ?flat=true la lista plana con parent_id.
+func ListCategories(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		cm := &models.CategoryModel{DB: db}
+		var (
+			categories []models.Category
+			err        error
+		)
+		if r.URL.Query().Get("flat") == "true" {
+			categories, err = cm.GetAllForUser(userID)
+		} else {
+			categories, err = cm.GetTree(userID)
+		}
+		if err != nil {
+			http.Error(w, "could not fetch categories", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(categories)
+	}
+}
+
+// CreateCategory handles POST /api/v1/categories
+func CreateCategory(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var in CategoryInput
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+		c, valid := categoryFromInput(in)
+		if !valid {
+			http.Error(w, "name is required", http.StatusBadRequest)
+			return
+		}
+		c.UserID = userID
+
+		cm := &models.CategoryModel{DB: db}
+		if err := cm.Insert(c); err != nil {
+			writeCategoryError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(c)
+	}
+}
+
+// UpdateCategory handles PUT /api/v1/categories/{id}
+// Cambiar parent_id mueve la categoría con todas sus subcategorías.
+func UpdateCategory(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+
+		var in CategoryInput
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+		c, valid := categoryFromInput(in)
+		if !valid {
+			http.Error(w, "name is required", http.StatusBadRequest)
+			return
+		}
+
+		cm := &models.CategoryModel{DB: db}
+		if err := cm.Update(id, userID, c); err != nil {
+			writeCategoryError(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// DeleteCategory handles DELETE /api/v1/categories/{id}
+func DeleteCategory(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+
+		cm := &models.CategoryModel{DB: db}
+		if err := cm.Delete(id, userID); err != nil {
+			writeCategoryError(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// ListTags handles GET /api/v1/tags
+func ListTags(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		tm := &models.TagModel{DB: db}
+		tags, err := tm.GetAllForUser(userID)
+		if err != nil {
+			http.Error(w, "could not fetch tags", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(tags)
+	}
+}
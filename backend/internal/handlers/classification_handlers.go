@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"stock-in-order/backend/internal/middleware"
+	"stock-in-order/backend/internal/rabbitmq"
+)
+
+// ClassificationRunRequest es la tarea que procesa el worker en la cola de alertas de stock.
+type ClassificationRunRequest struct {
+	TaskType string `json:"task_type"`
+	UserID   int64  `json:"user_id"`
+}
+
+// RequestProductClassification handles POST /api/v1/products/classify
+// Encola la clasificación ABC/XYZ del catálogo del usuario sin esperar al job programado; el
+// resultado queda en abc_class / xyz_class de cada producto (GET /products?abc_class=A&xyz_class=X).
+func RequestProductClassification(rabbit *rabbitmq.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		body, err := json.Marshal(ClassificationRunRequest{
+			TaskType: "classify_products",
+			UserID:   userID,
+		})
+		if err != nil {
+			http.Error(w, "could not queue classification", http.StatusInternalServerError)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		if err := rabbit.PublishMessage(ctx, "stock_alerts_queue", body); err != nil {
+			http.Error(w, "could not queue classification", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"message": "La clasificación ABC/XYZ de los productos se está calculando.",
+		})
+	}
+}
@@ -53,7 +53,7 @@ func CreateCustomer(db *pgxpool.Pool) http.HandlerFunc {
 	}
 }
 
-// ListCustomers handles GET /api/v1/customers
+// ListCustomers handles GET /api/v1/customers?archived=
 func ListCustomers(db *pgxpool.Pool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userID, ok := middleware.UserIDFromContext(r.Context())
@@ -62,8 +62,14 @@ func ListCustomers(db *pgxpool.Pool) http.HandlerFunc {
 			return
 		}
 
+		archived, ok := archiveFilterFromRequest(r)
+		if !ok {
+			http.Error(w, "archived must be only or include", http.StatusBadRequest)
+			return
+		}
+
 		cm := &models.CustomerModel{DB: db}
-		items, err := cm.GetAllForUser(userID)
+		items, err := cm.GetAllForUser(userID, archived)
 		if err != nil {
 			http.Error(w, "could not fetch customers", http.StatusInternalServerError)
 			return
@@ -166,7 +172,7 @@ func DeleteCustomer(db *pgxpool.Pool) http.HandlerFunc {
 			if err == models.ErrHasReferences {
 				w.WriteHeader(http.StatusConflict)
 				_ = json.NewEncoder(w).Encode(map[string]string{
-					"error": "No se puede eliminar el cliente porque tiene órdenes de venta asociadas",
+					"error": "No se puede eliminar el cliente porque tiene órdenes de venta asociadas; archivalo en su lugar",
 				})
 				return
 			}
@@ -178,3 +184,50 @@ func DeleteCustomer(db *pgxpool.Pool) http.HandlerFunc {
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
+
+// ArchiveCustomer handles POST /api/v1/customers/{id}/archive
+func ArchiveCustomer(db *pgxpool.Pool) http.HandlerFunc {
+	return setCustomerArchived(db, true)
+}
+
+// RestoreCustomer handles POST /api/v1/customers/{id}/restore
+func RestoreCustomer(db *pgxpool.Pool) http.HandlerFunc {
+	return setCustomerArchived(db, false)
+}
+
+func setCustomerArchived(db *pgxpool.Pool, archive bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+
+		cm := &models.CustomerModel{DB: db}
+		var err error
+		if archive {
+			err = cm.Archive(id, userID)
+		} else {
+			err = cm.Restore(id, userID)
+		}
+		if err != nil {
+			if err == models.ErrNotFound {
+				http.NotFound(w, r)
+				return
+			}
+			http.Error(w, "could not update customer", http.StatusInternalServerError)
+			return
+		}
+
+		item, err := cm.GetByID(id, userID)
+		if err != nil {
+			http.Error(w, "could not fetch customer", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(item)
+	}
+}
@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"stock-in-order/backend/internal/middleware"
+	"stock-in-order/backend/internal/models"
+)
+
+// DTOs for cycle count sessions
+
+type CreateCycleCountInput struct {
+	WarehouseID          int64    `json:"warehouse_id"`           // 0 = depósito por defecto
+	ProductIDs           []int64  `json:"product_ids"`            // vacío = todos los productos
+	VarianceThresholdPct *float64 `json:"variance_threshold_pct"` // nil = umbral por defecto
+	Notes                string   `json:"notes"`
+}
+
+type CycleCountEntryInput struct {
+	ProductID int64   `json:"product_id"`
+	Quantity  float64 `json:"quantity"` // en unidad base
+}
+
+type RecordCycleCountInput struct {
+	Items []CycleCountEntryInput `json:"items"`
+}
+
+// writeCycleCountError traduce los errores del modelo de conteos a respuestas HTTP.
+func writeCycleCountError(w http.ResponseWriter, r *http.Request, err error) {
+	switch err {
+	case models.ErrNotFound:
+		http.NotFound(w, r)
+	case models.ErrWarehouseNotFound:
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]any{"error": "warehouse not found"})
+	case models.ErrEmptyCycleCount:
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]any{"error": "no products to count"})
+	case models.ErrSerializedProduct:
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]any{"error": "serialized products change stock only through purchase and sales orders"})
+	case models.ErrCycleCountProductNotFound:
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]any{"error": "product not part of the cycle count"})
+	case models.ErrInvalidCycleCountStatus:
+		w.WriteHeader(http.StatusConflict)
+		_ = json.NewEncoder(w).Encode(map[string]any{"error": "invalid cycle count status transition"})
+	case models.ErrInsufficientStock:
+		w.WriteHeader(http.StatusConflict)
+		_ = json.NewEncoder(w).Encode(map[string]any{"error": "variance would leave reserved stock uncovered"})
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// CreateCycleCount handles POST /api/v1/cycle-counts
+func CreateCycleCount(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var in CreateCycleCountInput
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		c := &models.CycleCount{
+			WarehouseID:          in.WarehouseID,
+			VarianceThresholdPct: models.DefaultVarianceThresholdPct,
+			Notes:                in.Notes,
+			UserID:               userID,
+		}
+		if in.VarianceThresholdPct != nil {
+			if *in.VarianceThresholdPct < 0 {
+				http.Error(w, "variance_threshold_pct must be >= 0", http.StatusBadRequest)
+				return
+			}
+			c.VarianceThresholdPct = *in.VarianceThresholdPct
+		}
+
+		cm := &models.CycleCountModel{DB: db}
+		lines, err := cm.Create(c, in.ProductIDs)
+		if err != nil {
+			if err == models.ErrNotFound {
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "product not found"})
+				return
+			}
+			writeCycleCountError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"cycle_count": c,
+			"lines":       lines,
+		})
+	}
+}
+
+// GetCycleCounts handles GET /api/v1/cycle-counts
+func GetCycleCounts(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		cm := &models.CycleCountModel{DB: db}
+		counts, err := cm.GetAllForUser(userID)
+		if err != nil {
+			http.Error(w, "could not fetch cycle counts", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(counts)
+	}
+}
+
+// GetPendingCycleCounts handles GET /api/v1/cycle-counts/pending-approval (admin)
+func GetPendingCycleCounts(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cm := &models.CycleCountModel{DB: db}
+		counts, err := cm.GetPendingApproval()
+		if err != nil {
+			http.Error(w, "could not fetch cycle counts", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(counts)
+	}
+}
+
+// GetCycleCountByID handles GET /api/v1/cycle-counts/{id}
+func GetCycleCountByID(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+
+		cm := &models.CycleCountModel{DB: db}
+		c, lines, err := cm.GetByID(id, userID)
+		if err != nil {
+			if err == models.ErrNotFound {
+				http.NotFound(w, r)
+				return
+			}
+			http.Error(w, "could not fetch cycle count", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"cycle_count": c,
+			"lines":       lines,
+		})
+	}
+}
+
+// RecordCycleCount handles POST /api/v1/cycle-counts/{id}/counts
+// Cada llamada es una pasada de conteo; se puede repetir mientras la sesión esté abierta.
+func RecordCycleCount(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+
+		var in RecordCycleCountInput
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if len(in.Items) == 0 {
+			http.Error(w, "items required", http.StatusBadRequest)
+			return
+		}
+
+		entries := make([]models.CycleCountEntry, 0, len(in.Items))
+		for _, it := range in.Items {
+			if it.Quantity < 0 {
+				http.Error(w, "quantity must be >= 0", http.StatusBadRequest)
+				return
+			}
+			entries = append(entries, models.CycleCountEntry{ProductID: it.ProductID, Quantity: it.Quantity})
+		}
+
+		cm := &models.CycleCountModel{DB: db}
+		if err := cm.RecordCounts(id, userID, entries); err != nil {
+			writeCycleCountError(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// CloseCycleCount handles POST /api/v1/cycle-counts/{id}/close
+func CloseCycleCount(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+
+		cm := &models.CycleCountModel{DB: db}
+		status, err := cm.Close(id, userID)
+		if err != nil {
+			writeCycleCountError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"status": status})
+	}
+}
+
+// ApproveCycleCount handles POST /api/v1/cycle-counts/{id}/approve (admin)
+// Con {"reject": true} la sesión vuelve a quedar abierta para recontar.
+func ApproveCycleCount(db *pgxpool.Pool) http.HandlerFunc {
+	type approveInput struct {
+		Reject bool `json:"reject"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+
+		var in approveInput
+		if r.ContentLength > 0 {
+			if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+				http.Error(w, "invalid JSON", http.StatusBadRequest)
+				return
+			}
+		}
+
+		cm := &models.CycleCountModel{DB: db}
+		if err := cm.Approve(id, userID, in.Reject); err != nil {
+			writeCycleCountError(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -65,7 +65,15 @@ func GetDashboardCharts(db *pgxpool.Pool) http.HandlerFunc {
 		}
 
 		dm := &models.DashboardModel{DB: db}
-		chartData, err := dm.GetChartData(userID)
+		// ?rollup=true suma las ventas de las variantes a su producto padre;
+		// ?group_by=category agrega ventas y stock bajo por categoría;
+		// ?forecast_weeks=N agrega la demanda total pronosticada para las próximas N semanas
+		forecastWeeks, err := queryInt64(r, "forecast_weeks")
+		if err != nil || forecastWeeks < 0 || forecastWeeks > models.MaxForecastWeeks {
+			http.Error(w, "forecast_weeks must be between 1 and 52", http.StatusBadRequest)
+			return
+		}
+		chartData, err := dm.GetChartData(userID, r.URL.Query().Get("rollup") == "true", r.URL.Query().Get("group_by") == "category", int(forecastWeeks))
 		if err != nil {
 			http.Error(w, "could not fetch chart data", http.StatusInternalServerError)
 			return
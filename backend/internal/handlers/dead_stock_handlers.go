@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/xuri/excelize/v2"
+
+	"stock-in-order/backend/internal/middleware"
+	"stock-in-order/backend/internal/models"
+	"stock-in-order/backend/internal/rabbitmq"
+)
+
+// deadStockOptionsFromRequest lee ?days= (período sin ventas, 90 por defecto) y ?max_turnover=
+// (rotación mínima, 0,5 por defecto; 0 lista sólo los productos sin ventas).
+func deadStockOptionsFromRequest(r *http.Request) (models.DeadStockOptions, string) {
+	opts := models.DeadStockOptions{MaxTurnover: models.DefaultDeadStockMaxTurnover}
+	days, err := queryInt64(r, "days")
+	if err != nil || days < 0 || days > models.MaxDeadStockDays {
+		return opts, "days must be between 1 and 730"
+	}
+	opts.Days = int(days)
+	if s := r.URL.Query().Get("max_turnover"); s != "" {
+		turnover, err := strconv.ParseFloat(s, 64)
+		if err != nil || turnover < 0 {
+			return opts, "max_turnover must be a number >= 0"
+		}
+		opts.MaxTurnover = turnover
+	}
+	return opts, ""
+}
+
+// GetDeadStock handles GET /api/v1/reports/dead-stock?days=&max_turnover=
+// Lista los productos sin ventas en el período o de baja rotación, con su stock valorizado al costo.
+func GetDeadStock(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		opts, msg := deadStockOptionsFromRequest(r)
+		if msg != "" {
+			http.Error(w, msg, http.StatusBadRequest)
+			return
+		}
+
+		dm := &models.DeadStockModel{DB: db}
+		report, err := dm.Get(userID, opts)
+		if err != nil {
+			http.Error(w, "could not build dead stock report", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(report)
+	}
+}
+
+// ExportDeadStockXLSX maneja GET /api/v1/reports/dead-stock/xlsx?days=&max_turnover=
+// Genera el reporte de stock inmovilizado para decidir qué liquidar
+func ExportDeadStockXLSX(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		opts, msg := deadStockOptionsFromRequest(r)
+		if msg != "" {
+			http.Error(w, msg, http.StatusBadRequest)
+			return
+		}
+
+		dm := &models.DeadStockModel{DB: db}
+		report, err := dm.Get(userID, opts)
+		if err != nil {
+			http.Error(w, "could not build dead stock report", http.StatusInternalServerError)
+			return
+		}
+
+		f := excelize.NewFile()
+		defer func() {
+			_ = f.Close()
+		}()
+
+		sheetName := "Stock Inmovilizado"
+		index, err := f.NewSheet(sheetName)
+		if err != nil {
+			http.Error(w, "could not create Excel sheet", http.StatusInternalServerError)
+			return
+		}
+		f.SetActiveSheet(index)
+
+		headers := []string{"ID", "SKU", "Nombre", "Estado", "Cantidad", "Unidad", "Costo Unitario", "Valor", "Vendido en el Período", "Rotación", "Última Venta", "Última Recepción", "Días sin Ventas"}
+		for i, header := range headers {
+			cell, _ := excelize.CoordinatesToCellName(i+1, 1)
+			f.SetCellValue(sheetName, cell, header)
+		}
+
+		for rowIndex, p := range report.Products {
+			row := strconv.Itoa(rowIndex + 2)
+			f.SetCellValue(sheetName, "A"+row, p.ProductID)
+			f.SetCellValue(sheetName, "B"+row, p.SKU)
+			f.SetCellValue(sheetName, "C"+row, p.Name)
+			f.SetCellValue(sheetName, "D"+row, deadStockStatusLabel(p.Status))
+			f.SetCellValue(sheetName, "E"+row, p.OnHand)
+			f.SetCellValue(sheetName, "F"+row, p.BaseUnit)
+			f.SetCellValue(sheetName, "G"+row, p.UnitCost)
+			f.SetCellValue(sheetName, "H"+row, p.Value)
+			f.SetCellValue(sheetName, "I"+row, p.SoldInPeriod)
+			f.SetCellValue(sheetName, "J"+row, p.Turnover)
+			f.SetCellValue(sheetName, "K"+row, dateLabel(p.LastSaleDate))
+			f.SetCellValue(sheetName, "L"+row, dateLabel(p.LastReceiptDate))
+			if p.DaysSinceLastSale != nil {
+				f.SetCellValue(sheetName, "M"+row, *p.DaysSinceLastSale)
+			}
+		}
+
+		// Fila de total y parámetros del reporte
+		totalRow := strconv.Itoa(len(report.Products) + 2)
+		f.SetCellValue(sheetName, "G"+totalRow, "Total")
+		f.SetCellValue(sheetName, "H"+totalRow, report.TotalValue)
+		f.SetCellValue(sheetName, "O1", "Período (días)")
+		f.SetCellValue(sheetName, "P1", report.Days)
+		f.SetCellValue(sheetName, "O2", "Rotación mínima")
+		f.SetCellValue(sheetName, "P2", report.MaxTurnover)
+		f.SetCellValue(sheetName, "O3", "Generado el")
+		f.SetCellValue(sheetName, "P3", report.AsOf.Format("2006-01-02 15:04:05"))
+
+		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		w.Header().Set("Content-Disposition", "attachment; filename=\"stock_inmovilizado.xlsx\"")
+
+		if err := f.Write(w); err != nil {
+			http.Error(w, "could not write Excel file", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// RequestDeadStockReportByEmail maneja POST /api/v1/reports/dead-stock/email?days=&max_turnover=
+// Publica un mensaje a RabbitMQ para que el worker genere el reporte y lo envíe por email
+func RequestDeadStockReportByEmail(db *pgxpool.Pool, rabbit *rabbitmq.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		opts, msg := deadStockOptionsFromRequest(r)
+		if msg != "" {
+			http.Error(w, msg, http.StatusBadRequest)
+			return
+		}
+
+		um := &models.UserModel{DB: db}
+		user, err := um.GetByID(userID)
+		if err != nil {
+			http.Error(w, "could not fetch user info", http.StatusInternalServerError)
+			return
+		}
+
+		maxTurnover := opts.MaxTurnover
+		req := rabbitmq.ReportRequest{
+			UserID:      userID,
+			Email:       user.Email,
+			ReportType:  "dead_stock",
+			Days:        opts.Days,
+			MaxTurnover: &maxTurnover,
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		if err := rabbit.PublishReportRequest(ctx, req); err != nil {
+			http.Error(w, "could not queue report request", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"message": "Tu reporte se está generando y te llegará por email en unos minutos.",
+		})
+	}
+}
+
+// deadStockStatusLabel traduce el estado del producto para el reporte.
+func deadStockStatusLabel(status string) string {
+	if status == models.DeadStockDead {
+		return "Sin ventas"
+	}
+	return "Baja rotación"
+}
+
+// dateLabel muestra una fecha opcional en los reportes ("" si no hay).
+func dateLabel(at *time.Time) string {
+	if at == nil {
+		return ""
+	}
+	return at.Format("2006-01-02")
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"stock-in-order/backend/internal/middleware"
+	"stock-in-order/backend/internal/models"
+)
+
+// forecastOptionsFromRequest lee ?weeks= (1 a 52, 8 por defecto) y ?lead_time_days= (plazo de
+// reposición usado para sugerir el stock mínimo, 7 por defecto).
+func forecastOptionsFromRequest(r *http.Request) (models.ForecastOptions, string) {
+	var opts models.ForecastOptions
+	weeks, err := queryInt64(r, "weeks")
+	if err != nil || weeks < 0 || weeks > models.MaxForecastWeeks {
+		return opts, "weeks must be between 1 and 52"
+	}
+	leadTime, err := queryInt64(r, "lead_time_days")
+	if err != nil || leadTime < 0 || leadTime > 365 {
+		return opts, "lead_time_days must be between 1 and 365"
+	}
+	opts.Weeks = int(weeks)
+	opts.LeadTimeDays = int(leadTime)
+	return opts, ""
+}
+
+// GetProductForecasts handles GET /api/v1/forecast/products?weeks=&lead_time_days=
+// Pronostica la demanda de todos los productos, los que se agotan antes primero.
+func GetProductForecasts(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		opts, msg := forecastOptionsFromRequest(r)
+		if msg != "" {
+			http.Error(w, msg, http.StatusBadRequest)
+			return
+		}
+
+		fm := &models.ForecastModel{DB: db}
+		forecasts, err := fm.GetForUser(userID, opts)
+		if err != nil {
+			http.Error(w, "could not build forecast", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(forecasts)
+	}
+}
+
+// GetProductForecast handles GET /api/v1/products/{id}/forecast?weeks=&lead_time_days=
+// Incluye el historial semanal de demanda sobre el que se ajustó el modelo.
+func GetProductForecast(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+
+		opts, msg := forecastOptionsFromRequest(r)
+		if msg != "" {
+			http.Error(w, msg, http.StatusBadRequest)
+			return
+		}
+
+		fm := &models.ForecastModel{DB: db}
+		f, err := fm.GetForProduct(id, userID, opts)
+		if err != nil {
+			if err == models.ErrNotFound {
+				http.NotFound(w, r)
+				return
+			}
+			http.Error(w, "could not build forecast", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(f)
+	}
+}
+
+// ApplySuggestedStockMinimo handles POST /api/v1/products/{id}/forecast/apply?lead_time_days=
+// Reemplaza el stock_minimo del producto por el sugerido a partir del pronóstico.
+func ApplySuggestedStockMinimo(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+
+		opts, msg := forecastOptionsFromRequest(r)
+		if msg != "" {
+			http.Error(w, msg, http.StatusBadRequest)
+			return
+		}
+
+		fm := &models.ForecastModel{DB: db}
+		f, err := fm.ApplySuggestedMinimum(id, userID, opts)
+		if err != nil {
+			if err == models.ErrNotFound {
+				http.NotFound(w, r)
+				return
+			}
+			http.Error(w, "could not apply suggested stock minimo", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(f)
+	}
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"stock-in-order/backend/internal/middleware"
+	"stock-in-order/backend/internal/models"
+)
+
+// KitComponentInput DTO de un componente de kit (cantidad en unidad base del componente por kit)
+type KitComponentInput struct {
+	ComponentID int64   `json:"component_id"`
+	Quantity    float64 `json:"quantity"`
+}
+
+// SetKitComponentsInput DTO para reemplazar los componentes de un kit
+type SetKitComponentsInput struct {
+	Components []KitComponentInput `json:"components"`
+}
+
+func kitComponentsFromInput(in []KitComponentInput) []models.KitComponent {
+	out := make([]models.KitComponent, 0, len(in))
+	for _, c := range in {
+		out = append(out, models.KitComponent{ComponentID: c.ComponentID, Quantity: c.Quantity})
+	}
+	return out
+}
+
+// GetProductComponents handles GET /api/v1/products/{id}/components
+func GetProductComponents(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+
+		pm := &models.ProductModel{DB: db}
+		components, err := pm.GetComponents(id, userID)
+		if err != nil {
+			if err == models.ErrNotFound {
+				http.NotFound(w, r)
+				return
+			}
+			http.Error(w, "could not fetch components", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(components)
+	}
+}
+
+// SetProductComponents handles PUT /api/v1/products/{id}/components
+func SetProductComponents(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+
+		var in SetKitComponentsInput
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		pm := &models.ProductModel{DB: db}
+		if err := pm.SetComponents(id, userID, kitComponentsFromInput(in.Components)); err != nil {
+			if err == models.ErrNotFound {
+				http.NotFound(w, r)
+				return
+			}
+			if err == models.ErrInvalidKitComponent {
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "product is not a kit or components are invalid"})
+				return
+			}
+			http.Error(w, "could not update components", http.StatusInternalServerError)
+			return
+		}
+
+		components, err := pm.GetComponents(id, userID)
+		if err != nil {
+			http.Error(w, "could not fetch components", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(components)
+	}
+}
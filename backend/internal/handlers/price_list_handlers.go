@@ -0,0 +1,367 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"stock-in-order/backend/internal/middleware"
+	"stock-in-order/backend/internal/models"
+)
+
+// DTOs for price lists
+
+type PriceListItemInput struct {
+	ProductID   int64   `json:"product_id"`
+	MinQuantity float64 `json:"min_quantity"` // en unidad base; 0 = desde la primera unidad
+	UnitPrice   float64 `json:"unit_price"`   // por unidad base
+}
+
+type PriceListInput struct {
+	Name      string               `json:"name"`
+	ValidFrom string               `json:"valid_from"` // YYYY-MM-DD, vacío = sin inicio
+	ValidTo   string               `json:"valid_to"`   // YYYY-MM-DD, vacío = sin vencimiento
+	IsDefault bool                 `json:"is_default"`
+	Items     []PriceListItemInput `json:"items"`
+}
+
+type PriceFloorPolicyInput struct {
+	Policy string `json:"policy"` // "reject" o "flag"
+}
+
+// parseOptionalDate lee una fecha YYYY-MM-DD; vacío devuelve nil.
+func parseOptionalDate(s string) (*time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// priceListFromInput valida el cuerpo y arma la lista; devuelve el motivo si no es válido.
+func priceListFromInput(in PriceListInput) (*models.PriceList, string) {
+	name := strings.TrimSpace(in.Name)
+	if name == "" {
+		return nil, "name is required"
+	}
+	from, err := parseOptionalDate(in.ValidFrom)
+	if err != nil {
+		return nil, "valid_from must be YYYY-MM-DD"
+	}
+	to, err := parseOptionalDate(in.ValidTo)
+	if err != nil {
+		return nil, "valid_to must be YYYY-MM-DD"
+	}
+
+	pl := &models.PriceList{Name: name, ValidFrom: from, ValidTo: to, IsDefault: in.IsDefault}
+	for _, it := range in.Items {
+		pl.Items = append(pl.Items, models.PriceListItem{
+			ProductID:   it.ProductID,
+			MinQuantity: it.MinQuantity,
+			UnitPrice:   it.UnitPrice,
+		})
+	}
+	return pl, ""
+}
+
+// writePriceListError traduce los errores del modelo de listas de precios.
+func writePriceListError(w http.ResponseWriter, r *http.Request, err error, fallback string) {
+	switch err {
+	case models.ErrPriceListNotFound:
+		http.NotFound(w, r)
+	case models.ErrDuplicatePriceList:
+		w.WriteHeader(http.StatusConflict)
+		_ = json.NewEncoder(w).Encode(map[string]any{"error": "price list name already exists"})
+	case models.ErrInvalidPriceListItem:
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]any{"error": "items need a product of your own, non-negative min_quantity and unit_price, and one price per product and min_quantity"})
+	case models.ErrInvalidPriceListPeriod:
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]any{"error": "valid_to cannot be before valid_from"})
+	case models.ErrHasReferences:
+		w.WriteHeader(http.StatusConflict)
+		_ = json.NewEncoder(w).Encode(map[string]any{"error": "price list is assigned to customers or used in sales orders"})
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+// ListPriceLists handles GET /api/v1/price-lists
+func ListPriceLists(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		plm := &models.PriceListModel{DB: db}
+		lists, err := plm.GetAllForUser(userID)
+		if err != nil {
+			http.Error(w, "could not fetch price lists", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(lists)
+	}
+}
+
+// GetPriceList handles GET /api/v1/price-lists/{id}
+func GetPriceList(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+
+		plm := &models.PriceListModel{DB: db}
+		pl, err := plm.GetByID(id, userID)
+		if err != nil {
+			writePriceListError(w, r, err, "could not fetch price list")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(pl)
+	}
+}
+
+// CreatePriceList handles POST /api/v1/price-lists
+func CreatePriceList(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var in PriceListInput
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+		pl, msg := priceListFromInput(in)
+		if msg != "" {
+			http.Error(w, msg, http.StatusBadRequest)
+			return
+		}
+		pl.UserID = userID
+
+		plm := &models.PriceListModel{DB: db}
+		if err := plm.Insert(pl); err != nil {
+			writePriceListError(w, r, err, "could not create price list")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(pl)
+	}
+}
+
+// UpdatePriceList handles PUT /api/v1/price-lists/{id}
+// Reemplaza la lista completa, ítems incluidos.
+func UpdatePriceList(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+
+		var in PriceListInput
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+		pl, msg := priceListFromInput(in)
+		if msg != "" {
+			http.Error(w, msg, http.StatusBadRequest)
+			return
+		}
+
+		plm := &models.PriceListModel{DB: db}
+		if err := plm.Update(id, userID, pl); err != nil {
+			writePriceListError(w, r, err, "could not update price list")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// DeletePriceList handles DELETE /api/v1/price-lists/{id}
+func DeletePriceList(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+
+		plm := &models.PriceListModel{DB: db}
+		if err := plm.Delete(id, userID); err != nil {
+			writePriceListError(w, r, err, "could not delete price list")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// QuotePrice handles GET /api/v1/price-lists/quote?product_id=&customer_id=&quantity=
+// Devuelve el precio por unidad base que tomaría hoy una orden de venta sin unit_price.
+func QuotePrice(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		productID, err := queryInt64(r, "product_id")
+		if err != nil || productID <= 0 {
+			http.Error(w, "product_id is required", http.StatusBadRequest)
+			return
+		}
+		customerID, err := queryInt64(r, "customer_id")
+		if err != nil {
+			http.Error(w, "invalid customer_id", http.StatusBadRequest)
+			return
+		}
+		quantity := 1.0
+		if s := r.URL.Query().Get("quantity"); s != "" {
+			quantity, err = strconv.ParseFloat(s, 64)
+			if err != nil || quantity <= 0 {
+				http.Error(w, "quantity must be > 0", http.StatusBadRequest)
+				return
+			}
+		}
+
+		plm := &models.PriceListModel{DB: db}
+		quote, err := plm.Quote(userID, customerID, productID, quantity)
+		if err != nil {
+			if err == models.ErrNotFound {
+				http.NotFound(w, r)
+				return
+			}
+			http.Error(w, "could not quote price", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(quote)
+	}
+}
+
+// GetPriceFloorPolicy handles GET /api/v1/price-lists/policy
+func GetPriceFloorPolicy(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		plm := &models.PriceListModel{DB: db}
+		policy, err := plm.GetPolicy(userID)
+		if err != nil {
+			http.Error(w, "could not fetch price floor policy", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(PriceFloorPolicyInput{Policy: policy})
+	}
+}
+
+// SetPriceFloorPolicy handles PUT /api/v1/price-lists/policy
+// "reject" rechaza las órdenes con líneas bajo el precio mínimo; "flag" las acepta marcándolas.
+func SetPriceFloorPolicy(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var in PriceFloorPolicyInput
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		plm := &models.PriceListModel{DB: db}
+		if err := plm.SetPolicy(userID, strings.ToLower(strings.TrimSpace(in.Policy))); err != nil {
+			if err == models.ErrInvalidPriceFloorRule {
+				http.Error(w, "policy must be reject or flag", http.StatusBadRequest)
+				return
+			}
+			if err == models.ErrNotFound {
+				http.NotFound(w, r)
+				return
+			}
+			http.Error(w, "could not update price floor policy", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// SetCustomerPriceList handles PUT /api/v1/customers/{id}/price-list
+// Body {"price_list_id": n}; null o 0 vuelve a la lista por defecto.
+func SetCustomerPriceList(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+
+		var in struct {
+			PriceListID *int64 `json:"price_list_id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if in.PriceListID != nil && *in.PriceListID == 0 {
+			in.PriceListID = nil
+		}
+
+		cm := &models.CustomerModel{DB: db}
+		if err := cm.SetPriceList(id, userID, in.PriceListID); err != nil {
+			if err == models.ErrPriceListNotFound {
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "price list not found"})
+				return
+			}
+			if err == models.ErrNotFound {
+				http.NotFound(w, r)
+				return
+			}
+			http.Error(w, "could not update customer price list", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
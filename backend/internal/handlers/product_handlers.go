@@ -2,15 +2,18 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"stock-in-order/backend/internal/middleware"
 	"stock-in-order/backend/internal/models"
+	"stock-in-order/backend/internal/storage"
 )
 
 // CreateProduct handles POST /api/v1/products
@@ -23,11 +26,29 @@ func CreateProduct(db *pgxpool.Pool) http.HandlerFunc {
 		}
 
 		var in struct {
-			Name        string `json:"name"`
-			SKU         string `json:"sku"`
-			Description string `json:"description"`
-			Quantity    int    `json:"quantity"`
-			StockMinimo int    `json:"stock_minimo"`
+			Name         string  `json:"name"`
+			SKU          string  `json:"sku"`
+			Description  string  `json:"description"`
+			Quantity     float64 `json:"quantity"`
+			StockMinimo  float64 `json:"stock_minimo"`
+			IsSerialized bool    `json:"is_serialized"`
+			BaseUnit     string  `json:"base_unit"` // vacío = "unidad"
+
+			// Kits: los componentes son obligatorios si is_kit es true
+			IsKit      bool                `json:"is_kit"`
+			Components []KitComponentInput `json:"components"`
+
+			// Variantes
+			ParentID      *int64            `json:"parent_id"`
+			Attributes    map[string]string `json:"attributes"`
+			MLVariationID *int64            `json:"ml_variation_id"`
+
+			// Clasificación
+			CategoryID *int64   `json:"category_id"`
+			Tags       []string `json:"tags"`
+
+			// Precio mínimo de venta por unidad base
+			PriceFloor *float64 `json:"price_floor"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
 			http.Error(w, "invalid JSON", http.StatusBadRequest)
@@ -40,14 +61,29 @@ func CreateProduct(db *pgxpool.Pool) http.HandlerFunc {
 			_ = json.NewEncoder(w).Encode(map[string]any{"error": "stock_minimo cannot be negative"})
 			return
 		}
+		if in.PriceFloor != nil && *in.PriceFloor < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]any{"error": "price_floor cannot be negative"})
+			return
+		}
 
 		p := &models.Product{
-			Name:        in.Name,
-			SKU:         in.SKU,
-			Description: &in.Description,
-			Quantity:    in.Quantity,
-			StockMinimo: in.StockMinimo,
-			UserID:      userID,
+			Name:          in.Name,
+			SKU:           in.SKU,
+			Description:   &in.Description,
+			Quantity:      in.Quantity,
+			StockMinimo:   in.StockMinimo,
+			UserID:        userID,
+			IsSerialized:  in.IsSerialized,
+			BaseUnit:      in.BaseUnit,
+			IsKit:         in.IsKit,
+			Components:    kitComponentsFromInput(in.Components),
+			ParentID:      in.ParentID,
+			Attributes:    in.Attributes,
+			MLVariationID: in.MLVariationID,
+			CategoryID:    in.CategoryID,
+			Tags:          in.Tags,
+			PriceFloor:    in.PriceFloor,
 		}
 
 		pm := &models.ProductModel{DB: db}
@@ -57,6 +93,26 @@ func CreateProduct(db *pgxpool.Pool) http.HandlerFunc {
 				_ = json.NewEncoder(w).Encode(map[string]any{"error": "sku already exists"})
 				return
 			}
+			if err == models.ErrInvalidParent {
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "parent product not found or is itself a variant"})
+				return
+			}
+			if err == models.ErrDuplicateVariation {
+				w.WriteHeader(http.StatusConflict)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "ml_variation_id already mapped to another product"})
+				return
+			}
+			if err == models.ErrInvalidKitComponent {
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "kits require distinct components of your own that are not kits, with quantity > 0"})
+				return
+			}
+			if err == models.ErrCategoryNotFound {
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "category not found"})
+				return
+			}
 			http.Error(w, "could not create product", http.StatusInternalServerError)
 			return
 		}
@@ -67,7 +123,35 @@ func CreateProduct(db *pgxpool.Pool) http.HandlerFunc {
 	}
 }
 
-// ListProducts handles GET /api/v1/products
+// productFilterFromRequest lee los filtros ?category_id= (incluye subcategorías), ?tag=, ?archived=,
+// ?abc_class= y ?xyz_class= de la query string.
+func productFilterFromRequest(r *http.Request) (models.ProductFilter, error) {
+	categoryID, err := queryInt64(r, "category_id")
+	if err != nil {
+		return models.ProductFilter{}, errors.New("invalid category_id")
+	}
+	archived, ok := archiveFilterFromRequest(r)
+	if !ok {
+		return models.ProductFilter{}, errors.New("archived must be only or include")
+	}
+	abcClass := strings.ToUpper(r.URL.Query().Get("abc_class"))
+	if abcClass != "" && !models.ValidAbcClass(abcClass) {
+		return models.ProductFilter{}, errors.New("abc_class must be A, B or C")
+	}
+	xyzClass := strings.ToUpper(r.URL.Query().Get("xyz_class"))
+	if xyzClass != "" && !models.ValidXyzClass(xyzClass) {
+		return models.ProductFilter{}, errors.New("xyz_class must be X, Y or Z")
+	}
+	return models.ProductFilter{
+		CategoryID: categoryID,
+		Tag:        r.URL.Query().Get("tag"),
+		Archived:   archived,
+		AbcClass:   abcClass,
+		XyzClass:   xyzClass,
+	}, nil
+}
+
+// ListProducts handles GET /api/v1/products?category_id=&tag=&archived=&abc_class=&xyz_class=
 func ListProducts(db *pgxpool.Pool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userID, ok := middleware.UserIDFromContext(r.Context())
@@ -76,13 +160,24 @@ func ListProducts(db *pgxpool.Pool) http.HandlerFunc {
 			return
 		}
 
+		filter, err := productFilterFromRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		pm := &models.ProductModel{DB: db}
-		items, err := pm.GetAllForUser(userID)
+		items, err := pm.GetFiltered(userID, filter)
 		if err != nil {
 			slog.Error("ListProducts failed", "error", err, "userID", userID)
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+
+		// ?rollup=true agrupa las variantes bajo su producto padre
+		if r.URL.Query().Get("rollup") == "true" {
+			items = models.RollUpVariants(items)
+		}
 		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(items)
 	}
@@ -129,11 +224,25 @@ func UpdateProduct(db *pgxpool.Pool) http.HandlerFunc {
 		id, _ := strconv.ParseInt(vars["id"], 10, 64)
 
 		var in struct {
-			Name        string `json:"name"`
-			SKU         string `json:"sku"`
-			Description string `json:"description"`
-			Quantity    int    `json:"quantity"`
-			StockMinimo int    `json:"stock_minimo"`
+			Name         string   `json:"name"`
+			SKU          string   `json:"sku"`
+			Description  string   `json:"description"`
+			Quantity     *float64 `json:"quantity"` // nil = sin cambios; una diferencia se rechaza: el stock se ajusta con adjust-stock
+			StockMinimo  float64  `json:"stock_minimo"`
+			IsSerialized *bool    `json:"is_serialized"` // nil = sin cambios
+			BaseUnit     string   `json:"base_unit"`     // vacío = sin cambios
+
+			// Variantes: nil = sin cambios; parent_id / ml_variation_id en 0 los quitan
+			ParentID      *int64            `json:"parent_id"`
+			Attributes    map[string]string `json:"attributes"`
+			MLVariationID *int64            `json:"ml_variation_id"`
+
+			// Clasificación: nil = sin cambios; category_id en 0 la quita y tags vacío quita las etiquetas
+			CategoryID *int64   `json:"category_id"`
+			Tags       []string `json:"tags"`
+
+			// Precio mínimo de venta por unidad base: nil = sin cambios, 0 lo quita
+			PriceFloor *float64 `json:"price_floor"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
 			http.Error(w, "invalid JSON", http.StatusBadRequest)
@@ -146,16 +255,73 @@ func UpdateProduct(db *pgxpool.Pool) http.HandlerFunc {
 			_ = json.NewEncoder(w).Encode(map[string]any{"error": "stock_minimo cannot be negative"})
 			return
 		}
+		if in.PriceFloor != nil && *in.PriceFloor < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]any{"error": "price_floor cannot be negative"})
+			return
+		}
 
 		p := &models.Product{
 			Name:        in.Name,
 			SKU:         in.SKU,
 			Description: &in.Description,
-			Quantity:    in.Quantity,
 			StockMinimo: in.StockMinimo,
+			BaseUnit:    in.BaseUnit,
 		}
 
+		// Los campos opcionales no enviados conservan su valor actual
 		pm := &models.ProductModel{DB: db}
+		current, err := pm.GetByID(id, userID)
+		if err != nil {
+			if err == models.ErrNotFound {
+				http.NotFound(w, r)
+				return
+			}
+			http.Error(w, "could not update product", http.StatusInternalServerError)
+			return
+		}
+		p.Quantity = current.Quantity
+		if in.Quantity != nil {
+			p.Quantity = *in.Quantity
+		}
+		p.IsSerialized = current.IsSerialized
+		if in.IsSerialized != nil {
+			p.IsSerialized = *in.IsSerialized
+		}
+		p.ParentID = current.ParentID
+		if in.ParentID != nil {
+			p.ParentID = in.ParentID
+			if *in.ParentID == 0 {
+				p.ParentID = nil
+			}
+		}
+		p.Attributes = current.Attributes
+		if in.Attributes != nil {
+			p.Attributes = in.Attributes
+		}
+		p.MLVariationID = current.MLVariationID
+		if in.MLVariationID != nil {
+			p.MLVariationID = in.MLVariationID
+			if *in.MLVariationID == 0 {
+				p.MLVariationID = nil
+			}
+		}
+		p.CategoryID = current.CategoryID
+		if in.CategoryID != nil {
+			p.CategoryID = in.CategoryID
+			if *in.CategoryID == 0 {
+				p.CategoryID = nil
+			}
+		}
+		p.Tags = in.Tags
+		p.PriceFloor = current.PriceFloor
+		if in.PriceFloor != nil {
+			p.PriceFloor = in.PriceFloor
+			if *in.PriceFloor == 0 {
+				p.PriceFloor = nil
+			}
+		}
+
 		if err := pm.Update(id, userID, p); err != nil {
 			if err == models.ErrNotFound {
 				http.NotFound(w, r)
@@ -166,6 +332,31 @@ func UpdateProduct(db *pgxpool.Pool) http.HandlerFunc {
 				_ = json.NewEncoder(w).Encode(map[string]any{"error": "sku already exists"})
 				return
 			}
+			if err == models.ErrQuantityNotEditable {
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "quantity cannot be edited; use POST /products/{id}/adjust-stock"})
+				return
+			}
+			if err == models.ErrBaseUnitNotEditable {
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "base unit cannot change once the product has stock, units, lots or movements"})
+				return
+			}
+			if err == models.ErrInvalidParent {
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "parent product not found, is itself a variant, or product has variants"})
+				return
+			}
+			if err == models.ErrDuplicateVariation {
+				w.WriteHeader(http.StatusConflict)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "ml_variation_id already mapped to another product"})
+				return
+			}
+			if err == models.ErrCategoryNotFound {
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "category not found"})
+				return
+			}
 			http.Error(w, "could not update product", http.StatusInternalServerError)
 			return
 		}
@@ -175,7 +366,7 @@ func UpdateProduct(db *pgxpool.Pool) http.HandlerFunc {
 }
 
 // DeleteProduct handles DELETE /api/v1/products/{id}
-func DeleteProduct(db *pgxpool.Pool) http.HandlerFunc {
+func DeleteProduct(db *pgxpool.Pool, store storage.Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userID, ok := middleware.UserIDFromContext(r.Context())
 		if !ok {
@@ -186,6 +377,14 @@ func DeleteProduct(db *pgxpool.Pool) http.HandlerFunc {
 		vars := mux.Vars(r)
 		id, _ := strconv.ParseInt(vars["id"], 10, 64)
 
+		// Los adjuntos se leen antes de borrar: sus registros caen en cascada con el producto
+		am := &models.AttachmentModel{DB: db}
+		attachments, err := am.GetForProduct(id, userID)
+		if err != nil && err != models.ErrNotFound {
+			http.Error(w, "could not fetch attachments", http.StatusInternalServerError)
+			return
+		}
+
 		pm := &models.ProductModel{DB: db}
 		if err := pm.Delete(id, userID); err != nil {
 			if err == models.ErrNotFound {
@@ -195,7 +394,7 @@ func DeleteProduct(db *pgxpool.Pool) http.HandlerFunc {
 			if err == models.ErrHasReferences {
 				w.WriteHeader(http.StatusConflict)
 				_ = json.NewEncoder(w).Encode(map[string]string{
-					"error": "No se puede eliminar el producto porque está siendo usado en órdenes de venta o compra",
+					"error": "No se puede eliminar el producto porque está siendo usado en órdenes de venta o compra; archivalo en su lugar",
 				})
 				return
 			}
@@ -204,6 +403,9 @@ func DeleteProduct(db *pgxpool.Pool) http.HandlerFunc {
 			return
 		}
 
+		for _, a := range attachments {
+			deleteStoredFiles(r, store, a.StorageKeys())
+		}
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
@@ -231,17 +433,72 @@ func GetProductMovements(db *pgxpool.Pool) http.HandlerFunc {
 	}
 }
 
-// Constante estandarizada para razón de ajuste manual
-const ReasonManualAdjustment = "MANUAL_ADJUSTMENT"
+// GetProductVariants maneja GET /api/v1/products/{id}/variants
+func GetProductVariants(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+
+		pm := &models.ProductModel{DB: db}
+		variants, err := pm.GetVariants(id, userID)
+		if err != nil {
+			if err == models.ErrNotFound {
+				http.NotFound(w, r)
+				return
+			}
+			http.Error(w, "could not fetch variants", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(variants)
+	}
+}
+
+// GetProductLots maneja GET /api/v1/products/{id}/lots
+func GetProductLots(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+
+		lm := &models.LotModel{DB: db}
+		lots, err := lm.GetForProduct(id, userID)
+		if err != nil {
+			if err == models.ErrNotFound {
+				http.NotFound(w, r)
+				return
+			}
+			http.Error(w, "could not fetch lots", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(lots)
+	}
+}
 
 // StockAdjustmentInput DTO para ajuste manual
 type StockAdjustmentInput struct {
-	QuantityChange int    `json:"quantity_change" validate:"required,ne=0"`
-	Reason         string `json:"reason" validate:"required"`
+	QuantityChange float64 `json:"quantity_change" validate:"required,ne=0"` // en unidad base
+	ReasonCode     string  `json:"reason_code" validate:"required"`          // motivo del catálogo (GET /adjustment-reasons)
+	Note           string  `json:"note"`
+	WarehouseID    int64   `json:"warehouse_id"` // 0 = depósito por defecto
 }
 
 // AdjustProductStock maneja POST /api/v1/products/{id}/adjust-stock
-func AdjustProductStock(db *pgxpool.Pool) http.HandlerFunc {
+// Responde 201 con el ajuste registrado, o 202 si supera los límites de policy y queda pendiente
+// de aprobación de un admin (POST /stock-adjustments/{id}/approve).
+func AdjustProductStock(db *pgxpool.Pool, policy models.AdjustmentPolicy) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userID, ok := middleware.UserIDFromContext(r.Context())
 		if !ok {
@@ -263,22 +520,68 @@ func AdjustProductStock(db *pgxpool.Pool) http.HandlerFunc {
 			return
 		}
 
-		// Normalizamos la razón; si viene vacía la reemplazamos por la constante
-		reason := in.Reason
-		if reason == "" {
-			reason = ReasonManualAdjustment
+		pm := &models.ProductModel{DB: db}
+		adj, err := pm.AdjustStock(id, userID, in.WarehouseID, in.QuantityChange, in.ReasonCode, in.Note, policy)
+		if err != nil {
+			writeStockAdjustmentError(w, r, err)
+			return
 		}
 
+		w.Header().Set("Content-Type", "application/json")
+		if adj.Status == models.AdjustmentPending {
+			w.WriteHeader(http.StatusAccepted)
+		} else {
+			w.WriteHeader(http.StatusCreated)
+		}
+		_ = json.NewEncoder(w).Encode(adj)
+	}
+}
+
+// ArchiveProduct handles POST /api/v1/products/{id}/archive
+// Archiva el producto y sus variantes: dejan de listarse y venderse, pero siguen en el historial.
+func ArchiveProduct(db *pgxpool.Pool) http.HandlerFunc {
+	return setProductArchived(db, true)
+}
+
+// RestoreProduct handles POST /api/v1/products/{id}/restore
+func RestoreProduct(db *pgxpool.Pool) http.HandlerFunc {
+	return setProductArchived(db, false)
+}
+
+func setProductArchived(db *pgxpool.Pool, archive bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+
 		pm := &models.ProductModel{DB: db}
-		if err := pm.AdjustStock(id, userID, in.QuantityChange, reason); err != nil {
+		var err error
+		if archive {
+			err = pm.Archive(id, userID)
+		} else {
+			err = pm.Restore(id, userID)
+		}
+		if err != nil {
 			if err == models.ErrNotFound {
 				http.NotFound(w, r)
 				return
 			}
-			http.Error(w, "could not adjust stock", http.StatusInternalServerError)
+			slog.Error("setProductArchived failed", "error", err, "productID", id, "userID", userID)
+			http.Error(w, "could not update product", http.StatusInternalServerError)
 			return
 		}
 
-		w.WriteHeader(http.StatusNoContent)
+		p, err := pm.GetByID(id, userID)
+		if err != nil {
+			http.Error(w, "could not fetch product", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(p)
 	}
 }
@@ -0,0 +1,316 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/xuri/excelize/v2"
+
+	"stock-in-order/backend/internal/middleware"
+	"stock-in-order/backend/internal/models"
+	"stock-in-order/backend/internal/rabbitmq"
+)
+
+// Límites de la importación masiva de productos
+const (
+	MaxImportBytes = 10 << 20
+	MaxImportRows  = 50000
+
+	// ImportSyncMaxRows es la cantidad de filas hasta la que se importa dentro del request;
+	// los archivos más grandes se procesan en el worker.
+	ImportSyncMaxRows = 500
+)
+
+// ImportProductsRequest es la tarea que procesa el worker en la cola de alertas de stock.
+type ImportProductsRequest struct {
+	TaskType string `json:"task_type"`
+	ImportID int64  `json:"import_id"`
+}
+
+// Columnas que se importan (las mismas cabeceras que genera ExportProductsXLSX). ID, Fecha de
+// Creación, Variantes y Archivado se ignoran: el producto se identifica por SKU.
+const (
+	importColumnName        = "nombre"
+	importColumnSKU         = "sku"
+	importColumnDescription = "descripción"
+	importColumnQuantity    = "cantidad"
+	importColumnParent      = "producto padre"
+	importColumnCategory    = "categoría"
+	importColumnTags        = "etiquetas"
+)
+
+// importColumnAliases acepta las cabeceras escritas sin tilde.
+var importColumnAliases = map[string]string{
+	"descripcion": importColumnDescription,
+	"categoria":   importColumnCategory,
+}
+
+// parseImportFile lee un CSV (separado por coma o punto y coma) o un XLSX (la hoja "Productos"
+// o la primera) según la extensión del archivo.
+func parseImportFile(fileName string, data []byte) (models.ImportColumns, []models.ImportRow, error) {
+	var records [][]string
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".csv":
+		data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")) // BOM de Excel
+		firstLine, _, _ := bytes.Cut(data, []byte("\n"))
+		reader := csv.NewReader(bytes.NewReader(data))
+		if bytes.Count(firstLine, []byte(";")) > bytes.Count(firstLine, []byte(",")) {
+			reader.Comma = ';'
+		}
+		reader.FieldsPerRecord = -1
+		var err error
+		if records, err = reader.ReadAll(); err != nil {
+			return models.ImportColumns{}, nil, fmt.Errorf("CSV inválido: %w", err)
+		}
+	case ".xlsx":
+		f, err := excelize.OpenReader(bytes.NewReader(data))
+		if err != nil {
+			return models.ImportColumns{}, nil, errors.New("XLSX inválido")
+		}
+		defer f.Close()
+		sheet := "Productos"
+		if idx, _ := f.GetSheetIndex(sheet); idx < 0 {
+			sheet = f.GetSheetList()[0]
+		}
+		if records, err = f.GetRows(sheet); err != nil {
+			return models.ImportColumns{}, nil, errors.New("XLSX inválido")
+		}
+	default:
+		return models.ImportColumns{}, nil, errors.New("el archivo debe ser .csv o .xlsx")
+	}
+	return importRowsFromRecords(records)
+}
+
+// importRowsFromRecords convierte las filas del archivo (la primera es la cabecera) en filas de
+// importación. Nombre y SKU son obligatorias; las demás columnas son opcionales.
+func importRowsFromRecords(records [][]string) (models.ImportColumns, []models.ImportRow, error) {
+	var cols models.ImportColumns
+	if len(records) == 0 {
+		return cols, nil, errors.New("el archivo está vacío")
+	}
+
+	index := map[string]int{}
+	for i, h := range records[0] {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if alias, ok := importColumnAliases[h]; ok {
+			h = alias
+		}
+		if _, dup := index[h]; !dup {
+			index[h] = i
+		}
+	}
+	for _, required := range []string{importColumnName, importColumnSKU} {
+		if _, ok := index[required]; !ok {
+			return cols, nil, fmt.Errorf("falta la columna %q", required)
+		}
+	}
+	_, cols.Description = index[importColumnDescription]
+	_, cols.Quantity = index[importColumnQuantity]
+	_, cols.Parent = index[importColumnParent]
+	_, cols.Category = index[importColumnCategory]
+	_, cols.Tags = index[importColumnTags]
+
+	var rows []models.ImportRow
+	for i, record := range records[1:] {
+		cell := func(column string) string {
+			if idx, ok := index[column]; ok && idx < len(record) {
+				return strings.TrimSpace(record[idx])
+			}
+			return ""
+		}
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue // fila vacía
+		}
+
+		row := models.ImportRow{
+			Line:        i + 2,
+			SKU:         cell(importColumnSKU),
+			Name:        cell(importColumnName),
+			Description: cell(importColumnDescription),
+			ParentSKU:   cell(importColumnParent),
+			Category:    cell(importColumnCategory),
+		}
+		if quantity := cell(importColumnQuantity); quantity != "" {
+			// Acepta coma decimal ("1,5") además de punto
+			if !strings.Contains(quantity, ".") {
+				quantity = strings.Replace(quantity, ",", ".", 1)
+			}
+			q, err := strconv.ParseFloat(quantity, 64)
+			if err != nil {
+				row.QuantityError = fmt.Sprintf("cantidad inválida: %q", cell(importColumnQuantity))
+			}
+			row.Quantity = q
+		}
+		if tags := cell(importColumnTags); tags != "" {
+			row.Tags = strings.Split(tags, ",")
+		}
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 {
+		return cols, nil, errors.New("el archivo no tiene filas de productos")
+	}
+	return cols, rows, nil
+}
+
+// ImportProducts handles POST /api/v1/products/import?dry_run=true
+// Formulario multipart con el campo file (CSV o XLSX con las columnas de la exportación de productos).
+// Valida todas las filas y, con dry_run=true, sólo devuelve el reporte. Si no hay errores crea o
+// actualiza los productos por SKU: hasta ImportSyncMaxRows filas en una transacción dentro del request,
+// los archivos más grandes en el worker (202 con la importación para consultar su progreso).
+func ImportProducts(db *pgxpool.Pool, rabbit *rabbitmq.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, MaxImportBytes+1<<20)
+		if err := r.ParseMultipartForm(8 << 20); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(w, fmt.Sprintf("file too large (max %d MB)", MaxImportBytes>>20), http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, "invalid multipart form", http.StatusBadRequest)
+			return
+		}
+		defer func() { _ = r.MultipartForm.RemoveAll() }()
+
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, "file required", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		data, err := io.ReadAll(io.LimitReader(file, MaxImportBytes+1))
+		if err != nil {
+			http.Error(w, "could not read file", http.StatusBadRequest)
+			return
+		}
+		if len(data) > MaxImportBytes {
+			http.Error(w, fmt.Sprintf("file too large (max %d MB)", MaxImportBytes>>20), http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		writeError := func(status int, msg string) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			_ = json.NewEncoder(w).Encode(map[string]any{"error": msg})
+		}
+
+		cols, rows, err := parseImportFile(header.Filename, data)
+		if err != nil {
+			writeError(http.StatusBadRequest, err.Error())
+			return
+		}
+		if len(rows) > MaxImportRows {
+			writeError(http.StatusBadRequest, fmt.Sprintf("el archivo supera el máximo de %d filas", MaxImportRows))
+			return
+		}
+
+		im := &models.ProductImportModel{DB: db}
+		report, err := im.Validate(userID, cols, rows)
+		if err != nil {
+			http.Error(w, "could not validate import", http.StatusInternalServerError)
+			return
+		}
+
+		dryRun := r.URL.Query().Get("dry_run") == "true"
+		w.Header().Set("Content-Type", "application/json")
+		if dryRun {
+			_ = json.NewEncoder(w).Encode(map[string]any{"dry_run": true, "report": report})
+			return
+		}
+		if !report.Valid() {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"error":  "el archivo tiene errores; no se importó ningún producto",
+				"report": report,
+			})
+			return
+		}
+
+		if len(report.Rows) <= ImportSyncMaxRows {
+			created, updated, err := im.Apply(userID, cols, report.Rows)
+			if errors.Is(err, models.ErrProductArchived) {
+				// Se archivó un producto entre la validación y la importación
+				w.WriteHeader(http.StatusConflict)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": err.Error()})
+				return
+			}
+			if err != nil {
+				slog.Error("product import failed", "error", err, "user_id", userID)
+				http.Error(w, "could not import products", http.StatusInternalServerError)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"report": report, "created": created, "updated": updated})
+			return
+		}
+
+		job, err := im.CreateJob(userID, header.Filename, cols, report.Rows)
+		if err != nil {
+			http.Error(w, "could not queue import", http.StatusInternalServerError)
+			return
+		}
+		body, err := json.Marshal(ImportProductsRequest{TaskType: "import_products", ImportID: job.ID})
+		if err == nil {
+			ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+			defer cancel()
+			err = rabbit.PublishMessage(ctx, "stock_alerts_queue", body)
+		}
+		if err != nil {
+			_ = im.MarkJobFailed(job.ID, "no se pudo encolar la importación")
+			http.Error(w, "could not queue import", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"message": "La importación se está procesando.",
+			"import":  job,
+			"report":  report,
+		})
+	}
+}
+
+// GetProductImport handles GET /api/v1/products/imports/{id}
+// Devuelve el estado y el progreso de una importación en segundo plano.
+func GetProductImport(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+
+		im := &models.ProductImportModel{DB: db}
+		job, err := im.GetJob(id, userID)
+		if err != nil {
+			if err == models.ErrNotFound {
+				http.NotFound(w, r)
+				return
+			}
+			http.Error(w, "could not fetch import", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(job)
+	}
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"testing"
+)
+
+func TestParseImportFileCSV(t *testing.T) {
+	// CSV de Excel en español: BOM, punto y coma y coma decimal
+	data := []byte("\xef\xbb\xbfSKU;Nombre;Cantidad;Categoria;Etiquetas\n" +
+		"A-1;Remera;1,5;Ropa / Remeras;verano, Oferta\n" +
+		";;;;\n" +
+		"A-2;Pantalón;x;;\n")
+
+	cols, rows, err := parseImportFile("catalogo.CSV", data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cols.Quantity || !cols.Category || !cols.Tags || cols.Description || cols.Parent {
+		t.Fatalf("unexpected columns: %+v", cols)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 rows (blank row skipped), got %d", len(rows))
+	}
+
+	first := rows[0]
+	if first.Line != 2 || first.SKU != "A-1" || first.Quantity != 1.5 || first.Category != "Ropa / Remeras" || len(first.Tags) != 2 {
+		t.Fatalf("unexpected first row: %+v", first)
+	}
+	// La numeración conserva las filas vacías para que el reporte coincida con el archivo
+	if second := rows[1]; second.Line != 4 || second.QuantityError == "" {
+		t.Fatalf("expected quantity error on line 4, got %+v", second)
+	}
+}
+
+func TestParseImportFileErrors(t *testing.T) {
+	if _, _, err := parseImportFile("productos.csv", []byte("Nombre,Cantidad\nRemera,1\n")); err == nil {
+		t.Fatal("expected error for missing SKU column")
+	}
+	if _, _, err := parseImportFile("productos.csv", []byte("SKU,Nombre\n")); err == nil {
+		t.Fatal("expected error for file without rows")
+	}
+	if _, _, err := parseImportFile("productos.txt", []byte("SKU,Nombre\nA-1,Remera\n")); err == nil {
+		t.Fatal("expected error for unsupported extension")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -15,14 +16,39 @@ import (
 // DTOs for creating a purchase order
 
 type PurchaseOrderItemInput struct {
-	ProductID int64   `json:"product_id"`
-	Quantity  int     `json:"quantity"`
-	UnitCost  float64 `json:"unit_cost"`
+	ProductID     int64    `json:"product_id"`
+	Quantity      float64  `json:"quantity"` // en la unidad indicada
+	Unit          string   `json:"unit"`     // vacío = unidad base del producto
+	UnitCost      float64  `json:"unit_cost"`
+	LotNumber     string   `json:"lot_number"`
+	ExpiryDate    string   `json:"expiry_date"` // YYYY-MM-DD
+	SerialNumbers []string `json:"serial_numbers"`
+}
+
+// PurchaseOrderItemReceiptInput informa lote y series recibidos de un ítem al completar la orden
+type PurchaseOrderItemReceiptInput struct {
+	ItemID        int64    `json:"item_id"`
+	LotNumber     string   `json:"lot_number"`
+	ExpiryDate    string   `json:"expiry_date"` // YYYY-MM-DD
+	SerialNumbers []string `json:"serial_numbers"`
+}
+
+// parseExpiryDate convierte una fecha YYYY-MM-DD opcional en *time.Time
+func parseExpiryDate(s string) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
 }
 
 type CreatePurchaseOrderInput struct {
-	SupplierID int64                    `json:"supplier_id"`
-	Items      []PurchaseOrderItemInput `json:"items"`
+	SupplierID  int64                    `json:"supplier_id"`
+	WarehouseID int64                    `json:"warehouse_id"` // 0 = depósito por defecto
+	Items       []PurchaseOrderItemInput `json:"items"`
 }
 
 // CreatePurchaseOrder handles POST /api/v1/purchase-orders
@@ -45,8 +71,9 @@ func CreatePurchaseOrder(db *pgxpool.Pool) http.HandlerFunc {
 		}
 
 		order := &models.PurchaseOrder{
-			UserID: userID,
-			Status: "pending",
+			UserID:      userID,
+			Status:      "pending",
+			WarehouseID: in.WarehouseID,
 		}
 		if in.SupplierID > 0 {
 			order.SupplierID.Int64 = in.SupplierID
@@ -63,15 +90,42 @@ func CreatePurchaseOrder(db *pgxpool.Pool) http.HandlerFunc {
 				http.Error(w, "unit_cost must be >= 0", http.StatusBadRequest)
 				return
 			}
+			expiry, err := parseExpiryDate(it.ExpiryDate)
+			if err != nil {
+				http.Error(w, "expiry_date must be YYYY-MM-DD", http.StatusBadRequest)
+				return
+			}
 			items = append(items, models.PurchaseOrderItem{
-				ProductID: it.ProductID,
-				Quantity:  it.Quantity,
-				UnitCost:  it.UnitCost,
+				ProductID:     it.ProductID,
+				UnitQuantity:  it.Quantity,
+				Unit:          it.Unit,
+				UnitCost:      it.UnitCost,
+				LotNumber:     it.LotNumber,
+				ExpiryDate:    expiry,
+				SerialNumbers: it.SerialNumbers,
 			})
 		}
 
 		pom := &models.PurchaseOrderModel{DB: db}
 		if err := pom.Create(order, items); err != nil {
+			if writeArchivedError(w, err) {
+				return
+			}
+			if err == models.ErrWarehouseNotFound {
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "warehouse not found"})
+				return
+			}
+			if err == models.ErrNotFound {
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "product not found"})
+				return
+			}
+			if err == models.ErrUnknownUnit {
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "unit not configured for product"})
+				return
+			}
 			http.Error(w, "could not create purchase order", http.StatusInternalServerError)
 			return
 		}
@@ -85,7 +139,7 @@ func CreatePurchaseOrder(db *pgxpool.Pool) http.HandlerFunc {
 	}
 }
 
-// GetPurchaseOrders handles GET /api/v1/purchase-orders
+// GetPurchaseOrders handles GET /api/v1/purchase-orders?status=
 func GetPurchaseOrders(db *pgxpool.Pool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userID, ok := middleware.UserIDFromContext(r.Context())
@@ -94,8 +148,15 @@ func GetPurchaseOrders(db *pgxpool.Pool) http.HandlerFunc {
 			return
 		}
 
+		// ?status=draft lista los borradores del motor de reposición pendientes de revisión
 		pom := &models.PurchaseOrderModel{DB: db}
-		orders, err := pom.GetAllForUser(userID)
+		var orders []models.PurchaseOrder
+		var err error
+		if status := r.URL.Query().Get("status"); status != "" {
+			orders, err = pom.GetAllForUserWithFilters(userID, models.PurchaseOrderFilters{Status: status})
+		} else {
+			orders, err = pom.GetAllForUser(userID)
+		}
 		if err != nil {
 			http.Error(w, "could not fetch purchase orders", http.StatusInternalServerError)
 			return
@@ -139,7 +200,9 @@ func GetPurchaseOrderByID(db *pgxpool.Pool) http.HandlerFunc {
 // UpdatePurchaseOrderStatus handles PUT /api/v1/purchase-orders/{id}/status
 func UpdatePurchaseOrderStatus(db *pgxpool.Pool) http.HandlerFunc {
 	type statusInput struct {
-		Status string `json:"status"`
+		Status string                          `json:"status"`
+		Items  []PurchaseOrderItemReceiptInput `json:"items"`
+		Lots   []PurchaseOrderItemReceiptInput `json:"lots"` // nombre anterior de items, se sigue aceptando
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -162,12 +225,53 @@ func UpdatePurchaseOrderStatus(db *pgxpool.Pool) http.HandlerFunc {
 			return
 		}
 
+		in.Items = append(in.Items, in.Lots...)
+		receipts := make([]models.PurchaseOrderItemReceipt, 0, len(in.Items))
+		for _, rc := range in.Items {
+			expiry, err := parseExpiryDate(rc.ExpiryDate)
+			if err != nil {
+				http.Error(w, "expiry_date must be YYYY-MM-DD", http.StatusBadRequest)
+				return
+			}
+			receipts = append(receipts, models.PurchaseOrderItemReceipt{
+				ItemID:        rc.ItemID,
+				LotNumber:     rc.LotNumber,
+				ExpiryDate:    expiry,
+				SerialNumbers: rc.SerialNumbers,
+			})
+		}
+
 		pom := &models.PurchaseOrderModel{DB: db}
-		if err := pom.UpdateStatus(id, userID, in.Status); err != nil {
+		if err := pom.UpdateStatus(id, userID, in.Status, receipts); err != nil {
 			if err == models.ErrNotFound {
 				http.NotFound(w, r)
 				return
 			}
+			if err == models.ErrReceiptItemNotFound {
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "receipt item_id is not part of the purchase order"})
+				return
+			}
+			if err == models.ErrSerialsRequired {
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "serialized products require one serial number per unit received"})
+				return
+			}
+			if err == models.ErrDuplicateSerial {
+				w.WriteHeader(http.StatusConflict)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "serial number already registered"})
+				return
+			}
+			if err == models.ErrKitNotStocked {
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "kits have no stock of their own; move their components instead"})
+				return
+			}
+			if err == models.ErrInvalidPurchaseOrderStatus {
+				w.WriteHeader(http.StatusConflict)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "draft purchase orders can only be sent (pending) or cancelled"})
+				return
+			}
 			// Log the actual error for debugging
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"stock-in-order/backend/internal/middleware"
+	"stock-in-order/backend/internal/models"
+	"stock-in-order/backend/internal/rabbitmq"
+)
+
+// ReorderRuleInput es el cuerpo de PUT /api/v1/products/{id}/reorder-rule.
+type ReorderRuleInput struct {
+	ReorderPoint        float64 `json:"reorder_point"`
+	TargetLevel         float64 `json:"target_level"`
+	PreferredSupplierID *int64  `json:"preferred_supplier_id"`
+	MinOrderQuantity    float64 `json:"min_order_quantity"`
+}
+
+// ReorderRunRequest es la tarea que procesa el worker en la cola de alertas de stock.
+type ReorderRunRequest struct {
+	TaskType string `json:"task_type"`
+	UserID   int64  `json:"user_id"`
+}
+
+// GetReorderRules handles GET /api/v1/reorder-rules
+func GetReorderRules(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		rm := &models.ReorderRuleModel{DB: db}
+		rules, err := rm.GetAllForUser(userID)
+		if err != nil {
+			http.Error(w, "could not fetch reorder rules", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(rules)
+	}
+}
+
+// GetProductReorderRule handles GET /api/v1/products/{id}/reorder-rule
+func GetProductReorderRule(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+
+		rm := &models.ReorderRuleModel{DB: db}
+		rule, err := rm.GetForProduct(id, userID)
+		if err != nil {
+			if err == models.ErrNotFound {
+				http.NotFound(w, r)
+				return
+			}
+			http.Error(w, "could not fetch reorder rule", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(rule)
+	}
+}
+
+// SetProductReorderRule handles PUT /api/v1/products/{id}/reorder-rule
+// Crea o reemplaza la regla: punto de pedido, nivel objetivo, proveedor preferido y cantidad mínima.
+func SetProductReorderRule(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+
+		var in ReorderRuleInput
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		rm := &models.ReorderRuleModel{DB: db}
+		rule := &models.ReorderRule{
+			ProductID:           id,
+			ReorderPoint:        in.ReorderPoint,
+			TargetLevel:         in.TargetLevel,
+			PreferredSupplierID: in.PreferredSupplierID,
+			MinOrderQuantity:    in.MinOrderQuantity,
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := rm.Set(userID, rule); err != nil {
+			if err == models.ErrNotFound {
+				http.NotFound(w, r)
+				return
+			}
+			if err == models.ErrInvalidReorderRule {
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "reorder_point and min_order_quantity must be >= 0, target_level must exceed reorder_point, and kits cannot have reorder rules"})
+				return
+			}
+			if writeArchivedError(w, err) {
+				return
+			}
+			http.Error(w, "could not save reorder rule", http.StatusInternalServerError)
+			return
+		}
+
+		saved, err := rm.GetForProduct(id, userID)
+		if err != nil {
+			http.Error(w, "could not fetch reorder rule", http.StatusInternalServerError)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(saved)
+	}
+}
+
+// DeleteProductReorderRule handles DELETE /api/v1/products/{id}/reorder-rule
+func DeleteProductReorderRule(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+
+		rm := &models.ReorderRuleModel{DB: db}
+		if err := rm.Delete(id, userID); err != nil {
+			if err == models.ErrNotFound {
+				http.NotFound(w, r)
+				return
+			}
+			http.Error(w, "could not delete reorder rule", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// RequestReorderRun handles POST /api/v1/purchase-orders/reorder
+// Encola el motor de reposición para el usuario sin esperar al job programado; las órdenes
+// quedan en borrador (GET /purchase-orders?status=draft) hasta que un repositor las revise.
+func RequestReorderRun(rabbit *rabbitmq.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		body, err := json.Marshal(ReorderRunRequest{
+			TaskType: "draft_reorder_purchase_orders",
+			UserID:   userID,
+		})
+		if err != nil {
+			http.Error(w, "could not queue reorder run", http.StatusInternalServerError)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		if err := rabbit.PublishMessage(ctx, "stock_alerts_queue", body); err != nil {
+			http.Error(w, "could not queue reorder run", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"message": "Las órdenes de compra sugeridas se están generando.",
+		})
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -15,7 +17,7 @@ import (
 	"stock-in-order/backend/internal/rabbitmq"
 )
 
-// ExportProductsXLSX maneja GET /api/v1/reports/products/xlsx
+// ExportProductsXLSX maneja GET /api/v1/reports/products/xlsx?category_id=&tag=&archived=&abc_class=&xyz_class=&group_by=category
 // Genera un archivo Excel profesional con todos los productos del usuario
 func ExportProductsXLSX(db *pgxpool.Pool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -25,14 +27,51 @@ func ExportProductsXLSX(db *pgxpool.Pool) http.HandlerFunc {
 			return
 		}
 
-		// Obtener todos los productos del usuario
+		filter, err := productFilterFromRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// Los reportes incluyen los productos archivados (columna Archivado) salvo ?archived=only
+		if filter.Archived == models.ArchiveActive {
+			filter.Archived = models.ArchiveIncluded
+		}
+
+		// Obtener los productos del usuario (con los filtros de categoría / etiqueta)
 		pm := &models.ProductModel{DB: db}
-		products, err := pm.GetAllForUser(userID)
+		products, err := pm.GetFiltered(userID, filter)
 		if err != nil {
 			http.Error(w, "could not fetch products", http.StatusInternalServerError)
 			return
 		}
 
+		cm := &models.CategoryModel{DB: db}
+		categories, err := cm.GetAllForUser(userID)
+		if err != nil {
+			http.Error(w, "could not fetch categories", http.StatusInternalServerError)
+			return
+		}
+		categoryPaths := models.CategoryPaths(categories)
+
+		// SKU de cada producto para mostrar el padre de las variantes
+		skuByID := make(map[int64]string, len(products))
+		for _, p := range products {
+			skuByID[p.ID] = p.SKU
+		}
+
+		// ?rollup=true agrupa las variantes en su producto padre (cantidad total)
+		if r.URL.Query().Get("rollup") == "true" {
+			products = models.RollUpVariants(products)
+		}
+
+		// ?group_by=category ordena por categoría y agrega la hoja de resumen por categoría
+		groupByCategory := r.URL.Query().Get("group_by") == "category"
+		if groupByCategory {
+			sort.SliceStable(products, func(i, j int) bool {
+				return models.CategoryLabel(categoryPaths, products[i].CategoryID) < models.CategoryLabel(categoryPaths, products[j].CategoryID)
+			})
+		}
+
 		// Crear un nuevo archivo Excel en memoria
 		f := excelize.NewFile()
 		defer func() {
@@ -53,7 +92,7 @@ func ExportProductsXLSX(db *pgxpool.Pool) http.HandlerFunc {
 		f.SetActiveSheet(index)
 
 		// Escribir cabeceras en la fila 1
-		headers := []string{"ID", "Nombre", "SKU", "Descripción", "Cantidad", "Fecha de Creación"}
+		headers := []string{"ID", "Nombre", "SKU", "Descripción", "Cantidad", "Fecha de Creación", "Producto Padre", "Variantes", "Categoría", "Etiquetas", "Archivado", "Clase ABC", "Clase XYZ"}
 		for i, header := range headers {
 			cell, _ := excelize.CoordinatesToCellName(i+1, 1)
 			f.SetCellValue(sheetName, cell, header)
@@ -65,6 +104,10 @@ func ExportProductsXLSX(db *pgxpool.Pool) http.HandlerFunc {
 			if product.Description != nil {
 				description = *product.Description
 			}
+			parentSKU := ""
+			if product.ParentID != nil {
+				parentSKU = skuByID[*product.ParentID]
+			}
 
 			row := rowIndex + 2 // Comenzar desde la fila 2 (después de headers)
 
@@ -74,6 +117,20 @@ func ExportProductsXLSX(db *pgxpool.Pool) http.HandlerFunc {
 			f.SetCellValue(sheetName, "D"+strconv.Itoa(row), description)
 			f.SetCellValue(sheetName, "E"+strconv.Itoa(row), product.Quantity)
 			f.SetCellValue(sheetName, "F"+strconv.Itoa(row), product.CreatedAt.Format("2006-01-02 15:04:05"))
+			f.SetCellValue(sheetName, "G"+strconv.Itoa(row), parentSKU)
+			f.SetCellValue(sheetName, "H"+strconv.Itoa(row), len(product.Variants))
+			f.SetCellValue(sheetName, "I"+strconv.Itoa(row), models.CategoryLabel(categoryPaths, product.CategoryID))
+			f.SetCellValue(sheetName, "J"+strconv.Itoa(row), strings.Join(product.Tags, ", "))
+			f.SetCellValue(sheetName, "K"+strconv.Itoa(row), archivedLabel(product.ArchivedAt))
+			f.SetCellValue(sheetName, "L"+strconv.Itoa(row), classLabel(product.AbcClass))
+			f.SetCellValue(sheetName, "M"+strconv.Itoa(row), classLabel(product.XyzClass))
+		}
+
+		if groupByCategory {
+			if err := writeCategorySummarySheet(f, products, categoryPaths); err != nil {
+				http.Error(w, "could not create Excel sheet", http.StatusInternalServerError)
+				return
+			}
 		}
 
 		// Configurar headers HTTP para descarga de archivo Excel
@@ -88,7 +145,52 @@ func ExportProductsXLSX(db *pgxpool.Pool) http.HandlerFunc {
 	}
 }
 
-// ExportCustomersXLSX maneja GET /api/v1/reports/customers/xlsx
+// writeCategorySummarySheet agrega la hoja "Por categoría" con productos, cantidad total y
+// productos con stock bajo (cantidad <= stock mínimo) de cada categoría.
+func writeCategorySummarySheet(f *excelize.File, products []models.Product, categoryPaths map[int64]string) error {
+	type summary struct {
+		products int
+		quantity float64
+		lowStock int
+	}
+	var order []string
+	byCategory := map[string]*summary{}
+	for _, p := range products {
+		label := models.CategoryLabel(categoryPaths, p.CategoryID)
+		s, ok := byCategory[label]
+		if !ok {
+			s = &summary{}
+			byCategory[label] = s
+			order = append(order, label)
+		}
+		s.products++
+		s.quantity += p.Quantity
+		if !p.IsKit && p.Quantity <= p.StockMinimo {
+			s.lowStock++
+		}
+	}
+
+	sheetName := "Por categoría"
+	if _, err := f.NewSheet(sheetName); err != nil {
+		return err
+	}
+	headers := []string{"Categoría", "Productos", "Cantidad Total", "Con Stock Bajo"}
+	for i, header := range headers {
+		cell, _ := excelize.CoordinatesToCellName(i+1, 1)
+		f.SetCellValue(sheetName, cell, header)
+	}
+	for rowIndex, label := range order {
+		s := byCategory[label]
+		row := strconv.Itoa(rowIndex + 2)
+		f.SetCellValue(sheetName, "A"+row, label)
+		f.SetCellValue(sheetName, "B"+row, s.products)
+		f.SetCellValue(sheetName, "C"+row, s.quantity)
+		f.SetCellValue(sheetName, "D"+row, s.lowStock)
+	}
+	return nil
+}
+
+// ExportCustomersXLSX maneja GET /api/v1/reports/customers/xlsx?archived=
 // Genera un archivo Excel profesional con todos los clientes del usuario
 func ExportCustomersXLSX(db *pgxpool.Pool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -98,9 +200,18 @@ func ExportCustomersXLSX(db *pgxpool.Pool) http.HandlerFunc {
 			return
 		}
 
-		// Obtener todos los clientes del usuario
+		archived, ok := archiveFilterFromRequest(r)
+		if !ok {
+			http.Error(w, "archived must be only or include", http.StatusBadRequest)
+			return
+		}
+		if archived == models.ArchiveActive {
+			archived = models.ArchiveIncluded
+		}
+
+		// Obtener los clientes del usuario; como en productos, se incluyen los archivados salvo ?archived=only
 		cm := &models.CustomerModel{DB: db}
-		customers, err := cm.GetAllForUser(userID)
+		customers, err := cm.GetAllForUser(userID, archived)
 		if err != nil {
 			http.Error(w, "could not fetch customers", http.StatusInternalServerError)
 			return
@@ -126,7 +237,7 @@ func ExportCustomersXLSX(db *pgxpool.Pool) http.HandlerFunc {
 		f.SetActiveSheet(index)
 
 		// Escribir cabeceras en la fila 1
-		headers := []string{"ID", "Nombre", "Email", "Teléfono", "Dirección", "Fecha de Creación"}
+		headers := []string{"ID", "Nombre", "Email", "Teléfono", "Dirección", "Fecha de Creación", "Archivado"}
 		for i, header := range headers {
 			cell, _ := excelize.CoordinatesToCellName(i+1, 1)
 			f.SetCellValue(sheetName, cell, header)
@@ -142,6 +253,7 @@ func ExportCustomersXLSX(db *pgxpool.Pool) http.HandlerFunc {
 			f.SetCellValue(sheetName, "D"+strconv.Itoa(row), customer.Phone)
 			f.SetCellValue(sheetName, "E"+strconv.Itoa(row), customer.Address)
 			f.SetCellValue(sheetName, "F"+strconv.Itoa(row), customer.CreatedAt.Format("2006-01-02 15:04:05"))
+			f.SetCellValue(sheetName, "G"+strconv.Itoa(row), archivedLabel(customer.ArchivedAt))
 		}
 
 		// Configurar headers HTTP para descarga de archivo Excel
@@ -156,7 +268,7 @@ func ExportCustomersXLSX(db *pgxpool.Pool) http.HandlerFunc {
 	}
 }
 
-// ExportSuppliersXLSX maneja GET /api/v1/reports/suppliers/xlsx
+// ExportSuppliersXLSX maneja GET /api/v1/reports/suppliers/xlsx?archived=
 // Genera un archivo Excel profesional con todos los proveedores del usuario
 func ExportSuppliersXLSX(db *pgxpool.Pool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -166,9 +278,18 @@ func ExportSuppliersXLSX(db *pgxpool.Pool) http.HandlerFunc {
 			return
 		}
 
-		// Obtener todos los proveedores del usuario
+		archived, ok := archiveFilterFromRequest(r)
+		if !ok {
+			http.Error(w, "archived must be only or include", http.StatusBadRequest)
+			return
+		}
+		if archived == models.ArchiveActive {
+			archived = models.ArchiveIncluded
+		}
+
+		// Obtener los proveedores del usuario, incluidos los archivados salvo ?archived=only
 		sm := &models.SupplierModel{DB: db}
-		suppliers, err := sm.GetAllForUser(userID)
+		suppliers, err := sm.GetAllForUser(userID, archived)
 		if err != nil {
 			http.Error(w, "could not fetch suppliers", http.StatusInternalServerError)
 			return
@@ -194,7 +315,7 @@ func ExportSuppliersXLSX(db *pgxpool.Pool) http.HandlerFunc {
 		f.SetActiveSheet(index)
 
 		// Escribir cabeceras en la fila 1
-		headers := []string{"ID", "Nombre", "Email", "Teléfono", "Dirección", "Fecha de Creación"}
+		headers := []string{"ID", "Nombre", "Email", "Teléfono", "Dirección", "Fecha de Creación", "Archivado"}
 		for i, header := range headers {
 			cell, _ := excelize.CoordinatesToCellName(i+1, 1)
 			f.SetCellValue(sheetName, cell, header)
@@ -210,6 +331,7 @@ func ExportSuppliersXLSX(db *pgxpool.Pool) http.HandlerFunc {
 			f.SetCellValue(sheetName, "D"+strconv.Itoa(row), supplier.Phone)
 			f.SetCellValue(sheetName, "E"+strconv.Itoa(row), supplier.Address)
 			f.SetCellValue(sheetName, "F"+strconv.Itoa(row), supplier.CreatedAt.Format("2006-01-02 15:04:05"))
+			f.SetCellValue(sheetName, "G"+strconv.Itoa(row), archivedLabel(supplier.ArchivedAt))
 		}
 
 		// Configurar headers HTTP para descarga de archivo Excel
@@ -505,3 +627,19 @@ func RequestSuppliersReportByEmail(db *pgxpool.Pool, rabbit *rabbitmq.Client) ht
 		})
 	}
 }
+
+// archivedLabel muestra la fecha de archivo en los reportes ("" para los registros activos).
+func archivedLabel(at *time.Time) string {
+	if at == nil {
+		return ""
+	}
+	return at.Format("2006-01-02")
+}
+
+// classLabel es la clase ABC / XYZ del producto, vacía si todavía no se clasificó.
+func classLabel(class *string) string {
+	if class == nil {
+		return ""
+	}
+	return *class
+}
@@ -15,13 +15,17 @@ import (
 // DTOs for creating a sales order
 
 type OrderItemInput struct {
-	ProductID int64 `json:"product_id"`
-	Quantity  int   `json:"quantity"`
+	ProductID     int64    `json:"product_id"`
+	Quantity      float64  `json:"quantity"`       // en la unidad indicada
+	Unit          string   `json:"unit"`           // vacío = unidad base del producto
+	SerialNumbers []string `json:"serial_numbers"` // obligatorio para productos serializados
+	UnitPrice     *float64 `json:"unit_price"`     // por la unidad indicada; nil = lista de precios del cliente
 }
 
 type CreateOrderInput struct {
-	CustomerID int64            `json:"customer_id"`
-	Items      []OrderItemInput `json:"items"`
+	CustomerID  int64            `json:"customer_id"`
+	WarehouseID int64            `json:"warehouse_id"` // 0 = depósito por defecto
+	Items       []OrderItemInput `json:"items"`
 }
 
 // CreateSalesOrder handles POST /api/v1/sales-orders
@@ -45,8 +49,9 @@ func CreateSalesOrder(db *pgxpool.Pool) http.HandlerFunc {
 
 		// Build model structs
 		order := &models.SalesOrder{
-			UserID: userID,
-			Status: "pending",
+			UserID:      userID,
+			Status:      "pending",
+			WarehouseID: in.WarehouseID,
 		}
 		if in.CustomerID > 0 {
 			order.CustomerID.Int64 = in.CustomerID
@@ -59,20 +64,62 @@ func CreateSalesOrder(db *pgxpool.Pool) http.HandlerFunc {
 				http.Error(w, "quantity must be > 0", http.StatusBadRequest)
 				return
 			}
-			items = append(items, models.OrderItem{
-				ProductID: it.ProductID,
-				Quantity:  it.Quantity,
-				UnitPrice: 0,
-			})
+			if it.UnitPrice != nil && *it.UnitPrice < 0 {
+				http.Error(w, "unit_price cannot be negative", http.StatusBadRequest)
+				return
+			}
+			item := models.OrderItem{
+				ProductID:     it.ProductID,
+				UnitQuantity:  it.Quantity,
+				Unit:          it.Unit,
+				SerialNumbers: it.SerialNumbers,
+			}
+			if it.UnitPrice != nil {
+				item.UnitPrice, item.PriceGiven = *it.UnitPrice, true
+			}
+			items = append(items, item)
 		}
 
 		som := &models.SalesOrderModel{DB: db}
 		if err := som.Create(order, items); err != nil {
+			if writeArchivedError(w, err) {
+				return
+			}
 			if err == models.ErrInsufficientStock {
 				w.WriteHeader(http.StatusConflict)
 				_ = json.NewEncoder(w).Encode(map[string]any{"error": "insufficient stock"})
 				return
 			}
+			if err == models.ErrWarehouseNotFound {
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "warehouse not found"})
+				return
+			}
+			if err == models.ErrNotFound {
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "product not found"})
+				return
+			}
+			if err == models.ErrUnknownUnit {
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "unit not configured for product"})
+				return
+			}
+			if err == models.ErrSerialsRequired || err == models.ErrDuplicateSerial {
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "serialized products require one distinct serial number per unit"})
+				return
+			}
+			if err == models.ErrSerialNotAvailable {
+				w.WriteHeader(http.StatusConflict)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "serial number not in stock"})
+				return
+			}
+			if err == models.ErrPriceBelowFloor {
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "unit price below the product's price floor"})
+				return
+			}
 			http.Error(w, "could not create order", http.StatusInternalServerError)
 			return
 		}
@@ -128,11 +175,128 @@ func GetSalesOrderByID(db *pgxpool.Pool) http.HandlerFunc {
 			http.Error(w, "could not fetch order", http.StatusInternalServerError)
 			return
 		}
+		changes, err := som.GetStatusChanges(id, userID)
+		if err != nil {
+			http.Error(w, "could not fetch order", http.StatusInternalServerError)
+			return
+		}
 
 		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(map[string]any{
-			"order": order,
-			"items": items,
+			"order":          order,
+			"items":          items,
+			"status_changes": changes,
 		})
 	}
 }
+
+// UpdateSalesOrderStatus handles PUT /api/v1/sales-orders/{id}/status
+// Ciclo de vida: pending → confirmed → picking → shipped → delivered. shipped descuenta el stock
+// reservado; cancelled libera la reserva y devuelve el stock que la orden haya descontado.
+// Las transiciones no permitidas responden 409.
+func UpdateSalesOrderStatus(db *pgxpool.Pool) http.HandlerFunc {
+	type statusInput struct {
+		Status string `json:"status"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+
+		var in statusInput
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+		switch in.Status {
+		case "":
+			http.Error(w, "status required", http.StatusBadRequest)
+			return
+		case models.SalesOrderPending, models.SalesOrderConfirmed, models.SalesOrderPicking,
+			models.SalesOrderShipped, models.SalesOrderDelivered, models.SalesOrderCancelled:
+		default:
+			http.Error(w, "invalid status", http.StatusBadRequest)
+			return
+		}
+
+		som := &models.SalesOrderModel{DB: db}
+		if err := som.UpdateStatus(id, userID, in.Status); err != nil {
+			switch err {
+			case models.ErrNotFound:
+				http.NotFound(w, r)
+			case models.ErrInvalidOrderStatus:
+				w.WriteHeader(http.StatusConflict)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "invalid status transition"})
+			case models.ErrInsufficientStock:
+				w.WriteHeader(http.StatusConflict)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "insufficient stock"})
+			case models.ErrSerialsRequired:
+				w.WriteHeader(http.StatusConflict)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "assign the serial numbers of every serialized line before shipping"})
+			default:
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// AssignSalesOrderSerials handles PUT /api/v1/sales-orders/{id}/items/{itemId}/serials
+// Reemplaza las series reservadas de una línea de una orden que todavía no se despachó; las órdenes
+// cargadas sin series (ventas de Mercado Libre de productos serializados) no se despachan sin ellas.
+func AssignSalesOrderSerials(db *pgxpool.Pool) http.HandlerFunc {
+	type serialsInput struct {
+		SerialNumbers []string `json:"serial_numbers"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+		itemID, _ := strconv.ParseInt(vars["itemId"], 10, 64)
+
+		var in serialsInput
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		som := &models.SalesOrderModel{DB: db}
+		if err := som.AssignSerials(id, itemID, userID, in.SerialNumbers); err != nil {
+			switch err {
+			case models.ErrNotFound:
+				http.NotFound(w, r)
+			case models.ErrInvalidOrderStatus:
+				w.WriteHeader(http.StatusConflict)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "serial numbers can only be assigned before shipping"})
+			case models.ErrNotSerialized:
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "product is not serialized"})
+			case models.ErrSerialsRequired, models.ErrDuplicateSerial:
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "serialized products require one distinct serial number per unit"})
+			case models.ErrSerialNotAvailable:
+				w.WriteHeader(http.StatusConflict)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "serial number not in stock"})
+			default:
+				http.Error(w, "could not assign serial numbers", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"stock-in-order/backend/internal/middleware"
+	"stock-in-order/backend/internal/models"
+)
+
+// RegisterSerialsInput DTO para dar de alta series de unidades ya en stock
+type RegisterSerialsInput struct {
+	SerialNumbers []string `json:"serial_numbers" validate:"required,min=1"`
+}
+
+// GetProductSerials handles GET /api/v1/products/{id}/serials
+func GetProductSerials(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+
+		sm := &models.SerialModel{DB: db}
+		serials, err := sm.GetForProduct(id, userID)
+		if err != nil {
+			http.Error(w, "could not fetch serial numbers", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(serials)
+	}
+}
+
+// RegisterProductSerials handles POST /api/v1/products/{id}/serials
+func RegisterProductSerials(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+
+		var in RegisterSerialsInput
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if err := validate.Struct(in); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]any{"error": "validation failed", "details": err.Error()})
+			return
+		}
+
+		sm := &models.SerialModel{DB: db}
+		if err := sm.Register(id, userID, in.SerialNumbers); err != nil {
+			switch err {
+			case models.ErrNotFound:
+				http.NotFound(w, r)
+			case models.ErrSerialsRequired:
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "product is not serialized or serial numbers are empty"})
+			case models.ErrDuplicateSerial:
+				w.WriteHeader(http.StatusConflict)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "serial number already registered"})
+			case models.ErrInsufficientStock:
+				w.WriteHeader(http.StatusConflict)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "more serial numbers than units in stock"})
+			default:
+				http.Error(w, "could not register serial numbers", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+	}
+}
+
+// LookupSerial handles GET /api/v1/serials/{serial}
+// Devuelve proveedor, OC, cliente y OV de un número de serie (garantías).
+func LookupSerial(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		serial := mux.Vars(r)["serial"]
+
+		sm := &models.SerialModel{DB: db}
+		history, err := sm.Lookup(serial, userID)
+		if err != nil {
+			if err == models.ErrNotFound {
+				http.NotFound(w, r)
+				return
+			}
+			http.Error(w, "could not fetch serial number", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(history)
+	}
+}
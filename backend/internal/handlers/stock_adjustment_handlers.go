@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"stock-in-order/backend/internal/middleware"
+	"stock-in-order/backend/internal/models"
+)
+
+// DTOs for the adjustment reason catalogue
+
+type CreateAdjustmentReasonInput struct {
+	Code string `json:"code" validate:"required"`
+	Name string `json:"name" validate:"required"`
+	Sign string `json:"sign" validate:"required,oneof=positive negative any"`
+}
+
+type UpdateAdjustmentReasonInput struct {
+	Name   string `json:"name" validate:"required"`
+	Sign   string `json:"sign" validate:"required,oneof=positive negative any"`
+	Active bool   `json:"active"`
+}
+
+// writeStockAdjustmentError traduce los errores del modelo de ajustes a respuestas HTTP.
+func writeStockAdjustmentError(w http.ResponseWriter, r *http.Request, err error) {
+	if writeArchivedError(w, err) {
+		return
+	}
+	switch err {
+	case models.ErrNotFound:
+		http.NotFound(w, r)
+	case models.ErrWarehouseNotFound:
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]any{"error": "warehouse not found"})
+	case models.ErrReasonNotFound:
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]any{"error": "adjustment reason not found or inactive"})
+	case models.ErrReasonSignMismatch:
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]any{"error": "quantity sign not allowed by the adjustment reason"})
+	case models.ErrKitNotStocked:
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]any{"error": "kits have no stock of their own; move their components instead"})
+	case models.ErrSerializedProduct:
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]any{"error": "serialized products change stock only through purchase and sales orders"})
+	case models.ErrInvalidAdjustmentStatus:
+		w.WriteHeader(http.StatusConflict)
+		_ = json.NewEncoder(w).Encode(map[string]any{"error": "adjustment is not pending approval"})
+	case models.ErrInsufficientStock:
+		w.WriteHeader(http.StatusConflict)
+		_ = json.NewEncoder(w).Encode(map[string]any{"error": "insufficient stock"})
+	default:
+		http.Error(w, "could not adjust stock", http.StatusInternalServerError)
+	}
+}
+
+// GetAdjustmentReasons handles GET /api/v1/adjustment-reasons
+// Por defecto sólo los motivos activos; ?include_inactive=true lista el catálogo completo.
+func GetAdjustmentReasons(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		onlyActive := r.URL.Query().Get("include_inactive") != "true"
+
+		rm := &models.AdjustmentReasonModel{DB: db}
+		reasons, err := rm.GetAll(onlyActive)
+		if err != nil {
+			http.Error(w, "could not fetch adjustment reasons", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(reasons)
+	}
+}
+
+// CreateAdjustmentReason handles POST /api/v1/adjustment-reasons (admin)
+func CreateAdjustmentReason(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var in CreateAdjustmentReasonInput
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if err := validate.Struct(in); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]any{"error": "validation failed", "details": err.Error()})
+			return
+		}
+
+		reason := &models.AdjustmentReason{Code: in.Code, Name: in.Name, Sign: in.Sign, Active: true}
+		rm := &models.AdjustmentReasonModel{DB: db}
+		if err := rm.Insert(reason); err != nil {
+			switch err {
+			case models.ErrDuplicateReasonCode:
+				w.WriteHeader(http.StatusConflict)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "adjustment reason code already exists"})
+			case models.ErrInvalidReason:
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "invalid adjustment reason"})
+			default:
+				http.Error(w, "could not create adjustment reason", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(reason)
+	}
+}
+
+// UpdateAdjustmentReason handles PUT /api/v1/adjustment-reasons/{id} (admin)
+// El código no se puede cambiar: los ajustes ya registrados lo referencian.
+func UpdateAdjustmentReason(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+
+		var in UpdateAdjustmentReasonInput
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if err := validate.Struct(in); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]any{"error": "validation failed", "details": err.Error()})
+			return
+		}
+
+		reason := &models.AdjustmentReason{ID: id, Name: in.Name, Sign: in.Sign, Active: in.Active}
+		rm := &models.AdjustmentReasonModel{DB: db}
+		if err := rm.Update(reason); err != nil {
+			switch err {
+			case models.ErrNotFound:
+				http.NotFound(w, r)
+			case models.ErrInvalidReason:
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "invalid adjustment reason"})
+			default:
+				http.Error(w, "could not update adjustment reason", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(reason)
+	}
+}
+
+// GetStockAdjustments handles GET /api/v1/stock-adjustments?status=
+func GetStockAdjustments(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		status := r.URL.Query().Get("status")
+		switch status {
+		case "", models.AdjustmentApplied, models.AdjustmentPending, models.AdjustmentApproved, models.AdjustmentRejected:
+		default:
+			http.Error(w, "invalid status", http.StatusBadRequest)
+			return
+		}
+
+		am := &models.StockAdjustmentModel{DB: db}
+		adjustments, err := am.GetAllForUser(userID, status)
+		if err != nil {
+			http.Error(w, "could not fetch stock adjustments", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(adjustments)
+	}
+}
+
+// GetPendingStockAdjustments handles GET /api/v1/stock-adjustments/pending-approval (admin)
+func GetPendingStockAdjustments(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		am := &models.StockAdjustmentModel{DB: db}
+		adjustments, err := am.GetPendingApproval()
+		if err != nil {
+			http.Error(w, "could not fetch stock adjustments", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(adjustments)
+	}
+}
+
+// ApproveStockAdjustment handles POST /api/v1/stock-adjustments/{id}/approve (admin)
+// Con {"reject": true} el ajuste se rechaza sin mover stock.
+func ApproveStockAdjustment(db *pgxpool.Pool) http.HandlerFunc {
+	type approveInput struct {
+		Reject bool `json:"reject"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+
+		var in approveInput
+		if r.ContentLength > 0 {
+			if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+				http.Error(w, "invalid JSON", http.StatusBadRequest)
+				return
+			}
+		}
+
+		am := &models.StockAdjustmentModel{DB: db}
+		adj, err := am.Approve(id, userID, in.Reject)
+		if err != nil {
+			writeStockAdjustmentError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(adj)
+	}
+}
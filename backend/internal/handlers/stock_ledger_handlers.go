@@ -0,0 +1,293 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/xuri/excelize/v2"
+
+	"stock-in-order/backend/internal/middleware"
+	"stock-in-order/backend/internal/models"
+	"stock-in-order/backend/internal/rabbitmq"
+)
+
+// parseCutoff convierte una fecha YYYY-MM-DD o un timestamp RFC3339 en el límite exclusivo de una
+// consulta al ledger: una fecha incluye todo ese día y un timestamp incluye ese instante.
+// Vacío devuelve def.
+func parseCutoff(s string, def time.Time) (time.Time, error) {
+	if s == "" {
+		return def, nil
+	}
+	if d, err := time.Parse("2006-01-02", s); err == nil {
+		return d.AddDate(0, 0, 1), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.Add(time.Microsecond), nil
+}
+
+// parseFrom convierte el inicio opcional de un período (YYYY-MM-DD o RFC3339, inclusive).
+func parseFrom(s string) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if d, err := time.Parse("2006-01-02", s); err == nil {
+		return &d, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// queryInt64 lee un parámetro numérico opcional de la query string (0 si no viene).
+func queryInt64(r *http.Request, name string) (int64, error) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
+
+// GetStockAsOf handles GET /api/v1/stock/as-of?at=YYYY-MM-DD&product_id=&warehouse_id=
+// Reconstruye la cantidad de cada producto a esa fecha sumando los movimientos del ledger.
+func GetStockAsOf(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		at, err := parseCutoff(r.URL.Query().Get("at"), time.Now())
+		if err != nil {
+			http.Error(w, "at must be YYYY-MM-DD or RFC3339", http.StatusBadRequest)
+			return
+		}
+		productID, err := queryInt64(r, "product_id")
+		if err != nil {
+			http.Error(w, "invalid product_id", http.StatusBadRequest)
+			return
+		}
+		warehouseID, err := queryInt64(r, "warehouse_id")
+		if err != nil {
+			http.Error(w, "invalid warehouse_id", http.StatusBadRequest)
+			return
+		}
+
+		smm := &models.StockMovementModel{DB: db}
+		stocks, err := smm.GetStockAsOf(userID, at, productID, warehouseID)
+		if err != nil {
+			if err == models.ErrNotFound {
+				http.NotFound(w, r)
+				return
+			}
+			http.Error(w, "could not rebuild stock", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"as_of":    at,
+			"products": stocks,
+		})
+	}
+}
+
+// kardexFromRequest lee producto, período y depósito de la request y arma el Kardex.
+func kardexFromRequest(db *pgxpool.Pool, r *http.Request, userID int64, productID int64) (*models.Kardex, int, string) {
+	from, err := parseFrom(r.URL.Query().Get("from"))
+	if err != nil {
+		return nil, http.StatusBadRequest, "from must be YYYY-MM-DD or RFC3339"
+	}
+	to, err := parseCutoff(r.URL.Query().Get("to"), time.Now())
+	if err != nil {
+		return nil, http.StatusBadRequest, "to must be YYYY-MM-DD or RFC3339"
+	}
+	warehouseID, err := queryInt64(r, "warehouse_id")
+	if err != nil {
+		return nil, http.StatusBadRequest, "invalid warehouse_id"
+	}
+
+	smm := &models.StockMovementModel{DB: db}
+	kardex, err := smm.GetKardex(productID, userID, from, to, warehouseID)
+	if err != nil {
+		if err == models.ErrNotFound {
+			return nil, http.StatusNotFound, "product not found"
+		}
+		return nil, http.StatusInternalServerError, "could not build kardex"
+	}
+	return kardex, http.StatusOK, ""
+}
+
+// GetProductKardex handles GET /api/v1/products/{id}/kardex?from=&to=&warehouse_id=
+func GetProductKardex(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+
+		kardex, status, msg := kardexFromRequest(db, r, userID, id)
+		if kardex == nil {
+			http.Error(w, msg, status)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(kardex)
+	}
+}
+
+// ExportKardexXLSX maneja GET /api/v1/reports/kardex/xlsx?product_id=&from=&to=&warehouse_id=
+// Genera la ficha de stock del producto con el saldo acumulado de cada movimiento
+func ExportKardexXLSX(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		productID, err := queryInt64(r, "product_id")
+		if err != nil || productID == 0 {
+			http.Error(w, "product_id required", http.StatusBadRequest)
+			return
+		}
+
+		kardex, status, msg := kardexFromRequest(db, r, userID, productID)
+		if kardex == nil {
+			http.Error(w, msg, status)
+			return
+		}
+
+		f := excelize.NewFile()
+		defer func() {
+			_ = f.Close()
+		}()
+
+		sheetName := "Kardex"
+		index, err := f.NewSheet(sheetName)
+		if err != nil {
+			http.Error(w, "could not create Excel sheet", http.StatusInternalServerError)
+			return
+		}
+		f.SetActiveSheet(index)
+
+		// Encabezado del producto
+		f.SetCellValue(sheetName, "A1", "Producto")
+		f.SetCellValue(sheetName, "B1", kardex.SKU+" - "+kardex.Name)
+		f.SetCellValue(sheetName, "A2", "Unidad")
+		f.SetCellValue(sheetName, "B2", kardex.BaseUnit)
+
+		headers := []string{"Fecha", "Motivo", "Referencia", "Depósito", "Entrada", "Salida", "Saldo"}
+		for i, header := range headers {
+			cell, _ := excelize.CoordinatesToCellName(i+1, 4)
+			f.SetCellValue(sheetName, cell, header)
+		}
+
+		f.SetCellValue(sheetName, "B5", "Saldo inicial")
+		f.SetCellValue(sheetName, "G5", kardex.OpeningBalance)
+
+		for rowIndex, e := range kardex.Entries {
+			row := strconv.Itoa(rowIndex + 6)
+			f.SetCellValue(sheetName, "A"+row, e.CreatedAt.Format("2006-01-02 15:04:05"))
+			f.SetCellValue(sheetName, "B"+row, e.Reason)
+			f.SetCellValue(sheetName, "C"+row, e.ReferenceID)
+			f.SetCellValue(sheetName, "D"+row, e.WarehouseID)
+			f.SetCellValue(sheetName, "E"+row, e.In)
+			f.SetCellValue(sheetName, "F"+row, e.Out)
+			f.SetCellValue(sheetName, "G"+row, e.Balance)
+		}
+
+		closingRow := strconv.Itoa(len(kardex.Entries) + 6)
+		f.SetCellValue(sheetName, "B"+closingRow, "Saldo final")
+		f.SetCellValue(sheetName, "G"+closingRow, kardex.ClosingBalance)
+
+		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		w.Header().Set("Content-Disposition", "attachment; filename=\"kardex-"+kardex.SKU+".xlsx\"")
+
+		if err := f.Write(w); err != nil {
+			http.Error(w, "could not write Excel file", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// GetLedgerDrift handles GET /api/v1/stock/drift
+// Lista los productos cuya cantidad no coincide con la suma de sus movimientos.
+func GetLedgerDrift(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		smm := &models.StockMovementModel{DB: db}
+		drift, err := smm.GetDrift(userID)
+		if err != nil {
+			http.Error(w, "could not check ledger drift", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(drift)
+	}
+}
+
+// ReconcileLedgerRequest es la tarea que procesa el worker en la cola de alertas de stock.
+type ReconcileLedgerRequest struct {
+	TaskType string `json:"task_type"`
+	UserID   int64  `json:"user_id"`
+	Repair   bool   `json:"repair"`
+}
+
+// RequestLedgerReconciliation handles POST /api/v1/stock/reconcile
+// Encola la reconciliación del ledger del usuario; el worker registra movimientos
+// OPENING_BALANCE / RECONCILIATION para cada diferencia.
+func RequestLedgerReconciliation(rabbit *rabbitmq.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		body, err := json.Marshal(ReconcileLedgerRequest{
+			TaskType: "reconcile_stock_ledger",
+			UserID:   userID,
+			Repair:   true,
+		})
+		if err != nil {
+			http.Error(w, "could not queue reconciliation", http.StatusInternalServerError)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		if err := rabbit.PublishMessage(ctx, "stock_alerts_queue", body); err != nil {
+			http.Error(w, "could not queue reconciliation", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"message": "La reconciliación del stock se está procesando.",
+		})
+	}
+}
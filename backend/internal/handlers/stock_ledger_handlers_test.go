@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCutoff(t *testing.T) {
+	def := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	got, err := parseCutoff("", def)
+	if err != nil || !got.Equal(def) {
+		t.Fatalf("expected default cutoff, got %v (%v)", got, err)
+	}
+
+	// Una fecha incluye todo el día: el límite es el inicio del día siguiente
+	got, err = parseCutoff("2024-03-31", def)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	// Un timestamp incluye ese instante
+	got, err = parseCutoff("2024-03-31T18:00:00Z", def)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if at := time.Date(2024, 3, 31, 18, 0, 0, 0, time.UTC); !got.After(at) {
+		t.Fatalf("expected cutoff after %v, got %v", at, got)
+	}
+
+	if _, err := parseCutoff("31/03/2024", def); err == nil {
+		t.Fatal("expected error for invalid date")
+	}
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"stock-in-order/backend/internal/middleware"
+	"stock-in-order/backend/internal/models"
+)
+
+// DTOs for creating a stock transfer
+
+type StockTransferItemInput struct {
+	ProductID int64   `json:"product_id"`
+	Quantity  float64 `json:"quantity"` // en unidad base
+}
+
+type CreateStockTransferInput struct {
+	FromWarehouseID int64                    `json:"from_warehouse_id"`
+	ToWarehouseID   int64                    `json:"to_warehouse_id"`
+	Notes           string                   `json:"notes"`
+	Items           []StockTransferItemInput `json:"items"`
+}
+
+// CreateStockTransfer handles POST /api/v1/stock-transfers
+func CreateStockTransfer(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var in CreateStockTransferInput
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if in.FromWarehouseID == 0 || in.ToWarehouseID == 0 {
+			http.Error(w, "from_warehouse_id and to_warehouse_id required", http.StatusBadRequest)
+			return
+		}
+		if len(in.Items) == 0 {
+			http.Error(w, "items required", http.StatusBadRequest)
+			return
+		}
+
+		transfer := &models.StockTransfer{
+			FromWarehouseID: in.FromWarehouseID,
+			ToWarehouseID:   in.ToWarehouseID,
+			Notes:           in.Notes,
+			UserID:          userID,
+		}
+
+		items := make([]models.StockTransferItem, 0, len(in.Items))
+		for _, it := range in.Items {
+			if it.Quantity <= 0 {
+				http.Error(w, "quantity must be > 0", http.StatusBadRequest)
+				return
+			}
+			items = append(items, models.StockTransferItem{
+				ProductID: it.ProductID,
+				Quantity:  it.Quantity,
+			})
+		}
+
+		tm := &models.StockTransferModel{DB: db}
+		if err := tm.Create(transfer, items); err != nil {
+			switch err {
+			case models.ErrSameWarehouse:
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "source and destination warehouses must differ"})
+			case models.ErrWarehouseNotFound:
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "warehouse not found"})
+			case models.ErrTransferProductNotFound:
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "product not found"})
+			case models.ErrSerializedProduct:
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "serialized products change stock only through purchase and sales orders"})
+			default:
+				http.Error(w, "could not create stock transfer", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"transfer": transfer,
+			"items":    items,
+		})
+	}
+}
+
+// GetStockTransfers handles GET /api/v1/stock-transfers
+func GetStockTransfers(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		tm := &models.StockTransferModel{DB: db}
+		transfers, err := tm.GetAllForUser(userID)
+		if err != nil {
+			http.Error(w, "could not fetch stock transfers", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(transfers)
+	}
+}
+
+// GetStockTransferByID handles GET /api/v1/stock-transfers/{id}
+func GetStockTransferByID(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+
+		tm := &models.StockTransferModel{DB: db}
+		transfer, items, err := tm.GetByID(id, userID)
+		if err != nil {
+			if err == models.ErrNotFound {
+				http.NotFound(w, r)
+				return
+			}
+			http.Error(w, "could not fetch stock transfer", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"transfer": transfer,
+			"items":    items,
+		})
+	}
+}
+
+// UpdateStockTransferStatus handles PUT /api/v1/stock-transfers/{id}/status
+func UpdateStockTransferStatus(db *pgxpool.Pool) http.HandlerFunc {
+	type statusInput struct {
+		Status string `json:"status"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+
+		var in statusInput
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if in.Status == "" {
+			http.Error(w, "status required", http.StatusBadRequest)
+			return
+		}
+
+		tm := &models.StockTransferModel{DB: db}
+		if err := tm.UpdateStatus(id, userID, in.Status); err != nil {
+			switch err {
+			case models.ErrNotFound:
+				http.NotFound(w, r)
+			case models.ErrInvalidTransferStatus:
+				w.WriteHeader(http.StatusConflict)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "invalid status transition"})
+			case models.ErrInsufficientStock:
+				w.WriteHeader(http.StatusConflict)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "insufficient stock in source warehouse"})
+			case models.ErrKitNotStocked:
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "kits have no stock of their own; move their components instead"})
+			default:
+				slog.Error("UpdateStockTransferStatus: update failed", "error", err, "transferID", id, "userID", userID)
+				http.Error(w, "could not update stock transfer", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
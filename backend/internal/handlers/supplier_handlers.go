@@ -55,7 +55,7 @@ func CreateSupplier(db *pgxpool.Pool) http.HandlerFunc {
 	}
 }
 
-// ListSuppliers handles GET /api/v1/suppliers
+// ListSuppliers handles GET /api/v1/suppliers?archived=
 func ListSuppliers(db *pgxpool.Pool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userID, ok := middleware.UserIDFromContext(r.Context())
@@ -64,8 +64,14 @@ func ListSuppliers(db *pgxpool.Pool) http.HandlerFunc {
 			return
 		}
 
+		archived, ok := archiveFilterFromRequest(r)
+		if !ok {
+			http.Error(w, "archived must be only or include", http.StatusBadRequest)
+			return
+		}
+
 		sm := &models.SupplierModel{DB: db}
-		items, err := sm.GetAllForUser(userID)
+		items, err := sm.GetAllForUser(userID, archived)
 		if err != nil {
 			http.Error(w, "could not fetch suppliers", http.StatusInternalServerError)
 			return
@@ -170,7 +176,7 @@ func DeleteSupplier(db *pgxpool.Pool) http.HandlerFunc {
 			if err == models.ErrHasReferences {
 				w.WriteHeader(http.StatusConflict)
 				_ = json.NewEncoder(w).Encode(map[string]string{
-					"error": "No se puede eliminar el proveedor porque tiene órdenes de compra asociadas",
+					"error": "No se puede eliminar el proveedor porque tiene órdenes de compra asociadas; archivalo en su lugar",
 				})
 				return
 			}
@@ -182,3 +188,50 @@ func DeleteSupplier(db *pgxpool.Pool) http.HandlerFunc {
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
+
+// ArchiveSupplier handles POST /api/v1/suppliers/{id}/archive
+func ArchiveSupplier(db *pgxpool.Pool) http.HandlerFunc {
+	return setSupplierArchived(db, true)
+}
+
+// RestoreSupplier handles POST /api/v1/suppliers/{id}/restore
+func RestoreSupplier(db *pgxpool.Pool) http.HandlerFunc {
+	return setSupplierArchived(db, false)
+}
+
+func setSupplierArchived(db *pgxpool.Pool, archive bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+
+		sm := &models.SupplierModel{DB: db}
+		var err error
+		if archive {
+			err = sm.Archive(id, userID)
+		} else {
+			err = sm.Restore(id, userID)
+		}
+		if err != nil {
+			if err == models.ErrNotFound {
+				http.NotFound(w, r)
+				return
+			}
+			http.Error(w, "could not update supplier", http.StatusInternalServerError)
+			return
+		}
+
+		item, err := sm.GetByID(id, userID)
+		if err != nil {
+			http.Error(w, "could not fetch supplier", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(item)
+	}
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"stock-in-order/backend/internal/middleware"
+	"stock-in-order/backend/internal/models"
+)
+
+// CreateUnitInput DTO para agregar una unidad alternativa (ej. "caja" = 12 unidades)
+type CreateUnitInput struct {
+	Name   string  `json:"name" validate:"required,max=20"`
+	Factor float64 `json:"factor" validate:"required,gt=0"`
+}
+
+// GetProductUnits handles GET /api/v1/products/{id}/units
+func GetProductUnits(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+
+		um := &models.UnitModel{DB: db}
+		units, err := um.GetForProduct(id, userID)
+		if err != nil {
+			http.Error(w, "could not fetch units", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(units)
+	}
+}
+
+// CreateProductUnit handles POST /api/v1/products/{id}/units
+func CreateProductUnit(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+
+		var in CreateUnitInput
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if err := validate.Struct(in); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]any{"error": "validation failed", "details": err.Error()})
+			return
+		}
+
+		u := &models.ProductUnit{ProductID: id, Name: in.Name, Factor: in.Factor}
+		um := &models.UnitModel{DB: db}
+		if err := um.Insert(userID, u); err != nil {
+			if err == models.ErrNotFound {
+				http.NotFound(w, r)
+				return
+			}
+			if err == models.ErrDuplicateUnit {
+				w.WriteHeader(http.StatusConflict)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "unit already exists for product"})
+				return
+			}
+			http.Error(w, "could not create unit", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(u)
+	}
+}
+
+// DeleteProductUnit handles DELETE /api/v1/products/{id}/units/{unitId}
+func DeleteProductUnit(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+		unitID, _ := strconv.ParseInt(vars["unitId"], 10, 64)
+
+		um := &models.UnitModel{DB: db}
+		if err := um.Delete(id, unitID, userID); err != nil {
+			if err == models.ErrNotFound {
+				http.NotFound(w, r)
+				return
+			}
+			http.Error(w, "could not delete unit", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/xuri/excelize/v2"
+
+	"stock-in-order/backend/internal/middleware"
+	"stock-in-order/backend/internal/models"
+)
+
+// valuationAsOf interpreta ?date= como el cierre de ese día (o ese instante); sin fecha valoriza al momento actual.
+func valuationAsOf(r *http.Request) (time.Time, error) {
+	return parseCutoff(r.URL.Query().Get("date"), time.Now())
+}
+
+// GetValuation handles GET /api/v1/valuation?date=YYYY-MM-DD
+func GetValuation(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		asOf, err := valuationAsOf(r)
+		if err != nil {
+			http.Error(w, "date must be YYYY-MM-DD or RFC3339", http.StatusBadRequest)
+			return
+		}
+
+		vm := &models.ValuationModel{DB: db}
+		valuation, err := vm.GetAsOf(userID, asOf)
+		if err != nil {
+			http.Error(w, "could not compute valuation", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(valuation)
+	}
+}
+
+// ExportValuationXLSX maneja GET /api/v1/reports/valuation/xlsx?date=YYYY-MM-DD
+// Genera el inventario valorizado por producto para el cierre contable
+func ExportValuationXLSX(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		asOf, err := valuationAsOf(r)
+		if err != nil {
+			http.Error(w, "date must be YYYY-MM-DD or RFC3339", http.StatusBadRequest)
+			return
+		}
+
+		vm := &models.ValuationModel{DB: db}
+		valuation, err := vm.GetAsOf(userID, asOf)
+		if err != nil {
+			http.Error(w, "could not compute valuation", http.StatusInternalServerError)
+			return
+		}
+
+		f := excelize.NewFile()
+		defer func() {
+			_ = f.Close()
+		}()
+
+		sheetName := "Valorización"
+		index, err := f.NewSheet(sheetName)
+		if err != nil {
+			http.Error(w, "could not create Excel sheet", http.StatusInternalServerError)
+			return
+		}
+		f.SetActiveSheet(index)
+
+		headers := []string{"ID", "SKU", "Nombre", "Cantidad", "Costo Unitario", "Valor"}
+		for i, header := range headers {
+			cell, _ := excelize.CoordinatesToCellName(i+1, 1)
+			f.SetCellValue(sheetName, cell, header)
+		}
+
+		for rowIndex, p := range valuation.Products {
+			row := rowIndex + 2
+			f.SetCellValue(sheetName, "A"+strconv.Itoa(row), p.ProductID)
+			f.SetCellValue(sheetName, "B"+strconv.Itoa(row), p.SKU)
+			f.SetCellValue(sheetName, "C"+strconv.Itoa(row), p.Name)
+			f.SetCellValue(sheetName, "D"+strconv.Itoa(row), p.Quantity)
+			f.SetCellValue(sheetName, "E"+strconv.Itoa(row), p.UnitCost)
+			f.SetCellValue(sheetName, "F"+strconv.Itoa(row), p.Value)
+		}
+
+		// Fila de total y datos del corte
+		totalRow := strconv.Itoa(len(valuation.Products) + 2)
+		f.SetCellValue(sheetName, "E"+totalRow, "Total")
+		f.SetCellValue(sheetName, "F"+totalRow, valuation.TotalValue)
+		f.SetCellValue(sheetName, "H1", "Método")
+		f.SetCellValue(sheetName, "I1", valuation.Method)
+		f.SetCellValue(sheetName, "H2", "Valorizado al")
+		f.SetCellValue(sheetName, "I2", valuation.AsOf.Format("2006-01-02 15:04:05"))
+
+		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		w.Header().Set("Content-Disposition", "attachment; filename=\"valorizacion.xlsx\"")
+
+		if err := f.Write(w); err != nil {
+			http.Error(w, "could not write Excel file", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// GetCostingMethod handles GET /api/v1/valuation/method
+func GetCostingMethod(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vm := &models.ValuationModel{DB: db}
+		method, err := vm.GetMethod(userID)
+		if err != nil {
+			http.Error(w, "could not fetch costing method", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"method": method})
+	}
+}
+
+// SetCostingMethod handles PUT /api/v1/valuation/method
+// El cambio aplica a las salidas posteriores; lo ya valorizado no se recalcula.
+func SetCostingMethod(db *pgxpool.Pool) http.HandlerFunc {
+	type methodInput struct {
+		Method string `json:"method"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var in methodInput
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		vm := &models.ValuationModel{DB: db}
+		if err := vm.SetMethod(userID, in.Method); err != nil {
+			if err == models.ErrInvalidCostingMethod {
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "method must be fifo or wac"})
+				return
+			}
+			if err == models.ErrNotFound {
+				http.NotFound(w, r)
+				return
+			}
+			http.Error(w, "could not update costing method", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
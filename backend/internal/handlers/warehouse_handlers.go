@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"stock-in-order/backend/internal/middleware"
+	"stock-in-order/backend/internal/models"
+)
+
+// WarehouseInput DTO para crear o actualizar un depósito
+type WarehouseInput struct {
+	Name    string `json:"name" validate:"required"`
+	Code    string `json:"code" validate:"required"`
+	Address string `json:"address"`
+}
+
+// CreateWarehouse handles POST /api/v1/warehouses
+func CreateWarehouse(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var in WarehouseInput
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if err := validate.Struct(in); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]any{"error": "validation failed", "details": err.Error()})
+			return
+		}
+
+		wh := &models.Warehouse{
+			Name:    in.Name,
+			Code:    in.Code,
+			Address: in.Address,
+			UserID:  userID,
+		}
+
+		wm := &models.WarehouseModel{DB: db}
+		if err := wm.Insert(wh); err != nil {
+			if err == models.ErrDuplicateWarehouse {
+				w.WriteHeader(http.StatusConflict)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "warehouse code already exists"})
+				return
+			}
+			http.Error(w, "could not create warehouse", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(wh)
+	}
+}
+
+// ListWarehouses handles GET /api/v1/warehouses
+func ListWarehouses(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		wm := &models.WarehouseModel{DB: db}
+		items, err := wm.GetAllForUser(userID)
+		if err != nil {
+			http.Error(w, "could not fetch warehouses", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(items)
+	}
+}
+
+// GetWarehouse handles GET /api/v1/warehouses/{id}
+func GetWarehouse(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+
+		wm := &models.WarehouseModel{DB: db}
+		wh, err := wm.GetByID(id, userID)
+		if err != nil {
+			if err == models.ErrWarehouseNotFound {
+				http.NotFound(w, r)
+				return
+			}
+			http.Error(w, "could not fetch warehouse", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(wh)
+	}
+}
+
+// GetWarehouseStock handles GET /api/v1/warehouses/{id}/stock
+func GetWarehouseStock(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+
+		wm := &models.WarehouseModel{DB: db}
+		stock, err := wm.GetStock(id, userID)
+		if err != nil {
+			if err == models.ErrWarehouseNotFound {
+				http.NotFound(w, r)
+				return
+			}
+			http.Error(w, "could not fetch warehouse stock", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(stock)
+	}
+}
+
+// UpdateWarehouse handles PUT /api/v1/warehouses/{id}
+func UpdateWarehouse(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+
+		var in WarehouseInput
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if err := validate.Struct(in); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]any{"error": "validation failed", "details": err.Error()})
+			return
+		}
+
+		wh := &models.Warehouse{
+			Name:    in.Name,
+			Code:    in.Code,
+			Address: in.Address,
+		}
+
+		wm := &models.WarehouseModel{DB: db}
+		if err := wm.Update(id, userID, wh); err != nil {
+			if err == models.ErrWarehouseNotFound {
+				http.NotFound(w, r)
+				return
+			}
+			if err == models.ErrDuplicateWarehouse {
+				w.WriteHeader(http.StatusConflict)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "warehouse code already exists"})
+				return
+			}
+			http.Error(w, "could not update warehouse", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// SetDefaultWarehouse handles PUT /api/v1/warehouses/{id}/default
+func SetDefaultWarehouse(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+
+		wm := &models.WarehouseModel{DB: db}
+		if err := wm.SetDefault(id, userID); err != nil {
+			if err == models.ErrWarehouseNotFound {
+				http.NotFound(w, r)
+				return
+			}
+			http.Error(w, "could not set default warehouse", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// DeleteWarehouse handles DELETE /api/v1/warehouses/{id}
+func DeleteWarehouse(db *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.ParseInt(vars["id"], 10, 64)
+
+		wm := &models.WarehouseModel{DB: db}
+		if err := wm.Delete(id, userID); err != nil {
+			switch err {
+			case models.ErrWarehouseNotFound:
+				http.NotFound(w, r)
+			case models.ErrDefaultWarehouseDel:
+				w.WriteHeader(http.StatusConflict)
+				_ = json.NewEncoder(w).Encode(map[string]string{"error": "No se puede eliminar el depósito por defecto"})
+			case models.ErrWarehouseNotEmpty:
+				w.WriteHeader(http.StatusConflict)
+				_ = json.NewEncoder(w).Encode(map[string]string{"error": "No se puede eliminar un depósito que todavía tiene stock"})
+			case models.ErrHasReferences:
+				w.WriteHeader(http.StatusConflict)
+				_ = json.NewEncoder(w).Encode(map[string]string{
+					"error": "No se puede eliminar el depósito porque está siendo usado en órdenes o movimientos de stock",
+				})
+			default:
+				slog.Error("DeleteWarehouse failed", "error", err, "warehouseID", id, "userID", userID)
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ArchiveFilter indica qué registros devuelve un listado según su estado de archivo. Las consultas
+// lo reciben como parámetro de texto: ($n::text = 'include' OR (archived_at IS NOT NULL) = ($n::text = 'only')).
+type ArchiveFilter string
+
+// Filtros de archivo para productos, clientes y proveedores
+const (
+	ArchiveActive   ArchiveFilter = ""        // sólo los activos (por defecto)
+	ArchiveOnly     ArchiveFilter = "only"    // sólo los archivados, para restaurarlos
+	ArchiveIncluded ArchiveFilter = "include" // activos y archivados (reportes)
+)
+
+// Valid indica si f es un filtro conocido.
+func (f ArchiveFilter) Valid() bool {
+	return f == ArchiveActive || f == ArchiveOnly || f == ArchiveIncluded
+}
+
+// Errors for archived records
+var (
+	ErrProductArchived  = errors.New("product is archived")
+	ErrCustomerArchived = errors.New("customer is archived")
+	ErrSupplierArchived = errors.New("supplier is archived")
+	ErrCustomerNotFound = errors.New("customer not found")
+	ErrSupplierNotFound = errors.New("supplier not found")
+)
+
+// Tablas con baja lógica. Sólo se interpolan en SQL desde estas constantes.
+const (
+	archivableProducts  = "products"
+	archivableCustomers = "customers"
+	archivableSuppliers = "suppliers"
+)
+
+// checkActive verifica que el registro exista, sea del usuario y no esté archivado.
+func checkActive(ctx context.Context, q dbtx, table string, id int64, userID int64, notFound, archived error) error {
+	var isArchived bool
+	err := q.QueryRow(ctx, `SELECT archived_at IS NOT NULL FROM `+table+` WHERE id = $1 AND user_id = $2`, id, userID).Scan(&isArchived)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return notFound
+		}
+		return err
+	}
+	if isArchived {
+		return archived
+	}
+	return nil
+}
+
+// checkActiveProduct verifica que un producto pueda cargarse en una orden nueva.
+func checkActiveProduct(ctx context.Context, q dbtx, productID int64, userID int64) error {
+	return checkActive(ctx, q, archivableProducts, productID, userID, ErrNotFound, ErrProductArchived)
+}
+
+// checkActiveCustomer verifica que un cliente pueda usarse en una orden de venta nueva.
+func checkActiveCustomer(ctx context.Context, q dbtx, customerID int64, userID int64) error {
+	return checkActive(ctx, q, archivableCustomers, customerID, userID, ErrCustomerNotFound, ErrCustomerArchived)
+}
+
+// checkActiveSupplier verifica que un proveedor pueda usarse en una orden de compra nueva.
+func checkActiveSupplier(ctx context.Context, q dbtx, supplierID int64, userID int64) error {
+	return checkActive(ctx, q, archivableSuppliers, supplierID, userID, ErrSupplierNotFound, ErrSupplierArchived)
+}
+
+// setArchived archiva (o restaura) un registro del usuario. Archivar un registro ya archivado
+// conserva la fecha original.
+func setArchived(ctx context.Context, q dbtx, table string, id int64, userID int64, archive bool) error {
+	const set = `archived_at = CASE WHEN $3 THEN COALESCE(archived_at, NOW()) END`
+	tag, err := q.Exec(ctx, `UPDATE `+table+` SET `+set+` WHERE id = $1 AND user_id = $2`, id, userID, archive)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// archiveAs es setArchived en una transacción propia que queda en el historial a nombre del usuario.
+func archiveAs(ctx context.Context, db *pgxpool.Pool, table string, id int64, userID int64, archive bool) error {
+	return withActor(ctx, db, userID, func(tx pgx.Tx) error {
+		return setArchived(ctx, tx, table, id, userID, archive)
+	})
+}
@@ -0,0 +1,309 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Tipos de adjunto de un producto
+const (
+	AttachmentImage           = "image"
+	AttachmentSpecSheet       = "spec_sheet"
+	AttachmentSafetyDataSheet = "safety_data_sheet"
+	AttachmentDocument        = "document"
+)
+
+// ProductAttachment es una imagen o documento adjunto a un producto. Los archivos viven en el
+// storage configurado bajo StorageKey (y ThumbnailKey para las miniaturas de las imágenes).
+type ProductAttachment struct {
+	ID           int64     `json:"id"`
+	ProductID    int64     `json:"product_id"`
+	Kind         string    `json:"kind"`
+	FileName     string    `json:"file_name"`
+	ContentType  string    `json:"content_type"`
+	SizeBytes    int64     `json:"size_bytes"`
+	IsPrimary    bool      `json:"is_primary"`
+	CreatedAt    time.Time `json:"created_at"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url,omitempty"`
+
+	StorageKey   string  `json:"-"`
+	ThumbnailKey *string `json:"-"`
+}
+
+// Errors for attachment operations
+var (
+	ErrInvalidAttachmentKind = errors.New("invalid attachment kind")
+	ErrNotAnImage            = errors.New("attachment is not an image")
+)
+
+// ValidAttachmentKind indica si kind es un tipo de adjunto conocido.
+func ValidAttachmentKind(kind string) bool {
+	switch kind {
+	case AttachmentImage, AttachmentSpecSheet, AttachmentSafetyDataSheet, AttachmentDocument:
+		return true
+	}
+	return false
+}
+
+// setURLs completa las rutas de descarga del archivo y de la miniatura.
+func (a *ProductAttachment) setURLs() {
+	base := fmt.Sprintf("/api/v1/products/%d/attachments/%d", a.ProductID, a.ID)
+	a.URL = base + "/file"
+	a.ThumbnailURL = ""
+	if a.ThumbnailKey != nil {
+		a.ThumbnailURL = base + "/thumbnail"
+	}
+}
+
+const attachmentColumns = `id, product_id, kind, file_name, content_type, size_bytes, is_primary, created_at, storage_key, thumbnail_key`
+
+func scanAttachment(row pgx.Row, a *ProductAttachment) error {
+	if err := row.Scan(
+		&a.ID, &a.ProductID, &a.Kind, &a.FileName, &a.ContentType, &a.SizeBytes, &a.IsPrimary, &a.CreatedAt,
+		&a.StorageKey, &a.ThumbnailKey,
+	); err != nil {
+		return err
+	}
+	a.setURLs()
+	return nil
+}
+
+// productAttachments devuelve los adjuntos de un producto: la imagen principal primero, luego
+// el resto de las imágenes y los documentos, cada grupo del más antiguo al más nuevo.
+func productAttachments(ctx context.Context, q dbtx, productID int64) ([]ProductAttachment, error) {
+	const qAttachments = `
+		SELECT ` + attachmentColumns + `
+		FROM product_attachments
+		WHERE product_id = $1
+		ORDER BY is_primary DESC, kind <> 'image', id`
+
+	rows, err := q.Query(ctx, qAttachments, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []ProductAttachment{}
+	for rows.Next() {
+		var a ProductAttachment
+		if err := scanAttachment(rows, &a); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return out, nil
+}
+
+// AttachmentModel wraps DB access for product attachments.
+type AttachmentModel struct {
+	DB *pgxpool.Pool
+}
+
+// ProductExists indica si el producto existe y es del usuario; se consulta antes de subir archivos.
+func (m *AttachmentModel) ProductExists(productID int64, userID int64) (bool, error) {
+	var exists bool
+	err := m.DB.QueryRow(context.Background(), `SELECT EXISTS (SELECT 1 FROM products WHERE id = $1 AND user_id = $2)`, productID, userID).Scan(&exists)
+	return exists, err
+}
+
+// GetForProduct devuelve los adjuntos de un producto del usuario.
+func (m *AttachmentModel) GetForProduct(productID int64, userID int64) ([]ProductAttachment, error) {
+	exists, err := m.ProductExists(productID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return productAttachments(context.Background(), m.DB, productID)
+}
+
+// Get devuelve un adjunto de un producto del usuario.
+func (m *AttachmentModel) Get(productID int64, attachmentID int64, userID int64) (*ProductAttachment, error) {
+	const q = `
+		SELECT ` + attachmentColumns + `
+		FROM product_attachments
+		WHERE id = $1 AND product_id = $2 AND user_id = $3`
+
+	var a ProductAttachment
+	if err := scanAttachment(m.DB.QueryRow(context.Background(), q, attachmentID, productID, userID), &a); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &a, nil
+}
+
+// Insert registra un adjunto ya guardado en el storage. La primera imagen de un producto queda
+// como principal aunque no se pida; marcar otra como principal desmarca la anterior.
+func (m *AttachmentModel) Insert(userID int64, a *ProductAttachment) error {
+	if !ValidAttachmentKind(a.Kind) {
+		return ErrInvalidAttachmentKind
+	}
+	if a.Kind != AttachmentImage {
+		a.IsPrimary = false
+	}
+
+	ctx := context.Background()
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if tx != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	// Bloquea el producto para serializar los cambios de imagen principal
+	var hasPrimary bool
+	err = tx.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM product_attachments WHERE product_id = p.id AND is_primary)
+		FROM products p
+		WHERE p.id = $1 AND p.user_id = $2
+		FOR UPDATE`, a.ProductID, userID).Scan(&hasPrimary)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	if a.Kind == AttachmentImage && !hasPrimary {
+		a.IsPrimary = true
+	}
+	if a.IsPrimary && hasPrimary {
+		if _, err := tx.Exec(ctx, `UPDATE product_attachments SET is_primary = false WHERE product_id = $1 AND is_primary`, a.ProductID); err != nil {
+			return err
+		}
+	}
+
+	const q = `
+		INSERT INTO product_attachments
+			(product_id, user_id, kind, file_name, content_type, size_bytes, storage_key, thumbnail_key, is_primary)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at`
+	err = tx.QueryRow(ctx, q, a.ProductID, userID, a.Kind, a.FileName, a.ContentType, a.SizeBytes,
+		a.StorageKey, a.ThumbnailKey, a.IsPrimary).Scan(&a.ID, &a.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	tx = nil
+	a.setURLs()
+	return nil
+}
+
+// SetPrimary marca una imagen como la principal del producto.
+func (m *AttachmentModel) SetPrimary(productID int64, attachmentID int64, userID int64) error {
+	ctx := context.Background()
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if tx != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	var kind string
+	err = tx.QueryRow(ctx, `
+		SELECT a.kind
+		FROM product_attachments a
+		JOIN products p ON p.id = a.product_id
+		WHERE a.id = $1 AND a.product_id = $2 AND a.user_id = $3
+		FOR UPDATE OF p`, attachmentID, productID, userID).Scan(&kind)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+	if kind != AttachmentImage {
+		return ErrNotAnImage
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE product_attachments SET is_primary = false WHERE product_id = $1 AND is_primary AND id <> $2`, productID, attachmentID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE product_attachments SET is_primary = true WHERE id = $1`, attachmentID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	tx = nil
+	return nil
+}
+
+// Delete borra el registro de un adjunto y lo devuelve para que el llamador borre sus archivos.
+// Si era la imagen principal, pasa a serlo la imagen más antigua que quede.
+func (m *AttachmentModel) Delete(productID int64, attachmentID int64, userID int64) (*ProductAttachment, error) {
+	ctx := context.Background()
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if tx != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	const q = `
+		DELETE FROM product_attachments
+		WHERE id = $1 AND product_id = $2 AND user_id = $3
+		RETURNING ` + attachmentColumns
+
+	var a ProductAttachment
+	if err := scanAttachment(tx.QueryRow(ctx, q, attachmentID, productID, userID), &a); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	if a.IsPrimary {
+		_, err := tx.Exec(ctx, `
+			UPDATE product_attachments SET is_primary = true
+			WHERE id = (
+				SELECT id FROM product_attachments
+				WHERE product_id = $1 AND kind = 'image'
+				ORDER BY id
+				LIMIT 1
+			)`, productID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	tx = nil
+	return &a, nil
+}
+
+// StorageKeys devuelve las claves del archivo y, si la tiene, de su miniatura.
+func (a ProductAttachment) StorageKeys() []string {
+	keys := []string{a.StorageKey}
+	if a.ThumbnailKey != nil {
+		keys = append(keys, *a.ThumbnailKey)
+	}
+	return keys
+}
@@ -0,0 +1,162 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"stock-in-order/backend/internal/barcode"
+)
+
+// ProductBarcode es un código de barras de un producto.
+type ProductBarcode struct {
+	ID        int64     `json:"id"`
+	ProductID int64     `json:"product_id"`
+	Code      string    `json:"code"`
+	Symbology string    `json:"symbology"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ErrDuplicateBarcode is returned when the code is already assigned to a product of the account.
+var ErrDuplicateBarcode = errors.New("duplicate barcode")
+
+// productBarcodes devuelve los códigos de barras de un producto, el más antiguo primero.
+func productBarcodes(ctx context.Context, q dbtx, productID int64) ([]ProductBarcode, error) {
+	const qBarcodes = `
+		SELECT id, product_id, code, symbology, created_at
+		FROM product_barcodes
+		WHERE product_id = $1
+		ORDER BY id`
+
+	rows, err := q.Query(ctx, qBarcodes, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []ProductBarcode{}
+	for rows.Next() {
+		var b ProductBarcode
+		if err := rows.Scan(&b.ID, &b.ProductID, &b.Code, &b.Symbology, &b.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, b)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return out, nil
+}
+
+// BarcodeModel wraps DB access for product barcodes.
+type BarcodeModel struct {
+	DB *pgxpool.Pool
+}
+
+// GetForProduct devuelve los códigos de barras de un producto del usuario.
+func (m *BarcodeModel) GetForProduct(productID int64, userID int64) ([]ProductBarcode, error) {
+	ctx := context.Background()
+
+	var exists bool
+	if err := m.DB.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM products WHERE id = $1 AND user_id = $2)`, productID, userID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return productBarcodes(ctx, m.DB, productID)
+}
+
+// Insert agrega un código de barras a un producto del usuario. Sin simbología se deduce del código
+// (13 dígitos EAN-13, 12 dígitos UPC-A, el resto Code128); EAN-13 y UPC-A validan su dígito verificador.
+// Un UPC-A se guarda como su EAN-13 equivalente para que no se duplique con él.
+func (m *BarcodeModel) Insert(userID int64, b *ProductBarcode) error {
+	var err error
+	if b.Symbology == "" {
+		b.Symbology, err = barcode.Detect(b.Code)
+	} else {
+		err = barcode.Validate(b.Code, b.Symbology)
+	}
+	if err != nil {
+		return err
+	}
+	if b.Symbology == barcode.UPCA {
+		b.Code, b.Symbology = barcode.Canonical(b.Code), barcode.EAN13
+	}
+
+	const q = `
+		INSERT INTO product_barcodes (product_id, user_id, code, symbology)
+		SELECT id, user_id, $3, $4 FROM products WHERE id = $1 AND user_id = $2
+		RETURNING id, created_at`
+
+	err = m.DB.QueryRow(context.Background(), q, b.ProductID, userID, b.Code, b.Symbology).Scan(&b.ID, &b.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique_violation (user_id, code)
+			return ErrDuplicateBarcode
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete quita un código de barras de un producto del usuario.
+func (m *BarcodeModel) Delete(productID int64, barcodeID int64, userID int64) error {
+	const q = `DELETE FROM product_barcodes WHERE id = $1 AND product_id = $2 AND user_id = $3`
+
+	tag, err := m.DB.Exec(context.Background(), q, barcodeID, productID, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// FindProductID resuelve un código escaneado: primero entre los códigos de barras de la cuenta (un
+// UPC-A se busca como su EAN-13 equivalente) y, si no hay coincidencia, contra el SKU (los productos
+// sin código cargado siguen pudiendo escanearse).
+func (m *BarcodeModel) FindProductID(code string, userID int64) (int64, error) {
+	const q = `
+		SELECT product_id FROM (
+			SELECT product_id, 0 AS priority FROM product_barcodes WHERE user_id = $1 AND code = $3
+			UNION ALL
+			SELECT id, 1 FROM products WHERE user_id = $1 AND sku = $2
+		) matches
+		ORDER BY priority
+		LIMIT 1`
+
+	var productID int64
+	if err := m.DB.QueryRow(context.Background(), q, userID, code, barcode.Canonical(code)).Scan(&productID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrNotFound
+		}
+		return 0, err
+	}
+	return productID, nil
+}
+
+// LabelFor arma la etiqueta de un producto con el código indicado (debe ser del producto) o, si
+// code está vacío, con su primer código de barras. Sin códigos cargados se imprime el SKU en Code128.
+func LabelFor(p *Product, code string) (barcode.Label, error) {
+	label := barcode.Label{Title: p.Name}
+	for _, b := range p.Barcodes {
+		if code == "" || b.Code == barcode.Canonical(code) {
+			label.Code, label.Symbology = b.Code, b.Symbology
+			return label, nil
+		}
+	}
+	if code != "" && code != p.SKU {
+		return label, ErrNotFound
+	}
+	label.Code, label.Symbology = p.SKU, barcode.Code128
+	return label, barcode.Validate(p.SKU, barcode.Code128)
+}
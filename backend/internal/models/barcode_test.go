@@ -0,0 +1,30 @@
+package models
+
+import (
+	"testing"
+
+	"stock-in-order/backend/internal/barcode"
+)
+
+func TestLabelForUPCAndEAN(t *testing.T) {
+	p := &Product{Name: "Gaseosa", SKU: "GAS-1", Barcodes: []ProductBarcode{
+		{Code: "0036000291452", Symbology: barcode.EAN13}, // UPC-A 036000291452 guardado como EAN-13
+	}}
+
+	for _, code := range []string{"036000291452", "0036000291452"} {
+		label, err := LabelFor(p, code)
+		if err != nil {
+			t.Fatalf("LabelFor(%q): unexpected error %v", code, err)
+		}
+		if label.Code != "0036000291452" || label.Symbology != barcode.EAN13 {
+			t.Errorf("LabelFor(%q) = %s %s; want ean13 0036000291452", code, label.Symbology, label.Code)
+		}
+	}
+
+	if _, err := LabelFor(p, "036000291453"); err != ErrNotFound {
+		t.Errorf("unknown code: expected ErrNotFound, got %v", err)
+	}
+	if label, err := LabelFor(p, "GAS-1"); err != nil || label.Symbology != barcode.Code128 {
+		t.Errorf("SKU: expected a code128 label, got %+v (%v)", label, err)
+	}
+}
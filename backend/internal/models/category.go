@@ -0,0 +1,382 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Category es un nodo del árbol de categorías de productos del usuario.
+type Category struct {
+	ID        int64      `json:"id"`
+	Name      string     `json:"name"`
+	ParentID  *int64     `json:"parent_id,omitempty"`
+	UserID    int64      `json:"user_id"`
+	CreatedAt time.Time  `json:"created_at"`
+	Children  []Category `json:"children,omitempty"`
+}
+
+// UncategorizedLabel es el nombre con el que se agrupan los productos sin categoría.
+const UncategorizedLabel = "Sin categoría"
+
+// Errors for category operations
+var (
+	ErrCategoryNotFound   = errors.New("category not found")
+	ErrDuplicateCategory  = errors.New("duplicate category name under the same parent")
+	ErrInvalidCategoryRef = errors.New("invalid parent category")
+)
+
+// categoryDescendantsCTE selecciona la categoría $2 del usuario $1 y todas sus subcategorías.
+const categoryDescendantsCTE = `
+	WITH RECURSIVE category_tree AS (
+		SELECT id FROM categories WHERE id = $2 AND user_id = $1
+		UNION ALL
+		SELECT c.id FROM categories c JOIN category_tree t ON c.parent_id = t.id
+	)
+	SELECT id FROM category_tree`
+
+// validateCategory verifica que la categoría exista y sea del usuario.
+func validateCategory(ctx context.Context, q dbtx, categoryID *int64, userID int64) error {
+	if categoryID == nil {
+		return nil
+	}
+	var exists bool
+	err := q.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM categories WHERE id = $1 AND user_id = $2)`, *categoryID, userID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrCategoryNotFound
+	}
+	return nil
+}
+
+// CategoryModel wraps DB access for product categories.
+type CategoryModel struct {
+	DB *pgxpool.Pool
+}
+
+// Insert crea una categoría, como raíz o bajo ParentID.
+func (m *CategoryModel) Insert(c *Category) error {
+	ctx := context.Background()
+	if err := m.validateParent(ctx, 0, c.ParentID, c.UserID); err != nil {
+		return err
+	}
+
+	const q = `
+		INSERT INTO categories (name, parent_id, user_id)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at`
+
+	err := m.DB.QueryRow(ctx, q, c.Name, c.ParentID, c.UserID).Scan(&c.ID, &c.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique_violation (user_id, parent_id, name)
+			return ErrDuplicateCategory
+		}
+		return err
+	}
+	return nil
+}
+
+// validateParent verifica que el padre sea del usuario y no sea la categoría misma ni una de sus subcategorías.
+func (m *CategoryModel) validateParent(ctx context.Context, categoryID int64, parentID *int64, userID int64) error {
+	if parentID == nil {
+		return nil
+	}
+	if err := validateCategory(ctx, m.DB, parentID, userID); err != nil {
+		if err == ErrCategoryNotFound {
+			return ErrInvalidCategoryRef
+		}
+		return err
+	}
+	if categoryID == 0 {
+		return nil
+	}
+
+	var cycle bool
+	q := `SELECT $3 IN (` + categoryDescendantsCTE + `)`
+	if err := m.DB.QueryRow(ctx, q, userID, categoryID, *parentID).Scan(&cycle); err != nil {
+		return err
+	}
+	if cycle {
+		return ErrInvalidCategoryRef
+	}
+	return nil
+}
+
+// GetAllForUser devuelve todas las categorías del usuario como lista plana.
+func (m *CategoryModel) GetAllForUser(userID int64) ([]Category, error) {
+	const q = `
+		SELECT id, name, parent_id, user_id, created_at
+		FROM categories
+		WHERE user_id = $1
+		ORDER BY name, id`
+
+	rows, err := m.DB.Query(context.Background(), q, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Category{}
+	for rows.Next() {
+		var c Category
+		if err := rows.Scan(&c.ID, &c.Name, &c.ParentID, &c.UserID, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return out, nil
+}
+
+// GetTree devuelve las categorías raíz del usuario con sus subcategorías anidadas en Children.
+func (m *CategoryModel) GetTree(userID int64) ([]Category, error) {
+	flat, err := m.GetAllForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	return BuildCategoryTree(flat), nil
+}
+
+// BuildCategoryTree arma el árbol a partir de la lista plana, respetando el orden de entrada.
+func BuildCategoryTree(flat []Category) []Category {
+	children := make(map[int64][]Category, len(flat))
+	for _, c := range flat {
+		if c.ParentID != nil {
+			children[*c.ParentID] = append(children[*c.ParentID], c)
+		}
+	}
+
+	var attach func(c Category) Category
+	attach = func(c Category) Category {
+		for _, child := range children[c.ID] {
+			c.Children = append(c.Children, attach(child))
+		}
+		return c
+	}
+
+	roots := []Category{}
+	for _, c := range flat {
+		if c.ParentID == nil {
+			roots = append(roots, attach(c))
+		}
+	}
+	return roots
+}
+
+// CategoryPaths devuelve el nombre completo de cada categoría ("Bebidas / Gaseosas").
+func CategoryPaths(flat []Category) map[int64]string {
+	byID := make(map[int64]Category, len(flat))
+	for _, c := range flat {
+		byID[c.ID] = c
+	}
+
+	paths := make(map[int64]string, len(flat))
+	var path func(id int64) string
+	path = func(id int64) string {
+		if p, ok := paths[id]; ok {
+			return p
+		}
+		c := byID[id]
+		p := c.Name
+		if c.ParentID != nil {
+			p = path(*c.ParentID) + " / " + c.Name
+		}
+		paths[id] = p
+		return p
+	}
+	for _, c := range flat {
+		path(c.ID)
+	}
+	return paths
+}
+
+// CategoryLabel devuelve el nombre completo de la categoría de un producto, o UncategorizedLabel.
+func CategoryLabel(paths map[int64]string, categoryID *int64) string {
+	if categoryID == nil {
+		return UncategorizedLabel
+	}
+	return paths[*categoryID]
+}
+
+// Update cambia el nombre y el padre de una categoría.
+func (m *CategoryModel) Update(id int64, userID int64, c *Category) error {
+	ctx := context.Background()
+	if err := m.validateParent(ctx, id, c.ParentID, userID); err != nil {
+		return err
+	}
+
+	const q = `
+		UPDATE categories
+		SET name = $1, parent_id = $2
+		WHERE id = $3 AND user_id = $4`
+
+	tag, err := m.DB.Exec(ctx, q, c.Name, c.ParentID, id, userID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrDuplicateCategory
+		}
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrCategoryNotFound
+	}
+	return nil
+}
+
+// Delete elimina una categoría sin subcategorías ni productos asignados.
+func (m *CategoryModel) Delete(id int64, userID int64) error {
+	tag, err := m.DB.Exec(context.Background(), `DELETE FROM categories WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" { // foreign_key_violation
+			return ErrHasReferences
+		}
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrCategoryNotFound
+	}
+	return nil
+}
+
+// Tag es una etiqueta libre de productos.
+type Tag struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	Products int    `json:"products"`
+}
+
+// TagModel wraps DB access for product tags.
+type TagModel struct {
+	DB *pgxpool.Pool
+}
+
+// GetAllForUser devuelve las etiquetas del usuario con la cantidad de productos de cada una.
+func (m *TagModel) GetAllForUser(userID int64) ([]Tag, error) {
+	const q = `
+		SELECT t.id, t.name, COUNT(pt.product_id)
+		FROM tags t
+		LEFT JOIN product_tags pt ON pt.tag_id = t.id
+		WHERE t.user_id = $1
+		GROUP BY t.id, t.name
+		ORDER BY t.name`
+
+	rows, err := m.DB.Query(context.Background(), q, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Tag{}
+	for rows.Next() {
+		var t Tag
+		if err := rows.Scan(&t.ID, &t.Name, &t.Products); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return out, nil
+}
+
+// NormalizeTag deja una etiqueta en su forma canónica: sin espacios en los extremos y en minúsculas.
+func NormalizeTag(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// normalizeTags normaliza, descarta vacías y quita repetidas, ordenando el resultado.
+func normalizeTags(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	out := []string{}
+	for _, n := range names {
+		n = NormalizeTag(n)
+		if n == "" || seen[n] {
+			continue
+		}
+		seen[n] = true
+		out = append(out, n)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// setProductTags reemplaza las etiquetas del producto, creando las que el usuario todavía no tiene.
+// Las etiquetas que quedan sin productos se conservan para seguir ofreciéndolas.
+func setProductTags(ctx context.Context, tx pgx.Tx, productID int64, userID int64, names []string) ([]string, error) {
+	names = normalizeTags(names)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM product_tags WHERE product_id = $1`, productID); err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return names, nil
+	}
+
+	const upsert = `
+		INSERT INTO tags (name, user_id)
+		SELECT unnest($1::text[]), $2
+		ON CONFLICT (user_id, name) DO NOTHING`
+	if _, err := tx.Exec(ctx, upsert, names, userID); err != nil {
+		return nil, err
+	}
+
+	const link = `
+		INSERT INTO product_tags (product_id, tag_id)
+		SELECT $1, id FROM tags WHERE user_id = $2 AND name = ANY($3)`
+	if _, err := tx.Exec(ctx, link, productID, userID, names); err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// loadProductTags devuelve las etiquetas de los productos del usuario (o de uno solo si productID != 0).
+func loadProductTags(ctx context.Context, q dbtx, userID int64, productID int64) (map[int64][]string, error) {
+	const qTags = `
+		SELECT pt.product_id, t.name
+		FROM product_tags pt
+		JOIN tags t ON t.id = pt.tag_id
+		WHERE t.user_id = $1 AND ($2::bigint = 0 OR pt.product_id = $2)
+		ORDER BY pt.product_id, t.name`
+
+	rows, err := q.Query(ctx, qTags, userID, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[int64][]string{}
+	for rows.Next() {
+		var pid int64
+		var name string
+		if err := rows.Scan(&pid, &name); err != nil {
+			return nil, err
+		}
+		out[pid] = append(out[pid], name)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return out, nil
+}
+
+// tagsOrEmpty evita serializar null cuando un producto no tiene etiquetas.
+func tagsOrEmpty(t []string) []string {
+	if t == nil {
+		return []string{}
+	}
+	return t
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCategoryTreeAndPaths(t *testing.T) {
+	bebidas, gaseosas := int64(1), int64(2)
+	flat := []Category{
+		{ID: 1, Name: "Bebidas"},
+		{ID: 2, Name: "Gaseosas", ParentID: &bebidas},
+		{ID: 3, Name: "Cola", ParentID: &gaseosas},
+		{ID: 4, Name: "Limpieza"},
+	}
+
+	tree := BuildCategoryTree(flat)
+	if len(tree) != 2 || len(tree[0].Children) != 1 || len(tree[0].Children[0].Children) != 1 {
+		t.Fatalf("unexpected tree: %+v", tree)
+	}
+
+	paths := CategoryPaths(flat)
+	if paths[3] != "Bebidas / Gaseosas / Cola" {
+		t.Fatalf("unexpected path: %q", paths[3])
+	}
+	if got := CategoryLabel(paths, nil); got != UncategorizedLabel {
+		t.Fatalf("expected %q for products without category, got %q", UncategorizedLabel, got)
+	}
+}
+
+func TestNormalizeTags(t *testing.T) {
+	got := normalizeTags([]string{" Oferta", "oferta", "", "Verano "})
+	if want := []string{"oferta", "verano"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,26 @@
+package models
+
+// Clases ABC: aporte a la facturación de los últimos 12 meses (A = el 80% de la facturación,
+// B = el 15% siguiente, C = el resto y los productos sin ventas).
+const (
+	AbcClassA = "A"
+	AbcClassB = "B"
+	AbcClassC = "C"
+)
+
+// Clases XYZ: variabilidad de la demanda semanal (X = estable, Y = variable, Z = errática o sin ventas).
+const (
+	XyzClassX = "X"
+	XyzClassY = "Y"
+	XyzClassZ = "Z"
+)
+
+// ValidAbcClass indica si c es una clase ABC conocida.
+func ValidAbcClass(c string) bool {
+	return c == AbcClassA || c == AbcClassB || c == AbcClassC
+}
+
+// ValidXyzClass indica si c es una clase XYZ conocida.
+func ValidXyzClass(c string) bool {
+	return c == XyzClassX || c == XyzClassY || c == XyzClassZ
+}
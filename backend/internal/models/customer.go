@@ -19,6 +19,12 @@ type Customer struct {
 	Address   string    `json:"address,omitempty"`
 	UserID    int64     `json:"user_id"`
 	CreatedAt time.Time `json:"created_at"`
+
+	// ArchivedAt marca un cliente archivado: no se lista ni se usa en órdenes nuevas, pero sigue en el historial.
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+
+	// PriceListID es la lista de precios acordada con el cliente (nil = lista por defecto).
+	PriceListID *int64 `json:"price_list_id,omitempty"`
 }
 
 // CustomerModel wraps DB access for customers.
@@ -39,13 +45,13 @@ func (m *CustomerModel) Insert(c *Customer) error {
 // GetByID returns a customer by ID if it belongs to the user.
 func (m *CustomerModel) GetByID(id int64, userID int64) (*Customer, error) {
 	const q = `
-		SELECT id, name, email, phone, address, user_id, created_at
+		SELECT id, name, email, phone, address, user_id, created_at, price_list_id, archived_at
 		FROM customers
 		WHERE id = $1 AND user_id = $2`
 
 	var c Customer
 	err := m.DB.QueryRow(context.Background(), q, id, userID).Scan(
-		&c.ID, &c.Name, &c.Email, &c.Phone, &c.Address, &c.UserID, &c.CreatedAt,
+		&c.ID, &c.Name, &c.Email, &c.Phone, &c.Address, &c.UserID, &c.CreatedAt, &c.PriceListID, &c.ArchivedAt,
 	)
 	if err != nil {
 		if err == pgx.ErrNoRows {
@@ -56,15 +62,16 @@ func (m *CustomerModel) GetByID(id int64, userID int64) (*Customer, error) {
 	return &c, nil
 }
 
-// GetAllForUser lists all customers for a user.
-func (m *CustomerModel) GetAllForUser(userID int64) ([]Customer, error) {
+// GetAllForUser lists the customers of a user; archived lists which ones by archive state.
+func (m *CustomerModel) GetAllForUser(userID int64, archived ArchiveFilter) ([]Customer, error) {
 	const q = `
-		SELECT id, name, email, phone, address, user_id, created_at
+		SELECT id, name, email, phone, address, user_id, created_at, price_list_id, archived_at
 		FROM customers
 		WHERE user_id = $1
+			AND ($2::text = 'include' OR (archived_at IS NOT NULL) = ($2::text = 'only'))
 		ORDER BY id`
 
-	rows, err := m.DB.Query(context.Background(), q, userID)
+	rows, err := m.DB.Query(context.Background(), q, userID, string(archived))
 	if err != nil {
 		return nil, err
 	}
@@ -73,7 +80,7 @@ func (m *CustomerModel) GetAllForUser(userID int64) ([]Customer, error) {
 	out := []Customer{} // Initialize as empty slice instead of nil
 	for rows.Next() {
 		var c Customer
-		if err := rows.Scan(&c.ID, &c.Name, &c.Email, &c.Phone, &c.Address, &c.UserID, &c.CreatedAt); err != nil {
+		if err := rows.Scan(&c.ID, &c.Name, &c.Email, &c.Phone, &c.Address, &c.UserID, &c.CreatedAt, &c.PriceListID, &c.ArchivedAt); err != nil {
 			return nil, err
 		}
 		out = append(out, c)
@@ -91,14 +98,35 @@ func (m *CustomerModel) Update(id int64, userID int64, c *Customer) error {
 		SET name = $1, email = $2, phone = $3, address = $4
 		WHERE id = $5 AND user_id = $6`
 
-	tag, err := m.DB.Exec(context.Background(), q, c.Name, c.Email, c.Phone, c.Address, id, userID)
-	if err != nil {
-		return err
-	}
-	if tag.RowsAffected() == 0 {
-		return ErrNotFound
+	return updateAs(context.Background(), m.DB, userID, q, c.Name, c.Email, c.Phone, c.Address, id, userID)
+}
+
+// SetPriceList asigna al cliente una lista de precios del usuario; nil vuelve a la lista por defecto.
+func (m *CustomerModel) SetPriceList(id int64, userID int64, priceListID *int64) error {
+	ctx := context.Background()
+
+	if priceListID != nil {
+		var exists bool
+		err := m.DB.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM price_lists WHERE id = $1 AND user_id = $2)`, *priceListID, userID).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return ErrPriceListNotFound
+		}
 	}
-	return nil
+
+	return updateAs(ctx, m.DB, userID, `UPDATE customers SET price_list_id = $1 WHERE id = $2 AND user_id = $3`, priceListID, id, userID)
+}
+
+// Archive archiva un cliente; sus órdenes anteriores no cambian.
+func (m *CustomerModel) Archive(id int64, userID int64) error {
+	return archiveAs(context.Background(), m.DB, archivableCustomers, id, userID, true)
+}
+
+// Restore vuelve a activar un cliente archivado.
+func (m *CustomerModel) Restore(id int64, userID int64) error {
+	return archiveAs(context.Background(), m.DB, archivableCustomers, id, userID, false)
 }
 
 // Delete deletes a customer if it belongs to the user.
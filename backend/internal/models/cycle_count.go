@@ -0,0 +1,477 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Estados de una sesión de conteo físico
+const (
+	CycleCountOpen            = "open"
+	CycleCountPendingApproval = "pending_approval"
+	CycleCountClosed          = "closed"
+)
+
+// ReasonCycleCount es el motivo de los movimientos que registran las diferencias de un conteo.
+const ReasonCycleCount = "CYCLE_COUNT"
+
+// DefaultVarianceThresholdPct es el porcentaje de diferencia sobre lo esperado a partir del cual
+// una línea requiere aprobación de un admin, si la sesión no indica otro.
+const DefaultVarianceThresholdPct = 5.0
+
+// Errors for cycle count operations
+var (
+	ErrInvalidCycleCountStatus   = errors.New("invalid cycle count status transition")
+	ErrCycleCountProductNotFound = errors.New("product not part of the cycle count")
+	ErrEmptyCycleCount           = errors.New("cycle count has no products")
+)
+
+// CycleCount es una sesión de conteo físico de un depósito.
+type CycleCount struct {
+	ID                   int64      `json:"id"`
+	WarehouseID          int64      `json:"warehouse_id"`
+	WarehouseName        string     `json:"warehouse_name,omitempty"`
+	Status               string     `json:"status"`
+	VarianceThresholdPct float64    `json:"variance_threshold_pct"`
+	Notes                string     `json:"notes"`
+	UserID               int64      `json:"user_id"`
+	CreatedAt            time.Time  `json:"created_at"`
+	ClosedAt             *time.Time `json:"closed_at,omitempty"`
+	ApprovedBy           *int64     `json:"approved_by,omitempty"`
+	ApprovedAt           *time.Time `json:"approved_at,omitempty"`
+}
+
+// CycleCountLine es el conteo de un producto: la cantidad esperada se congela al abrir la sesión
+// y CountedQuantity guarda la última pasada (nil si todavía no se contó).
+type CycleCountLine struct {
+	ID               int64      `json:"id"`
+	CycleCountID     int64      `json:"cycle_count_id"`
+	ProductID        int64      `json:"product_id"`
+	ProductName      string     `json:"product_name,omitempty"`
+	SKU              string     `json:"sku,omitempty"`
+	ExpectedQuantity float64    `json:"expected_quantity"`
+	CountedQuantity  *float64   `json:"counted_quantity"`
+	Variance         float64    `json:"variance"`
+	NeedsApproval    bool       `json:"needs_approval"`
+	Passes           int        `json:"passes"`
+	CountedAt        *time.Time `json:"counted_at,omitempty"`
+}
+
+// CycleCountEntry es una cantidad contada de un producto en una pasada.
+type CycleCountEntry struct {
+	ProductID int64   `json:"product_id"`
+	Quantity  float64 `json:"quantity"`
+}
+
+// CycleCountModel wraps DB access for cycle count sessions.
+type CycleCountModel struct {
+	DB *pgxpool.Pool
+}
+
+// varianceExceeds indica si la diferencia entre lo contado y lo esperado supera el umbral porcentual.
+// Con stock esperado 0 cualquier diferencia lo supera.
+func varianceExceeds(expected, counted, thresholdPct float64) bool {
+	variance := math.Abs(roundQuantity(counted - expected))
+	if variance == 0 {
+		return false
+	}
+	if expected <= 0 {
+		return true
+	}
+	return variance/expected*100 > thresholdPct
+}
+
+// setVariance completa Variance y NeedsApproval de una línea contada.
+func (l *CycleCountLine) setVariance(thresholdPct float64) {
+	if l.CountedQuantity == nil {
+		l.Variance = 0
+		l.NeedsApproval = false
+		return
+	}
+	l.Variance = roundQuantity(*l.CountedQuantity - l.ExpectedQuantity)
+	l.NeedsApproval = varianceExceeds(l.ExpectedQuantity, *l.CountedQuantity, thresholdPct)
+}
+
+// Create abre una sesión de conteo y congela la cantidad esperada de cada producto en el depósito.
+// Si productIDs está vacío se cuentan todos los productos con stock propio (no kits) del usuario.
+// Los productos serializados no se cuentan (ErrSerializedProduct si se piden).
+func (m *CycleCountModel) Create(c *CycleCount, productIDs []int64) ([]CycleCountLine, error) {
+	ctx := context.Background()
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if tx != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	warehouseID, err := resolveWarehouse(ctx, tx, c.UserID, c.WarehouseID)
+	if err != nil {
+		return nil, err
+	}
+	c.WarehouseID = warehouseID
+
+	const insertCount = `
+		INSERT INTO cycle_counts (user_id, warehouse_id, status, variance_threshold_pct, notes)
+		VALUES ($1, $2, 'open', $3, $4)
+		RETURNING id, status, created_at`
+	if err := tx.QueryRow(ctx, insertCount, c.UserID, c.WarehouseID, c.VarianceThresholdPct, c.Notes).
+		Scan(&c.ID, &c.Status, &c.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	// Los productos serializados no se cuentan: una diferencia no diría qué series faltan o sobran
+	if productIDs == nil {
+		productIDs = []int64{}
+	}
+	var serialized bool
+	const qSerialized = `SELECT EXISTS (SELECT 1 FROM products WHERE user_id = $1 AND id = ANY($2) AND is_serialized)`
+	if err := tx.QueryRow(ctx, qSerialized, c.UserID, productIDs).Scan(&serialized); err != nil {
+		return nil, err
+	}
+	if serialized {
+		return nil, ErrSerializedProduct
+	}
+
+	// Congelar el stock actual del depósito para cada producto incluido
+	const insertLines = `
+		INSERT INTO cycle_count_lines (cycle_count_id, product_id, expected_quantity)
+		SELECT $1, p.id, COALESCE(ps.quantity, 0)
+		FROM products p
+		LEFT JOIN product_stocks ps ON ps.product_id = p.id AND ps.warehouse_id = $3
+		WHERE p.user_id = $2 AND NOT p.is_kit AND NOT p.is_serialized
+		  AND (cardinality($4::bigint[]) = 0 OR p.id = ANY($4))
+		ORDER BY p.id`
+	tag, err := tx.Exec(ctx, insertLines, c.ID, c.UserID, c.WarehouseID, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, ErrEmptyCycleCount
+	}
+	if len(productIDs) > 0 && tag.RowsAffected() != int64(len(uniqueIDs(productIDs))) {
+		return nil, ErrNotFound
+	}
+
+	lines, err := loadCycleCountLines(ctx, tx, c.ID, c.VarianceThresholdPct)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	tx = nil
+	return lines, nil
+}
+
+// uniqueIDs devuelve los ids sin repetir.
+func uniqueIDs(ids []int64) []int64 {
+	seen := make(map[int64]bool, len(ids))
+	out := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+const selectCycleCount = `
+	SELECT c.id, c.warehouse_id, w.name, c.status, c.variance_threshold_pct, c.notes,
+		c.user_id, c.created_at, c.closed_at, c.approved_by, c.approved_at
+	FROM cycle_counts c
+	JOIN warehouses w ON w.id = c.warehouse_id`
+
+func scanCycleCount(row pgx.Row, c *CycleCount) error {
+	return row.Scan(
+		&c.ID, &c.WarehouseID, &c.WarehouseName, &c.Status, &c.VarianceThresholdPct, &c.Notes,
+		&c.UserID, &c.CreatedAt, &c.ClosedAt, &c.ApprovedBy, &c.ApprovedAt,
+	)
+}
+
+func (m *CycleCountModel) list(where string, args ...any) ([]CycleCount, error) {
+	rows, err := m.DB.Query(context.Background(), selectCycleCount+where+` ORDER BY c.id DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []CycleCount{}
+	for rows.Next() {
+		var c CycleCount
+		if err := scanCycleCount(rows, &c); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return out, nil
+}
+
+// GetAllForUser returns all cycle count sessions for the given user, newest first.
+func (m *CycleCountModel) GetAllForUser(userID int64) ([]CycleCount, error) {
+	return m.list(` WHERE c.user_id = $1`, userID)
+}
+
+// GetPendingApproval devuelve las sesiones de todos los usuarios que esperan aprobación de un admin.
+func (m *CycleCountModel) GetPendingApproval() ([]CycleCount, error) {
+	return m.list(` WHERE c.status = $1`, CycleCountPendingApproval)
+}
+
+// GetByID returns a cycle count session for the user along with its lines.
+func (m *CycleCountModel) GetByID(id int64, userID int64) (*CycleCount, []CycleCountLine, error) {
+	ctx := context.Background()
+
+	var c CycleCount
+	err := scanCycleCount(m.DB.QueryRow(ctx, selectCycleCount+` WHERE c.id = $1 AND c.user_id = $2`, id, userID), &c)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil, ErrNotFound
+		}
+		return nil, nil, err
+	}
+
+	lines, err := loadCycleCountLines(ctx, m.DB, id, c.VarianceThresholdPct)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &c, lines, nil
+}
+
+func loadCycleCountLines(ctx context.Context, q dbtx, cycleCountID int64, thresholdPct float64) ([]CycleCountLine, error) {
+	const qLines = `
+		SELECT l.id, l.cycle_count_id, l.product_id, p.name, p.sku,
+			l.expected_quantity, l.counted_quantity, l.passes, l.counted_at
+		FROM cycle_count_lines l
+		JOIN products p ON p.id = l.product_id
+		WHERE l.cycle_count_id = $1
+		ORDER BY l.id`
+	rows, err := q.Query(ctx, qLines, cycleCountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lines := []CycleCountLine{}
+	for rows.Next() {
+		var l CycleCountLine
+		if err := rows.Scan(&l.ID, &l.CycleCountID, &l.ProductID, &l.ProductName, &l.SKU,
+			&l.ExpectedQuantity, &l.CountedQuantity, &l.Passes, &l.CountedAt); err != nil {
+			return nil, err
+		}
+		l.setVariance(thresholdPct)
+		lines = append(lines, l)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return lines, nil
+}
+
+// lockCycleCount bloquea la sesión para modificarla. Con userID 0 no filtra por dueño (aprobación de admin).
+func lockCycleCount(ctx context.Context, tx pgx.Tx, id int64, userID int64) (*CycleCount, error) {
+	const q = `
+		SELECT id, warehouse_id, status, variance_threshold_pct, user_id
+		FROM cycle_counts
+		WHERE id = $1 AND ($2::bigint = 0 OR user_id = $2)
+		FOR UPDATE`
+	var c CycleCount
+	err := tx.QueryRow(ctx, q, id, userID).Scan(&c.ID, &c.WarehouseID, &c.Status, &c.VarianceThresholdPct, &c.UserID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+// RecordCounts registra una pasada de conteo en una sesión abierta. Cada entrada queda en el
+// historial de la línea y la cantidad contada de la línea pasa a ser la de la última pasada.
+func (m *CycleCountModel) RecordCounts(id int64, userID int64, entries []CycleCountEntry) error {
+	ctx := context.Background()
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if tx != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	c, err := lockCycleCount(ctx, tx, id, userID)
+	if err != nil {
+		return err
+	}
+	if c.Status != CycleCountOpen {
+		return ErrInvalidCycleCountStatus
+	}
+
+	const updLine = `
+		UPDATE cycle_count_lines
+		SET counted_quantity = $1, passes = passes + 1, counted_at = NOW()
+		WHERE cycle_count_id = $2 AND product_id = $3
+		RETURNING id`
+	const insertEntry = `
+		INSERT INTO cycle_count_entries (line_id, quantity, user_id)
+		VALUES ($1, $2, $3)`
+	for _, e := range entries {
+		qty := roundQuantity(e.Quantity)
+		var lineID int64
+		if err := tx.QueryRow(ctx, updLine, qty, id, e.ProductID).Scan(&lineID); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrCycleCountProductNotFound
+			}
+			return err
+		}
+		if _, err := tx.Exec(ctx, insertEntry, lineID, qty, userID); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	tx = nil
+	return nil
+}
+
+// Close cierra una sesión abierta. Si alguna diferencia supera el umbral la sesión queda
+// pendiente de aprobación sin mover stock; si no, se registran los ajustes CYCLE_COUNT.
+// Devuelve el estado final de la sesión.
+func (m *CycleCountModel) Close(id int64, userID int64) (string, error) {
+	ctx := context.Background()
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if tx != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	c, err := lockCycleCount(ctx, tx, id, userID)
+	if err != nil {
+		return "", err
+	}
+	if c.Status != CycleCountOpen {
+		return "", ErrInvalidCycleCountStatus
+	}
+
+	lines, err := loadCycleCountLines(ctx, tx, id, c.VarianceThresholdPct)
+	if err != nil {
+		return "", err
+	}
+
+	status := CycleCountClosed
+	for _, l := range lines {
+		if l.NeedsApproval {
+			status = CycleCountPendingApproval
+			break
+		}
+	}
+
+	if status == CycleCountClosed {
+		if err := postCycleCountVariances(ctx, tx, c, lines); err != nil {
+			return "", err
+		}
+	}
+
+	const upd = `UPDATE cycle_counts SET status = $1, closed_at = NOW() WHERE id = $2`
+	if _, err := tx.Exec(ctx, upd, status, id); err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", err
+	}
+	tx = nil
+	return status, nil
+}
+
+// Approve aprueba una sesión pendiente (de cualquier usuario) y registra sus ajustes.
+// Si reject es true la sesión vuelve a quedar abierta para recontar.
+func (m *CycleCountModel) Approve(id int64, adminID int64, reject bool) error {
+	ctx := context.Background()
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if tx != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	c, err := lockCycleCount(ctx, tx, id, 0)
+	if err != nil {
+		return err
+	}
+	if c.Status != CycleCountPendingApproval {
+		return ErrInvalidCycleCountStatus
+	}
+
+	if reject {
+		const reopen = `UPDATE cycle_counts SET status = 'open', closed_at = NULL WHERE id = $1`
+		if _, err := tx.Exec(ctx, reopen, id); err != nil {
+			return err
+		}
+	} else {
+		lines, err := loadCycleCountLines(ctx, tx, id, c.VarianceThresholdPct)
+		if err != nil {
+			return err
+		}
+		if err := postCycleCountVariances(ctx, tx, c, lines); err != nil {
+			return err
+		}
+		const upd = `
+			UPDATE cycle_counts SET status = 'closed', approved_by = $1, approved_at = NOW()
+			WHERE id = $2`
+		if _, err := tx.Exec(ctx, upd, adminID, id); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	tx = nil
+	return nil
+}
+
+// postCycleCountVariances registra la diferencia de cada línea contada como movimiento CYCLE_COUNT
+// con la sesión como referencia. Se aplica la diferencia contra lo esperado, de modo que los
+// movimientos ocurridos después de congelar el stock se conservan.
+func postCycleCountVariances(ctx context.Context, tx pgx.Tx, c *CycleCount, lines []CycleCountLine) error {
+	reference := fmt.Sprintf("%d", c.ID)
+	for _, l := range lines {
+		if l.CountedQuantity == nil || l.Variance == 0 {
+			continue
+		}
+		if _, err := applyStockChange(ctx, tx, stockChange{
+			ProductID:      l.ProductID,
+			WarehouseID:    c.WarehouseID,
+			UserID:         c.UserID,
+			QuantityChange: l.Variance,
+			Reason:         ReasonCycleCount,
+			ReferenceID:    &reference,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -25,8 +25,8 @@ type DashboardKPIs struct {
 
 // TopSellingProduct representa un producto más vendido
 type TopSellingProduct struct {
-	ProductName string `json:"product_name"`
-	TotalSold   int    `json:"total_sold"`
+	ProductName string  `json:"product_name"`
+	TotalSold   float64 `json:"total_sold"`
 }
 
 // SalesEvolutionPoint representa un punto en el gráfico de evolución de ventas
@@ -35,10 +35,22 @@ type SalesEvolutionPoint struct {
 	Total float64 `json:"total"`
 }
 
+// CategorySummary resume ventas y stock bajo de los productos de una categoría
+type CategorySummary struct {
+	CategoryID       *int64  `json:"category_id"`
+	CategoryName     string  `json:"category_name"`
+	TotalSold        float64 `json:"total_sold"`
+	TotalSales       float64 `json:"total_sales"`
+	Products         int     `json:"products"`
+	LowStockProducts int     `json:"low_stock_products"`
+}
+
 // ChartData contiene todos los datos para los gráficos del dashboard
 type ChartData struct {
 	TopSellingProducts []TopSellingProduct   `json:"top_selling_products"`
 	SalesEvolution     []SalesEvolutionPoint `json:"sales_evolution"`
+	Categories         []CategorySummary     `json:"categories,omitempty"`
+	DemandForecast     []DemandForecastPoint `json:"demand_forecast,omitempty"`
 }
 
 // DashboardModel accede a datos agregados
@@ -53,15 +65,15 @@ func (m *DashboardModel) GetMetrics(userID int64) (DashboardMetrics, error) {
 	var metrics DashboardMetrics
 
 	// TotalProducts
-	if err := m.DB.QueryRow(ctx, `SELECT COUNT(*) FROM products WHERE user_id = $1`, userID).Scan(&metrics.TotalProducts); err != nil {
+	if err := m.DB.QueryRow(ctx, `SELECT COUNT(*) FROM products WHERE user_id = $1 AND archived_at IS NULL`, userID).Scan(&metrics.TotalProducts); err != nil {
 		return DashboardMetrics{}, err
 	}
 	// TotalCustomers
-	if err := m.DB.QueryRow(ctx, `SELECT COUNT(*) FROM customers WHERE user_id = $1`, userID).Scan(&metrics.TotalCustomers); err != nil {
+	if err := m.DB.QueryRow(ctx, `SELECT COUNT(*) FROM customers WHERE user_id = $1 AND archived_at IS NULL`, userID).Scan(&metrics.TotalCustomers); err != nil {
 		return DashboardMetrics{}, err
 	}
 	// TotalSuppliers
-	if err := m.DB.QueryRow(ctx, `SELECT COUNT(*) FROM suppliers WHERE user_id = $1`, userID).Scan(&metrics.TotalSuppliers); err != nil {
+	if err := m.DB.QueryRow(ctx, `SELECT COUNT(*) FROM suppliers WHERE user_id = $1 AND archived_at IS NULL`, userID).Scan(&metrics.TotalSuppliers); err != nil {
 		return DashboardMetrics{}, err
 	}
 	// PendingSalesOrders
@@ -69,7 +81,7 @@ func (m *DashboardModel) GetMetrics(userID int64) (DashboardMetrics, error) {
 		return DashboardMetrics{}, err
 	}
 	// ProductsLowStock (threshold fijo = 5)
-	if err := m.DB.QueryRow(ctx, `SELECT COUNT(*) FROM products WHERE user_id = $1 AND quantity <= 5`, userID).Scan(&metrics.ProductsLowStock); err != nil {
+	if err := m.DB.QueryRow(ctx, `SELECT COUNT(*) FROM products WHERE user_id = $1 AND quantity <= 5 AND NOT is_kit AND archived_at IS NULL`, userID).Scan(&metrics.ProductsLowStock); err != nil {
 		return DashboardMetrics{}, err
 	}
 
@@ -85,7 +97,7 @@ func (m *DashboardModel) GetDashboardKPIs(userID int64) (*DashboardKPIs, error)
 	err := m.DB.QueryRow(ctx, `
 		SELECT COUNT(*) 
 		FROM products 
-		WHERE user_id = $1
+		WHERE user_id = $1 AND archived_at IS NULL
 	`, userID).Scan(&kpis.TotalProducts)
 	if err != nil {
 		return nil, err
@@ -95,7 +107,7 @@ func (m *DashboardModel) GetDashboardKPIs(userID int64) (*DashboardKPIs, error)
 	err = m.DB.QueryRow(ctx, `
 		SELECT COUNT(*) 
 		FROM products 
-		WHERE user_id = $1 AND quantity <= 5
+		WHERE user_id = $1 AND quantity <= 5 AND NOT is_kit AND archived_at IS NULL
 	`, userID).Scan(&kpis.LowStockProducts)
 	if err != nil {
 		return nil, err
@@ -103,7 +115,7 @@ func (m *DashboardModel) GetDashboardKPIs(userID int64) (*DashboardKPIs, error)
 
 	// Ventas del mes actual
 	err = m.DB.QueryRow(ctx, `
-		SELECT COALESCE(SUM(oi.quantity * oi.unit_price), 0) 
+		SELECT COALESCE(SUM(oi.unit_quantity * oi.unit_price), 0) 
 		FROM sales_orders so 
 		JOIN order_items oi ON so.id = oi.order_id 
 		WHERE so.user_id = $1 
@@ -126,8 +138,11 @@ func (m *DashboardModel) GetDashboardKPIs(userID int64) (*DashboardKPIs, error)
 	return kpis, nil
 }
 
-// GetChartData obtiene los datos para los gráficos del dashboard
-func (m *DashboardModel) GetChartData(userID int64) (*ChartData, error) {
+// GetChartData obtiene los datos para los gráficos del dashboard.
+// Con rollup las ventas de las variantes se suman a su producto padre en el top de vendidos.
+// Con byCategory se agrega el resumen por categoría (Categories).
+// Con forecastWeeks > 0 se agrega la demanda total real y pronosticada (DemandForecast).
+func (m *DashboardModel) GetChartData(userID int64, rollup bool, byCategory bool, forecastWeeks int) (*ChartData, error) {
 	data := &ChartData{
 		TopSellingProducts: []TopSellingProduct{},
 		SalesEvolution:     []SalesEvolutionPoint{},
@@ -135,7 +150,7 @@ func (m *DashboardModel) GetChartData(userID int64) (*ChartData, error) {
 	ctx := context.Background()
 
 	// Top 5 productos más vendidos
-	rows, err := m.DB.Query(ctx, `
+	topSellersQuery := `
 		SELECT 
 			p.name,
 			COALESCE(SUM(oi.quantity), 0) as total_sold
@@ -146,7 +161,23 @@ func (m *DashboardModel) GetChartData(userID int64) (*ChartData, error) {
 		GROUP BY p.id, p.name
 		ORDER BY total_sold DESC
 		LIMIT 5
-	`, userID)
+	`
+	if rollup {
+		topSellersQuery = `
+		SELECT 
+			root.name,
+			COALESCE(SUM(oi.quantity), 0) as total_sold
+		FROM products root
+		LEFT JOIN products p ON COALESCE(p.parent_id, p.id) = root.id
+		LEFT JOIN order_items oi ON p.id = oi.product_id
+		LEFT JOIN sales_orders so ON oi.order_id = so.id AND so.user_id = $1
+		WHERE root.user_id = $1 AND root.parent_id IS NULL
+		GROUP BY root.id, root.name
+		ORDER BY total_sold DESC
+		LIMIT 5
+	`
+	}
+	rows, err := m.DB.Query(ctx, topSellersQuery, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -171,7 +202,7 @@ func (m *DashboardModel) GetChartData(userID int64) (*ChartData, error) {
 		)
 		SELECT 
 			ds.date::text,
-			COALESCE(SUM(oi.quantity * oi.unit_price), 0) as total
+			COALESCE(SUM(oi.unit_quantity * oi.unit_price), 0) as total
 		FROM date_series ds
 		LEFT JOIN sales_orders so ON DATE(so.order_date) = ds.date AND so.user_id = $1
 		LEFT JOIN order_items oi ON so.id = oi.order_id
@@ -191,5 +222,67 @@ func (m *DashboardModel) GetChartData(userID int64) (*ChartData, error) {
 		data.SalesEvolution = append(data.SalesEvolution, point)
 	}
 
+	if byCategory {
+		if data.Categories, err = m.categorySummaries(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	if forecastWeeks > 0 {
+		fm := &ForecastModel{DB: m.DB}
+		if data.DemandForecast, err = fm.TotalDemandSeries(userID, forecastWeeks); err != nil {
+			return nil, err
+		}
+	}
+
 	return data, nil
 }
+
+// categorySummaries agrupa ventas y productos con stock bajo (cantidad <= stock_minimo) por la
+// categoría directa de cada producto, nombrada con su ruta completa. Ordena por unidades vendidas.
+func (m *DashboardModel) categorySummaries(ctx context.Context, userID int64) ([]CategorySummary, error) {
+	cm := &CategoryModel{DB: m.DB}
+	categories, err := cm.GetAllForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	paths := CategoryPaths(categories)
+
+	rows, err := m.DB.Query(ctx, `
+		SELECT
+			p.category_id,
+			COALESCE(SUM(s.sold), 0),
+			COALESCE(SUM(s.sales), 0),
+			COUNT(*),
+			COUNT(*) FILTER (WHERE NOT p.is_kit AND p.quantity <= p.stock_minimo)
+		FROM products p
+		LEFT JOIN (
+			SELECT oi.product_id, SUM(oi.quantity) AS sold, SUM(oi.unit_quantity * oi.unit_price) AS sales
+			FROM order_items oi
+			JOIN sales_orders so ON so.id = oi.order_id
+			WHERE so.user_id = $1
+			GROUP BY oi.product_id
+		) s ON s.product_id = p.id
+		WHERE p.user_id = $1
+		GROUP BY p.category_id
+		ORDER BY 2 DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []CategorySummary{}
+	for rows.Next() {
+		var c CategorySummary
+		if err := rows.Scan(&c.CategoryID, &c.TotalSold, &c.TotalSales, &c.Products, &c.LowStockProducts); err != nil {
+			return nil, err
+		}
+		c.CategoryName = CategoryLabel(paths, c.CategoryID)
+		out = append(out, c)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return out, nil
+}
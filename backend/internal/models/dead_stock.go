@@ -0,0 +1,173 @@
+package models
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Parámetros por defecto del reporte de stock inmovilizado
+const (
+	DefaultDeadStockDays        = 90
+	MaxDeadStockDays            = 730
+	DefaultDeadStockMaxTurnover = 0.5 // vendido en el período / stock actual
+)
+
+// Estado de un producto en el reporte de stock inmovilizado
+const (
+	DeadStockDead = "dead" // sin ventas en el período
+	DeadStockSlow = "slow" // con ventas, pero con rotación por debajo del umbral
+)
+
+// DeadStockOptions configura el reporte: período analizado en días y rotación mínima. Con
+// MaxTurnover 0 sólo se listan los productos sin ventas.
+type DeadStockOptions struct {
+	Days        int     `json:"days"`
+	MaxTurnover float64 `json:"max_turnover"`
+}
+
+func (o *DeadStockOptions) normalize() {
+	if o.Days <= 0 {
+		o.Days = DefaultDeadStockDays
+	}
+	if o.Days > MaxDeadStockDays {
+		o.Days = MaxDeadStockDays
+	}
+	if o.MaxTurnover < 0 {
+		o.MaxTurnover = 0
+	}
+}
+
+// DeadStockItem es un producto sin ventas o de baja rotación. OnHand es la suma de sus movimientos
+// de stock y Value lo valoriza al costo promedio de sus capas; Turnover es lo vendido en el período
+// sobre OnHand. Las cantidades están en unidad base.
+type DeadStockItem struct {
+	ProductID         int64      `json:"product_id"`
+	SKU               string     `json:"sku"`
+	Name              string     `json:"name"`
+	BaseUnit          string     `json:"base_unit"`
+	Status            string     `json:"status"`
+	OnHand            float64    `json:"on_hand"`
+	UnitCost          float64    `json:"unit_cost"`
+	Value             float64    `json:"value"`
+	SoldInPeriod      float64    `json:"sold_in_period"`
+	Turnover          float64    `json:"turnover"`
+	LastSaleDate      *time.Time `json:"last_sale_date"`
+	LastReceiptDate   *time.Time `json:"last_receipt_date"`
+	DaysSinceLastSale *int       `json:"days_since_last_sale"`
+}
+
+// DeadStockReport lista el stock inmovilizado de la cuenta, el de mayor valor primero.
+type DeadStockReport struct {
+	AsOf        time.Time       `json:"as_of"`
+	Days        int             `json:"days"`
+	MaxTurnover float64         `json:"max_turnover"`
+	TotalValue  float64         `json:"total_value"`
+	Products    []DeadStockItem `json:"products"`
+}
+
+// DeadStockModel arma el reporte de stock inmovilizado y de baja rotación.
+type DeadStockModel struct {
+	DB *pgxpool.Pool
+}
+
+// Get lista los productos activos con stock que no se vendieron en los últimos opts.Days días
+// (salvo los dados de alta dentro del período, que todavía no tuvieron tiempo de venderse) o cuya
+// rotación quedó por debajo de opts.MaxTurnover. Las ventas de kits cuentan para sus componentes.
+func (m *DeadStockModel) Get(userID int64, opts DeadStockOptions) (*DeadStockReport, error) {
+	opts.normalize()
+	ctx := context.Background()
+
+	asOf := time.Now()
+	from := asOf.AddDate(0, 0, -opts.Days)
+
+	const q = `
+		WITH sold AS (
+			SELECT oi.product_id, so.order_date, oi.quantity
+			FROM order_items oi
+			JOIN sales_orders so ON so.id = oi.order_id
+			WHERE so.user_id = $1 AND so.status <> 'cancelled'
+			UNION ALL
+			SELECT pc.component_id, so.order_date, oi.quantity * pc.quantity
+			FROM order_items oi
+			JOIN sales_orders so ON so.id = oi.order_id
+			JOIN product_components pc ON pc.kit_id = oi.product_id
+			WHERE so.user_id = $1 AND so.status <> 'cancelled'
+		),
+		sales AS (
+			SELECT product_id, MAX(order_date) AS last_sale,
+				COALESCE(SUM(quantity) FILTER (WHERE order_date >= $2), 0) AS sold
+			FROM sold
+			GROUP BY product_id
+		),
+		moves AS (
+			SELECT product_id, SUM(quantity_change) AS on_hand,
+				MAX(created_at) FILTER (WHERE reason = 'PURCHASE_ORDER' AND quantity_change > 0) AS last_receipt
+			FROM stock_movements
+			WHERE user_id = $1
+			GROUP BY product_id
+		),
+		costs AS (
+			SELECT product_id, SUM(value) / NULLIF(SUM(quantity), 0) AS unit_cost
+			FROM cost_entries
+			WHERE user_id = $1
+			GROUP BY product_id
+		)
+		SELECT p.id, p.sku, p.name, p.base_unit, m.on_hand, COALESCE(c.unit_cost, 0),
+			COALESCE(s.sold, 0), s.last_sale, m.last_receipt
+		FROM products p
+		JOIN moves m ON m.product_id = p.id
+		LEFT JOIN sales s ON s.product_id = p.id
+		LEFT JOIN costs c ON c.product_id = p.id
+		WHERE p.user_id = $1 AND NOT p.is_kit AND p.archived_at IS NULL
+			AND m.on_hand > 0 AND p.created_at < $2`
+
+	rows, err := m.DB.Query(ctx, q, userID, from)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	report := &DeadStockReport{AsOf: asOf, Days: opts.Days, MaxTurnover: opts.MaxTurnover, Products: []DeadStockItem{}}
+	for rows.Next() {
+		var it DeadStockItem
+		if err := rows.Scan(&it.ProductID, &it.SKU, &it.Name, &it.BaseUnit, &it.OnHand, &it.UnitCost,
+			&it.SoldInPeriod, &it.LastSaleDate, &it.LastReceiptDate); err != nil {
+			return nil, err
+		}
+		it.OnHand = roundQuantity(it.OnHand)
+		it.SoldInPeriod = roundQuantity(it.SoldInPeriod)
+		it.Turnover = math.Round(it.SoldInPeriod/it.OnHand*100) / 100
+
+		switch {
+		case it.SoldInPeriod <= 0:
+			it.Status = DeadStockDead
+		case it.Turnover < opts.MaxTurnover:
+			it.Status = DeadStockSlow
+		default:
+			continue
+		}
+
+		it.UnitCost = roundValue(it.UnitCost)
+		it.Value = roundValue(it.OnHand * it.UnitCost)
+		if it.LastSaleDate != nil {
+			days := int(asOf.Sub(*it.LastSaleDate).Hours() / 24)
+			it.DaysSinceLastSale = &days
+		}
+		report.TotalValue += it.Value
+		report.Products = append(report.Products, it)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	// Lo que más capital inmoviliza primero
+	sort.SliceStable(report.Products, func(i, j int) bool {
+		return report.Products[i].Value > report.Products[j].Value
+	})
+	report.TotalValue = roundValue(report.TotalValue)
+	return report, nil
+}
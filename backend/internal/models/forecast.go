@@ -0,0 +1,314 @@
+package models
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"stock-in-order/backend/internal/forecast"
+)
+
+// Parámetros por defecto del pronóstico de demanda
+const (
+	DefaultForecastWeeks  = 8
+	MaxForecastWeeks      = 52
+	ForecastHistoryWeeks  = 156 // tres años: sin las semanas previas a la primera venta siguen quedando dos temporadas
+	ForecastSeasonWeeks   = 52
+	DefaultLeadTimeDays   = 7
+	forecastServiceFactor = 1.65 // z de un nivel de servicio del 95%
+)
+
+// ForecastOptions configura el pronóstico: semanas a proyectar y plazo de reposición (en días)
+// usado para sugerir el stock mínimo.
+type ForecastOptions struct {
+	Weeks        int
+	LeadTimeDays int
+}
+
+func (o *ForecastOptions) normalize() {
+	if o.Weeks <= 0 {
+		o.Weeks = DefaultForecastWeeks
+	}
+	if o.Weeks > MaxForecastWeeks {
+		o.Weeks = MaxForecastWeeks
+	}
+	if o.LeadTimeDays <= 0 {
+		o.LeadTimeDays = DefaultLeadTimeDays
+	}
+}
+
+// ForecastWeek es la demanda (real o pronosticada) de la semana que empieza el lunes WeekStart.
+type ForecastWeek struct {
+	WeekStart string  `json:"week_start"`
+	Quantity  float64 `json:"quantity"`
+}
+
+// ProductForecast es la proyección de demanda de un producto. DaysOfCover es cuántos días alcanza
+// el stock disponible al ritmo pronosticado (nil si no hay demanda) y StockoutDate cuándo se agota
+// dentro del horizonte (nil si alcanza). Las cantidades están en unidad base.
+type ProductForecast struct {
+	ProductID            int64          `json:"product_id"`
+	SKU                  string         `json:"sku"`
+	Name                 string         `json:"name"`
+	BaseUnit             string         `json:"base_unit"`
+	Available            float64        `json:"available"`
+	StockMinimo          float64        `json:"stock_minimo"`
+	SuggestedStockMinimo float64        `json:"suggested_stock_minimo"`
+	AvgWeeklyDemand      float64        `json:"avg_weekly_demand"`
+	DaysOfCover          *float64       `json:"days_of_cover"`
+	StockoutDate         *string        `json:"stockout_date"`
+	Seasonal             bool           `json:"seasonal"`
+	Forecast             []ForecastWeek `json:"forecast"`
+	History              []ForecastWeek `json:"history,omitempty"`
+}
+
+// ForecastModel calcula pronósticos de demanda a partir del historial de ventas.
+type ForecastModel struct {
+	DB *pgxpool.Pool
+}
+
+// weeklyDemand devuelve la demanda semanal de los productos del usuario (o de uno si productID != 0)
+// en las ForecastHistoryWeeks semanas completas anteriores a currentWeek, la más antigua primero.
+// Las ventas de kits cuentan como demanda de sus componentes; las órdenes canceladas no cuentan.
+func weeklyDemand(ctx context.Context, q dbtx, userID int64, productID int64, currentWeek time.Time) (map[int64][]float64, error) {
+	const qDemand = `
+		WITH sold AS (
+			SELECT oi.product_id, so.order_date, oi.quantity
+			FROM order_items oi
+			JOIN sales_orders so ON so.id = oi.order_id
+			WHERE so.user_id = $1 AND so.status <> 'cancelled' AND so.order_date >= $2 AND so.order_date < $3
+			UNION ALL
+			SELECT pc.component_id, so.order_date, oi.quantity * pc.quantity
+			FROM order_items oi
+			JOIN sales_orders so ON so.id = oi.order_id
+			JOIN product_components pc ON pc.kit_id = oi.product_id
+			WHERE so.user_id = $1 AND so.status <> 'cancelled' AND so.order_date >= $2 AND so.order_date < $3
+		)
+		SELECT product_id, (date_trunc('week', order_date)::date - $5::date) / 7, SUM(quantity)
+		FROM sold
+		WHERE $4::bigint = 0 OR product_id = $4
+		GROUP BY 1, 2`
+
+	from := currentWeek.AddDate(0, 0, -7*ForecastHistoryWeeks)
+	rows, err := q.Query(ctx, qDemand, userID, from, currentWeek, productID, from)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[int64][]float64{}
+	for rows.Next() {
+		var id int64
+		var week int
+		var qty float64
+		if err := rows.Scan(&id, &week, &qty); err != nil {
+			return nil, err
+		}
+		if week < 0 || week >= ForecastHistoryWeeks {
+			continue
+		}
+		series, ok := out[id]
+		if !ok {
+			series = make([]float64, ForecastHistoryWeeks)
+			out[id] = series
+		}
+		series[week] += qty
+	}
+	return out, rows.Err()
+}
+
+// trimLeadingZeros descarta las semanas anteriores a la primera venta, para no contar como
+// demanda cero el tiempo en que el producto todavía no se vendía.
+func trimLeadingZeros(series []float64) []float64 {
+	for i, v := range series {
+		if v != 0 {
+			return series[i:]
+		}
+	}
+	return nil
+}
+
+// currentWeekStart devuelve el lunes de la semana en curso según la base de datos.
+func currentWeekStart(ctx context.Context, q dbtx) (time.Time, error) {
+	var week time.Time
+	err := q.QueryRow(ctx, `SELECT date_trunc('week', NOW())::date`).Scan(&week)
+	return week, err
+}
+
+// GetForUser pronostica la demanda de los productos activos del usuario (los kits no, su demanda
+// se reparte en sus componentes), los que se agotan antes primero.
+func (m *ForecastModel) GetForUser(userID int64, opts ForecastOptions) ([]ProductForecast, error) {
+	return m.get(userID, 0, opts, false)
+}
+
+// GetForProduct pronostica la demanda de un producto del usuario e incluye el historial semanal.
+func (m *ForecastModel) GetForProduct(productID int64, userID int64, opts ForecastOptions) (*ProductForecast, error) {
+	out, err := m.get(userID, productID, opts, true)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, ErrNotFound
+	}
+	return &out[0], nil
+}
+
+func (m *ForecastModel) get(userID int64, productID int64, opts ForecastOptions, withHistory bool) ([]ProductForecast, error) {
+	opts.normalize()
+	ctx := context.Background()
+
+	week, err := currentWeekStart(ctx, m.DB)
+	if err != nil {
+		return nil, err
+	}
+	demand, err := weeklyDemand(ctx, m.DB, userID, productID, week)
+	if err != nil {
+		return nil, err
+	}
+	elapsed := min(max(int(time.Since(week).Hours()/24), 0), 6)
+
+	const qProducts = `
+		SELECT p.id, p.sku, p.name, p.base_unit, p.stock_minimo,
+			p.quantity - (SELECT COALESCE(SUM(ps.reserved), 0) FROM product_stocks ps WHERE ps.product_id = p.id)
+		FROM products p
+		WHERE p.user_id = $1 AND NOT p.is_kit AND p.archived_at IS NULL AND ($2::bigint = 0 OR p.id = $2)
+		ORDER BY p.id`
+
+	rows, err := m.DB.Query(ctx, qProducts, userID, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []ProductForecast{}
+	for rows.Next() {
+		var f ProductForecast
+		if err := rows.Scan(&f.ProductID, &f.SKU, &f.Name, &f.BaseUnit, &f.StockMinimo, &f.Available); err != nil {
+			return nil, err
+		}
+		f.Available = roundQuantity(f.Available)
+
+		f.project(demand[f.ProductID], week, elapsed, opts, withHistory)
+		out = append(out, f)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	// Los que se agotan antes primero; los que no tienen demanda al final
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i].DaysOfCover, out[j].DaysOfCover
+		if a == nil || b == nil {
+			return a != nil
+		}
+		return *a < *b
+	})
+	return out, nil
+}
+
+// project completa el pronóstico del producto a partir de su demanda semanal (ForecastHistoryWeeks
+// semanas anteriores a week) y su disponible. elapsed son los días ya transcurridos de la semana.
+func (f *ProductForecast) project(series []float64, week time.Time, elapsed int, opts ForecastOptions, withHistory bool) {
+	if withHistory {
+		f.History = forecastWeeks(week.AddDate(0, 0, -7*ForecastHistoryWeeks), series, ForecastHistoryWeeks)
+	}
+	history := trimLeadingZeros(series)
+	model := forecast.Fit(history, ForecastSeasonWeeks)
+	projected := model.Forecast(opts.Weeks)
+	f.Seasonal = model.Period > 0
+	f.Forecast = forecastWeeks(week, projected, opts.Weeks)
+
+	var total float64
+	for _, v := range projected {
+		total += v
+	}
+	f.AvgWeeklyDemand = roundQuantity(total / float64(opts.Weeks))
+	f.SuggestedStockMinimo = math.Ceil(forecast.SuggestedMinimum(f.AvgWeeklyDemand, model.RMSE, opts.LeadTimeDays, forecastServiceFactor)*1000) / 1000
+
+	if f.AvgWeeklyDemand > 0 {
+		cover := math.Round(math.Max(f.Available, 0)/(f.AvgWeeklyDemand/7)*10) / 10
+		f.DaysOfCover = &cover
+	}
+	// El pronóstico arranca el lunes: la demanda esperada de los días ya transcurridos de la
+	// semana ya salió del stock, así que se suma al disponible para contar desde el lunes
+	available := math.Max(f.Available, 0) + projected[0]*float64(elapsed)/7
+	if days, ok := forecast.DaysUntilStockout(available, projected); ok {
+		s := week.AddDate(0, 0, int(math.Floor(days))).Format("2006-01-02")
+		f.StockoutDate = &s
+	}
+}
+
+// forecastWeeks etiqueta una serie semanal con la fecha de inicio de cada semana.
+func forecastWeeks(start time.Time, values []float64, weeks int) []ForecastWeek {
+	out := make([]ForecastWeek, weeks)
+	for i := range out {
+		out[i].WeekStart = start.AddDate(0, 0, 7*i).Format("2006-01-02")
+		if i < len(values) {
+			out[i].Quantity = roundQuantity(values[i])
+		}
+	}
+	return out
+}
+
+// ApplySuggestedMinimum reemplaza el stock mínimo del producto por el sugerido por el pronóstico
+// y devuelve el pronóstico con el nuevo valor.
+func (m *ForecastModel) ApplySuggestedMinimum(productID int64, userID int64, opts ForecastOptions) (*ProductForecast, error) {
+	f, err := m.GetForProduct(productID, userID, opts)
+	if err != nil {
+		return nil, err
+	}
+	const q = `UPDATE products SET stock_minimo = $1 WHERE id = $2 AND user_id = $3`
+	if err := updateAs(context.Background(), m.DB, userID, q, f.SuggestedStockMinimo, productID, userID); err != nil {
+		return nil, err
+	}
+	f.StockMinimo = f.SuggestedStockMinimo
+	return f, nil
+}
+
+// DemandForecastPoint es un punto de la serie de demanda total del dashboard: las últimas semanas
+// reales seguidas de las pronosticadas (Forecast = true).
+type DemandForecastPoint struct {
+	WeekStart string  `json:"week_start"`
+	Quantity  float64 `json:"quantity"`
+	Forecast  bool    `json:"forecast"`
+}
+
+// demandChartHistoryWeeks son las semanas reales que acompañan al pronóstico en el gráfico.
+const demandChartHistoryWeeks = 12
+
+// TotalDemandSeries pronostica la demanda total (en unidades base, todos los productos) de las
+// próximas weeks semanas, precedida por las últimas semanas reales.
+func (m *ForecastModel) TotalDemandSeries(userID int64, weeks int) ([]DemandForecastPoint, error) {
+	opts := ForecastOptions{Weeks: weeks}
+	opts.normalize()
+	ctx := context.Background()
+
+	week, err := currentWeekStart(ctx, m.DB)
+	if err != nil {
+		return nil, err
+	}
+	demand, err := weeklyDemand(ctx, m.DB, userID, 0, week)
+	if err != nil {
+		return nil, err
+	}
+	total := make([]float64, ForecastHistoryWeeks)
+	for _, series := range demand {
+		for i, v := range series {
+			total[i] += v
+		}
+	}
+
+	model := forecast.Fit(trimLeadingZeros(total), ForecastSeasonWeeks)
+	out := make([]DemandForecastPoint, 0, demandChartHistoryWeeks+opts.Weeks)
+	start := week.AddDate(0, 0, -7*demandChartHistoryWeeks)
+	for _, w := range forecastWeeks(start, total[ForecastHistoryWeeks-demandChartHistoryWeeks:], demandChartHistoryWeeks) {
+		out = append(out, DemandForecastPoint{WeekStart: w.WeekStart, Quantity: w.Quantity})
+	}
+	for _, w := range forecastWeeks(week, model.Forecast(opts.Weeks), opts.Weeks) {
+		out = append(out, DemandForecastPoint{WeekStart: w.WeekStart, Quantity: w.Quantity, Forecast: true})
+	}
+	return out, nil
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestProjectSeasonalDemand(t *testing.T) {
+	// Un producto que empezó a venderse a las 20 semanas de la ventana, con un pico anual en las
+	// dos primeras semanas de cada temporada: sin esas semanas sigue habiendo dos temporadas
+	series := make([]float64, ForecastHistoryWeeks)
+	for i := 20; i < len(series); i++ {
+		series[i] = 10
+		if i%ForecastSeasonWeeks < 2 {
+			series[i] = 40
+		}
+	}
+
+	f := ProductForecast{Available: 60}
+	week := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	f.project(series, week, 0, ForecastOptions{Weeks: 4, LeadTimeDays: 7}, true)
+
+	if !f.Seasonal {
+		t.Fatal("expected a seasonal forecast")
+	}
+	want := []float64{40, 40, 10, 10}
+	for i, w := range f.Forecast {
+		if math.Abs(w.Quantity-want[i]) > 1e-3 {
+			t.Fatalf("week %d: expected %v, got %v (forecast %v)", i, want[i], w.Quantity, f.Forecast)
+		}
+	}
+	if len(f.History) != ForecastHistoryWeeks {
+		t.Fatalf("expected %d history weeks, got %d", ForecastHistoryWeeks, len(f.History))
+	}
+	if f.StockoutDate == nil || *f.StockoutDate != "2026-01-15" {
+		t.Fatalf("expected stockout on 2026-01-15, got %v", f.StockoutDate)
+	}
+}
@@ -0,0 +1,142 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Entidades con historial de cambios (nombre de la tabla, como lo guarda el trigger)
+const (
+	HistoryProducts  = "products"
+	HistoryCustomers = "customers"
+	HistorySuppliers = "suppliers"
+	HistoryUsers     = "users"
+)
+
+// FieldChange es el cambio de un campo de un registro maestro. Los valores son el JSON de la
+// columna (null si el campo se registra sin valores, como password_hash). ChangedBy es nil para
+// los cambios hechos fuera del backend o por procesos del sistema.
+type FieldChange struct {
+	ID            int64           `json:"id"`
+	Entity        string          `json:"entity"`
+	EntityID      int64           `json:"entity_id"`
+	Field         string          `json:"field"`
+	OldValue      json.RawMessage `json:"old_value"`
+	NewValue      json.RawMessage `json:"new_value"`
+	ChangedBy     *int64          `json:"changed_by,omitempty"`
+	ChangedByName *string         `json:"changed_by_name,omitempty"`
+	ChangedAt     time.Time       `json:"changed_at"`
+}
+
+// setActor registra a actorID como autor de los cambios de la transacción (lo lee el trigger de historial).
+func setActor(ctx context.Context, tx pgx.Tx, actorID int64) error {
+	_, err := tx.Exec(ctx, `SELECT set_config('app.actor_id', $1, true)`, strconv.FormatInt(actorID, 10))
+	return err
+}
+
+// withActor ejecuta fn en una transacción cuyos cambios quedan en el historial a nombre de actorID.
+func withActor(ctx context.Context, db *pgxpool.Pool, actorID int64, fn func(tx pgx.Tx) error) error {
+	return pgx.BeginFunc(ctx, db, func(tx pgx.Tx) error {
+		if err := setActor(ctx, tx, actorID); err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
+
+// updateAs ejecuta un UPDATE de una fila a nombre de actorID; ErrNotFound si no afectó ninguna.
+func updateAs(ctx context.Context, db *pgxpool.Pool, actorID int64, sql string, args ...any) error {
+	return withActor(ctx, db, actorID, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, sql, args...)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+// HistoryModel wraps DB access for the field change history.
+type HistoryModel struct {
+	DB *pgxpool.Pool
+}
+
+// owned indica si el registro existe y pertenece al usuario. Cada usuario es su propia cuenta,
+// así que el historial de un usuario sólo lo ve él mismo.
+func (m *HistoryModel) owned(ctx context.Context, entity string, id int64, userID int64) (bool, error) {
+	var row pgx.Row
+	switch entity {
+	case HistoryProducts:
+		row = m.DB.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM products WHERE id = $1 AND user_id = $2)`, id, userID)
+	case HistoryCustomers:
+		row = m.DB.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM customers WHERE id = $1 AND user_id = $2)`, id, userID)
+	case HistorySuppliers:
+		row = m.DB.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM suppliers WHERE id = $1 AND user_id = $2)`, id, userID)
+	case HistoryUsers:
+		if id != userID {
+			return false, nil
+		}
+		row = m.DB.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)`, id)
+	default:
+		return false, nil
+	}
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+// GetForEntity devuelve el historial de cambios de un registro del usuario, el más reciente primero.
+func (m *HistoryModel) GetForEntity(entity string, id int64, userID int64) ([]FieldChange, error) {
+	ctx := context.Background()
+
+	exists, err := m.owned(ctx, entity, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	const q = `
+		SELECT fc.id, fc.entity, fc.entity_id, fc.field, fc.old_value, fc.new_value, fc.changed_by, u.name, fc.changed_at
+		FROM field_changes fc
+		LEFT JOIN users u ON u.id = fc.changed_by
+		WHERE fc.entity = $1 AND fc.entity_id = $2
+		ORDER BY fc.changed_at DESC, fc.id DESC`
+
+	rows, err := m.DB.Query(ctx, q, entity, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []FieldChange{}
+	for rows.Next() {
+		var c FieldChange
+		var oldValue, newValue []byte
+		if err := rows.Scan(&c.ID, &c.Entity, &c.EntityID, &c.Field, &oldValue, &newValue, &c.ChangedBy, &c.ChangedByName, &c.ChangedAt); err != nil {
+			return nil, err
+		}
+		c.OldValue, c.NewValue = jsonOrNull(oldValue), jsonOrNull(newValue)
+		out = append(out, c)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return out, nil
+}
+
+// jsonOrNull evita serializar un RawMessage vacío (JSON inválido) para las columnas NULL.
+func jsonOrNull(b []byte) json.RawMessage {
+	if b == nil {
+		return json.RawMessage("null")
+	}
+	return json.RawMessage(b)
+}
@@ -0,0 +1,172 @@
+package models
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Errors for kit operations
+var (
+	ErrInvalidKitComponent = errors.New("invalid kit component")
+	ErrKitNotStocked       = errors.New("kit stock is computed from its components")
+)
+
+// KitComponent es un componente de un kit: Quantity unidades base del componente por cada kit.
+type KitComponent struct {
+	ComponentID  int64   `json:"component_id"`
+	SKU          string  `json:"sku,omitempty"`
+	Name         string  `json:"name,omitempty"`
+	Quantity     float64 `json:"quantity"`
+	IsSerialized bool    `json:"is_serialized"`
+}
+
+// kitComponents devuelve los componentes de un kit. Para productos que no son kit devuelve una lista vacía.
+func kitComponents(ctx context.Context, q dbtx, kitID int64) ([]KitComponent, error) {
+	const qComponents = `
+		SELECT pc.component_id, p.sku, p.name, pc.quantity, p.is_serialized
+		FROM product_components pc
+		JOIN products p ON p.id = pc.component_id
+		WHERE pc.kit_id = $1
+		ORDER BY pc.id`
+
+	rows, err := q.Query(ctx, qComponents, kitID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []KitComponent{}
+	for rows.Next() {
+		var c KitComponent
+		if err := rows.Scan(&c.ComponentID, &c.SKU, &c.Name, &c.Quantity, &c.IsSerialized); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return out, nil
+}
+
+// setKitComponents reemplaza la lista de componentes de un kit. Los componentes deben ser productos
+// del usuario que no sean a su vez kits, sin repetir.
+func setKitComponents(ctx context.Context, q dbtx, kitID int64, userID int64, components []KitComponent) error {
+	const qComponent = `SELECT EXISTS (SELECT 1 FROM products WHERE id = $1 AND user_id = $2 AND NOT is_kit)`
+	seen := make(map[int64]bool, len(components))
+	for _, c := range components {
+		if c.ComponentID == kitID || c.Quantity <= 0 || seen[c.ComponentID] {
+			return ErrInvalidKitComponent
+		}
+		seen[c.ComponentID] = true
+
+		var ok bool
+		if err := q.QueryRow(ctx, qComponent, c.ComponentID, userID).Scan(&ok); err != nil {
+			return err
+		}
+		if !ok {
+			return ErrInvalidKitComponent
+		}
+	}
+
+	if _, err := q.Exec(ctx, `DELETE FROM product_components WHERE kit_id = $1`, kitID); err != nil {
+		return err
+	}
+	const insert = `INSERT INTO product_components (kit_id, component_id, quantity) VALUES ($1, $2, $3)`
+	for _, c := range components {
+		if _, err := q.Exec(ctx, insert, kitID, c.ComponentID, roundQuantity(c.Quantity)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reserveKitSerials reserva las series de los componentes serializados de una línea de kit.
+// serials debe traer exactamente una serie por cada unidad de componente serializado.
+func reserveKitSerials(ctx context.Context, q dbtx, components []KitComponent, kitQty float64, salesOrderID int64, orderItemID int64, serials []string) error {
+	var needed float64
+	for _, c := range components {
+		if c.IsSerialized {
+			needed += kitQty * c.Quantity
+		}
+	}
+	if err := uniqueSerials(serials, needed); err != nil {
+		return err
+	}
+	if needed == 0 {
+		return nil
+	}
+
+	const upd = `
+		UPDATE product_serials
+		SET status = 'reserved', sales_order_id = $1, order_item_id = $2
+		WHERE product_id = $3 AND serial_number = ANY($4) AND status = 'in_stock'`
+	for _, c := range components {
+		if !c.IsSerialized {
+			continue
+		}
+		tag, err := q.Exec(ctx, upd, salesOrderID, orderItemID, c.ComponentID, serials)
+		if err != nil {
+			return err
+		}
+		if float64(tag.RowsAffected()) != kitQty*c.Quantity {
+			return ErrSerialNotAvailable
+		}
+	}
+	return nil
+}
+
+// kitQuantity es la cantidad vendible de un kit: la suma de lo que puede armarse en cada depósito
+// con el disponible (no reservado) de sus componentes.
+func kitQuantity(stocks []ProductStock) float64 {
+	var total float64
+	for _, s := range stocks {
+		total += s.Quantity
+	}
+	return total
+}
+
+// GetComponents devuelve los componentes de un kit del usuario.
+func (m *ProductModel) GetComponents(kitID int64, userID int64) ([]KitComponent, error) {
+	if _, err := m.GetByID(kitID, userID); err != nil {
+		return nil, err
+	}
+	return kitComponents(context.Background(), m.DB, kitID)
+}
+
+// SetComponents reemplaza los componentes de un kit del usuario.
+func (m *ProductModel) SetComponents(kitID int64, userID int64, components []KitComponent) error {
+	ctx := context.Background()
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if tx != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	var isKit bool
+	if err := tx.QueryRow(ctx, `SELECT is_kit FROM products WHERE id = $1 AND user_id = $2 FOR UPDATE`, kitID, userID).Scan(&isKit); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+	if !isKit || len(components) == 0 {
+		return ErrInvalidKitComponent
+	}
+
+	if err := setKitComponents(ctx, tx, kitID, userID, components); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	tx = nil
+	return nil
+}
@@ -0,0 +1,197 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ProductLot representa un lote de un producto en un depósito, con su vencimiento y cantidad remanente.
+type ProductLot struct {
+	ID               int64      `json:"id"`
+	ProductID        int64      `json:"product_id"`
+	WarehouseID      int64      `json:"warehouse_id"`
+	WarehouseName    string     `json:"warehouse_name"`
+	LotNumber        string     `json:"lot_number"`
+	ExpiryDate       *time.Time `json:"expiry_date,omitempty"`
+	Quantity         float64    `json:"quantity"`
+	ReceivedQuantity float64    `json:"received_quantity"`
+	PurchaseOrderID  *int64     `json:"purchase_order_id,omitempty"`
+	SupplierName     string     `json:"supplier_name,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// LotAllocation indica cuántas unidades de un lote entraron o salieron en un movimiento.
+type LotAllocation struct {
+	LotID      int64      `json:"lot_id"`
+	LotNumber  string     `json:"lot_number"`
+	ExpiryDate *time.Time `json:"expiry_date,omitempty"`
+	Quantity   float64    `json:"quantity"`
+
+	// PurchaseOrderID es la orden de compra con la que ingresó el lote.
+	PurchaseOrderID *int64 `json:"purchase_order_id,omitempty"`
+}
+
+// ErrLotQuantityMismatch is returned when the lots received exceed the quantity of the movement.
+var ErrLotQuantityMismatch = errors.New("lot quantities exceed received quantity")
+
+// receiveLots suma cantidades a los lotes del producto en el depósito, creándolos si no existen.
+func receiveLots(ctx context.Context, q dbtx, productID int64, warehouseID int64, lots []LotAllocation) error {
+	const upsert = `
+		INSERT INTO product_lots (product_id, warehouse_id, lot_number, expiry_date, quantity, received_quantity, purchase_order_id)
+		VALUES ($1, $2, $3, $4, $5, $5, $6)
+		ON CONFLICT (product_id, warehouse_id, lot_number)
+		DO UPDATE SET
+			quantity = product_lots.quantity + EXCLUDED.quantity,
+			received_quantity = product_lots.received_quantity + EXCLUDED.received_quantity,
+			expiry_date = COALESCE(product_lots.expiry_date, EXCLUDED.expiry_date)
+		RETURNING id`
+
+	for i := range lots {
+		if err := q.QueryRow(ctx, upsert,
+			productID, warehouseID, lots[i].LotNumber, lots[i].ExpiryDate, lots[i].Quantity, lots[i].PurchaseOrderID,
+		).Scan(&lots[i].LotID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// moveLots suma a los lotes del producto en el depósito (por número, creándolos si no existen)
+// cantidades que vienen de otro depósito. A diferencia de receiveLots no toca received_quantity:
+// la mercadería ya se contó como recibida en el lote del que salió.
+func moveLots(ctx context.Context, q dbtx, productID int64, warehouseID int64, lots []LotAllocation) error {
+	const upsert = `
+		INSERT INTO product_lots (product_id, warehouse_id, lot_number, expiry_date, quantity, received_quantity, purchase_order_id)
+		VALUES ($1, $2, $3, $4, $5, 0, $6)
+		ON CONFLICT (product_id, warehouse_id, lot_number)
+		DO UPDATE SET
+			quantity = product_lots.quantity + EXCLUDED.quantity,
+			expiry_date = COALESCE(product_lots.expiry_date, EXCLUDED.expiry_date)
+		RETURNING id`
+
+	for i := range lots {
+		if err := q.QueryRow(ctx, upsert,
+			productID, warehouseID, lots[i].LotNumber, lots[i].ExpiryDate, lots[i].Quantity, lots[i].PurchaseOrderID,
+		).Scan(&lots[i].LotID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// consumeLots descuenta de los lotes indicados (por número) del producto en el depósito.
+// Devuelve ErrInsufficientStock si alguno no tiene la cantidad pedida.
+func consumeLots(ctx context.Context, q dbtx, productID int64, warehouseID int64, lots []LotAllocation) ([]LotAllocation, error) {
+	const dec = `
+		UPDATE product_lots SET quantity = quantity - $1
+		WHERE product_id = $2 AND warehouse_id = $3 AND lot_number = $4 AND quantity >= $1
+		RETURNING id`
+
+	allocations := make([]LotAllocation, 0, len(lots))
+	for _, l := range lots {
+		a := l
+		if err := q.QueryRow(ctx, dec, l.Quantity, productID, warehouseID, l.LotNumber).Scan(&a.LotID); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, ErrInsufficientStock
+			}
+			return nil, err
+		}
+		allocations = append(allocations, a)
+	}
+	return allocations, nil
+}
+
+// consumeLotsFEFO descuenta qty unidades de los lotes del producto en el depósito, empezando por el
+// que vence primero. Si los lotes no alcanzan, el resto se toma del stock sin lote.
+func consumeLotsFEFO(ctx context.Context, q dbtx, productID int64, warehouseID int64, qty float64) ([]LotAllocation, error) {
+	const qLots = `
+		SELECT id, lot_number, expiry_date, quantity, purchase_order_id
+		FROM product_lots
+		WHERE product_id = $1 AND warehouse_id = $2 AND quantity > 0
+		ORDER BY expiry_date NULLS LAST, id
+		FOR UPDATE`
+
+	rows, err := q.Query(ctx, qLots, productID, warehouseID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Leer todos los lotes antes de actualizarlos (no se puede usar la tx mientras se itera)
+	var allocations []LotAllocation
+	remaining := qty
+	for remaining > 0 && rows.Next() {
+		var a LotAllocation
+		var available float64
+		if err := rows.Scan(&a.LotID, &a.LotNumber, &a.ExpiryDate, &available, &a.PurchaseOrderID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		a.Quantity = min(available, remaining)
+		remaining = roundQuantity(remaining - a.Quantity)
+		allocations = append(allocations, a)
+	}
+	rows.Close()
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	const dec = `UPDATE product_lots SET quantity = quantity - $1 WHERE id = $2`
+	for _, a := range allocations {
+		if _, err := q.Exec(ctx, dec, a.Quantity, a.LotID); err != nil {
+			return nil, err
+		}
+	}
+	return allocations, nil
+}
+
+// LotModel wraps DB access for product lots.
+type LotModel struct {
+	DB *pgxpool.Pool
+}
+
+// GetForProduct devuelve todos los lotes de un producto (incluidos los agotados) para trazabilidad de retiros.
+func (m *LotModel) GetForProduct(productID int64, userID int64) ([]ProductLot, error) {
+	ctx := context.Background()
+
+	var exists bool
+	if err := m.DB.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM products WHERE id = $1 AND user_id = $2)`, productID, userID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	const q = `
+		SELECT l.id, l.product_id, l.warehouse_id, w.name, l.lot_number, l.expiry_date,
+			l.quantity, l.received_quantity, l.purchase_order_id, COALESCE(s.name, ''), l.created_at
+		FROM product_lots l
+		JOIN warehouses w ON w.id = l.warehouse_id
+		LEFT JOIN purchase_orders po ON po.id = l.purchase_order_id
+		LEFT JOIN suppliers s ON s.id = po.supplier_id
+		WHERE l.product_id = $1
+		ORDER BY l.expiry_date NULLS LAST, l.id`
+
+	rows, err := m.DB.Query(ctx, q, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []ProductLot{}
+	for rows.Next() {
+		var l ProductLot
+		if err := rows.Scan(&l.ID, &l.ProductID, &l.WarehouseID, &l.WarehouseName, &l.LotNumber, &l.ExpiryDate,
+			&l.Quantity, &l.ReceivedQuantity, &l.PurchaseOrderID, &l.SupplierName, &l.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, l)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return out, nil
+}
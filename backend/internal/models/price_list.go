@@ -0,0 +1,391 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Qué hacer con una línea de venta cuyo precio queda por debajo del precio mínimo del producto
+const (
+	PriceFloorReject = "reject"
+	PriceFloorFlag   = "flag"
+)
+
+// Errors for price list operations
+var (
+	ErrPriceListNotFound      = errors.New("price list not found")
+	ErrDuplicatePriceList     = errors.New("duplicate price list name")
+	ErrInvalidPriceListItem   = errors.New("invalid price list item")
+	ErrInvalidPriceFloorRule  = errors.New("invalid price floor policy")
+	ErrPriceBelowFloor        = errors.New("unit price below the product's price floor")
+	ErrInvalidPriceListPeriod = errors.New("price list valid_to is before valid_from")
+)
+
+// roundPrice redondea un precio a la precisión de las columnas de precios (2 decimales).
+func roundPrice(p float64) float64 {
+	return math.Round(p*100) / 100
+}
+
+// PriceList es una lista de precios con nombre (minorista, mayorista, acuerdo con un cliente).
+// Sin fechas de vigencia rige siempre.
+type PriceList struct {
+	ID        int64           `json:"id"`
+	Name      string          `json:"name"`
+	ValidFrom *time.Time      `json:"valid_from,omitempty"`
+	ValidTo   *time.Time      `json:"valid_to,omitempty"`
+	IsDefault bool            `json:"is_default"`
+	UserID    int64           `json:"user_id"`
+	CreatedAt time.Time       `json:"created_at"`
+	Items     []PriceListItem `json:"items,omitempty"`
+}
+
+// PriceListItem es el precio por unidad base de un producto a partir de MinQuantity unidades base.
+type PriceListItem struct {
+	ID          int64   `json:"id"`
+	PriceListID int64   `json:"price_list_id"`
+	ProductID   int64   `json:"product_id"`
+	MinQuantity float64 `json:"min_quantity"`
+	UnitPrice   float64 `json:"unit_price"`
+}
+
+// PriceQuote es el precio que se aplicaría a una venta.
+type PriceQuote struct {
+	ProductID   int64    `json:"product_id"`
+	Quantity    float64  `json:"quantity"`
+	UnitPrice   float64  `json:"unit_price"` // por unidad base
+	PriceListID *int64   `json:"price_list_id"`
+	PriceFloor  *float64 `json:"price_floor,omitempty"`
+}
+
+// resolvePrice busca el precio por unidad base de un producto para una cantidad (en unidad base):
+// primero en la lista del cliente y si no en la lista por defecto, sólo entre listas vigentes en at,
+// tomando la escala de cantidad más alta alcanzada. Sin precio devuelve 0 y lista nil.
+func resolvePrice(ctx context.Context, q dbtx, userID int64, customerID *int64, productID int64, quantity float64, at time.Time) (float64, *int64, error) {
+	const qPrice = `
+		SELECT pli.unit_price, pl.id
+		FROM price_lists pl
+		JOIN price_list_items pli ON pli.price_list_id = pl.id
+		WHERE pl.user_id = $1 AND pli.product_id = $3 AND pli.min_quantity <= $4
+			AND (pl.valid_from IS NULL OR pl.valid_from <= $5::date)
+			AND (pl.valid_to IS NULL OR pl.valid_to >= $5::date)
+			AND (pl.id = (SELECT price_list_id FROM customers WHERE id = $2::bigint AND user_id = $1) OR pl.is_default)
+		ORDER BY pl.is_default, pli.min_quantity DESC
+		LIMIT 1`
+
+	var price float64
+	var listID int64
+	err := q.QueryRow(ctx, qPrice, userID, customerID, productID, quantity, at).Scan(&price, &listID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, nil, nil
+		}
+		return 0, nil, err
+	}
+	return price, &listID, nil
+}
+
+// priceFloorPolicy devuelve la política de la cuenta para precios bajo el mínimo.
+func priceFloorPolicy(ctx context.Context, q dbtx, userID int64) (string, error) {
+	var policy string
+	err := q.QueryRow(ctx, `SELECT price_floor_policy FROM users WHERE id = $1`, userID).Scan(&policy)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return PriceFloorReject, nil
+	}
+	return policy, err
+}
+
+// belowPriceFloor indica si un precio por unidad base queda por debajo del mínimo del producto.
+func belowPriceFloor(ctx context.Context, q dbtx, productID int64, basePrice float64) (bool, error) {
+	var floor *float64
+	if err := q.QueryRow(ctx, `SELECT price_floor FROM products WHERE id = $1`, productID).Scan(&floor); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, ErrNotFound
+		}
+		return false, err
+	}
+	return floor != nil && roundPrice(basePrice) < *floor, nil
+}
+
+// PriceListModel wraps DB access for price lists.
+type PriceListModel struct {
+	DB *pgxpool.Pool
+}
+
+// validatePriceList revisa la vigencia y que los precios sean de productos del usuario.
+func validatePriceList(ctx context.Context, tx pgx.Tx, pl *PriceList) error {
+	if pl.ValidFrom != nil && pl.ValidTo != nil && pl.ValidTo.Before(*pl.ValidFrom) {
+		return ErrInvalidPriceListPeriod
+	}
+	for _, it := range pl.Items {
+		if it.MinQuantity < 0 || it.UnitPrice < 0 {
+			return ErrInvalidPriceListItem
+		}
+		var exists bool
+		err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM products WHERE id = $1 AND user_id = $2)`, it.ProductID, pl.UserID).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return ErrInvalidPriceListItem
+		}
+	}
+	return nil
+}
+
+// savePriceListItems reemplaza los precios de una lista.
+func savePriceListItems(ctx context.Context, tx pgx.Tx, pl *PriceList) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM price_list_items WHERE price_list_id = $1`, pl.ID); err != nil {
+		return err
+	}
+
+	const insert = `
+		INSERT INTO price_list_items (price_list_id, product_id, min_quantity, unit_price)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id`
+	for i := range pl.Items {
+		it := &pl.Items[i]
+		it.PriceListID = pl.ID
+		it.MinQuantity = roundQuantity(it.MinQuantity)
+		it.UnitPrice = roundPrice(it.UnitPrice)
+		if err := tx.QueryRow(ctx, insert, pl.ID, it.ProductID, it.MinQuantity, it.UnitPrice).Scan(&it.ID); err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23505" { // misma escala repetida para un producto
+				return ErrInvalidPriceListItem
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// clearDefaultPriceList quita la marca de lista por defecto de las demás listas del usuario.
+func clearDefaultPriceList(ctx context.Context, tx pgx.Tx, userID int64, keepID int64) error {
+	_, err := tx.Exec(ctx, `UPDATE price_lists SET is_default = false WHERE user_id = $1 AND is_default AND id <> $2`, userID, keepID)
+	return err
+}
+
+// Insert crea una lista de precios con sus ítems.
+func (m *PriceListModel) Insert(pl *PriceList) error {
+	ctx := context.Background()
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if tx != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	if err := validatePriceList(ctx, tx, pl); err != nil {
+		return err
+	}
+	if pl.IsDefault {
+		if err := clearDefaultPriceList(ctx, tx, pl.UserID, 0); err != nil {
+			return err
+		}
+	}
+
+	const q = `
+		INSERT INTO price_lists (name, valid_from, valid_to, is_default, user_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+	if err := tx.QueryRow(ctx, q, pl.Name, pl.ValidFrom, pl.ValidTo, pl.IsDefault, pl.UserID).Scan(&pl.ID, &pl.CreatedAt); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique_violation (user_id, name)
+			return ErrDuplicatePriceList
+		}
+		return err
+	}
+
+	if err := savePriceListItems(ctx, tx, pl); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	tx = nil
+	return nil
+}
+
+// GetAllForUser devuelve las listas de precios del usuario (sin ítems), la lista por defecto primero.
+func (m *PriceListModel) GetAllForUser(userID int64) ([]PriceList, error) {
+	const q = `
+		SELECT id, name, valid_from, valid_to, is_default, user_id, created_at
+		FROM price_lists
+		WHERE user_id = $1
+		ORDER BY is_default DESC, name`
+
+	rows, err := m.DB.Query(context.Background(), q, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []PriceList{}
+	for rows.Next() {
+		var pl PriceList
+		if err := rows.Scan(&pl.ID, &pl.Name, &pl.ValidFrom, &pl.ValidTo, &pl.IsDefault, &pl.UserID, &pl.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, pl)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return out, nil
+}
+
+// GetByID devuelve una lista de precios del usuario con sus ítems.
+func (m *PriceListModel) GetByID(id int64, userID int64) (*PriceList, error) {
+	ctx := context.Background()
+
+	const q = `
+		SELECT id, name, valid_from, valid_to, is_default, user_id, created_at
+		FROM price_lists
+		WHERE id = $1 AND user_id = $2`
+
+	var pl PriceList
+	err := m.DB.QueryRow(ctx, q, id, userID).Scan(&pl.ID, &pl.Name, &pl.ValidFrom, &pl.ValidTo, &pl.IsDefault, &pl.UserID, &pl.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrPriceListNotFound
+		}
+		return nil, err
+	}
+
+	const qItems = `
+		SELECT id, price_list_id, product_id, min_quantity, unit_price
+		FROM price_list_items
+		WHERE price_list_id = $1
+		ORDER BY product_id, min_quantity`
+	rows, err := m.DB.Query(ctx, qItems, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pl.Items = []PriceListItem{}
+	for rows.Next() {
+		var it PriceListItem
+		if err := rows.Scan(&it.ID, &it.PriceListID, &it.ProductID, &it.MinQuantity, &it.UnitPrice); err != nil {
+			return nil, err
+		}
+		pl.Items = append(pl.Items, it)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return &pl, nil
+}
+
+// Update reemplaza la cabecera y los ítems de una lista. Las líneas de venta ya cargadas
+// conservan el precio con el que se vendieron.
+func (m *PriceListModel) Update(id int64, userID int64, pl *PriceList) error {
+	ctx := context.Background()
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if tx != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	pl.ID, pl.UserID = id, userID
+	if err := validatePriceList(ctx, tx, pl); err != nil {
+		return err
+	}
+	if pl.IsDefault {
+		if err := clearDefaultPriceList(ctx, tx, userID, id); err != nil {
+			return err
+		}
+	}
+
+	const q = `
+		UPDATE price_lists
+		SET name = $1, valid_from = $2, valid_to = $3, is_default = $4
+		WHERE id = $5 AND user_id = $6`
+	tag, err := tx.Exec(ctx, q, pl.Name, pl.ValidFrom, pl.ValidTo, pl.IsDefault, id, userID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrDuplicatePriceList
+		}
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrPriceListNotFound
+	}
+
+	if err := savePriceListItems(ctx, tx, pl); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	tx = nil
+	return nil
+}
+
+// Delete elimina una lista que no esté asignada a clientes ni usada en ventas.
+func (m *PriceListModel) Delete(id int64, userID int64) error {
+	tag, err := m.DB.Exec(context.Background(), `DELETE FROM price_lists WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" { // foreign_key_violation
+			return ErrHasReferences
+		}
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrPriceListNotFound
+	}
+	return nil
+}
+
+// Quote devuelve el precio que tendría hoy una venta del producto al cliente (0 = sin cliente).
+func (m *PriceListModel) Quote(userID int64, customerID int64, productID int64, quantity float64) (*PriceQuote, error) {
+	ctx := context.Background()
+
+	quote := &PriceQuote{ProductID: productID, Quantity: quantity}
+	err := m.DB.QueryRow(ctx, `SELECT price_floor FROM products WHERE id = $1 AND user_id = $2`, productID, userID).Scan(&quote.PriceFloor)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	var customer *int64
+	if customerID != 0 {
+		customer = &customerID
+	}
+	quote.UnitPrice, quote.PriceListID, err = resolvePrice(ctx, m.DB, userID, customer, productID, roundQuantity(quantity), time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return quote, nil
+}
+
+// GetPolicy devuelve la política de la cuenta para precios bajo el mínimo.
+func (m *PriceListModel) GetPolicy(userID int64) (string, error) {
+	return priceFloorPolicy(context.Background(), m.DB, userID)
+}
+
+// SetPolicy cambia la política de la cuenta para precios bajo el mínimo (reject o flag).
+func (m *PriceListModel) SetPolicy(userID int64, policy string) error {
+	if policy != PriceFloorReject && policy != PriceFloorFlag {
+		return ErrInvalidPriceFloorRule
+	}
+	return updateAs(context.Background(), m.DB, userID, `UPDATE users SET price_floor_policy = $1 WHERE id = $2`, policy, userID)
+}
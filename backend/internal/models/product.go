@@ -3,7 +3,6 @@ package models
 import (
 	"context"
 	"errors"
-	"log/slog"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -17,73 +16,313 @@ type Product struct {
 	Name        string    `json:"name"`
 	SKU         string    `json:"sku"`
 	Description *string   `json:"description,omitempty"`
-	Quantity    int       `json:"quantity"`
-	StockMinimo int       `json:"stock_minimo"`
+	Quantity    float64   `json:"quantity"`
+	StockMinimo float64   `json:"stock_minimo"`
 	Notificado  bool      `json:"notificado"`
 	UserID      int64     `json:"user_id"`
 	CreatedAt   time.Time `json:"created_at"`
+
+	// IsSerialized indica que cada unidad se identifica por número de serie.
+	IsSerialized bool `json:"is_serialized"`
+
+	// IsKit indica que el producto es un kit: no tiene stock propio, Quantity y Stocks se calculan
+	// a partir de Components y al venderlo se descuenta el stock de cada componente.
+	IsKit      bool           `json:"is_kit"`
+	Components []KitComponent `json:"components,omitempty"`
+
+	// BaseUnit es la unidad en la que se guardan quantity, stock_minimo y los movimientos (unidad, kg, m...).
+	BaseUnit string `json:"base_unit"`
+
+	// Variantes: un producto con ParentID es una variante (talle, color, etc.) del producto padre.
+	ParentID      *int64            `json:"parent_id,omitempty"`
+	Attributes    map[string]string `json:"attributes"`
+	MLVariationID *int64            `json:"ml_variation_id,omitempty"`
+	Variants      []Product         `json:"variants,omitempty"`
+
+	// Stocks detalla la cantidad por depósito; Quantity es el total de todos los depósitos.
+	Stocks []ProductStock `json:"stocks"`
+
+	// Reserved es lo comprometido por órdenes de venta pendientes; Available = Quantity - Reserved.
+	Reserved  float64 `json:"reserved"`
+	Available float64 `json:"available"`
+
+	// Clasificación: una categoría del árbol del usuario y etiquetas libres (normalizadas en minúsculas).
+	CategoryID *int64   `json:"category_id,omitempty"`
+	Tags       []string `json:"tags"`
+
+	// Barcodes son los códigos de barras del producto; sólo se cargan al consultar un producto.
+	Barcodes []ProductBarcode `json:"barcodes,omitempty"`
+
+	// Attachments son las imágenes (la principal primero) y documentos del producto; sólo se cargan
+	// al consultar un producto.
+	Attachments []ProductAttachment `json:"attachments,omitempty"`
+
+	// PriceFloor es el precio mínimo de venta por unidad base (nil = sin mínimo).
+	PriceFloor *float64 `json:"price_floor,omitempty"`
+
+	// ArchivedAt marca un producto archivado: no se lista ni se vende, pero sigue en el historial.
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+
+	// Clasificación ABC (aporte a la facturación) y XYZ (variabilidad de la demanda) calculada por
+	// un job periódico; nil hasta la primera corrida.
+	AbcClass     *string    `json:"abc_class,omitempty"`
+	XyzClass     *string    `json:"xyz_class,omitempty"`
+	ClassifiedAt *time.Time `json:"classified_at,omitempty"`
+}
+
+// ProductFilter restringe los productos listados. Los valores cero no filtran.
+type ProductFilter struct {
+	CategoryID int64  // incluye las subcategorías
+	Tag        string // etiqueta exacta (se normaliza)
+	Archived   ArchiveFilter
+	AbcClass   string // A, B o C
+	XyzClass   string // X, Y o Z
+}
+
+// ProductStock representa el stock de un producto en un depósito.
+type ProductStock struct {
+	WarehouseID   int64   `json:"warehouse_id"`
+	WarehouseName string  `json:"warehouse_name"`
+	Quantity      float64 `json:"quantity"`
+	Reserved      float64 `json:"reserved"`
+	Available     float64 `json:"available"`
 }
 
 // Errors for product operations
 var (
-	ErrNotFound      = errors.New("record not found")
-	ErrDuplicateSKU  = errors.New("duplicate sku")
-	ErrHasReferences = errors.New("cannot delete: record has references in other tables")
+	ErrNotFound           = errors.New("record not found")
+	ErrDuplicateSKU       = errors.New("duplicate sku")
+	ErrHasReferences      = errors.New("cannot delete: record has references in other tables")
+	ErrInvalidParent      = errors.New("invalid parent product")
+	ErrDuplicateVariation = errors.New("duplicate mercado libre variation")
 )
 
+// productUniqueViolation traduce un 23505 de products al error de dominio según el índice violado.
+func productUniqueViolation(pgErr *pgconn.PgError) error {
+	if pgErr.ConstraintName == "idx_products_ml_variation" {
+		return ErrDuplicateVariation
+	}
+	return ErrDuplicateSKU
+}
+
+// validateParent verifica que el padre exista, sea del usuario y no sea a su vez una variante.
+// Un producto que ya tiene variantes tampoco puede convertirse en variante.
+func validateParent(ctx context.Context, q dbtx, productID int64, parentID *int64, userID int64) error {
+	if parentID == nil {
+		return nil
+	}
+	if *parentID == productID {
+		return ErrInvalidParent
+	}
+
+	var parentOfParent *int64
+	err := q.QueryRow(ctx, `SELECT parent_id FROM products WHERE id = $1 AND user_id = $2`, *parentID, userID).Scan(&parentOfParent)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInvalidParent
+		}
+		return err
+	}
+	if parentOfParent != nil {
+		return ErrInvalidParent
+	}
+
+	if productID != 0 {
+		var hasVariants bool
+		if err := q.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM products WHERE parent_id = $1)`, productID).Scan(&hasVariants); err != nil {
+			return err
+		}
+		if hasVariants {
+			return ErrInvalidParent
+		}
+	}
+	return nil
+}
+
+// attributesOrEmpty evita guardar NULL en la columna JSONB de atributos.
+func attributesOrEmpty(a map[string]string) map[string]string {
+	if a == nil {
+		return map[string]string{}
+	}
+	return a
+}
+
 // ProductModel wraps DB access for products.
 type ProductModel struct {
 	DB *pgxpool.Pool
 }
 
 // Insert inserts a new product for a user and sets ID and CreatedAt.
+// La cantidad inicial se asigna al depósito por defecto del usuario.
 func (m *ProductModel) Insert(p *Product) error {
+	ctx := context.Background()
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if tx != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	if err := validateParent(ctx, tx, 0, p.ParentID, p.UserID); err != nil {
+		return err
+	}
+	if err := validateCategory(ctx, tx, p.CategoryID, p.UserID); err != nil {
+		return err
+	}
+	p.Attributes = attributesOrEmpty(p.Attributes)
+	if p.BaseUnit == "" {
+		p.BaseUnit = DefaultBaseUnit
+	}
+	p.Quantity = roundQuantity(p.Quantity)
+
+	// Un kit no guarda stock propio ni lleva series: se definen en sus componentes
+	if p.IsKit {
+		if len(p.Components) == 0 {
+			return ErrInvalidKitComponent
+		}
+		p.Quantity = 0
+		p.IsSerialized = false
+	}
+
 	const q = `
-		INSERT INTO products (name, sku, description, quantity, stock_minimo, user_id)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO products (name, sku, description, quantity, stock_minimo, user_id, is_serialized,
+			parent_id, attributes, ml_variation_id, base_unit, is_kit, category_id, price_floor)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 		RETURNING id, created_at, notificado`
 
-	err := m.DB.QueryRow(context.Background(), q, p.Name, p.SKU, p.Description, p.Quantity, p.StockMinimo, p.UserID).
+	// La cantidad inicial entra como movimiento OPENING_BALANCE para que el ledger arranque completo
+	err = tx.QueryRow(ctx, q, p.Name, p.SKU, p.Description, 0, p.StockMinimo, p.UserID, p.IsSerialized,
+		p.ParentID, p.Attributes, p.MLVariationID, p.BaseUnit, p.IsKit, p.CategoryID, p.PriceFloor).
 		Scan(&p.ID, &p.CreatedAt, &p.Notificado)
 	if err != nil {
 		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique_violation (user_id, sku)
-			return ErrDuplicateSKU
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique_violation (user_id, sku) o variación ML
+			return productUniqueViolation(pgErr)
 		}
 		return err
 	}
+
+	if p.Tags, err = setProductTags(ctx, tx, p.ID, p.UserID, p.Tags); err != nil {
+		return err
+	}
+
+	if p.IsKit {
+		if err := setKitComponents(ctx, tx, p.ID, p.UserID, p.Components); err != nil {
+			return err
+		}
+		if p.Components, err = kitComponents(ctx, tx, p.ID); err != nil {
+			return err
+		}
+	} else {
+		warehouseID, err := resolveWarehouse(ctx, tx, p.UserID, 0)
+		if err != nil {
+			return err
+		}
+		if p.Quantity != 0 {
+			if _, err := applyStockChange(ctx, tx, stockChange{
+				ProductID:      p.ID,
+				WarehouseID:    warehouseID,
+				UserID:         p.UserID,
+				QuantityChange: p.Quantity,
+				Reason:         ReasonOpeningBalance,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	tx = nil
+
+	stocks, err := loadProductStocks(ctx, m.DB, p.UserID, p.ID)
+	if err != nil {
+		return err
+	}
+	setStocks(p, stocks)
 	return nil
 }
 
+// productColumns son las columnas leídas por scanProduct, en orden.
+const productColumns = `id, name, sku, description, quantity, stock_minimo, notificado, user_id, created_at, is_serialized,
+	parent_id, attributes, ml_variation_id, base_unit, is_kit, category_id, price_floor, archived_at, abc_class, xyz_class, classified_at`
+
+func scanProduct(row pgx.Row, p *Product) error {
+	return row.Scan(
+		&p.ID, &p.Name, &p.SKU, &p.Description, &p.Quantity, &p.StockMinimo, &p.Notificado, &p.UserID, &p.CreatedAt,
+		&p.IsSerialized, &p.ParentID, &p.Attributes, &p.MLVariationID, &p.BaseUnit, &p.IsKit, &p.CategoryID,
+		&p.PriceFloor, &p.ArchivedAt, &p.AbcClass, &p.XyzClass, &p.ClassifiedAt,
+	)
+}
+
 // GetByID returns a product by ID for a given user.
 func (m *ProductModel) GetByID(id int64, userID int64) (*Product, error) {
 	const q = `
-		SELECT id, name, sku, description, quantity, stock_minimo, notificado, user_id, created_at
+		SELECT ` + productColumns + `
 		FROM products
 		WHERE id = $1 AND user_id = $2`
 
 	var p Product
-	err := m.DB.QueryRow(context.Background(), q, id, userID).Scan(
-		&p.ID, &p.Name, &p.SKU, &p.Description, &p.Quantity, &p.StockMinimo, &p.Notificado, &p.UserID, &p.CreatedAt,
-	)
+	err := scanProduct(m.DB.QueryRow(context.Background(), q, id, userID), &p)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrNotFound
 		}
 		return nil, err
 	}
+
+	stocks, err := loadProductStocks(context.Background(), m.DB, userID, p.ID)
+	if err != nil {
+		return nil, err
+	}
+	setStocks(&p, stocks)
+
+	tags, err := loadProductTags(context.Background(), m.DB, userID, p.ID)
+	if err != nil {
+		return nil, err
+	}
+	p.Tags = tagsOrEmpty(tags[p.ID])
+
+	if p.Barcodes, err = productBarcodes(context.Background(), m.DB, p.ID); err != nil {
+		return nil, err
+	}
+	if p.Attachments, err = productAttachments(context.Background(), m.DB, p.ID); err != nil {
+		return nil, err
+	}
+
+	if p.IsKit {
+		if p.Components, err = kitComponents(context.Background(), m.DB, p.ID); err != nil {
+			return nil, err
+		}
+	}
 	return &p, nil
 }
 
-// GetAllForUser returns all products for a given user.
+// GetAllForUser returns all active (not archived) products for a given user.
 func (m *ProductModel) GetAllForUser(userID int64) ([]Product, error) {
+	return m.GetFiltered(userID, ProductFilter{})
+}
+
+// GetFiltered returns the products of a user that match the filter.
+func (m *ProductModel) GetFiltered(userID int64, f ProductFilter) ([]Product, error) {
 	const q = `
-		SELECT id, name, sku, description, quantity, stock_minimo, notificado, user_id, created_at
+		SELECT ` + productColumns + `
 		FROM products
 		WHERE user_id = $1
+			AND ($2::bigint = 0 OR category_id IN (` + categoryDescendantsCTE + `))
+			AND ($3::text = '' OR EXISTS (
+				SELECT 1 FROM product_tags pt JOIN tags t ON t.id = pt.tag_id
+				WHERE pt.product_id = products.id AND t.name = $3))
+			AND ($4::text = 'include' OR (products.archived_at IS NOT NULL) = ($4::text = 'only'))
+			AND ($5::text = '' OR abc_class = $5)
+			AND ($6::text = '' OR xyz_class = $6)
 		ORDER BY id`
 
-	rows, err := m.DB.Query(context.Background(), q, userID)
+	rows, err := m.DB.Query(context.Background(), q, userID, f.CategoryID, NormalizeTag(f.Tag), string(f.Archived), f.AbcClass, f.XyzClass)
 	if err != nil {
 		return nil, err
 	}
@@ -92,7 +331,7 @@ func (m *ProductModel) GetAllForUser(userID int64) ([]Product, error) {
 	products := []Product{} // Initialize as empty slice instead of nil
 	for rows.Next() {
 		var p Product
-		if err := rows.Scan(&p.ID, &p.Name, &p.SKU, &p.Description, &p.Quantity, &p.StockMinimo, &p.Notificado, &p.UserID, &p.CreatedAt); err != nil {
+		if err := scanProduct(rows, &p); err != nil {
 			return nil, err
 		}
 		products = append(products, p)
@@ -100,27 +339,223 @@ func (m *ProductModel) GetAllForUser(userID int64) ([]Product, error) {
 	if rows.Err() != nil {
 		return nil, rows.Err()
 	}
+
+	stocks, err := loadProductStocks(context.Background(), m.DB, userID, 0)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := loadProductTags(context.Background(), m.DB, userID, 0)
+	if err != nil {
+		return nil, err
+	}
+	for i := range products {
+		setStocks(&products[i], stocks)
+		products[i].Tags = tagsOrEmpty(tags[products[i].ID])
+	}
 	return products, nil
 }
 
+// stocksOrEmpty evita serializar null cuando un producto no tiene stock en ningún depósito.
+func stocksOrEmpty(s []ProductStock) []ProductStock {
+	if s == nil {
+		return []ProductStock{}
+	}
+	return s
+}
+
+// setStocks asigna el stock por depósito del producto; la cantidad de un kit es lo que puede armarse.
+func setStocks(p *Product, stocks map[int64][]ProductStock) {
+	p.Stocks = stocksOrEmpty(stocks[p.ID])
+	if p.IsKit {
+		p.Quantity = kitQuantity(p.Stocks)
+	}
+	p.Reserved = 0
+	for _, s := range p.Stocks {
+		p.Reserved += s.Reserved
+	}
+	p.Available = p.Quantity - p.Reserved
+}
+
+// GetVariants returns the variants of a parent product for a given user.
+func (m *ProductModel) GetVariants(parentID int64, userID int64) ([]Product, error) {
+	if _, err := m.GetByID(parentID, userID); err != nil {
+		return nil, err
+	}
+
+	const q = `
+		SELECT ` + productColumns + `
+		FROM products
+		WHERE parent_id = $1 AND user_id = $2 AND archived_at IS NULL
+		ORDER BY id`
+
+	rows, err := m.DB.Query(context.Background(), q, parentID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	variants := []Product{}
+	for rows.Next() {
+		var p Product
+		if err := scanProduct(rows, &p); err != nil {
+			return nil, err
+		}
+		variants = append(variants, p)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	stocks, err := loadProductStocks(context.Background(), m.DB, userID, 0)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := loadProductTags(context.Background(), m.DB, userID, 0)
+	if err != nil {
+		return nil, err
+	}
+	for i := range variants {
+		setStocks(&variants[i], stocks)
+		variants[i].Tags = tagsOrEmpty(tags[variants[i].ID])
+	}
+	return variants, nil
+}
+
+// RollUpVariants agrupa las variantes bajo su producto padre. El padre queda con la cantidad
+// total (propia más la de sus variantes) y las variantes en Variants. Las variantes cuyo padre
+// no está en la lista se devuelven como productos sueltos.
+func RollUpVariants(products []Product) []Product {
+	index := make(map[int64]int, len(products))
+	out := make([]Product, 0, len(products))
+	for _, p := range products {
+		if p.ParentID == nil {
+			index[p.ID] = len(out)
+			out = append(out, p)
+		}
+	}
+	for _, p := range products {
+		if p.ParentID == nil {
+			continue
+		}
+		i, ok := index[*p.ParentID]
+		if !ok {
+			out = append(out, p)
+			continue
+		}
+		out[i].Quantity += p.Quantity
+		out[i].Reserved += p.Reserved
+		out[i].Available += p.Available
+		out[i].Variants = append(out[i].Variants, p)
+	}
+	return out
+}
+
+// ErrQuantityNotEditable is returned when a product update changes its quantity.
+var ErrQuantityNotEditable = errors.New("quantity changes must go through a stock adjustment")
+
+// ErrBaseUnitNotEditable is returned when a product update changes the base unit of a product in use.
+var ErrBaseUnitNotEditable = errors.New("base unit cannot change once the product has stock, units, lots or movements")
+
+// checkBaseUnitChange valida el cambio de unidad base de un producto: requested vacío conserva la
+// actual, y un producto en uso (con stock, conversiones, lotes o movimientos) no puede cambiarla
+// porque todas esas cantidades están expresadas en ella.
+func checkBaseUnitChange(current, requested string, inUse bool) error {
+	if requested == "" || requested == current || !inUse {
+		return nil
+	}
+	return ErrBaseUnitNotEditable
+}
+
 // Update updates a product if it belongs to the user.
+// La cantidad no se edita directamente: una diferencia devuelve ErrQuantityNotEditable y debe registrarse
+// como ajuste de stock, que pasa por el motivo y la aprobación que correspondan. La unidad base sólo
+// cambia mientras el producto no está en uso (ErrBaseUnitNotEditable).
 func (m *ProductModel) Update(id int64, userID int64, p *Product) error {
+	ctx := context.Background()
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if tx != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+	if err := setActor(ctx, tx, userID); err != nil {
+		return err
+	}
+
+	var current float64
+	var isKit bool
+	var baseUnit string
+	err = tx.QueryRow(ctx, `SELECT quantity, is_kit, base_unit FROM products WHERE id = $1 AND user_id = $2 FOR UPDATE`, id, userID).
+		Scan(&current, &isKit, &baseUnit)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	if p.BaseUnit != "" && p.BaseUnit != baseUnit {
+		const qInUse = `
+			SELECT EXISTS (SELECT 1 FROM product_units WHERE product_id = $1)
+				OR EXISTS (SELECT 1 FROM product_stocks WHERE product_id = $1 AND (quantity <> 0 OR reserved <> 0))
+				OR EXISTS (SELECT 1 FROM product_lots WHERE product_id = $1)
+				OR EXISTS (SELECT 1 FROM stock_movements WHERE product_id = $1)`
+		var inUse bool
+		if err := tx.QueryRow(ctx, qInUse, id).Scan(&inUse); err != nil {
+			return err
+		}
+		if err := checkBaseUnitChange(baseUnit, p.BaseUnit, inUse || current != 0); err != nil {
+			return err
+		}
+	}
+
+	if err := validateParent(ctx, tx, id, p.ParentID, userID); err != nil {
+		return err
+	}
+	if err := validateCategory(ctx, tx, p.CategoryID, userID); err != nil {
+		return err
+	}
+
+	// La cantidad de un kit se calcula; sus componentes se editan con SetComponents
+	if isKit {
+		p.Quantity = current
+		p.IsSerialized = false
+	}
+	if roundQuantity(p.Quantity) != current {
+		return ErrQuantityNotEditable
+	}
+
 	const q = `
 		UPDATE products
-		SET name = $1, sku = $2, description = $3, quantity = $4, stock_minimo = $5
-		WHERE id = $6 AND user_id = $7`
+		SET name = $1, sku = $2, description = $3, stock_minimo = $4, is_serialized = $5,
+			parent_id = $6, attributes = $7, ml_variation_id = $8, base_unit = COALESCE(NULLIF($9, ''), base_unit),
+			category_id = $10, price_floor = $11
+		WHERE id = $12 AND user_id = $13`
 
-	tag, err := m.DB.Exec(context.Background(), q, p.Name, p.SKU, p.Description, p.Quantity, p.StockMinimo, id, userID)
-	if err != nil {
+	p.Quantity = current
+	if _, err := tx.Exec(ctx, q, p.Name, p.SKU, p.Description, p.StockMinimo, p.IsSerialized,
+		p.ParentID, attributesOrEmpty(p.Attributes), p.MLVariationID, p.BaseUnit, p.CategoryID, p.PriceFloor, id, userID); err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique_violation
-			return ErrDuplicateSKU
+			return productUniqueViolation(pgErr)
 		}
 		return err
 	}
-	if tag.RowsAffected() == 0 {
-		return ErrNotFound
+
+	// Tags nil conserva las etiquetas actuales; una lista vacía las quita
+	if p.Tags != nil {
+		if p.Tags, err = setProductTags(ctx, tx, id, userID, p.Tags); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
 	}
+	tx = nil
 	return nil
 }
 
@@ -144,8 +579,17 @@ func (m *ProductModel) Delete(id int64, userID int64) error {
 	return nil
 }
 
-// AdjustStock ajusta la cantidad de un producto y registra el movimiento de stock en una transacción.
-func (m *ProductModel) AdjustStock(productID int64, userID int64, quantityChange int, reason string) error {
+// Archive archiva un producto junto con sus variantes. Sigue visible en órdenes, reportes y movimientos.
+func (m *ProductModel) Archive(id int64, userID int64) error {
+	return m.setArchived(id, userID, true)
+}
+
+// Restore vuelve a activar un producto archivado y sus variantes.
+func (m *ProductModel) Restore(id int64, userID int64) error {
+	return m.setArchived(id, userID, false)
+}
+
+func (m *ProductModel) setArchived(id int64, userID int64, archive bool) error {
 	ctx := context.Background()
 	tx, err := m.DB.Begin(ctx)
 	if err != nil {
@@ -157,32 +601,16 @@ func (m *ProductModel) AdjustStock(productID int64, userID int64, quantityChange
 		}
 	}()
 
-	// Actualiza la cantidad del producto, validando que pertenezca al usuario
-	const upd = `UPDATE products SET quantity = quantity + $1 WHERE id = $2 AND user_id = $3`
-	tag, err := tx.Exec(ctx, upd, quantityChange, productID, userID)
-	if err != nil {
+	if err := setActor(ctx, tx, userID); err != nil {
 		return err
 	}
-	if tag.RowsAffected() == 0 {
-		return ErrNotFound
-	}
-
-	// Reset notificado flag if stock is now above minimum
-	const resetNotified = `UPDATE products SET notificado = false WHERE id = $1 AND quantity > stock_minimo`
-	resetResult, err := tx.Exec(ctx, resetNotified, productID)
-	if err != nil {
+	if err := setArchived(ctx, tx, archivableProducts, id, userID, archive); err != nil {
 		return err
 	}
-	if resetResult.RowsAffected() > 0 {
-		slog.Info("AdjustStock: notificado flag reset to false", "productID", productID)
-	}
-
-	// Inserta el movimiento de stock; reference_id es NULL para ajuste manual
-	const insertMovement = `
-		INSERT INTO stock_movements (product_id, quantity_change, reason, reference_id, user_id)
-		VALUES ($1, $2, $3, $4, $5)`
-	var refID any = nil
-	if _, err := tx.Exec(ctx, insertMovement, productID, quantityChange, reason, refID, userID); err != nil {
+	const qVariants = `
+		UPDATE products SET archived_at = CASE WHEN $3 THEN COALESCE(archived_at, NOW()) END
+		WHERE parent_id = $1 AND user_id = $2`
+	if _, err := tx.Exec(ctx, qVariants, id, userID, archive); err != nil {
 		return err
 	}
 
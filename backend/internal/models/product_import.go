@@ -0,0 +1,399 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Estados de una importación en segundo plano
+const (
+	ImportPending   = "pending"
+	ImportRunning   = "running"
+	ImportCompleted = "completed"
+	ImportFailed    = "failed"
+)
+
+// ImportColumns indica qué columnas opcionales trae el archivo. Una columna ausente conserva el
+// valor actual de los productos existentes; una presente y vacía lo borra.
+type ImportColumns struct {
+	Description bool `json:"description"`
+	Quantity    bool `json:"quantity"`
+	Parent      bool `json:"parent"`
+	Category    bool `json:"category"`
+	Tags        bool `json:"tags"`
+}
+
+// ImportRow es una fila del archivo de importación. Line es el número de fila en el archivo
+// (la 1 es la cabecera). Category llega como texto ("Bebidas / Gaseosas") y Validate lo resuelve a CategoryID.
+type ImportRow struct {
+	Line        int      `json:"line"`
+	SKU         string   `json:"sku"`
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Quantity    float64  `json:"quantity,omitempty"`
+	ParentSKU   string   `json:"parent_sku,omitempty"`
+	CategoryID  *int64   `json:"category_id,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+
+	Category string `json:"-"`
+
+	// QuantityError es el error de formato de la columna Cantidad, detectado al leer el archivo.
+	QuantityError string `json:"-"`
+}
+
+// ImportIssue es un error (o advertencia) de una fila del archivo.
+type ImportIssue struct {
+	Line    int    `json:"line"`
+	SKU     string `json:"sku,omitempty"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// ImportReport es el resultado de validar un archivo: qué productos se crearían y cuáles se
+// actualizarían, y los problemas de cada fila. Sólo se importa si no hay errores.
+type ImportReport struct {
+	TotalRows int           `json:"total_rows"`
+	ToCreate  int           `json:"to_create"`
+	ToUpdate  int           `json:"to_update"`
+	Errors    []ImportIssue `json:"errors"`
+	Warnings  []ImportIssue `json:"warnings"`
+
+	// Rows son las filas validadas, los productos padre antes que sus variantes.
+	Rows []ImportRow `json:"-"`
+}
+
+// Valid indica si el archivo puede importarse.
+func (r *ImportReport) Valid() bool {
+	return len(r.Errors) == 0
+}
+
+// ImportPayload es lo que se guarda en product_imports.payload para que lo procese el worker.
+type ImportPayload struct {
+	Columns ImportColumns `json:"columns"`
+	Rows    []ImportRow   `json:"rows"`
+}
+
+// ProductImport es una importación procesada en segundo plano por el worker.
+type ProductImport struct {
+	ID            int64      `json:"id"`
+	FileName      string     `json:"file_name"`
+	Status        string     `json:"status"`
+	TotalRows     int        `json:"total_rows"`
+	ProcessedRows int        `json:"processed_rows"`
+	CreatedRows   int        `json:"created_rows"`
+	UpdatedRows   int        `json:"updated_rows"`
+	Progress      float64    `json:"progress"` // porcentaje procesado (0-100)
+	Error         *string    `json:"error,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	StartedAt     *time.Time `json:"started_at,omitempty"`
+	FinishedAt    *time.Time `json:"finished_at,omitempty"`
+}
+
+// ProductImportModel wraps DB access for bulk product imports.
+type ProductImportModel struct {
+	DB *pgxpool.Pool
+}
+
+// importTarget es lo que la validación necesita saber de un producto existente.
+type importTarget struct {
+	parentSKU   string
+	hasVariants bool
+	quantity    float64
+	isKit       bool
+	archived    bool
+}
+
+// existingProducts devuelve los productos del usuario por SKU.
+func (m *ProductImportModel) existingProducts(ctx context.Context, userID int64) (map[string]importTarget, error) {
+	const q = `
+		SELECT p.sku, COALESCE(pp.sku, ''), EXISTS (SELECT 1 FROM products v WHERE v.parent_id = p.id), p.quantity, p.is_kit,
+			p.archived_at IS NOT NULL
+		FROM products p
+		LEFT JOIN products pp ON pp.id = p.parent_id
+		WHERE p.user_id = $1`
+
+	rows, err := m.DB.Query(ctx, q, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[string]importTarget{}
+	for rows.Next() {
+		var sku string
+		var t importTarget
+		if err := rows.Scan(&sku, &t.parentSKU, &t.hasVariants, &t.quantity, &t.isKit, &t.archived); err != nil {
+			return nil, err
+		}
+		out[sku] = t
+	}
+	return out, rows.Err()
+}
+
+// Validate revisa todas las filas contra los productos y categorías del usuario sin modificar nada.
+// El SKU identifica al producto: si existe se actualiza, si no se crea. Un SKU de un producto archivado
+// es un error: hay que restaurarlo antes. Las filas válidas quedan en report.Rows con la categoría resuelta.
+func (m *ProductImportModel) Validate(userID int64, cols ImportColumns, rows []ImportRow) (*ImportReport, error) {
+	ctx := context.Background()
+
+	existing, err := m.existingProducts(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	categories, err := (&CategoryModel{DB: m.DB}).GetAllForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	categoryByPath := map[string]int64{}
+	for id, path := range CategoryPaths(categories) {
+		categoryByPath[strings.ToLower(path)] = id
+	}
+
+	report := &ImportReport{TotalRows: len(rows), Errors: []ImportIssue{}, Warnings: []ImportIssue{}}
+	fail := func(row ImportRow, column, format string, args ...any) {
+		report.Errors = append(report.Errors, ImportIssue{Line: row.Line, SKU: row.SKU, Column: column, Message: fmt.Sprintf(format, args...)})
+	}
+
+	// Padre de cada SKU del archivo (o el actual si el archivo no trae la columna)
+	inFile := make(map[string]int, len(rows))
+	parentOf := map[string]string{}
+	for _, row := range rows {
+		if _, dup := inFile[row.SKU]; dup || row.SKU == "" {
+			continue
+		}
+		inFile[row.SKU] = row.Line
+		if cols.Parent {
+			parentOf[row.SKU] = row.ParentSKU
+		} else {
+			parentOf[row.SKU] = existing[row.SKU].parentSKU
+		}
+	}
+	hasVariantsInFile := map[string]bool{}
+	for _, parent := range parentOf {
+		if parent != "" {
+			hasVariantsInFile[parent] = true
+		}
+	}
+
+	for i := range rows {
+		row := &rows[i]
+		errorsBefore := len(report.Errors)
+
+		if row.SKU == "" {
+			fail(*row, "SKU", "el SKU es obligatorio")
+		} else if line := inFile[row.SKU]; line != row.Line {
+			fail(*row, "SKU", "SKU repetido (ya aparece en la fila %d)", line)
+		}
+		if row.Name == "" {
+			fail(*row, "Nombre", "el nombre es obligatorio")
+		}
+
+		current, exists := existing[row.SKU]
+		if exists && current.archived {
+			fail(*row, "SKU", "el producto está archivado; restauralo antes de importarlo")
+		}
+		if cols.Quantity {
+			switch {
+			case row.QuantityError != "":
+				fail(*row, "Cantidad", "%s", row.QuantityError)
+			case row.Quantity < 0:
+				fail(*row, "Cantidad", "la cantidad no puede ser negativa")
+			case exists && !current.isKit && roundQuantity(row.Quantity) != roundQuantity(current.quantity):
+				report.Warnings = append(report.Warnings, ImportIssue{Line: row.Line, SKU: row.SKU, Column: "Cantidad",
+					Message: "la cantidad de un producto existente no se importa; usá un ajuste de stock o un conteo"})
+			}
+		}
+
+		if cols.Parent && row.ParentSKU != "" {
+			parentExists := false
+			if _, ok := inFile[row.ParentSKU]; ok {
+				parentExists = true
+			} else if _, ok := existing[row.ParentSKU]; ok {
+				parentExists = true
+				parentOf[row.ParentSKU] = existing[row.ParentSKU].parentSKU
+			}
+			switch {
+			case row.ParentSKU == row.SKU:
+				fail(*row, "Producto Padre", "un producto no puede ser variante de sí mismo")
+			case !parentExists:
+				fail(*row, "Producto Padre", "el producto padre %q no existe", row.ParentSKU)
+			case existing[row.ParentSKU].archived:
+				fail(*row, "Producto Padre", "el producto padre %q está archivado", row.ParentSKU)
+			case parentOf[row.ParentSKU] != "":
+				fail(*row, "Producto Padre", "el producto padre %q es una variante", row.ParentSKU)
+			case current.hasVariants || hasVariantsInFile[row.SKU]:
+				fail(*row, "Producto Padre", "un producto con variantes no puede ser variante de otro")
+			}
+		}
+
+		if cols.Category && row.Category != "" && row.Category != UncategorizedLabel {
+			id, ok := categoryByPath[strings.ToLower(row.Category)]
+			if !ok {
+				fail(*row, "Categoría", "la categoría %q no existe", row.Category)
+			} else {
+				row.CategoryID = &id
+			}
+		}
+		row.Tags = normalizeTags(row.Tags)
+
+		if len(report.Errors) > errorsBefore {
+			continue
+		}
+		if exists {
+			report.ToUpdate++
+		} else {
+			report.ToCreate++
+		}
+		report.Rows = append(report.Rows, *row)
+	}
+
+	// Los padres se importan antes que sus variantes
+	sort.SliceStable(report.Rows, func(i, j int) bool {
+		return report.Rows[i].ParentSKU == "" && report.Rows[j].ParentSKU != ""
+	})
+	return report, nil
+}
+
+// ImportProduct crea o actualiza (por SKU) el producto de una fila. Un producto nuevo entra con
+// su cantidad como saldo inicial; la cantidad de uno existente no se toca. Devuelve si lo creó, o
+// ErrProductArchived si el SKU es de un producto archivado. Lo usan Apply y las importaciones
+// grandes que procesa el worker.
+func ImportProduct(ctx context.Context, tx pgx.Tx, userID int64, cols ImportColumns, row ImportRow) (bool, error) {
+	var description *string
+	if row.Description != "" {
+		description = &row.Description
+	}
+
+	const q = `
+		INSERT INTO products (name, sku, description, user_id, parent_id, category_id)
+		VALUES ($1, $2, $3, $4, (SELECT id FROM products WHERE user_id = $4 AND sku = NULLIF($5, '')), $6)
+		ON CONFLICT (user_id, sku) DO UPDATE SET
+			name = EXCLUDED.name,
+			description = CASE WHEN $7 THEN EXCLUDED.description ELSE products.description END,
+			parent_id = CASE WHEN $8 THEN EXCLUDED.parent_id ELSE products.parent_id END,
+			category_id = CASE WHEN $9 THEN EXCLUDED.category_id ELSE products.category_id END
+		WHERE products.archived_at IS NULL
+		RETURNING id, xmax = 0`
+
+	var productID int64
+	var created bool
+	err := tx.QueryRow(ctx, q, row.Name, row.SKU, description, userID, row.ParentSKU, row.CategoryID,
+		cols.Description, cols.Parent, cols.Category).Scan(&productID, &created)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, ErrProductArchived
+		}
+		return false, err
+	}
+
+	if cols.Tags {
+		if _, err := setProductTags(ctx, tx, productID, userID, row.Tags); err != nil {
+			return false, err
+		}
+	}
+
+	if created && row.Quantity > 0 {
+		warehouseID, err := resolveWarehouse(ctx, tx, userID, 0)
+		if err != nil {
+			return false, err
+		}
+		if _, err := applyStockChange(ctx, tx, stockChange{
+			ProductID:      productID,
+			WarehouseID:    warehouseID,
+			UserID:         userID,
+			QuantityChange: row.Quantity,
+			Reason:         ReasonOpeningBalance,
+		}); err != nil {
+			return false, err
+		}
+	}
+	return created, nil
+}
+
+// Apply importa filas ya validadas en una sola transacción: si una falla no se importa ninguna.
+// Devuelve cuántos productos creó y cuántos actualizó.
+func (m *ProductImportModel) Apply(userID int64, cols ImportColumns, rows []ImportRow) (created int, updated int, err error) {
+	ctx := context.Background()
+	err = withActor(ctx, m.DB, userID, func(tx pgx.Tx) error {
+		for _, row := range rows {
+			isNew, err := ImportProduct(ctx, tx, userID, cols, row)
+			if err != nil {
+				return fmt.Errorf("fila %d: %w", row.Line, err)
+			}
+			if isNew {
+				created++
+			} else {
+				updated++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return created, updated, nil
+}
+
+// CreateJob registra una importación para que la procese el worker.
+func (m *ProductImportModel) CreateJob(userID int64, fileName string, cols ImportColumns, rows []ImportRow) (*ProductImport, error) {
+	payload, err := json.Marshal(ImportPayload{Columns: cols, Rows: rows})
+	if err != nil {
+		return nil, err
+	}
+
+	const q = `
+		INSERT INTO product_imports (user_id, file_name, payload, total_rows)
+		VALUES ($1, $2, $3, $4)
+		RETURNING ` + productImportColumns
+
+	var imp ProductImport
+	if err := scanProductImport(m.DB.QueryRow(context.Background(), q, userID, fileName, payload, len(rows)), &imp); err != nil {
+		return nil, err
+	}
+	return &imp, nil
+}
+
+const productImportColumns = `id, file_name, status, total_rows, processed_rows, created_rows, updated_rows, error, created_at, started_at, finished_at`
+
+func scanProductImport(row pgx.Row, imp *ProductImport) error {
+	if err := row.Scan(&imp.ID, &imp.FileName, &imp.Status, &imp.TotalRows, &imp.ProcessedRows, &imp.CreatedRows,
+		&imp.UpdatedRows, &imp.Error, &imp.CreatedAt, &imp.StartedAt, &imp.FinishedAt); err != nil {
+		return err
+	}
+	imp.Progress = 100
+	if imp.TotalRows > 0 {
+		imp.Progress = float64(imp.ProcessedRows*1000/imp.TotalRows) / 10
+	}
+	return nil
+}
+
+// GetJob devuelve una importación del usuario con su progreso.
+func (m *ProductImportModel) GetJob(id int64, userID int64) (*ProductImport, error) {
+	const q = `SELECT ` + productImportColumns + ` FROM product_imports WHERE id = $1 AND user_id = $2`
+
+	var imp ProductImport
+	if err := scanProductImport(m.DB.QueryRow(context.Background(), q, id, userID), &imp); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &imp, nil
+}
+
+// MarkJobFailed marca como fallida una importación que no pudo encolarse.
+func (m *ProductImportModel) MarkJobFailed(id int64, reason string) error {
+	const q = `
+		UPDATE product_imports SET status = 'failed', error = $2, payload = NULL, finished_at = NOW()
+		WHERE id = $1`
+	_, err := m.DB.Exec(context.Background(), q, id, reason)
+	return err
+}
@@ -0,0 +1,21 @@
+package models
+
+import "testing"
+
+func TestCheckBaseUnitChange(t *testing.T) {
+	cases := []struct {
+		current, requested string
+		inUse              bool
+		want               error
+	}{
+		{"unit", "", true, nil}, // sin unidad en el pedido se conserva la actual
+		{"unit", "unit", true, nil},
+		{"unit", "kg", false, nil}, // producto sin stock, conversiones, lotes ni movimientos
+		{"unit", "kg", true, ErrBaseUnitNotEditable},
+	}
+	for _, c := range cases {
+		if got := checkBaseUnitChange(c.current, c.requested, c.inUse); got != c.want {
+			t.Errorf("%q → %q (in use %t): expected %v, got %v", c.current, c.requested, c.inUse, c.want, got)
+		}
+	}
+}
@@ -19,6 +19,21 @@ type PurchaseOrderFilters struct {
 	Status   string
 }
 
+// Estados de una orden de compra. Los borradores los arma el motor de reposición y quedan a la
+// espera de que un repositor los envíe (pending) o los cancele.
+const (
+	PurchaseOrderDraft     = "draft"
+	PurchaseOrderPending   = "pending"
+	PurchaseOrderCompleted = "completed"
+	PurchaseOrderCancelled = "cancelled"
+)
+
+// ErrInvalidPurchaseOrderStatus is returned when a purchase order status transition is not allowed.
+var ErrInvalidPurchaseOrderStatus = errors.New("invalid purchase order status transition")
+
+// ErrReceiptItemNotFound is returned when a receipt references an item that is not part of the order.
+var ErrReceiptItemNotFound = errors.New("receipt item not found in purchase order")
+
 // PurchaseOrder represents the header of a purchase order.
 // Mirrors the sales order but linked to a supplier.
 type PurchaseOrder struct {
@@ -28,6 +43,13 @@ type PurchaseOrder struct {
 	OrderDate    *time.Time    `json:"order_date,omitempty"` // Pointer to allow NULL/omitted value
 	Status       string        `json:"status"`
 	UserID       int64         `json:"user_id"`
+
+	// WarehouseID es el depósito donde se recibe la mercadería.
+	WarehouseID   int64  `json:"warehouse_id"`
+	WarehouseName string `json:"warehouse_name,omitempty"`
+
+	// AutoGenerated marca las órdenes armadas por el motor de reposición (nacen en borrador).
+	AutoGenerated bool `json:"auto_generated"`
 }
 
 // PurchaseOrderItem represents a product item belonging to a purchase order.
@@ -36,8 +58,27 @@ type PurchaseOrderItem struct {
 	ID              int64   `json:"id"`
 	PurchaseOrderID int64   `json:"purchase_order_id"`
 	ProductID       int64   `json:"product_id"`
-	Quantity        int     `json:"quantity"`
+	Quantity        float64 `json:"quantity"` // en unidad base
 	UnitCost        float64 `json:"unit_cost"`
+
+	// Unidad en la que se cargó la línea (ej. caja x12): UnitQuantity * UnitFactor = Quantity.
+	// UnitCost es el costo por esa unidad.
+	Unit         string  `json:"unit"`
+	UnitQuantity float64 `json:"unit_quantity"`
+	UnitFactor   float64 `json:"unit_factor"`
+
+	// Lote, vencimiento y números de serie de la mercadería; pueden informarse al crear la orden o al recibirla.
+	LotNumber     string     `json:"lot_number,omitempty"`
+	ExpiryDate    *time.Time `json:"expiry_date,omitempty"`
+	SerialNumbers []string   `json:"serial_numbers,omitempty"`
+}
+
+// PurchaseOrderItemReceipt informa lote y números de serie recibidos para un ítem al completar la orden.
+type PurchaseOrderItemReceipt struct {
+	ItemID        int64
+	LotNumber     string
+	ExpiryDate    *time.Time
+	SerialNumbers []string
 }
 
 // PurchaseOrderModel wraps DB access for purchase orders.
@@ -58,27 +99,58 @@ func (m *PurchaseOrderModel) Create(order *PurchaseOrder, items []PurchaseOrderI
 		}
 	}()
 
+	warehouseID, err := resolveWarehouse(ctx, tx, order.UserID, order.WarehouseID)
+	if err != nil {
+		return err
+	}
+	order.WarehouseID = warehouseID
+
+	// Los proveedores y productos archivados no pueden usarse en órdenes nuevas
+	if order.SupplierID.Valid {
+		if err := checkActiveSupplier(ctx, tx, order.SupplierID.Int64, order.UserID); err != nil {
+			return err
+		}
+	}
+	for i := range items {
+		if err := checkActiveProduct(ctx, tx, items[i].ProductID, order.UserID); err != nil {
+			return err
+		}
+	}
+
 	const insertOrder = `
-		INSERT INTO purchase_orders (supplier_id, order_date, status, user_id)
-		VALUES ($1, NOW(), COALESCE($2, 'pending'), $3)
+		INSERT INTO purchase_orders (supplier_id, order_date, status, user_id, warehouse_id)
+		VALUES ($1, NOW(), COALESCE($2, 'pending'), $3, $4)
 		RETURNING id, order_date`
 
 	var orderDate time.Time
 	if err := tx.QueryRow(ctx, insertOrder,
-		order.SupplierID, order.Status, order.UserID,
+		order.SupplierID, order.Status, order.UserID, order.WarehouseID,
 	).Scan(&order.ID, &orderDate); err != nil {
 		return err
 	}
 	order.OrderDate = &orderDate
 
 	const insertItem = `
-		INSERT INTO purchase_order_items (purchase_order_id, product_id, quantity, unit_cost)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO purchase_order_items (purchase_order_id, product_id, quantity, unit_cost, lot_number, expiry_date, serial_numbers,
+			unit, unit_quantity, unit_factor)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, COALESCE($7, '{}'::text[]), $8, $9, $10)
 		RETURNING id`
 
 	for i := range items {
 		items[i].PurchaseOrderID = order.ID
-		if err := tx.QueryRow(ctx, insertItem, items[i].PurchaseOrderID, items[i].ProductID, items[i].Quantity, items[i].UnitCost).Scan(&items[i].ID); err != nil {
+		// El stock se recibe en unidad base: 2 cajas x12 = 24 unidades
+		unit, factor, err := resolveUnit(ctx, tx, items[i].ProductID, items[i].Unit)
+		if err != nil {
+			return err
+		}
+		if items[i].UnitQuantity == 0 {
+			items[i].UnitQuantity = items[i].Quantity
+		}
+		items[i].Unit, items[i].UnitFactor = unit, factor
+		items[i].Quantity = roundQuantity(items[i].UnitQuantity * factor)
+
+		if err := tx.QueryRow(ctx, insertItem, items[i].PurchaseOrderID, items[i].ProductID, items[i].Quantity, items[i].UnitCost, items[i].LotNumber, items[i].ExpiryDate, items[i].SerialNumbers,
+			items[i].Unit, items[i].UnitQuantity, items[i].UnitFactor).Scan(&items[i].ID); err != nil {
 			return err
 		}
 	}
@@ -95,9 +167,10 @@ func (m *PurchaseOrderModel) GetAllForUser(userID int64) ([]PurchaseOrder, error
 	const q = `
 		SELECT 
 			po.id, po.supplier_id, po.order_date, po.status, po.user_id,
-			s.name AS supplier_name
+			s.name AS supplier_name, COALESCE(po.warehouse_id, 0), COALESCE(w.name, ''), po.auto_generated
 		FROM purchase_orders po
 		LEFT JOIN suppliers s ON po.supplier_id = s.id
+		LEFT JOIN warehouses w ON po.warehouse_id = w.id
 		WHERE po.user_id = $1
 		ORDER BY po.id DESC`
 
@@ -112,7 +185,7 @@ func (m *PurchaseOrderModel) GetAllForUser(userID int64) ([]PurchaseOrder, error
 		var o PurchaseOrder
 		var supplierName sql.NullString
 		var orderDate time.Time
-		if err := rows.Scan(&o.ID, &o.SupplierID, &orderDate, &o.Status, &o.UserID, &supplierName); err != nil {
+		if err := rows.Scan(&o.ID, &o.SupplierID, &orderDate, &o.Status, &o.UserID, &supplierName, &o.WarehouseID, &o.WarehouseName, &o.AutoGenerated); err != nil {
 			return nil, err
 		}
 		o.OrderDate = &orderDate
@@ -132,9 +205,10 @@ func (m *PurchaseOrderModel) GetAllForUserWithFilters(userID int64, filters Purc
 	query := `
 		SELECT 
 			po.id, po.supplier_id, po.order_date, po.status, po.user_id,
-			s.name AS supplier_name
+			s.name AS supplier_name, COALESCE(po.warehouse_id, 0), COALESCE(w.name, ''), po.auto_generated
 		FROM purchase_orders po
 		LEFT JOIN suppliers s ON po.supplier_id = s.id
+		LEFT JOIN warehouses w ON po.warehouse_id = w.id
 		WHERE po.user_id = $1`
 
 	args := []interface{}{userID}
@@ -172,7 +246,7 @@ func (m *PurchaseOrderModel) GetAllForUserWithFilters(userID int64, filters Purc
 		var o PurchaseOrder
 		var supplierName sql.NullString
 		var orderDate time.Time
-		if err := rows.Scan(&o.ID, &o.SupplierID, &orderDate, &o.Status, &o.UserID, &supplierName); err != nil {
+		if err := rows.Scan(&o.ID, &o.SupplierID, &orderDate, &o.Status, &o.UserID, &supplierName, &o.WarehouseID, &o.WarehouseName, &o.AutoGenerated); err != nil {
 			return nil, err
 		}
 		o.OrderDate = &orderDate
@@ -192,16 +266,17 @@ func (m *PurchaseOrderModel) GetByID(orderID int64, userID int64) (*PurchaseOrde
 	const qOrder = `
 		SELECT 
 			po.id, po.supplier_id, po.order_date, po.status, po.user_id,
-			s.name AS supplier_name
+			s.name AS supplier_name, COALESCE(po.warehouse_id, 0), COALESCE(w.name, ''), po.auto_generated
 		FROM purchase_orders po
 		LEFT JOIN suppliers s ON po.supplier_id = s.id
+		LEFT JOIN warehouses w ON po.warehouse_id = w.id
 		WHERE po.id = $1 AND po.user_id = $2`
 
 	var o PurchaseOrder
 	var supplierName sql.NullString
 	var orderDate time.Time
 	err := m.DB.QueryRow(context.Background(), qOrder, orderID, userID).
-		Scan(&o.ID, &o.SupplierID, &orderDate, &o.Status, &o.UserID, &supplierName)
+		Scan(&o.ID, &o.SupplierID, &orderDate, &o.Status, &o.UserID, &supplierName, &o.WarehouseID, &o.WarehouseName, &o.AutoGenerated)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil, ErrNotFound
@@ -214,7 +289,8 @@ func (m *PurchaseOrderModel) GetByID(orderID int64, userID int64) (*PurchaseOrde
 	}
 
 	const qItems = `
-		SELECT id, purchase_order_id, product_id, quantity, unit_cost
+		SELECT id, purchase_order_id, product_id, quantity, unit_cost, COALESCE(lot_number, ''), expiry_date, serial_numbers,
+			unit, unit_quantity, unit_factor
 		FROM purchase_order_items
 		WHERE purchase_order_id = $1
 		ORDER BY id`
@@ -227,7 +303,8 @@ func (m *PurchaseOrderModel) GetByID(orderID int64, userID int64) (*PurchaseOrde
 	var items []PurchaseOrderItem
 	for rows.Next() {
 		var it PurchaseOrderItem
-		if err := rows.Scan(&it.ID, &it.PurchaseOrderID, &it.ProductID, &it.Quantity, &it.UnitCost); err != nil {
+		if err := rows.Scan(&it.ID, &it.PurchaseOrderID, &it.ProductID, &it.Quantity, &it.UnitCost, &it.LotNumber, &it.ExpiryDate, &it.SerialNumbers,
+			&it.Unit, &it.UnitQuantity, &it.UnitFactor); err != nil {
 			return nil, nil, err
 		}
 		items = append(items, it)
@@ -238,8 +315,11 @@ func (m *PurchaseOrderModel) GetByID(orderID int64, userID int64) (*PurchaseOrde
 	return &o, items, nil
 }
 
-// UpdateStatus updates the status of a purchase order. If setting to 'completed', increases product stock for all items.
-func (m *PurchaseOrderModel) UpdateStatus(orderID int64, userID int64, newStatus string) error {
+// UpdateStatus updates the status of a purchase order. If setting to 'completed', increases product stock
+// for all items in the order's warehouse. receipts informa lote, vencimiento y series recibidos por ítem:
+// los ítems con lote generan (o suman a) un lote del producto en el depósito y los productos serializados
+// deben traer un número de serie por unidad.
+func (m *PurchaseOrderModel) UpdateStatus(orderID int64, userID int64, newStatus string, receipts []PurchaseOrderItemReceipt) error {
 	ctx := context.Background()
 	tx, err := m.DB.Begin(ctx)
 	if err != nil {
@@ -252,22 +332,49 @@ func (m *PurchaseOrderModel) UpdateStatus(orderID int64, userID int64, newStatus
 	}()
 
 	// Lock the order row and verify ownership
-	const qOrder = `SELECT status FROM purchase_orders WHERE id = $1 AND user_id = $2 FOR UPDATE`
+	const qOrder = `SELECT status, COALESCE(warehouse_id, 0) FROM purchase_orders WHERE id = $1 AND user_id = $2 FOR UPDATE`
 	var current string
-	if err := tx.QueryRow(ctx, qOrder, orderID, userID).Scan(&current); err != nil {
+	var warehouseID int64
+	if err := tx.QueryRow(ctx, qOrder, orderID, userID).Scan(&current, &warehouseID); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return ErrNotFound
 		}
 		return err
 	}
 
+	// Un borrador sólo se envía o se cancela, y ninguna orden vuelve a borrador
+	if (current == PurchaseOrderDraft && newStatus != PurchaseOrderPending && newStatus != PurchaseOrderCancelled) ||
+		(newStatus == PurchaseOrderDraft && current != PurchaseOrderDraft) {
+		return ErrInvalidPurchaseOrderStatus
+	}
+
 	// If transitioning to completed and not already completed, increase stock
 	if newStatus == "completed" && current != "completed" {
 		slog.Info("UpdateStatus: transitioning to completed", "orderID", orderID, "userID", userID)
+
+		// Guardar lotes y series informados en la recepción
+		const updReceipt = `
+			UPDATE purchase_order_items
+			SET lot_number = COALESCE(NULLIF($1, ''), lot_number),
+				expiry_date = COALESCE($2, expiry_date),
+				serial_numbers = CASE WHEN cardinality($3::text[]) > 0 THEN $3::text[] ELSE serial_numbers END
+			WHERE id = $4 AND purchase_order_id = $5`
+		for _, rc := range receipts {
+			tag, err := tx.Exec(ctx, updReceipt, rc.LotNumber, rc.ExpiryDate, rc.SerialNumbers, rc.ItemID, orderID)
+			if err != nil {
+				return err
+			}
+			if tag.RowsAffected() == 0 {
+				return ErrReceiptItemNotFound
+			}
+		}
+
 		const qItems = `
-			SELECT product_id, quantity
-			FROM purchase_order_items
-			WHERE purchase_order_id = $1`
+			SELECT poi.product_id, poi.quantity, COALESCE(poi.lot_number, ''), poi.expiry_date, poi.serial_numbers, p.is_serialized,
+				poi.unit_cost / NULLIF(poi.unit_factor, 0)
+			FROM purchase_order_items poi
+			JOIN products p ON p.id = poi.product_id
+			WHERE poi.purchase_order_id = $1`
 		rows, err := tx.Query(ctx, qItems, orderID)
 		if err != nil {
 			slog.Error("UpdateStatus: failed to query items", "error", err)
@@ -276,19 +383,23 @@ func (m *PurchaseOrderModel) UpdateStatus(orderID int64, userID int64, newStatus
 
 		// Read all items into a slice first (can't use tx while iterating rows)
 		type item struct {
-			productID int64
-			qty       int
+			productID    int64
+			qty          float64
+			lotNumber    string
+			expiryDate   *time.Time
+			serials      []string
+			isSerialized bool
+			unitCost     *float64 // costo por unidad base
 		}
 		var items []item
 		for rows.Next() {
-			var productID int64
-			var qty int
-			if err := rows.Scan(&productID, &qty); err != nil {
+			var it item
+			if err := rows.Scan(&it.productID, &it.qty, &it.lotNumber, &it.expiryDate, &it.serials, &it.isSerialized, &it.unitCost); err != nil {
 				rows.Close()
 				slog.Error("UpdateStatus: failed to scan item", "error", err)
 				return err
 			}
-			items = append(items, item{productID: productID, qty: qty})
+			items = append(items, it)
 		}
 		rows.Close()
 
@@ -297,45 +408,48 @@ func (m *PurchaseOrderModel) UpdateStatus(orderID int64, userID int64, newStatus
 			return rows.Err()
 		}
 
+		// Órdenes anteriores a los depósitos se reciben en el depósito por defecto
+		warehouseID, err = resolveWarehouse(ctx, tx, userID, warehouseID)
+		if err != nil {
+			return err
+		}
+
 		// Now update products (tx is free now)
-		const incStock = `UPDATE products SET quantity = quantity + $1 WHERE id = $2 AND user_id = $3`
-		const resetNotified = `UPDATE products SET notificado = false WHERE id = $1 AND quantity > stock_minimo`
+		reference := fmt.Sprintf("%d", orderID)
 		for _, it := range items {
-			slog.Info("UpdateStatus: attempting to update product stock", "productID", it.productID, "qty", it.qty, "userID", userID)
-			result, err := tx.Exec(ctx, incStock, it.qty, it.productID, userID)
-			if err != nil {
-				slog.Error("UpdateStatus: failed to update product stock", "productID", it.productID, "error", err)
-				return err
+			slog.Info("UpdateStatus: attempting to update product stock", "productID", it.productID, "qty", it.qty, "userID", userID, "warehouseID", warehouseID)
+			change := stockChange{
+				ProductID:       it.productID,
+				WarehouseID:     warehouseID,
+				UserID:          userID,
+				QuantityChange:  it.qty,
+				Reason:          "PURCHASE_ORDER",
+				ReferenceID:     &reference,
+				UnitCost:        it.unitCost,
+				PurchaseOrderID: &orderID,
 			}
-			// Verify that the product was actually updated (exists and belongs to user)
-			if result.RowsAffected() == 0 {
-				slog.Error("UpdateStatus: product not updated", "productID", it.productID, "userID", userID)
-				return fmt.Errorf("product %d not found or does not belong to user %d", it.productID, userID)
+			if it.lotNumber != "" {
+				change.Lots = []LotAllocation{{LotNumber: it.lotNumber, ExpiryDate: it.expiryDate, Quantity: it.qty, PurchaseOrderID: &orderID}}
 			}
-			slog.Info("UpdateStatus: product stock updated successfully", "productID", it.productID, "rowsAffected", result.RowsAffected())
-
-			// Reset notificado flag if stock is now above minimum
-			resetResult, err := tx.Exec(ctx, resetNotified, it.productID)
+			// Insert stock movement (positive for purchase)
+			_, err := applyStockChange(ctx, tx, change)
 			if err != nil {
-				slog.Error("UpdateStatus: failed to reset notificado flag", "productID", it.productID, "error", err)
+				slog.Error("UpdateStatus: failed to update product stock", "productID", it.productID, "error", err)
+				if errors.Is(err, ErrNotFound) {
+					return fmt.Errorf("product %d not found or does not belong to user %d", it.productID, userID)
+				}
 				return err
 			}
-			if resetResult.RowsAffected() > 0 {
-				slog.Info("UpdateStatus: notificado flag reset to false", "productID", it.productID)
-			}
-
-			// Insert stock movement (positive for purchase)
-			const insertMovement = `
-				INSERT INTO stock_movements (product_id, quantity_change, reason, reference_id, user_id)
-				VALUES ($1, $2, $3, $4, $5)`
-			if _, err := tx.Exec(ctx, insertMovement,
-				it.productID,
-				it.qty,
-				"PURCHASE_ORDER",
-				fmt.Sprintf("%d", orderID),
-				userID,
-			); err != nil {
-				return err
+			slog.Info("UpdateStatus: product stock updated successfully", "productID", it.productID)
+
+			// Registrar las series de los productos serializados
+			if it.isSerialized {
+				if err := uniqueSerials(it.serials, it.qty); err != nil {
+					return err
+				}
+				if err := receiveSerials(ctx, tx, it.productID, &orderID, it.serials); err != nil {
+					return err
+				}
 			}
 		}
 	}
@@ -0,0 +1,134 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ReorderRule define cuándo y cuánto reponer un producto. Cuando el stock disponible más lo pedido
+// en órdenes de compra abiertas (borrador o pendientes) queda en ReorderPoint o menos, el motor de
+// reposición pide hasta llegar a TargetLevel, y al menos MinOrderQuantity, al proveedor preferido.
+// Las cantidades están en la unidad base del producto.
+type ReorderRule struct {
+	ProductID           int64     `json:"product_id"`
+	ProductName         string    `json:"product_name,omitempty"`
+	SKU                 string    `json:"sku,omitempty"`
+	ReorderPoint        float64   `json:"reorder_point"`
+	TargetLevel         float64   `json:"target_level"`
+	PreferredSupplierID *int64    `json:"preferred_supplier_id,omitempty"`
+	SupplierName        *string   `json:"supplier_name,omitempty"`
+	MinOrderQuantity    float64   `json:"min_order_quantity"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+// ErrInvalidReorderRule is returned when a reorder rule has inconsistent levels or targets a kit.
+var ErrInvalidReorderRule = errors.New("invalid reorder rule")
+
+// ReorderRuleModel wraps DB access for reorder rules.
+type ReorderRuleModel struct {
+	DB *pgxpool.Pool
+}
+
+const reorderRuleSelect = `
+	SELECT r.product_id, p.name, p.sku, r.reorder_point, r.target_level, r.preferred_supplier_id, s.name,
+		r.min_order_quantity, r.updated_at
+	FROM reorder_rules r
+	JOIN products p ON p.id = r.product_id
+	LEFT JOIN suppliers s ON s.id = r.preferred_supplier_id`
+
+func scanReorderRule(row pgx.Row, r *ReorderRule) error {
+	return row.Scan(&r.ProductID, &r.ProductName, &r.SKU, &r.ReorderPoint, &r.TargetLevel, &r.PreferredSupplierID,
+		&r.SupplierName, &r.MinOrderQuantity, &r.UpdatedAt)
+}
+
+// GetAllForUser devuelve las reglas de reposición de los productos del usuario.
+func (m *ReorderRuleModel) GetAllForUser(userID int64) ([]ReorderRule, error) {
+	rows, err := m.DB.Query(context.Background(), reorderRuleSelect+` WHERE r.user_id = $1 ORDER BY p.name`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []ReorderRule{}
+	for rows.Next() {
+		var r ReorderRule
+		if err := scanReorderRule(rows, &r); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return out, nil
+}
+
+// GetForProduct devuelve la regla de reposición de un producto del usuario.
+func (m *ReorderRuleModel) GetForProduct(productID int64, userID int64) (*ReorderRule, error) {
+	var r ReorderRule
+	err := scanReorderRule(m.DB.QueryRow(context.Background(), reorderRuleSelect+` WHERE r.product_id = $1 AND r.user_id = $2`, productID, userID), &r)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Set crea o reemplaza la regla de reposición de un producto. Los kits no se reponen (se compran
+// sus componentes) y el proveedor preferido debe ser del usuario y estar activo.
+func (m *ReorderRuleModel) Set(userID int64, r *ReorderRule) error {
+	r.ReorderPoint = roundQuantity(r.ReorderPoint)
+	r.TargetLevel = roundQuantity(r.TargetLevel)
+	r.MinOrderQuantity = roundQuantity(r.MinOrderQuantity)
+	if r.ReorderPoint < 0 || r.MinOrderQuantity < 0 || r.TargetLevel <= r.ReorderPoint {
+		return ErrInvalidReorderRule
+	}
+
+	ctx := context.Background()
+	var isKit bool
+	err := m.DB.QueryRow(ctx, `SELECT is_kit FROM products WHERE id = $1 AND user_id = $2`, r.ProductID, userID).Scan(&isKit)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+	if isKit {
+		return ErrInvalidReorderRule
+	}
+	if r.PreferredSupplierID != nil {
+		if err := checkActiveSupplier(ctx, m.DB, *r.PreferredSupplierID, userID); err != nil {
+			return err
+		}
+	}
+
+	const q = `
+		INSERT INTO reorder_rules (product_id, user_id, reorder_point, target_level, preferred_supplier_id, min_order_quantity)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (product_id) DO UPDATE SET
+			reorder_point = EXCLUDED.reorder_point,
+			target_level = EXCLUDED.target_level,
+			preferred_supplier_id = EXCLUDED.preferred_supplier_id,
+			min_order_quantity = EXCLUDED.min_order_quantity,
+			updated_at = NOW()`
+	_, err = m.DB.Exec(ctx, q, r.ProductID, userID, r.ReorderPoint, r.TargetLevel, r.PreferredSupplierID, r.MinOrderQuantity)
+	return err
+}
+
+// Delete quita la regla de reposición de un producto del usuario.
+func (m *ReorderRuleModel) Delete(productID int64, userID int64) error {
+	tag, err := m.DB.Exec(context.Background(), `DELETE FROM reorder_rules WHERE product_id = $1 AND user_id = $2`, productID, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
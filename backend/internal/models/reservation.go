@@ -0,0 +1,459 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Estados de una orden de venta. Las pendientes, confirmadas y en preparación reservan stock (sólo
+// las pendientes con vencimiento); al despacharse la reserva se descuenta.
+const (
+	SalesOrderPending   = "pending"
+	SalesOrderConfirmed = "confirmed"
+	SalesOrderPicking   = "picking"
+	SalesOrderShipped   = "shipped"
+	SalesOrderDelivered = "delivered"
+	SalesOrderCancelled = "cancelled"
+)
+
+// ReasonSalesOrderCancel es el motivo de los movimientos que devuelven al stock lo descontado por
+// una orden despachada que se cancela.
+const ReasonSalesOrderCancel = "SALES_ORDER_CANCEL"
+
+// salesOrderTransitions son los estados a los que puede pasar una orden desde cada estado.
+// Las órdenes entregadas y canceladas no cambian más.
+var salesOrderTransitions = map[string][]string{
+	SalesOrderPending:   {SalesOrderConfirmed, SalesOrderCancelled},
+	SalesOrderConfirmed: {SalesOrderPicking, SalesOrderCancelled},
+	SalesOrderPicking:   {SalesOrderShipped, SalesOrderCancelled},
+	SalesOrderShipped:   {SalesOrderDelivered, SalesOrderCancelled},
+}
+
+// CanTransitionSalesOrder indica si una orden de venta puede pasar del estado from al estado to.
+func CanTransitionSalesOrder(from, to string) bool {
+	return slices.Contains(salesOrderTransitions[from], to)
+}
+
+// DefaultReservationTTL es cuánto tiempo reserva stock una orden pendiente si no se indica otro vencimiento.
+const DefaultReservationTTL = 72 * time.Hour
+
+// ErrInvalidOrderStatus is returned when a sales order status transition is not allowed.
+var ErrInvalidOrderStatus = errors.New("invalid sales order status transition")
+
+// stockReservation es la reserva de stock de una línea de venta pendiente en un depósito.
+type stockReservation struct {
+	ID           int64
+	SalesOrderID int64
+	OrderItemID  int64
+	ProductID    int64
+	WarehouseID  int64
+	UserID       int64
+	Quantity     float64
+}
+
+// reserveStock reserva cantidad disponible (stock - reservado) de un producto en un depósito.
+// Devuelve ErrInsufficientStock si no alcanza el disponible.
+func reserveStock(ctx context.Context, tx pgx.Tx, r stockReservation) error {
+	r.Quantity = roundQuantity(r.Quantity)
+
+	var isKit bool
+	err := tx.QueryRow(ctx, `SELECT is_kit FROM products WHERE id = $1 AND user_id = $2`, r.ProductID, r.UserID).Scan(&isKit)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+	if isKit {
+		return ErrKitNotStocked
+	}
+
+	const upd = `
+		UPDATE product_stocks SET reserved = reserved + $1
+		WHERE product_id = $2 AND warehouse_id = $3 AND quantity - reserved >= $1`
+	tag, err := tx.Exec(ctx, upd, r.Quantity, r.ProductID, r.WarehouseID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrInsufficientStock
+	}
+
+	const insert = `
+		INSERT INTO stock_reservations (sales_order_id, order_item_id, product_id, warehouse_id, quantity)
+		VALUES ($1, $2, $3, $4, $5)`
+	_, err = tx.Exec(ctx, insert, r.SalesOrderID, r.OrderItemID, r.ProductID, r.WarehouseID, r.Quantity)
+	return err
+}
+
+// activeReservations devuelve (bloqueando) las reservas activas de una orden de venta.
+func activeReservations(ctx context.Context, tx pgx.Tx, salesOrderID int64) ([]stockReservation, error) {
+	const q = `
+		SELECT id, sales_order_id, order_item_id, product_id, warehouse_id, quantity
+		FROM stock_reservations
+		WHERE sales_order_id = $1 AND status = 'active'
+		ORDER BY id
+		FOR UPDATE`
+
+	rows, err := tx.Query(ctx, q, salesOrderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []stockReservation
+	for rows.Next() {
+		var r stockReservation
+		if err := rows.Scan(&r.ID, &r.SalesOrderID, &r.OrderItemID, &r.ProductID, &r.WarehouseID, &r.Quantity); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return out, nil
+}
+
+// closeReservation libera la cantidad reservada en el depósito y cierra la reserva con el estado indicado.
+func closeReservation(ctx context.Context, tx pgx.Tx, r stockReservation, status string) error {
+	const release = `
+		UPDATE product_stocks SET reserved = GREATEST(reserved - $1, 0)
+		WHERE product_id = $2 AND warehouse_id = $3`
+	if _, err := tx.Exec(ctx, release, r.Quantity, r.ProductID, r.WarehouseID); err != nil {
+		return err
+	}
+	const upd = `UPDATE stock_reservations SET status = $1, closed_at = NOW() WHERE id = $2`
+	_, err := tx.Exec(ctx, upd, status, r.ID)
+	return err
+}
+
+// releaseReservations libera todas las reservas activas de una orden (cancelación o vencimiento) y
+// devuelve el stock que haya descontado: las órdenes pendientes anteriores a las reservas no tienen
+// reservas, sino movimientos SALES_ORDER.
+func releaseReservations(ctx context.Context, tx pgx.Tx, salesOrderID int64, userID int64) error {
+	reservations, err := activeReservations(ctx, tx, salesOrderID)
+	if err != nil {
+		return err
+	}
+	for _, r := range reservations {
+		if err := closeReservation(ctx, tx, r, "released"); err != nil {
+			return err
+		}
+	}
+	if err := releaseReservedSerials(ctx, tx, salesOrderID); err != nil {
+		return err
+	}
+	return restoreSoldStock(ctx, tx, salesOrderID, userID)
+}
+
+// fulfilReservations convierte las reservas activas de una orden en descuentos de stock:
+// un movimiento SALES_ORDER por reserva, consumiendo lotes FEFO que quedan registrados en la línea.
+// Devuelve ErrSerialsRequired si alguna línea serializada no tiene todas sus series asignadas.
+func fulfilReservations(ctx context.Context, tx pgx.Tx, salesOrderID int64, userID int64) error {
+	missing, err := missingSerials(ctx, tx, salesOrderID)
+	if err != nil {
+		return err
+	}
+	if missing {
+		return ErrSerialsRequired
+	}
+
+	reservations, err := activeReservations(ctx, tx, salesOrderID)
+	if err != nil {
+		return err
+	}
+
+	const insertItemLot = `
+		INSERT INTO order_item_lots (order_item_id, lot_id, quantity)
+		VALUES ($1, $2, $3)`
+
+	reference := fmt.Sprintf("%d", salesOrderID)
+	for _, r := range reservations {
+		if err := closeReservation(ctx, tx, r, "fulfilled"); err != nil {
+			return err
+		}
+		lots, err := applyStockChange(ctx, tx, stockChange{
+			ProductID:      r.ProductID,
+			WarehouseID:    r.WarehouseID,
+			UserID:         userID,
+			QuantityChange: -r.Quantity,
+			Reason:         "SALES_ORDER",
+			ReferenceID:    &reference,
+			OrderItemID:    &r.OrderItemID,
+		})
+		if err != nil {
+			return err
+		}
+		for _, l := range lots {
+			if _, err := tx.Exec(ctx, insertItemLot, r.OrderItemID, l.LotID, l.Quantity); err != nil {
+				return err
+			}
+		}
+	}
+	return sellReservedSerials(ctx, tx, salesOrderID)
+}
+
+// restoreSoldStock devuelve al stock lo que descontó una orden: un movimiento SALES_ORDER_CANCEL por
+// producto y depósito de sus movimientos SALES_ORDER, valorizado al costo con el que salió. Los lotes
+// consumidos y las series vendidas vuelven a estar disponibles. No hace nada si la orden no descontó
+// stock (sólo reservó).
+func restoreSoldStock(ctx context.Context, tx pgx.Tx, salesOrderID int64, userID int64) error {
+	reference := fmt.Sprintf("%d", salesOrderID)
+
+	const qShipped = `
+		SELECT sm.product_id, sm.warehouse_id, -SUM(sm.quantity_change),
+			(SELECT SUM(ce.value) / NULLIF(SUM(ce.quantity), 0)
+			 FROM cost_entries ce
+			 WHERE ce.user_id = $2 AND ce.product_id = sm.product_id
+				AND ce.reason = 'SALES_ORDER' AND ce.reference_id = $1)
+		FROM stock_movements sm
+		WHERE sm.user_id = $2 AND sm.reason = 'SALES_ORDER' AND sm.reference_id = $1
+		GROUP BY sm.product_id, sm.warehouse_id
+		ORDER BY sm.product_id, sm.warehouse_id`
+
+	rows, err := tx.Query(ctx, qShipped, reference, userID)
+	if err != nil {
+		return err
+	}
+
+	// Leer todos los movimientos antes de revertirlos (no se puede usar la tx mientras se itera)
+	var changes []stockChange
+	for rows.Next() {
+		c := stockChange{UserID: userID, Reason: ReasonSalesOrderCancel, ReferenceID: &reference}
+		if err := rows.Scan(&c.ProductID, &c.WarehouseID, &c.QuantityChange, &c.UnitCost); err != nil {
+			rows.Close()
+			return err
+		}
+		changes = append(changes, c)
+	}
+	rows.Close()
+	if rows.Err() != nil {
+		return rows.Err()
+	}
+
+	for _, c := range changes {
+		if c.QuantityChange <= 0 {
+			continue
+		}
+		if _, err := applyStockChange(ctx, tx, c); err != nil {
+			return err
+		}
+	}
+
+	// Cada lote recupera lo que la orden tomó de él
+	const restoreLots = `
+		UPDATE product_lots l SET quantity = l.quantity + c.quantity
+		FROM (
+			SELECT oil.lot_id, SUM(oil.quantity) AS quantity
+			FROM order_item_lots oil
+			JOIN order_items oi ON oi.id = oil.order_item_id
+			WHERE oi.order_id = $1
+			GROUP BY oil.lot_id
+		) c
+		WHERE l.id = c.lot_id`
+	if _, err := tx.Exec(ctx, restoreLots, salesOrderID); err != nil {
+		return err
+	}
+	return returnSoldSerials(ctx, tx, salesOrderID)
+}
+
+// recordStatusChange registra un cambio de estado de una orden de venta. from vacío es el estado
+// inicial; changedBy nil es un cambio hecho por un proceso del sistema.
+func recordStatusChange(ctx context.Context, q dbtx, salesOrderID int64, from string, to string, changedBy *int64) error {
+	const insert = `
+		INSERT INTO sales_order_status_changes (sales_order_id, from_status, to_status, changed_by)
+		VALUES ($1, NULLIF($2, ''), $3, $4)`
+	_, err := q.Exec(ctx, insert, salesOrderID, from, to, changedBy)
+	return err
+}
+
+// UpdateStatus avanza una orden por su ciclo de vida (pending → confirmed → picking → shipped →
+// delivered) o la cancela, y registra el cambio a nombre de userID. Al despacharla se descuenta el
+// stock reservado; cancelarla libera la reserva y devuelve el stock que la orden haya descontado.
+// Devuelve ErrInvalidOrderStatus si la transición no está permitida.
+func (m *SalesOrderModel) UpdateStatus(orderID int64, userID int64, newStatus string) error {
+	ctx := context.Background()
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if tx != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	var current string
+	err = tx.QueryRow(ctx, `SELECT status FROM sales_orders WHERE id = $1 AND user_id = $2 FOR UPDATE`, orderID, userID).Scan(&current)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+	if !CanTransitionSalesOrder(current, newStatus) {
+		return ErrInvalidOrderStatus
+	}
+
+	switch newStatus {
+	case SalesOrderShipped:
+		err = fulfilReservations(ctx, tx, orderID, userID)
+	case SalesOrderCancelled:
+		// Lo que siga reservado se libera y lo que ya se descontó vuelve al stock, sea cual sea el estado
+		err = releaseReservations(ctx, tx, orderID, userID)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Sólo las órdenes pendientes vencen: una vez confirmada la reserva se mantiene hasta el despacho
+	const upd = `UPDATE sales_orders SET status = $1, reserved_until = NULL WHERE id = $2`
+	if _, err := tx.Exec(ctx, upd, newStatus, orderID); err != nil {
+		return err
+	}
+	if err := recordStatusChange(ctx, tx, orderID, current, newStatus, &userID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	tx = nil
+	return nil
+}
+
+// reservationExpired indica si la reserva de una orden venció: sólo vencen las órdenes pendientes
+// con fecha de vencimiento anterior a now.
+func reservationExpired(status string, reservedUntil *time.Time, now time.Time) bool {
+	return status == SalesOrderPending && reservedUntil != nil && reservedUntil.Before(now)
+}
+
+// ExpiredReservations devuelve las órdenes pendientes cuya reserva venció. Las que ya descontaron
+// stock (anteriores a las reservas) no se vencen: se cancelan a mano, que registra los movimientos
+// que lo devuelven.
+func (m *SalesOrderModel) ExpiredReservations() ([]int64, error) {
+	const q = `
+		SELECT so.id FROM sales_orders so
+		WHERE so.status = 'pending' AND so.reserved_until IS NOT NULL AND so.reserved_until < NOW()
+			AND NOT EXISTS (
+				SELECT 1 FROM stock_movements sm
+				WHERE sm.reason = 'SALES_ORDER' AND sm.reference_id = so.id::text
+			)
+		ORDER BY so.id`
+
+	rows, err := m.DB.Query(context.Background(), q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return ids, nil
+}
+
+// ExpireReservation cancela una orden pendiente cuya reserva venció y libera lo reservado por el
+// mismo camino que una cancelación, registrando el cambio sin usuario. Devuelve false si la orden
+// ya no estaba pendiente y vencida (se confirmó o canceló mientras tanto).
+func (m *SalesOrderModel) ExpireReservation(orderID int64) (bool, error) {
+	ctx := context.Background()
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if tx != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	var (
+		userID        int64
+		status        string
+		reservedUntil *time.Time
+	)
+	err = tx.QueryRow(ctx, `SELECT user_id, status, reserved_until FROM sales_orders WHERE id = $1 FOR UPDATE`, orderID).
+		Scan(&userID, &status, &reservedUntil)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, ErrNotFound
+		}
+		return false, err
+	}
+	if !reservationExpired(status, reservedUntil, time.Now()) {
+		return false, nil
+	}
+
+	if err := releaseReservations(ctx, tx, orderID, userID); err != nil {
+		return false, err
+	}
+	const upd = `UPDATE sales_orders SET status = $1, reserved_until = NULL WHERE id = $2`
+	if _, err := tx.Exec(ctx, upd, SalesOrderCancelled, orderID); err != nil {
+		return false, err
+	}
+	if err := recordStatusChange(ctx, tx, orderID, status, SalesOrderCancelled, nil); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, err
+	}
+	tx = nil
+	return true, nil
+}
+
+// SalesOrderStatusChange es un cambio de estado de una orden de venta. FromStatus es nil para el
+// estado inicial y ChangedBy para los cambios hechos por procesos del sistema.
+type SalesOrderStatusChange struct {
+	ID            int64     `json:"id"`
+	FromStatus    *string   `json:"from_status"`
+	ToStatus      string    `json:"to_status"`
+	ChangedBy     *int64    `json:"changed_by,omitempty"`
+	ChangedByName *string   `json:"changed_by_name,omitempty"`
+	ChangedAt     time.Time `json:"changed_at"`
+}
+
+// GetStatusChanges devuelve los cambios de estado de una orden del usuario, el más antiguo primero.
+func (m *SalesOrderModel) GetStatusChanges(orderID int64, userID int64) ([]SalesOrderStatusChange, error) {
+	const q = `
+		SELECT c.id, c.from_status, c.to_status, c.changed_by, u.name, c.changed_at
+		FROM sales_order_status_changes c
+		JOIN sales_orders so ON so.id = c.sales_order_id
+		LEFT JOIN users u ON u.id = c.changed_by
+		WHERE c.sales_order_id = $1 AND so.user_id = $2
+		ORDER BY c.changed_at, c.id`
+
+	rows, err := m.DB.Query(context.Background(), q, orderID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []SalesOrderStatusChange{}
+	for rows.Next() {
+		var c SalesOrderStatusChange
+		if err := rows.Scan(&c.ID, &c.FromStatus, &c.ToStatus, &c.ChangedBy, &c.ChangedByName, &c.ChangedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return out, nil
+}
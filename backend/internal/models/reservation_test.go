@@ -0,0 +1,58 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCanTransitionSalesOrder(t *testing.T) {
+	allowed := [][2]string{
+		{SalesOrderPending, SalesOrderConfirmed},
+		{SalesOrderConfirmed, SalesOrderPicking},
+		{SalesOrderPicking, SalesOrderShipped},
+		{SalesOrderShipped, SalesOrderDelivered},
+		{SalesOrderPending, SalesOrderCancelled},
+		{SalesOrderPicking, SalesOrderCancelled},
+		{SalesOrderShipped, SalesOrderCancelled},
+	}
+	for _, tr := range allowed {
+		if !CanTransitionSalesOrder(tr[0], tr[1]) {
+			t.Fatalf("expected %s → %s to be allowed", tr[0], tr[1])
+		}
+	}
+
+	rejected := [][2]string{
+		{SalesOrderPending, SalesOrderShipped},     // no se saltean pasos
+		{SalesOrderShipped, SalesOrderPicking},     // no se vuelve atrás
+		{SalesOrderDelivered, SalesOrderCancelled}, // entregada es final
+		{SalesOrderCancelled, SalesOrderPending},
+		{SalesOrderPending, SalesOrderPending},
+		{SalesOrderPending, "completed"},
+	}
+	for _, tr := range rejected {
+		if CanTransitionSalesOrder(tr[0], tr[1]) {
+			t.Fatalf("expected %s → %s to be rejected", tr[0], tr[1])
+		}
+	}
+}
+
+func TestReservationExpired(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Minute), now.Add(time.Minute)
+	cases := []struct {
+		status        string
+		reservedUntil *time.Time
+		want          bool
+	}{
+		{SalesOrderPending, &past, true},
+		{SalesOrderPending, &future, false},
+		{SalesOrderPending, nil, false},     // reserva sin vencimiento
+		{SalesOrderConfirmed, &past, false}, // confirmada: la reserva se mantiene hasta el despacho
+		{SalesOrderCancelled, &past, false},
+	}
+	for _, c := range cases {
+		if got := reservationExpired(c.status, c.reservedUntil, now); got != c.want {
+			t.Errorf("reservationExpired(%s, %v): expected %t, got %t", c.status, c.reservedUntil, c.want, got)
+		}
+	}
+}
@@ -27,6 +27,17 @@ type SalesOrder struct {
 	Status       string          `json:"status"`
 	TotalAmount  sql.NullFloat64 `json:"total_amount"`
 	UserID       int64           `json:"user_id"`
+
+	// WarehouseID es el depósito del que se toma el stock de la orden.
+	WarehouseID   int64  `json:"warehouse_id"`
+	WarehouseName string `json:"warehouse_name,omitempty"`
+
+	// ReservedUntil es el vencimiento de la reserva de stock de una orden pendiente.
+	ReservedUntil *time.Time `json:"reserved_until,omitempty"`
+
+	// FlagBelowFloor acepta los precios bajo el mínimo marcando la línea, sea cual sea la política de
+	// la cuenta: las ventas ya cerradas en otro canal (Mercado Libre) no pueden rechazarse.
+	FlagBelowFloor bool `json:"-"`
 }
 
 // OrderItem represents a product item belonging to a sales order.
@@ -34,8 +45,31 @@ type OrderItem struct {
 	ID        int64   `json:"id"`
 	OrderID   int64   `json:"order_id"`
 	ProductID int64   `json:"product_id"`
-	Quantity  int     `json:"quantity"`
+	Quantity  float64 `json:"quantity"` // en unidad base
 	UnitPrice float64 `json:"unit_price"`
+
+	// Unidad en la que se cargó la línea: UnitQuantity * UnitFactor = Quantity.
+	// UnitPrice es el precio por esa unidad.
+	Unit         string  `json:"unit"`
+	UnitQuantity float64 `json:"unit_quantity"`
+	UnitFactor   float64 `json:"unit_factor"`
+
+	// Lots son los lotes consumidos (FEFO) por esta línea.
+	Lots []LotAllocation `json:"lots,omitempty"`
+
+	// SerialNumbers son las unidades vendidas; obligatorio para productos serializados.
+	SerialNumbers []string `json:"serial_numbers,omitempty"`
+
+	// COGS es el costo de la mercadería vendida de la línea (nil mientras la orden no descuenta stock).
+	COGS *float64 `json:"cogs,omitempty"`
+
+	// PriceListID es la lista de la que salió UnitPrice (nil si lo envió el cliente o no había precio).
+	// BelowFloor marca las líneas aceptadas con un precio bajo el mínimo del producto.
+	PriceListID *int64 `json:"price_list_id,omitempty"`
+	BelowFloor  bool   `json:"below_floor"`
+
+	// PriceGiven indica que UnitPrice vino en el pedido; si no, se toma de la lista de precios.
+	PriceGiven bool `json:"-"`
 }
 
 // SalesOrderModel wraps DB access for sales orders.
@@ -46,7 +80,11 @@ type SalesOrderModel struct {
 // ErrInsufficientStock is returned when available stock is not enough.
 var ErrInsufficientStock = errors.New("insufficient stock")
 
-// Create inserts a sales order with items and updates stock atomically.
+// Create inserts a sales order with items and reserves their stock atomically.
+// Una orden pendiente sólo reserva stock (hasta ReservedUntil) y una confirmada lo reserva sin
+// vencimiento; si se crea entregada (venta en el mostrador) o despachada (venta de otro canal) se
+// descuenta en el acto. Las líneas serializadas pueden cargarse sin series en una orden que sólo
+// reserva: se asignan después con AssignSerials, antes de despacharla.
 func (m *SalesOrderModel) Create(order *SalesOrder, items []OrderItem) error {
 	ctx := context.Background()
 	tx, err := m.DB.Begin(ctx)
@@ -59,58 +97,170 @@ func (m *SalesOrderModel) Create(order *SalesOrder, items []OrderItem) error {
 		}
 	}()
 
+	warehouseID, err := resolveWarehouse(ctx, tx, order.UserID, order.WarehouseID)
+	if err != nil {
+		return err
+	}
+	order.WarehouseID = warehouseID
+
+	if order.Status == "" {
+		order.Status = SalesOrderPending
+	}
+	deductNow := order.Status == SalesOrderShipped || order.Status == SalesOrderDelivered
+	if order.Status != SalesOrderPending && order.Status != SalesOrderConfirmed && !deductNow {
+		return ErrInvalidOrderStatus
+	}
+	if order.Status == SalesOrderPending && order.ReservedUntil == nil {
+		until := time.Now().Add(DefaultReservationTTL)
+		order.ReservedUntil = &until
+	}
+	if order.Status != SalesOrderPending {
+		order.ReservedUntil = nil
+	}
+
+	// Los clientes y productos archivados no pueden usarse en órdenes nuevas
+	if order.CustomerID.Valid {
+		if err := checkActiveCustomer(ctx, tx, order.CustomerID.Int64, order.UserID); err != nil {
+			return err
+		}
+	}
+	for i := range items {
+		if err := checkActiveProduct(ctx, tx, items[i].ProductID, order.UserID); err != nil {
+			return err
+		}
+	}
+
 	// Insert order header
 	const insertOrder = `
-		INSERT INTO sales_orders (customer_id, order_date, status, total_amount, user_id)
-		VALUES ($1, COALESCE($2, NOW()), COALESCE($3, 'pending'), $4, $5)
+		INSERT INTO sales_orders (customer_id, order_date, status, total_amount, user_id, warehouse_id, reserved_until)
+		VALUES ($1, COALESCE($2, NOW()), $3, $4, $5, $6, $7)
 		RETURNING id, order_date`
 
 	if err := tx.QueryRow(ctx, insertOrder,
-		order.CustomerID, order.OrderDate, order.Status, order.TotalAmount, order.UserID,
+		order.CustomerID, order.OrderDate, order.Status, order.TotalAmount, order.UserID, order.WarehouseID, order.ReservedUntil,
 	).Scan(&order.ID, &order.OrderDate); err != nil {
 		return err
 	}
 
+	var customerID *int64
+	if order.CustomerID.Valid {
+		customerID = &order.CustomerID.Int64
+	}
+	floorPolicy, err := priceFloorPolicy(ctx, tx, order.UserID)
+	if err != nil {
+		return err
+	}
+	priceDate := time.Now()
+	total := 0.0
+
 	// Insert items and update stock
 	const insertItem = `
-		INSERT INTO order_items (order_id, product_id, quantity, unit_price)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO order_items (order_id, product_id, quantity, unit_price, unit, unit_quantity, unit_factor, price_list_id, below_floor)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 		RETURNING id`
-	const updateStock = `
-		UPDATE products SET quantity = quantity - $1
-		WHERE id = $2 AND quantity - $1 >= 0`
 
 	for i := range items {
 		items[i].OrderID = order.ID
-		// Default price 0 for now
+
+		// Convertir la cantidad cargada a la unidad base del producto
+		unit, factor, err := resolveUnit(ctx, tx, items[i].ProductID, items[i].Unit)
+		if err != nil {
+			return err
+		}
+		if items[i].UnitQuantity == 0 {
+			items[i].UnitQuantity = items[i].Quantity
+		}
+		items[i].Unit, items[i].UnitFactor = unit, factor
+		items[i].Quantity = roundQuantity(items[i].UnitQuantity * factor)
+
+		// Sin precio en el pedido se usa la lista del cliente (o la por defecto), por la unidad cargada
+		if !items[i].PriceGiven {
+			basePrice, listID, err := resolvePrice(ctx, tx, order.UserID, customerID, items[i].ProductID, items[i].Quantity, priceDate)
+			if err != nil {
+				return err
+			}
+			items[i].UnitPrice, items[i].PriceListID = roundPrice(basePrice*factor), listID
+		} else {
+			items[i].UnitPrice, items[i].PriceListID = roundPrice(items[i].UnitPrice), nil
+		}
+		below, err := belowPriceFloor(ctx, tx, items[i].ProductID, items[i].UnitPrice/factor)
+		if err != nil {
+			return err
+		}
+		if below && floorPolicy != PriceFloorFlag && !order.FlagBelowFloor {
+			return ErrPriceBelowFloor
+		}
+		items[i].BelowFloor = below
+		total += items[i].UnitQuantity * items[i].UnitPrice
+
 		// Insert item
-		if err := tx.QueryRow(ctx, insertItem, items[i].OrderID, items[i].ProductID, items[i].Quantity, items[i].UnitPrice).
+		if err := tx.QueryRow(ctx, insertItem, items[i].OrderID, items[i].ProductID, items[i].Quantity, items[i].UnitPrice,
+			items[i].Unit, items[i].UnitQuantity, items[i].UnitFactor, items[i].PriceListID, items[i].BelowFloor).
 			Scan(&items[i].ID); err != nil {
 			return err
 		}
-		// Update stock, ensure non-negative
-		tag, err := tx.Exec(ctx, updateStock, items[i].Quantity, items[i].ProductID)
+
+		// Un kit descuenta el stock de cada uno de sus componentes
+		components, err := kitComponents(ctx, tx, items[i].ProductID)
 		if err != nil {
 			return err
 		}
-		if tag.RowsAffected() == 0 {
-			return ErrInsufficientStock
+
+		reservations := []stockReservation{{ProductID: items[i].ProductID, Quantity: items[i].Quantity}}
+		if len(components) > 0 {
+			reservations = reservations[:0]
+			for _, c := range components {
+				reservations = append(reservations, stockReservation{ProductID: c.ComponentID, Quantity: items[i].Quantity * c.Quantity})
+			}
+			if len(items[i].SerialNumbers) > 0 {
+				if err := reserveKitSerials(ctx, tx, components, items[i].Quantity, order.ID, items[i].ID, items[i].SerialNumbers); err != nil {
+					return err
+				}
+			}
+		} else {
+			// Los productos serializados sólo se despachan con las series de cada unidad reservadas
+			serialized, err := isSerializedProduct(ctx, tx, items[i].ProductID)
+			if err != nil {
+				return err
+			}
+			if serialized && len(items[i].SerialNumbers) > 0 {
+				if err := uniqueSerials(items[i].SerialNumbers, items[i].Quantity); err != nil {
+					return err
+				}
+				if err := reserveSerials(ctx, tx, items[i].ProductID, order.ID, items[i].ID, items[i].SerialNumbers); err != nil {
+					return err
+				}
+			} else if !serialized {
+				items[i].SerialNumbers = nil
+			}
+		}
+
+		// Reservar el disponible del depósito elegido; cada componente de un kit tiene su propia reserva
+		for _, r := range reservations {
+			r.SalesOrderID = order.ID
+			r.OrderItemID = items[i].ID
+			r.WarehouseID = order.WarehouseID
+			r.UserID = order.UserID
+			if err := reserveStock(ctx, tx, r); err != nil {
+				return err
+			}
 		}
+	}
+
+	order.TotalAmount = sql.NullFloat64{Float64: roundPrice(total), Valid: true}
+	if _, err := tx.Exec(ctx, `UPDATE sales_orders SET total_amount = $1 WHERE id = $2`, order.TotalAmount, order.ID); err != nil {
+		return err
+	}
 
-		// Insert stock movement (negative for sales)
-		const insertMovement = `
-			INSERT INTO stock_movements (product_id, quantity_change, reason, reference_id, user_id)
-			VALUES ($1, $2, $3, $4, $5)`
-		if _, err := tx.Exec(ctx, insertMovement,
-			items[i].ProductID,
-			-items[i].Quantity,
-			"SALES_ORDER",
-			fmt.Sprintf("%d", order.ID),
-			order.UserID,
-		); err != nil {
+	// Una orden creada como entregada o despachada descuenta el stock en la misma transacción
+	if deductNow {
+		if err := fulfilReservations(ctx, tx, order.ID, order.UserID); err != nil {
 			return err
 		}
 	}
+	if err := recordStatusChange(ctx, tx, order.ID, "", order.Status, &order.UserID); err != nil {
+		return err
+	}
 
 	if err := tx.Commit(ctx); err != nil {
 		return err
@@ -124,9 +274,10 @@ func (m *SalesOrderModel) GetAllForUser(userID int64) ([]SalesOrder, error) {
 	const q = `
 		SELECT 
 			so.id, so.customer_id, so.order_date, so.status, so.total_amount, so.user_id,
-			c.name AS customer_name
+			c.name AS customer_name, COALESCE(so.warehouse_id, 0), COALESCE(w.name, ''), so.reserved_until
 		FROM sales_orders so
 		LEFT JOIN customers c ON so.customer_id = c.id
+		LEFT JOIN warehouses w ON so.warehouse_id = w.id
 		WHERE so.user_id = $1
 		ORDER BY so.id DESC`
 
@@ -140,7 +291,7 @@ func (m *SalesOrderModel) GetAllForUser(userID int64) ([]SalesOrder, error) {
 	for rows.Next() {
 		var o SalesOrder
 		var customerName sql.NullString
-		if err := rows.Scan(&o.ID, &o.CustomerID, &o.OrderDate, &o.Status, &o.TotalAmount, &o.UserID, &customerName); err != nil {
+		if err := rows.Scan(&o.ID, &o.CustomerID, &o.OrderDate, &o.Status, &o.TotalAmount, &o.UserID, &customerName, &o.WarehouseID, &o.WarehouseName, &o.ReservedUntil); err != nil {
 			return nil, err
 		}
 		if customerName.Valid {
@@ -159,9 +310,10 @@ func (m *SalesOrderModel) GetAllForUserWithFilters(userID int64, filters SalesOr
 	query := `
 		SELECT 
 			so.id, so.customer_id, so.order_date, so.status, so.total_amount, so.user_id,
-			c.name AS customer_name
+			c.name AS customer_name, COALESCE(so.warehouse_id, 0), COALESCE(w.name, ''), so.reserved_until
 		FROM sales_orders so
 		LEFT JOIN customers c ON so.customer_id = c.id
+		LEFT JOIN warehouses w ON so.warehouse_id = w.id
 		WHERE so.user_id = $1`
 
 	args := []interface{}{userID}
@@ -198,7 +350,7 @@ func (m *SalesOrderModel) GetAllForUserWithFilters(userID int64, filters SalesOr
 	for rows.Next() {
 		var o SalesOrder
 		var customerName sql.NullString
-		if err := rows.Scan(&o.ID, &o.CustomerID, &o.OrderDate, &o.Status, &o.TotalAmount, &o.UserID, &customerName); err != nil {
+		if err := rows.Scan(&o.ID, &o.CustomerID, &o.OrderDate, &o.Status, &o.TotalAmount, &o.UserID, &customerName, &o.WarehouseID, &o.WarehouseName, &o.ReservedUntil); err != nil {
 			return nil, err
 		}
 		if customerName.Valid {
@@ -217,15 +369,16 @@ func (m *SalesOrderModel) GetByID(orderID int64, userID int64) (*SalesOrder, []O
 	const qOrder = `
 		SELECT 
 			so.id, so.customer_id, so.order_date, so.status, so.total_amount, so.user_id,
-			c.name AS customer_name
+			c.name AS customer_name, COALESCE(so.warehouse_id, 0), COALESCE(w.name, ''), so.reserved_until
 		FROM sales_orders so
 		LEFT JOIN customers c ON so.customer_id = c.id
+		LEFT JOIN warehouses w ON so.warehouse_id = w.id
 		WHERE so.id = $1 AND so.user_id = $2`
 
 	var o SalesOrder
 	var customerName sql.NullString
 	err := m.DB.QueryRow(context.Background(), qOrder, orderID, userID).
-		Scan(&o.ID, &o.CustomerID, &o.OrderDate, &o.Status, &o.TotalAmount, &o.UserID, &customerName)
+		Scan(&o.ID, &o.CustomerID, &o.OrderDate, &o.Status, &o.TotalAmount, &o.UserID, &customerName, &o.WarehouseID, &o.WarehouseName, &o.ReservedUntil)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil, ErrNotFound
@@ -237,7 +390,7 @@ func (m *SalesOrderModel) GetByID(orderID int64, userID int64) (*SalesOrder, []O
 	}
 
 	const qItems = `
-		SELECT id, order_id, product_id, quantity, unit_price
+		SELECT id, order_id, product_id, quantity, unit_price, unit, unit_quantity, unit_factor, cogs, price_list_id, below_floor
 		FROM order_items
 		WHERE order_id = $1
 		ORDER BY id`
@@ -250,7 +403,7 @@ func (m *SalesOrderModel) GetByID(orderID int64, userID int64) (*SalesOrder, []O
 	var items []OrderItem
 	for rows.Next() {
 		var it OrderItem
-		if err := rows.Scan(&it.ID, &it.OrderID, &it.ProductID, &it.Quantity, &it.UnitPrice); err != nil {
+		if err := rows.Scan(&it.ID, &it.OrderID, &it.ProductID, &it.Quantity, &it.UnitPrice, &it.Unit, &it.UnitQuantity, &it.UnitFactor, &it.COGS, &it.PriceListID, &it.BelowFloor); err != nil {
 			return nil, nil, err
 		}
 		items = append(items, it)
@@ -258,5 +411,59 @@ func (m *SalesOrderModel) GetByID(orderID int64, userID int64) (*SalesOrder, []O
 	if rows.Err() != nil {
 		return nil, nil, rows.Err()
 	}
+
+	const qLots = `
+		SELECT oil.order_item_id, l.id, l.lot_number, l.expiry_date, oil.quantity
+		FROM order_item_lots oil
+		JOIN order_items oi ON oi.id = oil.order_item_id
+		JOIN product_lots l ON l.id = oil.lot_id
+		WHERE oi.order_id = $1
+		ORDER BY oil.id`
+	lotRows, err := m.DB.Query(context.Background(), qLots, orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer lotRows.Close()
+
+	lotsByItem := map[int64][]LotAllocation{}
+	for lotRows.Next() {
+		var itemID int64
+		var l LotAllocation
+		if err := lotRows.Scan(&itemID, &l.LotID, &l.LotNumber, &l.ExpiryDate, &l.Quantity); err != nil {
+			return nil, nil, err
+		}
+		lotsByItem[itemID] = append(lotsByItem[itemID], l)
+	}
+	if lotRows.Err() != nil {
+		return nil, nil, lotRows.Err()
+	}
+	const qSerials = `
+		SELECT order_item_id, serial_number
+		FROM product_serials
+		WHERE sales_order_id = $1
+		ORDER BY serial_number`
+	serialRows, err := m.DB.Query(context.Background(), qSerials, orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer serialRows.Close()
+
+	serialsByItem := map[int64][]string{}
+	for serialRows.Next() {
+		var itemID int64
+		var serial string
+		if err := serialRows.Scan(&itemID, &serial); err != nil {
+			return nil, nil, err
+		}
+		serialsByItem[itemID] = append(serialsByItem[itemID], serial)
+	}
+	if serialRows.Err() != nil {
+		return nil, nil, serialRows.Err()
+	}
+
+	for i := range items {
+		items[i].Lots = lotsByItem[items[i].ID]
+		items[i].SerialNumbers = serialsByItem[items[i].ID]
+	}
 	return &o, items, nil
 }
@@ -0,0 +1,375 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Estados de un número de serie
+const (
+	SerialStatusInStock  = "in_stock"
+	SerialStatusReserved = "reserved"
+	SerialStatusSold     = "sold"
+)
+
+// Errors for serial number operations
+var (
+	ErrSerialsRequired    = errors.New("serialized product requires one serial number per unit")
+	ErrSerialNotAvailable = errors.New("serial number not in stock")
+	ErrDuplicateSerial    = errors.New("duplicate serial number")
+	ErrNotSerialized      = errors.New("product is not serialized")
+	ErrSerializedProduct  = errors.New("serialized product stock only changes through purchase and sales orders")
+)
+
+// ProductSerial representa una unidad identificada por número de serie.
+type ProductSerial struct {
+	ID              int64      `json:"id"`
+	ProductID       int64      `json:"product_id"`
+	SerialNumber    string     `json:"serial_number"`
+	Status          string     `json:"status"`
+	PurchaseOrderID *int64     `json:"purchase_order_id,omitempty"`
+	SalesOrderID    *int64     `json:"sales_order_id,omitempty"`
+	ReceivedAt      time.Time  `json:"received_at"`
+	SoldAt          *time.Time `json:"sold_at,omitempty"`
+}
+
+// SerialHistory es la trazabilidad completa de un número de serie: de qué proveedor y OC vino
+// y a qué cliente y OV se vendió.
+type SerialHistory struct {
+	SerialNumber      string     `json:"serial_number"`
+	Status            string     `json:"status"`
+	ProductID         int64      `json:"product_id"`
+	ProductName       string     `json:"product_name"`
+	SKU               string     `json:"sku"`
+	ReceivedAt        time.Time  `json:"received_at"`
+	PurchaseOrderID   *int64     `json:"purchase_order_id,omitempty"`
+	PurchaseOrderDate *time.Time `json:"purchase_order_date,omitempty"`
+	SupplierID        *int64     `json:"supplier_id,omitempty"`
+	SupplierName      string     `json:"supplier_name,omitempty"`
+	SalesOrderID      *int64     `json:"sales_order_id,omitempty"`
+	SalesOrderDate    *time.Time `json:"sales_order_date,omitempty"`
+	CustomerID        *int64     `json:"customer_id,omitempty"`
+	CustomerName      string     `json:"customer_name,omitempty"`
+	SoldAt            *time.Time `json:"sold_at,omitempty"`
+}
+
+// isSerializedProduct indica si el producto requiere números de serie.
+func isSerializedProduct(ctx context.Context, q dbtx, productID int64) (bool, error) {
+	var serialized bool
+	err := q.QueryRow(ctx, `SELECT is_serialized FROM products WHERE id = $1`, productID).Scan(&serialized)
+	if err != nil {
+		return false, err
+	}
+	return serialized, nil
+}
+
+// uniqueSerials valida que haya exactamente qty números de serie, sin repetidos ni vacíos.
+func uniqueSerials(serials []string, qty float64) error {
+	if float64(len(serials)) != qty {
+		return ErrSerialsRequired
+	}
+	seen := make(map[string]bool, len(serials))
+	for _, s := range serials {
+		if s == "" {
+			return ErrSerialsRequired
+		}
+		if seen[s] {
+			return ErrDuplicateSerial
+		}
+		seen[s] = true
+	}
+	return nil
+}
+
+// receiveSerials registra en stock los números de serie recibidos de un producto.
+func receiveSerials(ctx context.Context, q dbtx, productID int64, purchaseOrderID *int64, serials []string) error {
+	const insert = `
+		INSERT INTO product_serials (product_id, serial_number, status, purchase_order_id)
+		VALUES ($1, $2, 'in_stock', $3)`
+	for _, s := range serials {
+		if _, err := q.Exec(ctx, insert, productID, s, purchaseOrderID); err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique_violation (product_id, serial_number)
+				return ErrDuplicateSerial
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// reserveSerials reserva para una línea de venta pendiente los números de serie indicados.
+// Todos deben estar en stock; si alguno no lo está devuelve ErrSerialNotAvailable.
+func reserveSerials(ctx context.Context, q dbtx, productID int64, salesOrderID int64, orderItemID int64, serials []string) error {
+	const upd = `
+		UPDATE product_serials
+		SET status = 'reserved', sales_order_id = $1, order_item_id = $2
+		WHERE product_id = $3 AND serial_number = ANY($4) AND status = 'in_stock'`
+	tag, err := q.Exec(ctx, upd, salesOrderID, orderItemID, productID, serials)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() != int64(len(serials)) {
+		return ErrSerialNotAvailable
+	}
+	return nil
+}
+
+// missingSerials indica si a alguna reserva activa de la orden de un producto serializado (línea o
+// componente de un kit) le faltan series: debe tener una serie reservada por unidad.
+func missingSerials(ctx context.Context, q dbtx, salesOrderID int64) (bool, error) {
+	const qMissing = `
+		SELECT EXISTS (
+			SELECT 1
+			FROM stock_reservations r
+			JOIN products p ON p.id = r.product_id
+			WHERE r.sales_order_id = $1 AND r.status = 'active' AND p.is_serialized
+				AND r.quantity <> (
+					SELECT COUNT(*) FROM product_serials s
+					WHERE s.order_item_id = r.order_item_id AND s.product_id = r.product_id AND s.status = 'reserved'
+				)
+		)`
+	var missing bool
+	err := q.QueryRow(ctx, qMissing, salesOrderID).Scan(&missing)
+	return missing, err
+}
+
+// AssignSerials reemplaza las series reservadas de una línea de una orden que todavía no se
+// despachó. Es el camino para las órdenes cargadas sin series (como las ventas de Mercado Libre de
+// productos serializados), que no pueden despacharse hasta tenerlas. Para un kit, serials son las
+// series de sus componentes serializados.
+func (m *SalesOrderModel) AssignSerials(orderID int64, itemID int64, userID int64, serials []string) error {
+	ctx := context.Background()
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if tx != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	var status string
+	err = tx.QueryRow(ctx, `SELECT status FROM sales_orders WHERE id = $1 AND user_id = $2 FOR UPDATE`, orderID, userID).Scan(&status)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+	if status != SalesOrderPending && status != SalesOrderConfirmed && status != SalesOrderPicking {
+		return ErrInvalidOrderStatus
+	}
+
+	var productID int64
+	var quantity float64
+	err = tx.QueryRow(ctx, `SELECT product_id, quantity FROM order_items WHERE id = $1 AND order_id = $2`, itemID, orderID).Scan(&productID, &quantity)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	const release = `
+		UPDATE product_serials
+		SET status = 'in_stock', sales_order_id = NULL, order_item_id = NULL
+		WHERE order_item_id = $1 AND status = 'reserved'`
+	if _, err := tx.Exec(ctx, release, itemID); err != nil {
+		return err
+	}
+
+	components, err := kitComponents(ctx, tx, productID)
+	if err != nil {
+		return err
+	}
+	if len(components) > 0 {
+		if !slices.ContainsFunc(components, func(c KitComponent) bool { return c.IsSerialized }) {
+			return ErrNotSerialized
+		}
+		if err := reserveKitSerials(ctx, tx, components, quantity, orderID, itemID, serials); err != nil {
+			return err
+		}
+	} else {
+		serialized, err := isSerializedProduct(ctx, tx, productID)
+		if err != nil {
+			return err
+		}
+		if !serialized {
+			return ErrNotSerialized
+		}
+		if err := uniqueSerials(serials, quantity); err != nil {
+			return err
+		}
+		if err := reserveSerials(ctx, tx, productID, orderID, itemID, serials); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	tx = nil
+	return nil
+}
+
+// sellReservedSerials marca como vendidas las series reservadas por una orden de venta.
+func sellReservedSerials(ctx context.Context, q dbtx, salesOrderID int64) error {
+	const upd = `
+		UPDATE product_serials
+		SET status = 'sold', sold_at = NOW()
+		WHERE sales_order_id = $1 AND status = 'reserved'`
+	_, err := q.Exec(ctx, upd, salesOrderID)
+	return err
+}
+
+// releaseReservedSerials devuelve a stock las series reservadas por una orden de venta.
+func releaseReservedSerials(ctx context.Context, q dbtx, salesOrderID int64) error {
+	const upd = `
+		UPDATE product_serials
+		SET status = 'in_stock', sales_order_id = NULL, order_item_id = NULL
+		WHERE sales_order_id = $1 AND status = 'reserved'`
+	_, err := q.Exec(ctx, upd, salesOrderID)
+	return err
+}
+
+// returnSoldSerials devuelve a stock las series vendidas por una orden de venta que se cancela.
+func returnSoldSerials(ctx context.Context, q dbtx, salesOrderID int64) error {
+	const upd = `
+		UPDATE product_serials
+		SET status = 'in_stock', sales_order_id = NULL, order_item_id = NULL, sold_at = NULL
+		WHERE sales_order_id = $1 AND status = 'sold'`
+	_, err := q.Exec(ctx, upd, salesOrderID)
+	return err
+}
+
+// SerialModel wraps DB access for product serial numbers.
+type SerialModel struct {
+	DB *pgxpool.Pool
+}
+
+// Register da de alta números de serie de unidades que ya están en stock (carga inicial).
+// No puede haber más series en stock que unidades del producto.
+func (m *SerialModel) Register(productID int64, userID int64, serials []string) error {
+	ctx := context.Background()
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if tx != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	const qProduct = `
+		SELECT p.is_serialized, p.quantity,
+			(SELECT COUNT(*) FROM product_serials s WHERE s.product_id = p.id AND s.status IN ('in_stock', 'reserved'))
+		FROM products p
+		WHERE p.id = $1 AND p.user_id = $2
+		FOR UPDATE`
+	var serialized bool
+	var quantity float64
+	var inStock int
+	if err := tx.QueryRow(ctx, qProduct, productID, userID).Scan(&serialized, &quantity, &inStock); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+	if !serialized {
+		return ErrSerialsRequired
+	}
+	if err := uniqueSerials(serials, float64(len(serials))); err != nil {
+		return err
+	}
+	if float64(inStock+len(serials)) > quantity {
+		return ErrInsufficientStock
+	}
+
+	if err := receiveSerials(ctx, tx, productID, nil, serials); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	tx = nil
+	return nil
+}
+
+// GetForProduct devuelve los números de serie de un producto.
+func (m *SerialModel) GetForProduct(productID int64, userID int64) ([]ProductSerial, error) {
+	const q = `
+		SELECT s.id, s.product_id, s.serial_number, s.status, s.purchase_order_id, s.sales_order_id, s.received_at, s.sold_at
+		FROM product_serials s
+		JOIN products p ON p.id = s.product_id
+		WHERE s.product_id = $1 AND p.user_id = $2
+		ORDER BY s.status, s.serial_number`
+
+	rows, err := m.DB.Query(context.Background(), q, productID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []ProductSerial{}
+	for rows.Next() {
+		var s ProductSerial
+		if err := rows.Scan(&s.ID, &s.ProductID, &s.SerialNumber, &s.Status, &s.PurchaseOrderID, &s.SalesOrderID, &s.ReceivedAt, &s.SoldAt); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return out, nil
+}
+
+// Lookup devuelve la historia de un número de serie. Puede haber más de un producto con el mismo número.
+func (m *SerialModel) Lookup(serial string, userID int64) ([]SerialHistory, error) {
+	const q = `
+		SELECT s.serial_number, s.status, p.id, p.name, p.sku, s.received_at,
+			s.purchase_order_id, po.order_date, sup.id, COALESCE(sup.name, ''),
+			s.sales_order_id, so.order_date, c.id, COALESCE(c.name, ''), s.sold_at
+		FROM product_serials s
+		JOIN products p ON p.id = s.product_id
+		LEFT JOIN purchase_orders po ON po.id = s.purchase_order_id
+		LEFT JOIN suppliers sup ON sup.id = po.supplier_id
+		LEFT JOIN sales_orders so ON so.id = s.sales_order_id
+		LEFT JOIN customers c ON c.id = so.customer_id
+		WHERE s.serial_number = $1 AND p.user_id = $2
+		ORDER BY p.id`
+
+	rows, err := m.DB.Query(context.Background(), q, serial, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []SerialHistory{}
+	for rows.Next() {
+		var h SerialHistory
+		if err := rows.Scan(&h.SerialNumber, &h.Status, &h.ProductID, &h.ProductName, &h.SKU, &h.ReceivedAt,
+			&h.PurchaseOrderID, &h.PurchaseOrderDate, &h.SupplierID, &h.SupplierName,
+			&h.SalesOrderID, &h.SalesOrderDate, &h.CustomerID, &h.CustomerName, &h.SoldAt); err != nil {
+			return nil, err
+		}
+		out = append(out, h)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	if len(out) == 0 {
+		return nil, ErrNotFound
+	}
+	return out, nil
+}
@@ -0,0 +1,18 @@
+package models
+
+import "testing"
+
+func TestUniqueSerials(t *testing.T) {
+	if err := uniqueSerials([]string{"SN1", "SN2"}, 2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := uniqueSerials([]string{"SN1"}, 2); err != ErrSerialsRequired {
+		t.Fatalf("expected ErrSerialsRequired for missing serials, got %v", err)
+	}
+	if err := uniqueSerials([]string{"SN1", ""}, 2); err != ErrSerialsRequired {
+		t.Fatalf("expected ErrSerialsRequired for empty serial, got %v", err)
+	}
+	if err := uniqueSerials([]string{"SN1", "SN1"}, 2); err != ErrDuplicateSerial {
+		t.Fatalf("expected ErrDuplicateSerial, got %v", err)
+	}
+}
@@ -0,0 +1,255 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// dbtx es la interfaz común de *pgxpool.Pool y pgx.Tx usada por los helpers de stock.
+type dbtx interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// stockChange describe un cambio de stock en un depósito que queda registrado en stock_movements.
+type stockChange struct {
+	ProductID      int64
+	WarehouseID    int64
+	UserID         int64
+	QuantityChange float64
+	Reason         string
+	ReferenceID    *string // NULL para ajustes manuales
+
+	// Lots son los lotes en los que entra la cantidad o, en una salida, los lotes (por número) de los
+	// que sale. Las salidas sin lotes consumen lotes FEFO automáticamente.
+	Lots []LotAllocation
+
+	// MoveLots indica que los lotes de la entrada vienen de otro depósito (transferencias): se suman
+	// al lote del mismo número sin contarse como recibidos.
+	MoveLots bool
+
+	// UnitCost es el costo unitario (en unidad base) de una entrada; si es nil la entrada no se valoriza.
+	// Las salidas consumen capas de costo automáticamente y, con OrderItemID, suman el costo a la línea de venta.
+	UnitCost        *float64
+	PurchaseOrderID *int64
+	OrderItemID     *int64
+}
+
+// resolveWarehouse valida que el depósito pertenezca al usuario y no sea su ubicación de tránsito.
+// Si warehouseID es 0 devuelve el depósito por defecto, creándolo si el usuario todavía no tiene uno.
+func resolveWarehouse(ctx context.Context, q dbtx, userID int64, warehouseID int64) (int64, error) {
+	if warehouseID != 0 {
+		var id int64
+		err := q.QueryRow(ctx, `SELECT id FROM warehouses WHERE id = $1 AND user_id = $2 AND NOT is_transit`, warehouseID, userID).Scan(&id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrWarehouseNotFound
+		}
+		return id, err
+	}
+
+	const qDefault = `SELECT id FROM warehouses WHERE user_id = $1 AND is_default`
+	var id int64
+	err := q.QueryRow(ctx, qDefault, userID).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return 0, err
+	}
+
+	// Usuario registrado después de la migración de depósitos: crear su depósito principal, o marcar
+	// como depósito por defecto el que ya tenga con ese código
+	const insertDefault = `
+		INSERT INTO warehouses (name, code, is_default, user_id)
+		VALUES ('Depósito Principal', 'PRINCIPAL', true, $1)
+		ON CONFLICT DO NOTHING`
+	const promoteDefault = `
+		UPDATE warehouses SET is_default = true
+		WHERE user_id = $1 AND code = 'PRINCIPAL' AND NOT is_transit
+			AND NOT EXISTS (SELECT 1 FROM warehouses WHERE user_id = $1 AND is_default)`
+	if _, err := q.Exec(ctx, insertDefault, userID); err != nil {
+		return 0, err
+	}
+	if _, err := q.Exec(ctx, promoteDefault, userID); err != nil {
+		return 0, err
+	}
+	if err := q.QueryRow(ctx, qDefault, userID).Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// DefaultWarehouse devuelve el depósito por defecto del usuario, creándolo (o marcando como tal su
+// depósito PRINCIPAL) si todavía no tiene uno. Es la resolución que usan los procesos del worker.
+func DefaultWarehouse(ctx context.Context, q dbtx, userID int64) (int64, error) {
+	return resolveWarehouse(ctx, q, userID, 0)
+}
+
+// addLocationStock suma delta al stock del producto en el depósito sin tocar products ni el ledger.
+// Devuelve ErrInsufficientStock si el depósito quedaría en negativo.
+func addLocationStock(ctx context.Context, q dbtx, productID int64, warehouseID int64, delta float64) error {
+	if delta >= 0 {
+		const upsert = `
+			INSERT INTO product_stocks (product_id, warehouse_id, quantity)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (product_id, warehouse_id)
+			DO UPDATE SET quantity = product_stocks.quantity + EXCLUDED.quantity`
+		_, err := q.Exec(ctx, upsert, productID, warehouseID, delta)
+		return err
+	}
+
+	const dec = `
+		UPDATE product_stocks SET quantity = quantity + $1
+		WHERE product_id = $2 AND warehouse_id = $3 AND quantity - reserved + $1 >= 0`
+	tag, err := q.Exec(ctx, dec, delta, productID, warehouseID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrInsufficientStock
+	}
+	return nil
+}
+
+// applyStockChange aplica un cambio de stock en un depósito: actualiza product_stocks,
+// el total en products y registra el movimiento en el ledger. Debe ejecutarse dentro de una transacción.
+// Devuelve los lotes afectados por el movimiento.
+func applyStockChange(ctx context.Context, tx pgx.Tx, c stockChange) ([]LotAllocation, error) {
+	c.QuantityChange = roundQuantity(c.QuantityChange)
+
+	// Actualiza el total del producto, validando que pertenezca al usuario. Los kits no tienen stock propio.
+	const upd = `UPDATE products SET quantity = quantity + $1 WHERE id = $2 AND user_id = $3 AND NOT is_kit`
+	tag, err := tx.Exec(ctx, upd, c.QuantityChange, c.ProductID, c.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		var isKit bool
+		err := tx.QueryRow(ctx, `SELECT is_kit FROM products WHERE id = $1 AND user_id = $2`, c.ProductID, c.UserID).Scan(&isKit)
+		if err == nil && isKit {
+			return nil, ErrKitNotStocked
+		}
+		return nil, ErrNotFound
+	}
+
+	if err := addLocationStock(ctx, tx, c.ProductID, c.WarehouseID, c.QuantityChange); err != nil {
+		return nil, err
+	}
+
+	var lots []LotAllocation
+	if c.QuantityChange > 0 && len(c.Lots) > 0 {
+		var total float64
+		for _, l := range c.Lots {
+			total += l.Quantity
+		}
+		if roundQuantity(total) > c.QuantityChange {
+			return nil, ErrLotQuantityMismatch
+		}
+		if c.MoveLots {
+			err = moveLots(ctx, tx, c.ProductID, c.WarehouseID, c.Lots)
+		} else {
+			err = receiveLots(ctx, tx, c.ProductID, c.WarehouseID, c.Lots)
+		}
+		if err != nil {
+			return nil, err
+		}
+		lots = c.Lots
+	} else if c.QuantityChange < 0 && len(c.Lots) > 0 {
+		if lots, err = consumeLots(ctx, tx, c.ProductID, c.WarehouseID, c.Lots); err != nil {
+			return nil, err
+		}
+	} else if c.QuantityChange < 0 {
+		if lots, err = consumeLotsFEFO(ctx, tx, c.ProductID, c.WarehouseID, -c.QuantityChange); err != nil {
+			return nil, err
+		}
+	}
+
+	// Valorización: las entradas con costo crean una capa y las salidas la consumen
+	if costedReason(c.Reason) {
+		if c.QuantityChange > 0 && c.UnitCost != nil {
+			if err := addCostLayer(ctx, tx, c, *c.UnitCost); err != nil {
+				return nil, err
+			}
+		} else if c.QuantityChange < 0 {
+			cogs, err := consumeCostLayers(ctx, tx, c, -c.QuantityChange)
+			if err != nil {
+				return nil, err
+			}
+			if c.OrderItemID != nil {
+				const addCOGS = `UPDATE order_items SET cogs = COALESCE(cogs, 0) + $1 WHERE id = $2`
+				if _, err := tx.Exec(ctx, addCOGS, cogs, *c.OrderItemID); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	// Reset notificado flag if stock is now above minimum
+	if c.QuantityChange > 0 {
+		const resetNotified = `UPDATE products SET notificado = false WHERE id = $1 AND notificado AND quantity > stock_minimo`
+		resetResult, err := tx.Exec(ctx, resetNotified, c.ProductID)
+		if err != nil {
+			return nil, err
+		}
+		if resetResult.RowsAffected() > 0 {
+			slog.Info("applyStockChange: notificado flag reset to false", "productID", c.ProductID)
+		}
+	}
+
+	const insertMovement = `
+		INSERT INTO stock_movements (product_id, warehouse_id, quantity_change, reason, reference_id, user_id)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+	if _, err := tx.Exec(ctx, insertMovement, c.ProductID, c.WarehouseID, c.QuantityChange, c.Reason, c.ReferenceID, c.UserID); err != nil {
+		return nil, err
+	}
+	return lots, nil
+}
+
+// loadProductStocks devuelve el stock por depósito de los productos del usuario, agrupado por producto.
+// Si productID es distinto de 0 se limita a ese producto. Para los kits devuelve cuántos pueden
+// armarse en cada depósito con el stock de sus componentes.
+func loadProductStocks(ctx context.Context, q dbtx, userID int64, productID int64) (map[int64][]ProductStock, error) {
+	const qStocks = `
+		SELECT product_id, warehouse_id, name, quantity, reserved
+		FROM (
+			SELECT ps.product_id, ps.warehouse_id, w.name, ps.quantity, ps.reserved, w.is_default
+			FROM product_stocks ps
+			JOIN warehouses w ON w.id = ps.warehouse_id
+			WHERE w.user_id = $1 AND ($2::bigint = 0 OR ps.product_id = $2)
+			UNION ALL
+			SELECT pc.kit_id, w.id, w.name, FLOOR(MIN(COALESCE(ps.quantity - ps.reserved, 0) / pc.quantity)), 0, w.is_default
+			FROM product_components pc
+			JOIN products k ON k.id = pc.kit_id
+			JOIN warehouses w ON w.user_id = k.user_id AND NOT w.is_transit
+			LEFT JOIN product_stocks ps ON ps.product_id = pc.component_id AND ps.warehouse_id = w.id
+			WHERE k.user_id = $1 AND ($2::bigint = 0 OR pc.kit_id = $2)
+			GROUP BY pc.kit_id, w.id, w.name, w.is_default
+		) s
+		ORDER BY product_id, is_default DESC, warehouse_id`
+
+	rows, err := q.Query(ctx, qStocks, userID, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[int64][]ProductStock{}
+	for rows.Next() {
+		var pid int64
+		var s ProductStock
+		if err := rows.Scan(&pid, &s.WarehouseID, &s.WarehouseName, &s.Quantity, &s.Reserved); err != nil {
+			return nil, err
+		}
+		s.Available = s.Quantity - s.Reserved
+		out[pid] = append(out[pid], s)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return out, nil
+}
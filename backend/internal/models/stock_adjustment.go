@@ -0,0 +1,389 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Estados de un ajuste manual de stock
+const (
+	AdjustmentApplied  = "applied"  // dentro de los límites: se registró al pedirlo
+	AdjustmentPending  = "pending"  // supera algún límite: espera la aprobación de un admin
+	AdjustmentApproved = "approved" // aprobado por un admin y registrado
+	AdjustmentRejected = "rejected" // rechazado por un admin, sin mover stock
+)
+
+// Signo permitido por un motivo de ajuste
+const (
+	ReasonSignPositive = "positive"
+	ReasonSignNegative = "negative"
+	ReasonSignAny      = "any"
+)
+
+// Errors for stock adjustments
+var (
+	ErrReasonNotFound          = errors.New("adjustment reason not found")
+	ErrReasonSignMismatch      = errors.New("quantity sign not allowed by the adjustment reason")
+	ErrInvalidReason           = errors.New("invalid adjustment reason")
+	ErrDuplicateReasonCode     = errors.New("duplicate adjustment reason code")
+	ErrInvalidAdjustmentStatus = errors.New("adjustment is not pending approval")
+)
+
+// AdjustmentReason es un motivo del catálogo de ajustes manuales. Sign restringe el signo de la
+// cantidad; los motivos inactivos no pueden usarse en ajustes nuevos.
+type AdjustmentReason struct {
+	ID        int64     `json:"id"`
+	Code      string    `json:"code"`
+	Name      string    `json:"name"`
+	Sign      string    `json:"sign"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Allows indica si el motivo admite un ajuste de quantityChange.
+func (r *AdjustmentReason) Allows(quantityChange float64) bool {
+	switch r.Sign {
+	case ReasonSignPositive:
+		return quantityChange > 0
+	case ReasonSignNegative:
+		return quantityChange < 0
+	}
+	return quantityChange != 0
+}
+
+// validate normaliza el nombre y verifica que tenga nombre y un signo conocido.
+func (r *AdjustmentReason) validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" {
+		return ErrInvalidReason
+	}
+	if r.Sign != ReasonSignPositive && r.Sign != ReasonSignNegative && r.Sign != ReasonSignAny {
+		return ErrInvalidReason
+	}
+	return nil
+}
+
+// AdjustmentPolicy son los límites a partir de los cuales un ajuste requiere aprobación: cantidad
+// absoluta (en unidad base) y valor al costo. 0 = sin límite.
+type AdjustmentPolicy struct {
+	MaxQuantity float64
+	MaxValue    float64
+}
+
+// requiresApproval indica si un ajuste supera alguno de los límites.
+func (p AdjustmentPolicy) requiresApproval(quantityChange, value float64) bool {
+	if p.MaxQuantity > 0 && math.Abs(quantityChange) > p.MaxQuantity {
+		return true
+	}
+	return p.MaxValue > 0 && math.Abs(value) > p.MaxValue
+}
+
+// StockAdjustment es un ajuste manual de stock. Value es la cantidad valorizada al costo promedio
+// del producto al pedirlo. ApprovedBy es el admin que resolvió la solicitud (la aprobó o la rechazó).
+type StockAdjustment struct {
+	ID             int64      `json:"id"`
+	UserID         int64      `json:"user_id"`
+	ProductID      int64      `json:"product_id"`
+	ProductSKU     string     `json:"product_sku"`
+	ProductName    string     `json:"product_name"`
+	WarehouseID    int64      `json:"warehouse_id"`
+	ReasonID       int64      `json:"reason_id"`
+	ReasonCode     string     `json:"reason_code"`
+	QuantityChange float64    `json:"quantity_change"`
+	UnitCost       float64    `json:"unit_cost"`
+	Value          float64    `json:"value"`
+	Note           string     `json:"note"`
+	Status         string     `json:"status"`
+	RequestedBy    int64      `json:"requested_by"`
+	RequestedAt    time.Time  `json:"requested_at"`
+	ApprovedBy     *int64     `json:"approved_by,omitempty"`
+	ApprovedAt     *time.Time `json:"approved_at,omitempty"`
+}
+
+// AdjustmentReasonModel wraps DB access for the adjustment reason catalogue.
+type AdjustmentReasonModel struct {
+	DB *pgxpool.Pool
+}
+
+const selectAdjustmentReason = `SELECT id, code, name, sign, active, created_at FROM adjustment_reasons`
+
+func scanAdjustmentReason(row pgx.Row, r *AdjustmentReason) error {
+	return row.Scan(&r.ID, &r.Code, &r.Name, &r.Sign, &r.Active, &r.CreatedAt)
+}
+
+// GetAll devuelve el catálogo de motivos; con onlyActive sólo los que pueden usarse.
+func (m *AdjustmentReasonModel) GetAll(onlyActive bool) ([]AdjustmentReason, error) {
+	rows, err := m.DB.Query(context.Background(), selectAdjustmentReason+` WHERE active OR NOT $1 ORDER BY code`, onlyActive)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []AdjustmentReason{}
+	for rows.Next() {
+		var r AdjustmentReason
+		if err := scanAdjustmentReason(rows, &r); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return out, nil
+}
+
+// Insert agrega un motivo al catálogo. El código se normaliza en minúsculas.
+func (m *AdjustmentReasonModel) Insert(r *AdjustmentReason) error {
+	r.Code = strings.ToLower(strings.TrimSpace(r.Code))
+	if r.Code == "" {
+		return ErrInvalidReason
+	}
+	if err := r.validate(); err != nil {
+		return err
+	}
+	const q = `
+		INSERT INTO adjustment_reasons (code, name, sign, active)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`
+	err := m.DB.QueryRow(context.Background(), q, r.Code, r.Name, r.Sign, r.Active).Scan(&r.ID, &r.CreatedAt)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique_violation
+		return ErrDuplicateReasonCode
+	}
+	return err
+}
+
+// Update cambia el nombre, el signo o el estado de un motivo. El código no cambia: los ajustes
+// ya registrados lo referencian.
+func (m *AdjustmentReasonModel) Update(r *AdjustmentReason) error {
+	const q = `
+		UPDATE adjustment_reasons SET name = $1, sign = $2, active = $3
+		WHERE id = $4
+		RETURNING code, created_at`
+	if err := r.validate(); err != nil {
+		return err
+	}
+	err := m.DB.QueryRow(context.Background(), q, r.Name, r.Sign, r.Active, r.ID).Scan(&r.Code, &r.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// AdjustStock registra un ajuste manual de stock de un producto en un depósito con un motivo del
+// catálogo. Si el ajuste supera algún límite de policy queda pendiente de aprobación sin mover
+// stock; si no, se registra el movimiento MANUAL_ADJUSTMENT en la misma transacción.
+// Si warehouseID es 0 se usa el depósito por defecto del usuario.
+func (m *ProductModel) AdjustStock(productID int64, userID int64, warehouseID int64, quantityChange float64, reasonCode string, note string, policy AdjustmentPolicy) (*StockAdjustment, error) {
+	ctx := context.Background()
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if tx != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	var reason AdjustmentReason
+	err = scanAdjustmentReason(tx.QueryRow(ctx, selectAdjustmentReason+` WHERE code = $1 AND active`, strings.ToLower(reasonCode)), &reason)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrReasonNotFound
+		}
+		return nil, err
+	}
+	quantityChange = roundQuantity(quantityChange)
+	if !reason.Allows(quantityChange) {
+		return nil, ErrReasonSignMismatch
+	}
+
+	if err := checkActiveProduct(ctx, tx, productID, userID); err != nil {
+		return nil, err
+	}
+	var isKit, isSerialized bool
+	if err := tx.QueryRow(ctx, `SELECT is_kit, is_serialized FROM products WHERE id = $1`, productID).Scan(&isKit, &isSerialized); err != nil {
+		return nil, err
+	}
+	if isKit {
+		return nil, ErrKitNotStocked
+	}
+	// Un ajuste no sabe qué unidades entran o salen: las series quedarían desfasadas del stock
+	if isSerialized {
+		return nil, ErrSerializedProduct
+	}
+	warehouseID, err = resolveWarehouse(ctx, tx, userID, warehouseID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Costo promedio de lo que hay en stock, para valorizar el ajuste
+	var unitCost float64
+	const qCost = `SELECT COALESCE(SUM(value) / NULLIF(SUM(quantity), 0), 0) FROM cost_entries WHERE product_id = $1`
+	if err := tx.QueryRow(ctx, qCost, productID).Scan(&unitCost); err != nil {
+		return nil, err
+	}
+
+	a := &StockAdjustment{
+		UserID:         userID,
+		ProductID:      productID,
+		WarehouseID:    warehouseID,
+		ReasonID:       reason.ID,
+		ReasonCode:     reason.Code,
+		QuantityChange: quantityChange,
+		UnitCost:       roundValue(unitCost),
+		Note:           strings.TrimSpace(note),
+		Status:         AdjustmentApplied,
+		RequestedBy:    userID,
+	}
+	a.Value = roundValue(a.QuantityChange * a.UnitCost)
+	if policy.requiresApproval(a.QuantityChange, a.Value) {
+		a.Status = AdjustmentPending
+	}
+
+	const insert = `
+		INSERT INTO stock_adjustments (user_id, product_id, warehouse_id, reason_id, quantity_change, unit_cost, note, status, requested_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $1)
+		RETURNING id, requested_at, (SELECT sku FROM products WHERE id = $2), (SELECT name FROM products WHERE id = $2)`
+	if err := tx.QueryRow(ctx, insert, userID, productID, warehouseID, reason.ID, a.QuantityChange, a.UnitCost, a.Note, a.Status).
+		Scan(&a.ID, &a.RequestedAt, &a.ProductSKU, &a.ProductName); err != nil {
+		return nil, err
+	}
+
+	if a.Status == AdjustmentApplied {
+		if err := postAdjustment(ctx, tx, a); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	tx = nil
+	return a, nil
+}
+
+// postAdjustment registra el movimiento MANUAL_ADJUSTMENT de un ajuste, con el ajuste como referencia.
+func postAdjustment(ctx context.Context, tx pgx.Tx, a *StockAdjustment) error {
+	reference := strconv.FormatInt(a.ID, 10)
+	_, err := applyStockChange(ctx, tx, stockChange{
+		ProductID:      a.ProductID,
+		WarehouseID:    a.WarehouseID,
+		UserID:         a.UserID,
+		QuantityChange: a.QuantityChange,
+		Reason:         ReasonManualAdjustment,
+		ReferenceID:    &reference,
+	})
+	return err
+}
+
+// StockAdjustmentModel wraps DB access for manual stock adjustments.
+type StockAdjustmentModel struct {
+	DB *pgxpool.Pool
+}
+
+const selectStockAdjustment = `
+	SELECT a.id, a.user_id, a.product_id, p.sku, p.name, a.warehouse_id, a.reason_id, r.code,
+		a.quantity_change, a.unit_cost, a.note, a.status, a.requested_by, a.requested_at, a.approved_by, a.approved_at
+	FROM stock_adjustments a
+	JOIN products p ON p.id = a.product_id
+	JOIN adjustment_reasons r ON r.id = a.reason_id`
+
+func scanStockAdjustment(row pgx.Row, a *StockAdjustment) error {
+	err := row.Scan(&a.ID, &a.UserID, &a.ProductID, &a.ProductSKU, &a.ProductName, &a.WarehouseID, &a.ReasonID, &a.ReasonCode,
+		&a.QuantityChange, &a.UnitCost, &a.Note, &a.Status, &a.RequestedBy, &a.RequestedAt, &a.ApprovedBy, &a.ApprovedAt)
+	if err != nil {
+		return err
+	}
+	a.Value = roundValue(a.QuantityChange * a.UnitCost)
+	return nil
+}
+
+func (m *StockAdjustmentModel) list(where string, args ...any) ([]StockAdjustment, error) {
+	rows, err := m.DB.Query(context.Background(), selectStockAdjustment+where+` ORDER BY a.id DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []StockAdjustment{}
+	for rows.Next() {
+		var a StockAdjustment
+		if err := scanStockAdjustment(rows, &a); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return out, nil
+}
+
+// GetAllForUser devuelve los ajustes del usuario, los más nuevos primero; status "" no filtra.
+func (m *StockAdjustmentModel) GetAllForUser(userID int64, status string) ([]StockAdjustment, error) {
+	return m.list(` WHERE a.user_id = $1 AND ($2::text = '' OR a.status = $2)`, userID, status)
+}
+
+// GetPendingApproval devuelve los ajustes de todos los usuarios que esperan aprobación de un admin.
+func (m *StockAdjustmentModel) GetPendingApproval() ([]StockAdjustment, error) {
+	return m.list(` WHERE a.status = $1`, AdjustmentPending)
+}
+
+// Approve aprueba un ajuste pendiente (de cualquier usuario) y registra su movimiento; con reject
+// lo rechaza sin mover stock. En ambos casos queda registrado el admin que lo resolvió.
+func (m *StockAdjustmentModel) Approve(id int64, adminID int64, reject bool) (*StockAdjustment, error) {
+	ctx := context.Background()
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if tx != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	var a StockAdjustment
+	if err := scanStockAdjustment(tx.QueryRow(ctx, selectStockAdjustment+` WHERE a.id = $1 FOR UPDATE OF a`, id), &a); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if a.Status != AdjustmentPending {
+		return nil, ErrInvalidAdjustmentStatus
+	}
+
+	a.Status = AdjustmentRejected
+	if !reject {
+		a.Status = AdjustmentApproved
+		if err := postAdjustment(ctx, tx, &a); err != nil {
+			return nil, err
+		}
+	}
+
+	const upd = `
+		UPDATE stock_adjustments SET status = $1, approved_by = $2, approved_at = NOW()
+		WHERE id = $3
+		RETURNING approved_by, approved_at`
+	if err := tx.QueryRow(ctx, upd, a.Status, adminID, id).Scan(&a.ApprovedBy, &a.ApprovedAt); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	tx = nil
+	return &a, nil
+}
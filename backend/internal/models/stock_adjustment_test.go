@@ -0,0 +1,41 @@
+package models
+
+import "testing"
+
+func TestAdjustmentReasonAllows(t *testing.T) {
+	cases := []struct {
+		sign string
+		qty  float64
+		want bool
+	}{
+		{ReasonSignNegative, -3, true},
+		{ReasonSignNegative, 3, false},
+		{ReasonSignPositive, 3, true},
+		{ReasonSignPositive, -3, false},
+		{ReasonSignAny, -3, true},
+		{ReasonSignAny, 3, true},
+		{ReasonSignAny, 0, false},
+	}
+	for _, c := range cases {
+		r := AdjustmentReason{Sign: c.sign}
+		if got := r.Allows(c.qty); got != c.want {
+			t.Errorf("%s reason with %v: expected %t, got %t", c.sign, c.qty, c.want, got)
+		}
+	}
+}
+
+func TestAdjustmentPolicyRequiresApproval(t *testing.T) {
+	p := AdjustmentPolicy{MaxQuantity: 10, MaxValue: 1000}
+	if p.requiresApproval(-10, -900) {
+		t.Fatal("adjustment within both limits should not require approval")
+	}
+	if !p.requiresApproval(-11, -100) {
+		t.Fatal("adjustment above the quantity limit should require approval")
+	}
+	if !p.requiresApproval(5, 1500) {
+		t.Fatal("adjustment above the value limit should require approval")
+	}
+	if (AdjustmentPolicy{}).requiresApproval(1e6, 1e9) {
+		t.Fatal("zero limits should never require approval")
+	}
+}
@@ -2,8 +2,10 @@ package models
 
 import (
 	"context"
+	"errors"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -11,16 +13,27 @@ import (
 // quantity_change > 0 para entradas; < 0 para salidas.
 // reason: 'SALES_ORDER', 'PURCHASE_ORDER', 'MANUAL_ADJUSTMENT', etc.
 // reference_id puede contener el ID de la orden asociada (opcional).
+// warehouse_id indica el depósito donde ocurrió el movimiento.
 type StockMovement struct {
 	ID             int64     `json:"id"`
 	ProductID      int64     `json:"product_id"`
-	QuantityChange int       `json:"quantity_change"`
+	WarehouseID    int64     `json:"warehouse_id"`
+	QuantityChange float64   `json:"quantity_change"`
 	Reason         string    `json:"reason"`
 	ReferenceID    string    `json:"reference_id"`
 	UserID         int64     `json:"user_id"`
 	CreatedAt      time.Time `json:"created_at"`
 }
 
+// Motivos de movimiento que registra el propio sistema
+const (
+	ReasonManualAdjustment = "MANUAL_ADJUSTMENT"
+	// ReasonOpeningBalance es el saldo inicial de un producto (alta con cantidad o reconciliación de un producto sin movimientos).
+	ReasonOpeningBalance = "OPENING_BALANCE"
+	// ReasonReconciliation corrige la diferencia entre el ledger y products.quantity.
+	ReasonReconciliation = "RECONCILIATION"
+)
+
 // StockMovementModel para acceso a datos de movimientos
 type StockMovementModel struct {
 	DB *pgxpool.Pool
@@ -29,7 +42,7 @@ type StockMovementModel struct {
 // GetForProduct devuelve los movimientos de stock de un producto para el usuario
 func (m *StockMovementModel) GetForProduct(productID int64, userID int64) ([]StockMovement, error) {
 	const q = `
-		SELECT id, product_id, quantity_change, reason, reference_id, user_id, created_at
+		SELECT id, product_id, COALESCE(warehouse_id, 0), quantity_change, reason, COALESCE(reference_id, ''), user_id, created_at
 		FROM stock_movements
 		WHERE product_id = $1 AND user_id = $2
 		ORDER BY created_at`
@@ -43,7 +56,7 @@ func (m *StockMovementModel) GetForProduct(productID int64, userID int64) ([]Sto
 	out := []StockMovement{} // Initialize as empty slice instead of nil
 	for rows.Next() {
 		var sm StockMovement
-		if err := rows.Scan(&sm.ID, &sm.ProductID, &sm.QuantityChange, &sm.Reason, &sm.ReferenceID, &sm.UserID, &sm.CreatedAt); err != nil {
+		if err := rows.Scan(&sm.ID, &sm.ProductID, &sm.WarehouseID, &sm.QuantityChange, &sm.Reason, &sm.ReferenceID, &sm.UserID, &sm.CreatedAt); err != nil {
 			return nil, err
 		}
 		out = append(out, sm)
@@ -53,3 +66,173 @@ func (m *StockMovementModel) GetForProduct(productID int64, userID int64) ([]Sto
 	}
 	return out, nil
 }
+
+// ProductStockAsOf es la cantidad de un producto a una fecha, reconstruida desde el ledger.
+type ProductStockAsOf struct {
+	ProductID int64   `json:"product_id"`
+	SKU       string  `json:"sku"`
+	Name      string  `json:"name"`
+	Quantity  float64 `json:"quantity"`
+}
+
+// GetStockAsOf reconstruye la cantidad de los productos del usuario sumando los movimientos
+// registrados antes de asOf (exclusive). Si productID o warehouseID son distintos de 0 se limita
+// a ese producto o depósito. Los kits no tienen movimientos propios y no se incluyen.
+func (m *StockMovementModel) GetStockAsOf(userID int64, asOf time.Time, productID int64, warehouseID int64) ([]ProductStockAsOf, error) {
+	const q = `
+		SELECT p.id, p.sku, p.name, COALESCE(SUM(sm.quantity_change), 0)
+		FROM products p
+		LEFT JOIN stock_movements sm ON sm.product_id = p.id AND sm.created_at < $2
+			AND ($4::bigint = 0 OR sm.warehouse_id = $4)
+		WHERE p.user_id = $1 AND NOT p.is_kit AND ($3::bigint = 0 OR p.id = $3)
+		GROUP BY p.id, p.sku, p.name
+		ORDER BY p.sku`
+
+	rows, err := m.DB.Query(context.Background(), q, userID, asOf, productID, warehouseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []ProductStockAsOf{}
+	for rows.Next() {
+		var s ProductStockAsOf
+		if err := rows.Scan(&s.ProductID, &s.SKU, &s.Name, &s.Quantity); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	if productID != 0 && len(out) == 0 {
+		return nil, ErrNotFound
+	}
+	return out, nil
+}
+
+// KardexEntry es un movimiento del Kardex con el saldo acumulado después de aplicarlo.
+type KardexEntry struct {
+	StockMovement
+	In      float64 `json:"in"`
+	Out     float64 `json:"out"`
+	Balance float64 `json:"balance"`
+}
+
+// Kardex es la ficha de stock de un producto en un período: saldo inicial, movimientos y saldo final.
+type Kardex struct {
+	ProductID      int64         `json:"product_id"`
+	SKU            string        `json:"sku"`
+	Name           string        `json:"name"`
+	BaseUnit       string        `json:"base_unit"`
+	WarehouseID    int64         `json:"warehouse_id,omitempty"`
+	From           *time.Time    `json:"from,omitempty"`
+	To             time.Time     `json:"to"`
+	OpeningBalance float64       `json:"opening_balance"`
+	Entries        []KardexEntry `json:"entries"`
+	ClosingBalance float64       `json:"closing_balance"`
+}
+
+// GetKardex arma el Kardex de un producto del usuario entre from (inclusive, nil = desde el inicio)
+// y to (exclusive). Si warehouseID es distinto de 0 sólo considera los movimientos de ese depósito.
+func (m *StockMovementModel) GetKardex(productID int64, userID int64, from *time.Time, to time.Time, warehouseID int64) (*Kardex, error) {
+	ctx := context.Background()
+
+	k := &Kardex{ProductID: productID, WarehouseID: warehouseID, From: from, To: to, Entries: []KardexEntry{}}
+	err := m.DB.QueryRow(ctx, `SELECT sku, name, base_unit FROM products WHERE id = $1 AND user_id = $2`, productID, userID).
+		Scan(&k.SKU, &k.Name, &k.BaseUnit)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	// Saldo inicial: todo lo registrado antes del período
+	if from != nil {
+		const qOpening = `
+			SELECT COALESCE(SUM(quantity_change), 0)
+			FROM stock_movements
+			WHERE product_id = $1 AND created_at < $2 AND ($3::bigint = 0 OR warehouse_id = $3)`
+		if err := m.DB.QueryRow(ctx, qOpening, productID, *from, warehouseID).Scan(&k.OpeningBalance); err != nil {
+			return nil, err
+		}
+	}
+
+	const qMovements = `
+		SELECT id, product_id, COALESCE(warehouse_id, 0), quantity_change, reason, COALESCE(reference_id, ''), user_id, created_at
+		FROM stock_movements
+		WHERE product_id = $1 AND ($2::timestamptz IS NULL OR created_at >= $2) AND created_at < $3
+		  AND ($4::bigint = 0 OR warehouse_id = $4)
+		ORDER BY created_at, id`
+	rows, err := m.DB.Query(ctx, qMovements, productID, from, to, warehouseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	balance := k.OpeningBalance
+	for rows.Next() {
+		var e KardexEntry
+		if err := rows.Scan(&e.ID, &e.ProductID, &e.WarehouseID, &e.QuantityChange, &e.Reason, &e.ReferenceID, &e.UserID, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		if e.QuantityChange >= 0 {
+			e.In = e.QuantityChange
+		} else {
+			e.Out = -e.QuantityChange
+		}
+		balance = roundQuantity(balance + e.QuantityChange)
+		e.Balance = balance
+		k.Entries = append(k.Entries, e)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	k.ClosingBalance = balance
+	return k, nil
+}
+
+// LedgerDrift es la diferencia entre products.quantity y la suma de los movimientos de un producto.
+type LedgerDrift struct {
+	ProductID      int64   `json:"product_id"`
+	SKU            string  `json:"sku"`
+	Name           string  `json:"name"`
+	Quantity       float64 `json:"quantity"`
+	LedgerQuantity float64 `json:"ledger_quantity"`
+	Drift          float64 `json:"drift"`
+	Movements      int64   `json:"movements"`
+}
+
+// GetDrift devuelve los productos del usuario cuya cantidad no coincide con su ledger.
+// Los kits no tienen stock propio y no se incluyen.
+func (m *StockMovementModel) GetDrift(userID int64) ([]LedgerDrift, error) {
+	const q = `
+		SELECT p.id, p.sku, p.name, p.quantity, COALESCE(SUM(sm.quantity_change), 0), COUNT(sm.id)
+		FROM products p
+		LEFT JOIN stock_movements sm ON sm.product_id = p.id
+		WHERE p.user_id = $1 AND NOT p.is_kit
+		GROUP BY p.id, p.sku, p.name, p.quantity
+		HAVING p.quantity <> COALESCE(SUM(sm.quantity_change), 0)
+		ORDER BY p.sku`
+
+	rows, err := m.DB.Query(context.Background(), q, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []LedgerDrift{}
+	for rows.Next() {
+		var d LedgerDrift
+		if err := rows.Scan(&d.ProductID, &d.SKU, &d.Name, &d.Quantity, &d.LedgerQuantity, &d.Movements); err != nil {
+			return nil, err
+		}
+		d.Drift = roundQuantity(d.Quantity - d.LedgerQuantity)
+		out = append(out, d)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return out, nil
+}
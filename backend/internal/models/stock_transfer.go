@@ -0,0 +1,381 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Estados de una transferencia de stock
+const (
+	TransferStatusDraft     = "draft"
+	TransferStatusInTransit = "in_transit"
+	TransferStatusReceived  = "received"
+	TransferStatusCancelled = "cancelled"
+)
+
+// Errors for stock transfer operations
+var (
+	ErrSameWarehouse           = errors.New("source and destination warehouses must differ")
+	ErrInvalidTransferStatus   = errors.New("invalid transfer status transition")
+	ErrTransferProductNotFound = errors.New("transfer product not found")
+)
+
+// StockTransfer es el documento que mueve mercadería de un depósito a otro.
+type StockTransfer struct {
+	ID                int64      `json:"id"`
+	FromWarehouseID   int64      `json:"from_warehouse_id"`
+	FromWarehouseName string     `json:"from_warehouse_name,omitempty"`
+	ToWarehouseID     int64      `json:"to_warehouse_id"`
+	ToWarehouseName   string     `json:"to_warehouse_name,omitempty"`
+	Status            string     `json:"status"`
+	Notes             string     `json:"notes"`
+	UserID            int64      `json:"user_id"`
+	CreatedAt         time.Time  `json:"created_at"`
+	ShippedAt         *time.Time `json:"shipped_at,omitempty"`
+	ReceivedAt        *time.Time `json:"received_at,omitempty"`
+}
+
+// StockTransferItem es una línea de producto de una transferencia.
+type StockTransferItem struct {
+	ID          int64   `json:"id"`
+	TransferID  int64   `json:"transfer_id"`
+	ProductID   int64   `json:"product_id"`
+	ProductName string  `json:"product_name,omitempty"`
+	SKU         string  `json:"sku,omitempty"`
+	Quantity    float64 `json:"quantity"`
+}
+
+// StockTransferModel wraps DB access for stock transfers.
+type StockTransferModel struct {
+	DB *pgxpool.Pool
+}
+
+// Create inserta una transferencia en borrador con sus ítems. No mueve stock. Los productos
+// serializados no se transfieren (ErrSerializedProduct).
+func (m *StockTransferModel) Create(t *StockTransfer, items []StockTransferItem) error {
+	if t.FromWarehouseID == t.ToWarehouseID {
+		return ErrSameWarehouse
+	}
+
+	ctx := context.Background()
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if tx != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	// Ambos depósitos deben existir y pertenecer al usuario
+	if _, err := resolveWarehouse(ctx, tx, t.UserID, t.FromWarehouseID); err != nil {
+		return err
+	}
+	if _, err := resolveWarehouse(ctx, tx, t.UserID, t.ToWarehouseID); err != nil {
+		return err
+	}
+
+	const insertTransfer = `
+		INSERT INTO stock_transfers (from_warehouse_id, to_warehouse_id, status, notes, user_id)
+		VALUES ($1, $2, 'draft', $3, $4)
+		RETURNING id, status, created_at`
+	if err := tx.QueryRow(ctx, insertTransfer, t.FromWarehouseID, t.ToWarehouseID, t.Notes, t.UserID).
+		Scan(&t.ID, &t.Status, &t.CreatedAt); err != nil {
+		return err
+	}
+
+	const checkProduct = `SELECT is_serialized FROM products WHERE id = $1 AND user_id = $2`
+	const insertItem = `
+		INSERT INTO stock_transfer_items (transfer_id, product_id, quantity)
+		VALUES ($1, $2, $3)
+		RETURNING id`
+	for i := range items {
+		var serialized bool
+		if err := tx.QueryRow(ctx, checkProduct, items[i].ProductID, t.UserID).Scan(&serialized); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrTransferProductNotFound
+			}
+			return err
+		}
+		// Las series no registran en qué depósito está cada unidad
+		if serialized {
+			return ErrSerializedProduct
+		}
+
+		items[i].TransferID = t.ID
+		if err := tx.QueryRow(ctx, insertItem, t.ID, items[i].ProductID, items[i].Quantity).Scan(&items[i].ID); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	tx = nil
+	return nil
+}
+
+const selectTransfer = `
+	SELECT t.id, t.from_warehouse_id, wf.name, t.to_warehouse_id, wt.name,
+		t.status, t.notes, t.user_id, t.created_at, t.shipped_at, t.received_at
+	FROM stock_transfers t
+	JOIN warehouses wf ON wf.id = t.from_warehouse_id
+	JOIN warehouses wt ON wt.id = t.to_warehouse_id`
+
+func scanTransfer(row pgx.Row, t *StockTransfer) error {
+	return row.Scan(
+		&t.ID, &t.FromWarehouseID, &t.FromWarehouseName, &t.ToWarehouseID, &t.ToWarehouseName,
+		&t.Status, &t.Notes, &t.UserID, &t.CreatedAt, &t.ShippedAt, &t.ReceivedAt,
+	)
+}
+
+// GetAllForUser returns all stock transfers for the given user, newest first.
+func (m *StockTransferModel) GetAllForUser(userID int64) ([]StockTransfer, error) {
+	rows, err := m.DB.Query(context.Background(), selectTransfer+` WHERE t.user_id = $1 ORDER BY t.id DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []StockTransfer{}
+	for rows.Next() {
+		var t StockTransfer
+		if err := scanTransfer(rows, &t); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return out, nil
+}
+
+// GetByID returns a stock transfer for the user along with its items.
+func (m *StockTransferModel) GetByID(id int64, userID int64) (*StockTransfer, []StockTransferItem, error) {
+	ctx := context.Background()
+
+	var t StockTransfer
+	err := scanTransfer(m.DB.QueryRow(ctx, selectTransfer+` WHERE t.id = $1 AND t.user_id = $2`, id, userID), &t)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil, ErrNotFound
+		}
+		return nil, nil, err
+	}
+
+	items, err := loadTransferItems(ctx, m.DB, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &t, items, nil
+}
+
+func loadTransferItems(ctx context.Context, q dbtx, transferID int64) ([]StockTransferItem, error) {
+	const qItems = `
+		SELECT ti.id, ti.transfer_id, ti.product_id, p.name, p.sku, ti.quantity
+		FROM stock_transfer_items ti
+		JOIN products p ON p.id = ti.product_id
+		WHERE ti.transfer_id = $1
+		ORDER BY ti.id`
+	rows, err := q.Query(ctx, qItems, transferID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []StockTransferItem{}
+	for rows.Next() {
+		var it StockTransferItem
+		if err := rows.Scan(&it.ID, &it.TransferID, &it.ProductID, &it.ProductName, &it.SKU, &it.Quantity); err != nil {
+			return nil, err
+		}
+		items = append(items, it)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return items, nil
+}
+
+// UpdateStatus avanza el estado de una transferencia y mueve el stock de cada tramo. La mercadería
+// despachada queda en la ubicación de tránsito del usuario, así el total del producto no baja mientras
+// está en camino:
+//   - draft → in_transit: TRANSFER_OUT del depósito origen y TRANSFER_IN en tránsito
+//   - in_transit → received: TRANSFER_OUT de tránsito y TRANSFER_IN en el depósito destino
+//   - in_transit → cancelled: TRANSFER_OUT de tránsito y TRANSFER_IN de vuelta en el depósito origen
+//   - draft → cancelled: sin movimientos
+func (m *StockTransferModel) UpdateStatus(id int64, userID int64, newStatus string) error {
+	ctx := context.Background()
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if tx != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	const qTransfer = `
+		SELECT status, from_warehouse_id, to_warehouse_id
+		FROM stock_transfers
+		WHERE id = $1 AND user_id = $2
+		FOR UPDATE`
+	var current string
+	var fromID, toID int64
+	if err := tx.QueryRow(ctx, qTransfer, id, userID).Scan(&current, &fromID, &toID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	var transitID int64
+	if current == TransferStatusInTransit || newStatus == TransferStatusInTransit {
+		if transitID, err = transitWarehouse(ctx, tx, userID); err != nil {
+			return err
+		}
+	}
+
+	var srcID, dstID int64
+	var stamp string
+	switch {
+	case current == TransferStatusDraft && newStatus == TransferStatusInTransit:
+		srcID, dstID, stamp = fromID, transitID, "shipped_at = NOW(),"
+	case current == TransferStatusInTransit && newStatus == TransferStatusReceived:
+		srcID, dstID, stamp = transitID, toID, "received_at = NOW(),"
+	case current == TransferStatusInTransit && newStatus == TransferStatusCancelled:
+		srcID, dstID = transitID, fromID
+	case current == TransferStatusDraft && newStatus == TransferStatusCancelled:
+		// Sin movimientos: el stock nunca salió del origen
+	default:
+		return ErrInvalidTransferStatus
+	}
+
+	if srcID != 0 {
+		items, err := loadTransferItems(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		reference := fmt.Sprintf("%d", id)
+		for _, it := range items {
+			out := stockChange{
+				ProductID:      it.ProductID,
+				WarehouseID:    srcID,
+				UserID:         userID,
+				QuantityChange: -it.Quantity,
+				Reason:         "TRANSFER_OUT",
+				ReferenceID:    &reference,
+			}
+			in := out
+			in.WarehouseID, in.QuantityChange, in.Reason = dstID, it.Quantity, "TRANSFER_IN"
+
+			if srcID == fromID {
+				// Despacho: sale del origen por FEFO y los lotes consumidos quedan registrados en la línea
+				lots, err := applyStockChange(ctx, tx, out)
+				if err != nil {
+					return err
+				}
+				const insertItemLot = `
+					INSERT INTO stock_transfer_item_lots (transfer_item_id, lot_id, quantity)
+					VALUES ($1, $2, $3)`
+				for _, l := range lots {
+					if _, err := tx.Exec(ctx, insertItemLot, it.ID, l.LotID, l.Quantity); err != nil {
+						return err
+					}
+				}
+				in.Lots = lots
+			} else {
+				// De tránsito salen los mismos lotes que se despacharon (al cancelar vuelven a sus
+				// propios lotes del origen)
+				lots, err := loadTransferItemLots(ctx, tx, it.ID)
+				if err != nil {
+					return err
+				}
+				out.Lots = lots
+				if _, err := applyStockChange(ctx, tx, out); err != nil {
+					return err
+				}
+				in.Lots = lots
+			}
+			// Los lotes cambian de depósito: no es una recepción nueva
+			in.MoveLots = true
+
+			if _, err := applyStockChange(ctx, tx, in); err != nil {
+				return err
+			}
+		}
+	}
+
+	upd := `UPDATE stock_transfers SET ` + stamp + ` status = $1 WHERE id = $2`
+	if _, err := tx.Exec(ctx, upd, newStatus, id); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	tx = nil
+	return nil
+}
+
+// transitWarehouse devuelve la ubicación de tránsito del usuario, creándola si todavía no existe.
+func transitWarehouse(ctx context.Context, q dbtx, userID int64) (int64, error) {
+	const qTransit = `SELECT id FROM warehouses WHERE user_id = $1 AND is_transit`
+	var id int64
+	err := q.QueryRow(ctx, qTransit, userID).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return 0, err
+	}
+
+	const insertTransit = `
+		INSERT INTO warehouses (name, code, is_transit, user_id)
+		VALUES ('En tránsito', 'TRANSITO', true, $1)
+		ON CONFLICT DO NOTHING`
+	if _, err := q.Exec(ctx, insertTransit, userID); err != nil {
+		return 0, err
+	}
+	if err := q.QueryRow(ctx, qTransit, userID).Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// loadTransferItemLots devuelve los lotes despachados en una línea de transferencia.
+func loadTransferItemLots(ctx context.Context, q dbtx, transferItemID int64) ([]LotAllocation, error) {
+	const qLots = `
+		SELECT l.id, l.lot_number, l.expiry_date, til.quantity, l.purchase_order_id
+		FROM stock_transfer_item_lots til
+		JOIN product_lots l ON l.id = til.lot_id
+		WHERE til.transfer_item_id = $1
+		ORDER BY til.id`
+	rows, err := q.Query(ctx, qLots, transferItemID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lots []LotAllocation
+	for rows.Next() {
+		var l LotAllocation
+		if err := rows.Scan(&l.LotID, &l.LotNumber, &l.ExpiryDate, &l.Quantity, &l.PurchaseOrderID); err != nil {
+			return nil, err
+		}
+		lots = append(lots, l)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return lots, nil
+}
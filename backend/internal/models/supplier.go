@@ -20,6 +20,9 @@ type Supplier struct {
 	Address       string    `json:"address,omitempty"`
 	UserID        int64     `json:"user_id"`
 	CreatedAt     time.Time `json:"created_at"`
+
+	// ArchivedAt marca un proveedor archivado: no se lista ni se usa en órdenes nuevas, pero sigue en el historial.
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
 }
 
 // SupplierModel wraps DB access for suppliers.
@@ -41,13 +44,13 @@ func (m *SupplierModel) Insert(s *Supplier) error {
 // GetByID returns a supplier by ID if it belongs to the user.
 func (m *SupplierModel) GetByID(id int64, userID int64) (*Supplier, error) {
 	const q = `
-		SELECT id, name, contact_person, email, phone, address, user_id, created_at
+		SELECT id, name, contact_person, email, phone, address, user_id, created_at, archived_at
 		FROM suppliers
 		WHERE id = $1 AND user_id = $2`
 
 	var s Supplier
 	err := m.DB.QueryRow(context.Background(), q, id, userID).Scan(
-		&s.ID, &s.Name, &s.ContactPerson, &s.Email, &s.Phone, &s.Address, &s.UserID, &s.CreatedAt,
+		&s.ID, &s.Name, &s.ContactPerson, &s.Email, &s.Phone, &s.Address, &s.UserID, &s.CreatedAt, &s.ArchivedAt,
 	)
 	if err != nil {
 		if err == pgx.ErrNoRows {
@@ -58,15 +61,16 @@ func (m *SupplierModel) GetByID(id int64, userID int64) (*Supplier, error) {
 	return &s, nil
 }
 
-// GetAllForUser lists all suppliers for a user.
-func (m *SupplierModel) GetAllForUser(userID int64) ([]Supplier, error) {
+// GetAllForUser lists the suppliers of a user; archived lists which ones by archive state.
+func (m *SupplierModel) GetAllForUser(userID int64, archived ArchiveFilter) ([]Supplier, error) {
 	const q = `
-		SELECT id, name, contact_person, email, phone, address, user_id, created_at
+		SELECT id, name, contact_person, email, phone, address, user_id, created_at, archived_at
 		FROM suppliers
 		WHERE user_id = $1
+			AND ($2::text = 'include' OR (archived_at IS NOT NULL) = ($2::text = 'only'))
 		ORDER BY id`
 
-	rows, err := m.DB.Query(context.Background(), q, userID)
+	rows, err := m.DB.Query(context.Background(), q, userID, string(archived))
 	if err != nil {
 		return nil, err
 	}
@@ -75,7 +79,7 @@ func (m *SupplierModel) GetAllForUser(userID int64) ([]Supplier, error) {
 	out := []Supplier{} // Initialize as empty slice instead of nil
 	for rows.Next() {
 		var s Supplier
-		if err := rows.Scan(&s.ID, &s.Name, &s.ContactPerson, &s.Email, &s.Phone, &s.Address, &s.UserID, &s.CreatedAt); err != nil {
+		if err := rows.Scan(&s.ID, &s.Name, &s.ContactPerson, &s.Email, &s.Phone, &s.Address, &s.UserID, &s.CreatedAt, &s.ArchivedAt); err != nil {
 			return nil, err
 		}
 		out = append(out, s)
@@ -93,14 +97,17 @@ func (m *SupplierModel) Update(id int64, userID int64, s *Supplier) error {
 		SET name = $1, contact_person = $2, email = $3, phone = $4, address = $5
 		WHERE id = $6 AND user_id = $7`
 
-	tag, err := m.DB.Exec(context.Background(), q, s.Name, s.ContactPerson, s.Email, s.Phone, s.Address, id, userID)
-	if err != nil {
-		return err
-	}
-	if tag.RowsAffected() == 0 {
-		return ErrNotFound
-	}
-	return nil
+	return updateAs(context.Background(), m.DB, userID, q, s.Name, s.ContactPerson, s.Email, s.Phone, s.Address, id, userID)
+}
+
+// Archive archiva un proveedor; sus órdenes anteriores no cambian.
+func (m *SupplierModel) Archive(id int64, userID int64) error {
+	return archiveAs(context.Background(), m.DB, archivableSuppliers, id, userID, true)
+}
+
+// Restore vuelve a activar un proveedor archivado.
+func (m *SupplierModel) Restore(id int64, userID int64) error {
+	return archiveAs(context.Background(), m.DB, archivableSuppliers, id, userID, false)
 }
 
 // Delete deletes a supplier if it belongs to the user.
@@ -0,0 +1,147 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DefaultBaseUnit es la unidad base de los productos que no indican otra.
+const DefaultBaseUnit = "unidad"
+
+// quantityScale es la precisión de las columnas NUMERIC(18,3) de cantidades.
+const quantityScale = 1000
+
+// Errors for unit of measure operations
+var (
+	ErrUnknownUnit   = errors.New("unknown unit of measure for product")
+	ErrDuplicateUnit = errors.New("duplicate unit of measure")
+)
+
+// ProductUnit es una unidad alternativa de un producto (caja, pack, docena...).
+// Factor indica cuántas unidades base contiene una unidad.
+type ProductUnit struct {
+	ID        int64     `json:"id"`
+	ProductID int64     `json:"product_id"`
+	Name      string    `json:"name"`
+	Factor    float64   `json:"factor"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// roundQuantity redondea una cantidad a la precisión con la que se guarda en la base.
+func roundQuantity(q float64) float64 {
+	return math.Round(q*quantityScale) / quantityScale
+}
+
+// resolveUnit devuelve el nombre y el factor de conversión a la unidad base de una unidad del producto.
+// Una unidad vacía o igual a la unidad base tiene factor 1.
+func resolveUnit(ctx context.Context, q dbtx, productID int64, unit string) (string, float64, error) {
+	var baseUnit string
+	if err := q.QueryRow(ctx, `SELECT base_unit FROM products WHERE id = $1`, productID).Scan(&baseUnit); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", 0, ErrNotFound
+		}
+		return "", 0, err
+	}
+	if unit == "" || unit == baseUnit {
+		return baseUnit, 1, nil
+	}
+
+	var factor float64
+	err := q.QueryRow(ctx, `SELECT factor FROM product_units WHERE product_id = $1 AND name = $2`, productID, unit).Scan(&factor)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", 0, ErrUnknownUnit
+		}
+		return "", 0, err
+	}
+	return unit, factor, nil
+}
+
+// UnitModel wraps DB access for product units of measure.
+type UnitModel struct {
+	DB *pgxpool.Pool
+}
+
+// GetForProduct devuelve las unidades alternativas de un producto del usuario.
+func (m *UnitModel) GetForProduct(productID int64, userID int64) ([]ProductUnit, error) {
+	const q = `
+		SELECT u.id, u.product_id, u.name, u.factor, u.created_at
+		FROM product_units u
+		JOIN products p ON p.id = u.product_id
+		WHERE u.product_id = $1 AND p.user_id = $2
+		ORDER BY u.factor, u.name`
+
+	rows, err := m.DB.Query(context.Background(), q, productID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []ProductUnit{}
+	for rows.Next() {
+		var u ProductUnit
+		if err := rows.Scan(&u.ID, &u.ProductID, &u.Name, &u.Factor, &u.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, u)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return out, nil
+}
+
+// Insert agrega una unidad alternativa a un producto del usuario.
+// No puede llamarse igual que la unidad base.
+func (m *UnitModel) Insert(userID int64, u *ProductUnit) error {
+	ctx := context.Background()
+
+	var baseUnit string
+	err := m.DB.QueryRow(ctx, `SELECT base_unit FROM products WHERE id = $1 AND user_id = $2`, u.ProductID, userID).Scan(&baseUnit)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+	if u.Name == baseUnit {
+		return ErrDuplicateUnit
+	}
+
+	const q = `
+		INSERT INTO product_units (product_id, name, factor)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at`
+
+	if err := m.DB.QueryRow(ctx, q, u.ProductID, u.Name, u.Factor).Scan(&u.ID, &u.CreatedAt); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique_violation (product_id, name)
+			return ErrDuplicateUnit
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete elimina una unidad alternativa. Las líneas ya cargadas conservan su unidad y factor.
+func (m *UnitModel) Delete(productID int64, unitID int64, userID int64) error {
+	const q = `
+		DELETE FROM product_units u
+		USING products p
+		WHERE u.id = $1 AND u.product_id = $2 AND p.id = u.product_id AND p.user_id = $3`
+
+	tag, err := m.DB.Exec(context.Background(), q, unitID, productID, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Métodos de costeo de inventario de una cuenta
+const (
+	CostingFIFO            = "fifo"
+	CostingWeightedAverage = "wac"
+)
+
+// ErrInvalidCostingMethod is returned when the costing method is not fifo or wac.
+var ErrInvalidCostingMethod = errors.New("invalid costing method")
+
+// valueScale es la precisión con la que se guardan los valores de inventario (4 decimales).
+const valueScale = 10000
+
+// roundValue redondea un valor monetario a la precisión de cost_entries.value.
+func roundValue(v float64) float64 {
+	return math.Round(v*valueScale) / valueScale
+}
+
+// costedReason indica si un movimiento afecta el valor del inventario. Las transferencias
+// mueven mercadería entre depósitos sin cambiar su costo.
+func costedReason(reason string) bool {
+	return reason != "TRANSFER_OUT" && reason != "TRANSFER_IN"
+}
+
+// costingMethod devuelve el método de costeo de la cuenta del usuario.
+func costingMethod(ctx context.Context, q dbtx, userID int64) (string, error) {
+	var method string
+	err := q.QueryRow(ctx, `SELECT costing_method FROM users WHERE id = $1`, userID).Scan(&method)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return CostingFIFO, nil
+	}
+	return method, err
+}
+
+// addCostLayer registra una entrada valorizada: crea la capa de costo y suma su valor al ledger.
+func addCostLayer(ctx context.Context, q dbtx, c stockChange, unitCost float64) error {
+	const insertLayer = `
+		INSERT INTO cost_layers (user_id, product_id, purchase_order_id, unit_cost, quantity, remaining)
+		VALUES ($1, $2, $3, $4, $5, $5)`
+	if _, err := q.Exec(ctx, insertLayer, c.UserID, c.ProductID, c.PurchaseOrderID, unitCost, c.QuantityChange); err != nil {
+		return err
+	}
+
+	const insertEntry = `
+		INSERT INTO cost_entries (user_id, product_id, quantity, value, reason, reference_id)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+	_, err := q.Exec(ctx, insertEntry, c.UserID, c.ProductID, c.QuantityChange, roundValue(c.QuantityChange*unitCost), c.Reason, c.ReferenceID)
+	return err
+}
+
+// consumeCostLayers da de baja qty unidades de las capas del producto (la más antigua primero) y
+// registra el valor de la salida según el método de la cuenta: FIFO valoriza con el costo de las
+// capas consumidas y el promedio ponderado con el costo medio del stock valorizado.
+// La parte de la salida que no cubren las capas no tiene costo. Devuelve el valor de la salida.
+func consumeCostLayers(ctx context.Context, q dbtx, c stockChange, qty float64) (float64, error) {
+	const qLayers = `
+		SELECT id, remaining, unit_cost
+		FROM cost_layers
+		WHERE product_id = $1 AND remaining > 0
+		ORDER BY received_at, id
+		FOR UPDATE`
+
+	rows, err := q.Query(ctx, qLayers, c.ProductID)
+	if err != nil {
+		return 0, err
+	}
+
+	type consumption struct {
+		layerID  int64
+		qty      float64
+		unitCost float64
+	}
+	var consumed []consumption
+	remaining := qty
+	for remaining > 0 && rows.Next() {
+		var l consumption
+		var available float64
+		if err := rows.Scan(&l.layerID, &available, &l.unitCost); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		l.qty = min(available, remaining)
+		remaining = roundQuantity(remaining - l.qty)
+		consumed = append(consumed, l)
+	}
+	rows.Close()
+	if rows.Err() != nil {
+		return 0, rows.Err()
+	}
+	if len(consumed) == 0 {
+		return 0, nil
+	}
+
+	method, err := costingMethod(ctx, q, c.UserID)
+	if err != nil {
+		return 0, err
+	}
+
+	var costedQty, value float64
+	if method == CostingWeightedAverage {
+		// Costo medio antes de la salida: valor total / cantidad valorizada del ledger
+		var ledgerQty, ledgerValue float64
+		const qAvg = `SELECT COALESCE(SUM(quantity), 0), COALESCE(SUM(value), 0) FROM cost_entries WHERE product_id = $1`
+		if err := q.QueryRow(ctx, qAvg, c.ProductID).Scan(&ledgerQty, &ledgerValue); err != nil {
+			return 0, err
+		}
+		for _, l := range consumed {
+			costedQty += l.qty
+		}
+		if ledgerQty > 0 {
+			value = costedQty * ledgerValue / ledgerQty
+		}
+	} else {
+		for _, l := range consumed {
+			costedQty += l.qty
+			value += l.qty * l.unitCost
+		}
+	}
+	costedQty = roundQuantity(costedQty)
+	value = roundValue(value)
+
+	const dec = `UPDATE cost_layers SET remaining = remaining - $1 WHERE id = $2`
+	for _, l := range consumed {
+		if _, err := q.Exec(ctx, dec, l.qty, l.layerID); err != nil {
+			return 0, err
+		}
+	}
+
+	const insertEntry = `
+		INSERT INTO cost_entries (user_id, product_id, quantity, value, reason, reference_id, order_item_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	if _, err := q.Exec(ctx, insertEntry, c.UserID, c.ProductID, -costedQty, -value, c.Reason, c.ReferenceID, c.OrderItemID); err != nil {
+		return 0, err
+	}
+	return value, nil
+}
+
+// ProductValuation es el stock valorizado de un producto a una fecha.
+type ProductValuation struct {
+	ProductID int64   `json:"product_id"`
+	SKU       string  `json:"sku"`
+	Name      string  `json:"name"`
+	Quantity  float64 `json:"quantity"`
+	UnitCost  float64 `json:"unit_cost"`
+	Value     float64 `json:"value"`
+}
+
+// Valuation es la valorización del inventario de la cuenta a una fecha.
+type Valuation struct {
+	AsOf       time.Time          `json:"as_of"`
+	Method     string             `json:"method"`
+	TotalValue float64            `json:"total_value"`
+	Products   []ProductValuation `json:"products"`
+}
+
+// ValuationModel wraps DB access for inventory valuation.
+type ValuationModel struct {
+	DB *pgxpool.Pool
+}
+
+// GetMethod devuelve el método de costeo de la cuenta.
+func (m *ValuationModel) GetMethod(userID int64) (string, error) {
+	return costingMethod(context.Background(), m.DB, userID)
+}
+
+// SetMethod cambia el método de costeo de la cuenta. Sólo afecta a las salidas posteriores.
+func (m *ValuationModel) SetMethod(userID int64, method string) error {
+	if method != CostingFIFO && method != CostingWeightedAverage {
+		return ErrInvalidCostingMethod
+	}
+	return updateAs(context.Background(), m.DB, userID, `UPDATE users SET costing_method = $1 WHERE id = $2`, method, userID)
+}
+
+// GetAsOf devuelve el stock valorizado de cada producto con todo lo registrado hasta asOf (exclusive).
+func (m *ValuationModel) GetAsOf(userID int64, asOf time.Time) (*Valuation, error) {
+	ctx := context.Background()
+
+	method, err := costingMethod(ctx, m.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	const q = `
+		SELECT p.id, p.sku, p.name, COALESCE(SUM(ce.quantity), 0), COALESCE(SUM(ce.value), 0)
+		FROM products p
+		LEFT JOIN cost_entries ce ON ce.product_id = p.id AND ce.created_at < $2
+		WHERE p.user_id = $1 AND NOT p.is_kit
+		GROUP BY p.id, p.sku, p.name
+		ORDER BY p.sku`
+	rows, err := m.DB.Query(ctx, q, userID, asOf)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	v := &Valuation{AsOf: asOf, Method: method, Products: []ProductValuation{}}
+	for rows.Next() {
+		var p ProductValuation
+		if err := rows.Scan(&p.ProductID, &p.SKU, &p.Name, &p.Quantity, &p.Value); err != nil {
+			return nil, err
+		}
+		if p.Quantity > 0 {
+			p.UnitCost = roundValue(p.Value / p.Quantity)
+		}
+		v.TotalValue += p.Value
+		v.Products = append(v.Products, p)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	v.TotalValue = roundValue(v.TotalValue)
+	return v, nil
+}
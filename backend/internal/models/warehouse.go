@@ -0,0 +1,230 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Warehouse representa un depósito o ubicación física donde se guarda stock.
+type Warehouse struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Code      string    `json:"code"`
+	Address   string    `json:"address,omitempty"`
+	IsDefault bool      `json:"is_default"`
+	UserID    int64     `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WarehouseStock representa la cantidad de un producto en un depósito.
+type WarehouseStock struct {
+	ProductID   int64   `json:"product_id"`
+	ProductName string  `json:"product_name"`
+	SKU         string  `json:"sku"`
+	Quantity    float64 `json:"quantity"`
+}
+
+// Errors for warehouse operations
+var (
+	ErrWarehouseNotFound   = errors.New("warehouse not found")
+	ErrDuplicateWarehouse  = errors.New("duplicate warehouse code")
+	ErrWarehouseNotEmpty   = errors.New("warehouse still has stock")
+	ErrDefaultWarehouseDel = errors.New("cannot delete the default warehouse")
+)
+
+// WarehouseModel wraps DB access for warehouses.
+type WarehouseModel struct {
+	DB *pgxpool.Pool
+}
+
+// Insert crea un depósito. El primer depósito de un usuario queda como depósito por defecto.
+func (m *WarehouseModel) Insert(wh *Warehouse) error {
+	const q = `
+		INSERT INTO warehouses (name, code, address, is_default, user_id)
+		VALUES ($1, $2, $3, NOT EXISTS (SELECT 1 FROM warehouses WHERE user_id = $4 AND NOT is_transit), $4)
+		RETURNING id, is_default, created_at`
+
+	err := m.DB.QueryRow(context.Background(), q, wh.Name, wh.Code, wh.Address, wh.UserID).
+		Scan(&wh.ID, &wh.IsDefault, &wh.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique_violation (user_id, code)
+			return ErrDuplicateWarehouse
+		}
+		return err
+	}
+	return nil
+}
+
+// GetByID returns a warehouse by ID for a given user.
+// La ubicación de tránsito de las transferencias no es un depósito operable y no se devuelve.
+func (m *WarehouseModel) GetByID(id int64, userID int64) (*Warehouse, error) {
+	const q = `
+		SELECT id, name, code, address, is_default, user_id, created_at
+		FROM warehouses
+		WHERE id = $1 AND user_id = $2 AND NOT is_transit`
+
+	var wh Warehouse
+	err := m.DB.QueryRow(context.Background(), q, id, userID).Scan(
+		&wh.ID, &wh.Name, &wh.Code, &wh.Address, &wh.IsDefault, &wh.UserID, &wh.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrWarehouseNotFound
+		}
+		return nil, err
+	}
+	return &wh, nil
+}
+
+// GetAllForUser returns all warehouses for a given user, default first.
+func (m *WarehouseModel) GetAllForUser(userID int64) ([]Warehouse, error) {
+	const q = `
+		SELECT id, name, code, address, is_default, user_id, created_at
+		FROM warehouses
+		WHERE user_id = $1 AND NOT is_transit
+		ORDER BY is_default DESC, id`
+
+	rows, err := m.DB.Query(context.Background(), q, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Warehouse{}
+	for rows.Next() {
+		var wh Warehouse
+		if err := rows.Scan(&wh.ID, &wh.Name, &wh.Code, &wh.Address, &wh.IsDefault, &wh.UserID, &wh.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, wh)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return out, nil
+}
+
+// Update updates a warehouse's name, code and address.
+func (m *WarehouseModel) Update(id int64, userID int64, wh *Warehouse) error {
+	const q = `
+		UPDATE warehouses
+		SET name = $1, code = $2, address = $3
+		WHERE id = $4 AND user_id = $5 AND NOT is_transit`
+
+	tag, err := m.DB.Exec(context.Background(), q, wh.Name, wh.Code, wh.Address, id, userID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrDuplicateWarehouse
+		}
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrWarehouseNotFound
+	}
+	return nil
+}
+
+// SetDefault marca un depósito como el depósito por defecto del usuario.
+func (m *WarehouseModel) SetDefault(id int64, userID int64) error {
+	ctx := context.Background()
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if tx != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	if _, err := tx.Exec(ctx, `UPDATE warehouses SET is_default = false WHERE user_id = $1 AND is_default`, userID); err != nil {
+		return err
+	}
+	tag, err := tx.Exec(ctx, `UPDATE warehouses SET is_default = true WHERE id = $1 AND user_id = $2 AND NOT is_transit`, id, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrWarehouseNotFound
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	tx = nil
+	return nil
+}
+
+// Delete elimina un depósito vacío que no sea el depósito por defecto.
+func (m *WarehouseModel) Delete(id int64, userID int64) error {
+	ctx := context.Background()
+
+	wh, err := m.GetByID(id, userID)
+	if err != nil {
+		return err
+	}
+	if wh.IsDefault {
+		return ErrDefaultWarehouseDel
+	}
+
+	var hasStock bool
+	const qStock = `SELECT EXISTS (SELECT 1 FROM product_stocks WHERE warehouse_id = $1 AND quantity <> 0)`
+	if err := m.DB.QueryRow(ctx, qStock, id).Scan(&hasStock); err != nil {
+		return err
+	}
+	if hasStock {
+		return ErrWarehouseNotEmpty
+	}
+
+	if _, err := m.DB.Exec(ctx, `DELETE FROM product_stocks WHERE warehouse_id = $1`, id); err != nil {
+		return err
+	}
+	if _, err := m.DB.Exec(ctx, `DELETE FROM warehouses WHERE id = $1 AND user_id = $2`, id, userID); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" { // foreign_key_violation
+			return ErrHasReferences
+		}
+		return err
+	}
+	return nil
+}
+
+// GetStock devuelve el stock de cada producto en un depósito.
+func (m *WarehouseModel) GetStock(id int64, userID int64) ([]WarehouseStock, error) {
+	if _, err := m.GetByID(id, userID); err != nil {
+		return nil, err
+	}
+
+	const q = `
+		SELECT p.id, p.name, p.sku, ps.quantity
+		FROM product_stocks ps
+		JOIN products p ON p.id = ps.product_id
+		WHERE ps.warehouse_id = $1 AND p.user_id = $2
+		ORDER BY p.id`
+
+	rows, err := m.DB.Query(context.Background(), q, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []WarehouseStock{}
+	for rows.Next() {
+		var s WarehouseStock
+		if err := rows.Scan(&s.ProductID, &s.ProductName, &s.SKU, &s.Quantity); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return out, nil
+}
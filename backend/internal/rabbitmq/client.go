@@ -22,6 +22,10 @@ type ReportRequest struct {
 	UserID     int64  `json:"user_id"`
 	Email      string `json:"email_to"`
 	ReportType string `json:"report_type"`
+
+	// Parámetros del reporte dead_stock: período en días y rotación mínima (nil = por defecto)
+	Days        int      `json:"days,omitempty"`
+	MaxTurnover *float64 `json:"max_turnover,omitempty"`
 }
 
 // Connect establece conexión a RabbitMQ y retorna un cliente
@@ -15,14 +15,21 @@ import (
 	"stock-in-order/backend/internal/models"
 	"stock-in-order/backend/internal/rabbitmq"
 	"stock-in-order/backend/internal/services"
+	"stock-in-order/backend/internal/storage"
 )
 
 // SetupRouter wires up HTTP routes.
-func SetupRouter(db *pgxpool.Pool, rabbit *rabbitmq.Client, cfg config.Config, logger *slog.Logger) http.Handler {
+func SetupRouter(db *pgxpool.Pool, rabbit *rabbitmq.Client, store storage.Storage, cfg config.Config, logger *slog.Logger) http.Handler {
 	r := mux.NewRouter()
 
 	// API v1
 	api := r.PathPrefix("/api/v1").Subrouter()
+
+	// Umbrales de aprobación de los ajustes manuales de stock
+	adjustmentPolicy := models.AdjustmentPolicy{
+		MaxQuantity: cfg.AdjustmentApprovalQuantity,
+		MaxValue:    cfg.AdjustmentApprovalValue,
+	}
 	api.HandleFunc("/health", handlers.Health()).Methods("GET")
 	api.HandleFunc("/users/register", handlers.RegisterUser(db)).Methods("POST")
 	api.HandleFunc("/users/login", handlers.LoginUser(db, cfg.JWTSecret)).Methods("POST")
@@ -37,6 +44,10 @@ func SetupRouter(db *pgxpool.Pool, rabbit *rabbitmq.Client, cfg config.Config, l
 		),
 	).Methods("POST")
 
+	// Historial de cambios de un usuario: el propio usuario o un admin
+	api.Handle("/users/{id:[0-9]+}/history",
+		middleware.JWTMiddleware(http.HandlerFunc(handlers.GetEntityHistory(db, models.HistoryUsers)), cfg.JWTSecret)).Methods("GET")
+
 	// RBAC Test endpoints (protected by JWT + Role middleware)
 	api.Handle("/test/admin-only",
 		middleware.JWTMiddleware(
@@ -62,6 +73,44 @@ func SetupRouter(db *pgxpool.Pool, rabbit *rabbitmq.Client, cfg config.Config, l
 		middleware.JWTMiddleware(http.HandlerFunc(handlers.GetProduct(db)), cfg.JWTSecret)).Methods("GET")
 	api.Handle("/products/{id:[0-9]+}/movements",
 		middleware.JWTMiddleware(http.HandlerFunc(handlers.GetProductMovements(db)), cfg.JWTSecret)).Methods("GET")
+	api.Handle("/products/{id:[0-9]+}/kardex",
+		middleware.JWTMiddleware(http.HandlerFunc(handlers.GetProductKardex(db)), cfg.JWTSecret)).Methods("GET")
+	api.Handle("/products/{id:[0-9]+}/variants",
+		middleware.JWTMiddleware(http.HandlerFunc(handlers.GetProductVariants(db)), cfg.JWTSecret)).Methods("GET")
+	api.Handle("/products/{id:[0-9]+}/units",
+		middleware.JWTMiddleware(http.HandlerFunc(handlers.GetProductUnits(db)), cfg.JWTSecret)).Methods("GET")
+	api.Handle("/products/{id:[0-9]+}/components",
+		middleware.JWTMiddleware(http.HandlerFunc(handlers.GetProductComponents(db)), cfg.JWTSecret)).Methods("GET")
+	api.Handle("/products/{id:[0-9]+}/lots",
+		middleware.JWTMiddleware(http.HandlerFunc(handlers.GetProductLots(db)), cfg.JWTSecret)).Methods("GET")
+	api.Handle("/products/{id:[0-9]+}/serials",
+		middleware.JWTMiddleware(http.HandlerFunc(handlers.GetProductSerials(db)), cfg.JWTSecret)).Methods("GET")
+	api.Handle("/serials/{serial}",
+		middleware.JWTMiddleware(http.HandlerFunc(handlers.LookupSerial(db)), cfg.JWTSecret)).Methods("GET")
+	api.Handle("/products/{id:[0-9]+}/barcodes",
+		middleware.JWTMiddleware(http.HandlerFunc(handlers.GetProductBarcodes(db)), cfg.JWTSecret)).Methods("GET")
+	api.Handle("/products/by-barcode/{code}",
+		middleware.JWTMiddleware(http.HandlerFunc(handlers.GetProductByBarcode(db)), cfg.JWTSecret)).Methods("GET")
+	api.Handle("/products/{id:[0-9]+}/attachments",
+		middleware.JWTMiddleware(http.HandlerFunc(handlers.GetProductAttachments(db)), cfg.JWTSecret)).Methods("GET")
+	api.Handle("/products/{id:[0-9]+}/history",
+		middleware.JWTMiddleware(http.HandlerFunc(handlers.GetEntityHistory(db, models.HistoryProducts)), cfg.JWTSecret)).Methods("GET")
+	api.Handle("/products/{id:[0-9]+}/reorder-rule",
+		middleware.JWTMiddleware(http.HandlerFunc(handlers.GetProductReorderRule(db)), cfg.JWTSecret)).Methods("GET")
+	api.Handle("/reorder-rules",
+		middleware.JWTMiddleware(http.HandlerFunc(handlers.GetReorderRules(db)), cfg.JWTSecret)).Methods("GET")
+	api.Handle("/products/{id:[0-9]+}/forecast",
+		middleware.JWTMiddleware(http.HandlerFunc(handlers.GetProductForecast(db)), cfg.JWTSecret)).Methods("GET")
+	api.Handle("/forecast/products",
+		middleware.JWTMiddleware(http.HandlerFunc(handlers.GetProductForecasts(db)), cfg.JWTSecret)).Methods("GET")
+	api.Handle("/products/{id:[0-9]+}/attachments/{attachmentId:[0-9]+}/file",
+		middleware.JWTMiddleware(http.HandlerFunc(handlers.GetProductAttachmentFile(db, store)), cfg.JWTSecret)).Methods("GET")
+	api.Handle("/products/{id:[0-9]+}/attachments/{attachmentId:[0-9]+}/thumbnail",
+		middleware.JWTMiddleware(http.HandlerFunc(handlers.GetProductAttachmentThumbnail(db, store)), cfg.JWTSecret)).Methods("GET")
+
+	// Impresión de etiquetas con código de barras: Todos los autenticados
+	api.Handle("/products/labels",
+		middleware.JWTMiddleware(http.HandlerFunc(handlers.PrintBarcodeLabels(db)), cfg.JWTSecret)).Methods("POST")
 
 	// Creación: Admin y Repositor
 	api.Handle("/products",
@@ -70,6 +119,25 @@ func SetupRouter(db *pgxpool.Pool, rabbit *rabbitmq.Client, cfg config.Config, l
 			cfg.JWTSecret,
 		)).Methods("POST")
 
+	// Importación masiva desde CSV / XLSX: Solo Admin
+	api.Handle("/products/import",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("admin")(http.HandlerFunc(handlers.ImportProducts(db, rabbit))),
+			cfg.JWTSecret,
+		)).Methods("POST")
+	api.Handle("/products/imports/{id:[0-9]+}",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("admin")(http.HandlerFunc(handlers.GetProductImport(db))),
+			cfg.JWTSecret,
+		)).Methods("GET")
+
+	// Clasificación ABC/XYZ a demanda: Solo Admin
+	api.Handle("/products/classify",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("admin")(http.HandlerFunc(handlers.RequestProductClassification(rabbit))),
+			cfg.JWTSecret,
+		)).Methods("POST")
+
 	// Actualización: Admin y Repositor
 	api.Handle("/products/{id:[0-9]+}",
 		middleware.JWTMiddleware(
@@ -80,17 +148,262 @@ func SetupRouter(db *pgxpool.Pool, rabbit *rabbitmq.Client, cfg config.Config, l
 	// Ajuste de Stock: Solo Repositor y Admin
 	api.Handle("/products/{id:[0-9]+}/adjust-stock",
 		middleware.JWTMiddleware(
-			middleware.RequireRole("repositor")(http.HandlerFunc(handlers.AdjustProductStock(db))),
+			middleware.RequireRole("repositor")(http.HandlerFunc(handlers.AdjustProductStock(db, adjustmentPolicy))),
+			cfg.JWTSecret,
+		)).Methods("POST")
+
+	// Alta de series de unidades en stock: Solo Repositor y Admin
+	api.Handle("/products/{id:[0-9]+}/serials",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("repositor")(http.HandlerFunc(handlers.RegisterProductSerials(db))),
+			cfg.JWTSecret,
+		)).Methods("POST")
+
+	// Componentes de kits: Solo Admin
+	api.Handle("/products/{id:[0-9]+}/components",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("admin")(http.HandlerFunc(handlers.SetProductComponents(db))),
+			cfg.JWTSecret,
+		)).Methods("PUT")
+
+	// Unidades de medida: Solo Admin
+	api.Handle("/products/{id:[0-9]+}/units",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("admin")(http.HandlerFunc(handlers.CreateProductUnit(db))),
+			cfg.JWTSecret,
+		)).Methods("POST")
+	api.Handle("/products/{id:[0-9]+}/units/{unitId:[0-9]+}",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("admin")(http.HandlerFunc(handlers.DeleteProductUnit(db))),
+			cfg.JWTSecret,
+		)).Methods("DELETE")
+
+	// Códigos de barras: Solo Admin
+	api.Handle("/products/{id:[0-9]+}/barcodes",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("admin")(http.HandlerFunc(handlers.CreateProductBarcode(db))),
+			cfg.JWTSecret,
+		)).Methods("POST")
+	api.Handle("/products/{id:[0-9]+}/barcodes/{barcodeId:[0-9]+}",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("admin")(http.HandlerFunc(handlers.DeleteProductBarcode(db))),
+			cfg.JWTSecret,
+		)).Methods("DELETE")
+
+	// Imágenes y documentos: Admin y Repositor
+	api.Handle("/products/{id:[0-9]+}/attachments",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("repositor")(http.HandlerFunc(handlers.UploadProductAttachment(db, store))),
+			cfg.JWTSecret,
+		)).Methods("POST")
+	api.Handle("/products/{id:[0-9]+}/attachments/{attachmentId:[0-9]+}/primary",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("repositor")(http.HandlerFunc(handlers.SetPrimaryProductAttachment(db))),
+			cfg.JWTSecret,
+		)).Methods("PUT")
+	api.Handle("/products/{id:[0-9]+}/attachments/{attachmentId:[0-9]+}",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("repositor")(http.HandlerFunc(handlers.DeleteProductAttachment(db, store))),
+			cfg.JWTSecret,
+		)).Methods("DELETE")
+
+	// Reglas de reposición: Solo Admin
+	api.Handle("/products/{id:[0-9]+}/reorder-rule",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("admin")(http.HandlerFunc(handlers.SetProductReorderRule(db))),
+			cfg.JWTSecret,
+		)).Methods("PUT")
+	api.Handle("/products/{id:[0-9]+}/reorder-rule",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("admin")(http.HandlerFunc(handlers.DeleteProductReorderRule(db))),
+			cfg.JWTSecret,
+		)).Methods("DELETE")
+
+	// Stock mínimo sugerido por el pronóstico: Solo Admin
+	api.Handle("/products/{id:[0-9]+}/forecast/apply",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("admin")(http.HandlerFunc(handlers.ApplySuggestedStockMinimo(db))),
+			cfg.JWTSecret,
+		)).Methods("POST")
+
+	// Archivo y restauración: Solo Admin
+	api.Handle("/products/{id:[0-9]+}/archive",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("admin")(http.HandlerFunc(handlers.ArchiveProduct(db))),
+			cfg.JWTSecret,
+		)).Methods("POST")
+	api.Handle("/products/{id:[0-9]+}/restore",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("admin")(http.HandlerFunc(handlers.RestoreProduct(db))),
 			cfg.JWTSecret,
 		)).Methods("POST")
 
 	// Eliminación: Solo Admin
 	api.Handle("/products/{id:[0-9]+}",
 		middleware.JWTMiddleware(
-			middleware.RequireRole("admin")(http.HandlerFunc(handlers.DeleteProduct(db))),
+			middleware.RequireRole("admin")(http.HandlerFunc(handlers.DeleteProduct(db, store))),
 			cfg.JWTSecret,
 		)).Methods("DELETE")
 
+	// ============================================
+	// CATEGORIES & TAGS - Clasificación de productos
+	// ============================================
+	// Lectura: Todos los autenticados
+	api.Handle("/categories",
+		middleware.JWTMiddleware(http.HandlerFunc(handlers.ListCategories(db)), cfg.JWTSecret)).Methods("GET")
+	api.Handle("/tags",
+		middleware.JWTMiddleware(http.HandlerFunc(handlers.ListTags(db)), cfg.JWTSecret)).Methods("GET")
+
+	// Gestión del árbol: Solo Admin (las etiquetas se crean al asignarlas a un producto)
+	api.Handle("/categories",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("admin")(http.HandlerFunc(handlers.CreateCategory(db))),
+			cfg.JWTSecret,
+		)).Methods("POST")
+	api.Handle("/categories/{id:[0-9]+}",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("admin")(http.HandlerFunc(handlers.UpdateCategory(db))),
+			cfg.JWTSecret,
+		)).Methods("PUT")
+	api.Handle("/categories/{id:[0-9]+}",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("admin")(http.HandlerFunc(handlers.DeleteCategory(db))),
+			cfg.JWTSecret,
+		)).Methods("DELETE")
+
+	// ============================================
+	// WAREHOUSES - Depósitos y stock por ubicación
+	// ============================================
+	// Lectura: Todos los autenticados
+	api.Handle("/warehouses",
+		middleware.JWTMiddleware(http.HandlerFunc(handlers.ListWarehouses(db)), cfg.JWTSecret)).Methods("GET")
+	api.Handle("/warehouses/{id:[0-9]+}",
+		middleware.JWTMiddleware(http.HandlerFunc(handlers.GetWarehouse(db)), cfg.JWTSecret)).Methods("GET")
+	api.Handle("/warehouses/{id:[0-9]+}/stock",
+		middleware.JWTMiddleware(http.HandlerFunc(handlers.GetWarehouseStock(db)), cfg.JWTSecret)).Methods("GET")
+
+	// Gestión: Solo Admin
+	api.Handle("/warehouses",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("admin")(http.HandlerFunc(handlers.CreateWarehouse(db))),
+			cfg.JWTSecret,
+		)).Methods("POST")
+	api.Handle("/warehouses/{id:[0-9]+}",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("admin")(http.HandlerFunc(handlers.UpdateWarehouse(db))),
+			cfg.JWTSecret,
+		)).Methods("PUT")
+	api.Handle("/warehouses/{id:[0-9]+}/default",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("admin")(http.HandlerFunc(handlers.SetDefaultWarehouse(db))),
+			cfg.JWTSecret,
+		)).Methods("PUT")
+	api.Handle("/warehouses/{id:[0-9]+}",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("admin")(http.HandlerFunc(handlers.DeleteWarehouse(db))),
+			cfg.JWTSecret,
+		)).Methods("DELETE")
+
+	// ============================================
+	// STOCK TRANSFERS - Transferencias entre depósitos
+	// ============================================
+	// Creación y Gestión: Admin y Repositor
+	api.Handle("/stock-transfers",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("repositor")(http.HandlerFunc(handlers.CreateStockTransfer(db))),
+			cfg.JWTSecret,
+		)).Methods("POST")
+	api.Handle("/stock-transfers",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("repositor")(http.HandlerFunc(handlers.GetStockTransfers(db))),
+			cfg.JWTSecret,
+		)).Methods("GET")
+	api.Handle("/stock-transfers/{id:[0-9]+}",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("repositor")(http.HandlerFunc(handlers.GetStockTransferByID(db))),
+			cfg.JWTSecret,
+		)).Methods("GET")
+	api.Handle("/stock-transfers/{id:[0-9]+}/status",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("repositor")(http.HandlerFunc(handlers.UpdateStockTransferStatus(db))),
+			cfg.JWTSecret,
+		)).Methods("PUT")
+
+	// ============================================
+	// CYCLE COUNTS - Conteos físicos de inventario
+	// ============================================
+	// Apertura, conteo y cierre: Repositor
+	api.Handle("/cycle-counts",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("repositor")(http.HandlerFunc(handlers.CreateCycleCount(db))),
+			cfg.JWTSecret,
+		)).Methods("POST")
+	api.Handle("/cycle-counts",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("repositor")(http.HandlerFunc(handlers.GetCycleCounts(db))),
+			cfg.JWTSecret,
+		)).Methods("GET")
+	api.Handle("/cycle-counts/{id:[0-9]+}",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("repositor")(http.HandlerFunc(handlers.GetCycleCountByID(db))),
+			cfg.JWTSecret,
+		)).Methods("GET")
+	api.Handle("/cycle-counts/{id:[0-9]+}/counts",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("repositor")(http.HandlerFunc(handlers.RecordCycleCount(db))),
+			cfg.JWTSecret,
+		)).Methods("POST")
+	api.Handle("/cycle-counts/{id:[0-9]+}/close",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("repositor")(http.HandlerFunc(handlers.CloseCycleCount(db))),
+			cfg.JWTSecret,
+		)).Methods("POST")
+	// Aprobación de diferencias sobre el umbral: Solo Admin
+	api.Handle("/cycle-counts/pending-approval",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("admin")(http.HandlerFunc(handlers.GetPendingCycleCounts(db))),
+			cfg.JWTSecret,
+		)).Methods("GET")
+	api.Handle("/cycle-counts/{id:[0-9]+}/approve",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("admin")(http.HandlerFunc(handlers.ApproveCycleCount(db))),
+			cfg.JWTSecret,
+		)).Methods("POST")
+
+	// ============================================
+	// STOCK ADJUSTMENTS - Motivos y aprobación de ajustes manuales
+	// ============================================
+	// Catálogo de motivos: lectura para todos los autenticados, alta y edición Solo Admin
+	api.Handle("/adjustment-reasons",
+		middleware.JWTMiddleware(http.HandlerFunc(handlers.GetAdjustmentReasons(db)), cfg.JWTSecret)).Methods("GET")
+	api.Handle("/adjustment-reasons",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("admin")(http.HandlerFunc(handlers.CreateAdjustmentReason(db))),
+			cfg.JWTSecret,
+		)).Methods("POST")
+	api.Handle("/adjustment-reasons/{id:[0-9]+}",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("admin")(http.HandlerFunc(handlers.UpdateAdjustmentReason(db))),
+			cfg.JWTSecret,
+		)).Methods("PUT")
+	// Historial de ajustes: Repositor
+	api.Handle("/stock-adjustments",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("repositor")(http.HandlerFunc(handlers.GetStockAdjustments(db))),
+			cfg.JWTSecret,
+		)).Methods("GET")
+	// Aprobación de ajustes sobre los umbrales: Solo Admin
+	api.Handle("/stock-adjustments/pending-approval",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("admin")(http.HandlerFunc(handlers.GetPendingStockAdjustments(db))),
+			cfg.JWTSecret,
+		)).Methods("GET")
+	api.Handle("/stock-adjustments/{id:[0-9]+}/approve",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("admin")(http.HandlerFunc(handlers.ApproveStockAdjustment(db))),
+			cfg.JWTSecret,
+		)).Methods("POST")
+
 	// ============================================
 	// DASHBOARD - Todos los autenticados
 	// ============================================
@@ -110,6 +423,8 @@ func SetupRouter(db *pgxpool.Pool, rabbit *rabbitmq.Client, cfg config.Config, l
 		middleware.JWTMiddleware(http.HandlerFunc(handlers.RequestCustomersReportByEmail(db, rabbit)), cfg.JWTSecret)).Methods("POST")
 	api.Handle("/reports/suppliers/email",
 		middleware.JWTMiddleware(http.HandlerFunc(handlers.RequestSuppliersReportByEmail(db, rabbit)), cfg.JWTSecret)).Methods("POST")
+	api.Handle("/reports/dead-stock/email",
+		middleware.JWTMiddleware(http.HandlerFunc(handlers.RequestDeadStockReportByEmail(db, rabbit)), cfg.JWTSecret)).Methods("POST")
 
 	api.Handle("/reports/products/xlsx",
 		middleware.JWTMiddleware(http.HandlerFunc(handlers.ExportProductsXLSX(db)), cfg.JWTSecret)).Methods("GET")
@@ -121,6 +436,43 @@ func SetupRouter(db *pgxpool.Pool, rabbit *rabbitmq.Client, cfg config.Config, l
 		middleware.JWTMiddleware(http.HandlerFunc(handlers.ExportSalesOrdersXLSX(db)), cfg.JWTSecret)).Methods("GET")
 	api.Handle("/reports/purchase-orders/xlsx",
 		middleware.JWTMiddleware(http.HandlerFunc(handlers.ExportPurchaseOrdersXLSX(db)), cfg.JWTSecret)).Methods("GET")
+	api.Handle("/reports/valuation/xlsx",
+		middleware.JWTMiddleware(http.HandlerFunc(handlers.ExportValuationXLSX(db)), cfg.JWTSecret)).Methods("GET")
+	api.Handle("/reports/kardex/xlsx",
+		middleware.JWTMiddleware(http.HandlerFunc(handlers.ExportKardexXLSX(db)), cfg.JWTSecret)).Methods("GET")
+	api.Handle("/reports/dead-stock/xlsx",
+		middleware.JWTMiddleware(http.HandlerFunc(handlers.ExportDeadStockXLSX(db)), cfg.JWTSecret)).Methods("GET")
+	api.Handle("/reports/dead-stock",
+		middleware.JWTMiddleware(http.HandlerFunc(handlers.GetDeadStock(db)), cfg.JWTSecret)).Methods("GET")
+
+	// ============================================
+	// STOCK LEDGER - Stock a una fecha reconstruido desde los movimientos
+	// ============================================
+	api.Handle("/stock/as-of",
+		middleware.JWTMiddleware(http.HandlerFunc(handlers.GetStockAsOf(db)), cfg.JWTSecret)).Methods("GET")
+	api.Handle("/stock/drift",
+		middleware.JWTMiddleware(http.HandlerFunc(handlers.GetLedgerDrift(db)), cfg.JWTSecret)).Methods("GET")
+	// Reparación del ledger: Solo Admin
+	api.Handle("/stock/reconcile",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("admin")(http.HandlerFunc(handlers.RequestLedgerReconciliation(rabbit))),
+			cfg.JWTSecret,
+		)).Methods("POST")
+
+	// ============================================
+	// VALUATION - Inventario valorizado (FIFO / promedio ponderado)
+	// ============================================
+	// Lectura: Todos los autenticados
+	api.Handle("/valuation",
+		middleware.JWTMiddleware(http.HandlerFunc(handlers.GetValuation(db)), cfg.JWTSecret)).Methods("GET")
+	api.Handle("/valuation/method",
+		middleware.JWTMiddleware(http.HandlerFunc(handlers.GetCostingMethod(db)), cfg.JWTSecret)).Methods("GET")
+	// Cambio de método de costeo: Solo Admin
+	api.Handle("/valuation/method",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("admin")(http.HandlerFunc(handlers.SetCostingMethod(db))),
+			cfg.JWTSecret,
+		)).Methods("PUT")
 
 	// ============================================
 	// SUPPLIERS - Con protección RBAC
@@ -130,6 +482,8 @@ func SetupRouter(db *pgxpool.Pool, rabbit *rabbitmq.Client, cfg config.Config, l
 		middleware.JWTMiddleware(http.HandlerFunc(handlers.ListSuppliers(db)), cfg.JWTSecret)).Methods("GET")
 	api.Handle("/suppliers/{id:[0-9]+}",
 		middleware.JWTMiddleware(http.HandlerFunc(handlers.GetSupplier(db)), cfg.JWTSecret)).Methods("GET")
+	api.Handle("/suppliers/{id:[0-9]+}/history",
+		middleware.JWTMiddleware(http.HandlerFunc(handlers.GetEntityHistory(db, models.HistorySuppliers)), cfg.JWTSecret)).Methods("GET")
 
 	// Creación: Admin y Repositor
 	api.Handle("/suppliers",
@@ -145,6 +499,18 @@ func SetupRouter(db *pgxpool.Pool, rabbit *rabbitmq.Client, cfg config.Config, l
 			cfg.JWTSecret,
 		)).Methods("PUT")
 
+	// Archivo y restauración: Solo Admin
+	api.Handle("/suppliers/{id:[0-9]+}/archive",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("admin")(http.HandlerFunc(handlers.ArchiveSupplier(db))),
+			cfg.JWTSecret,
+		)).Methods("POST")
+	api.Handle("/suppliers/{id:[0-9]+}/restore",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("admin")(http.HandlerFunc(handlers.RestoreSupplier(db))),
+			cfg.JWTSecret,
+		)).Methods("POST")
+
 	// Eliminación: Solo Admin
 	api.Handle("/suppliers/{id:[0-9]+}",
 		middleware.JWTMiddleware(
@@ -166,6 +532,11 @@ func SetupRouter(db *pgxpool.Pool, rabbit *rabbitmq.Client, cfg config.Config, l
 			middleware.RequireRole("vendedor")(http.HandlerFunc(handlers.GetCustomer(db))),
 			cfg.JWTSecret,
 		)).Methods("GET")
+	api.Handle("/customers/{id:[0-9]+}/history",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("vendedor")(http.HandlerFunc(handlers.GetEntityHistory(db, models.HistoryCustomers))),
+			cfg.JWTSecret,
+		)).Methods("GET")
 
 	// Creación: Admin y Vendedor
 	api.Handle("/customers",
@@ -181,6 +552,18 @@ func SetupRouter(db *pgxpool.Pool, rabbit *rabbitmq.Client, cfg config.Config, l
 			cfg.JWTSecret,
 		)).Methods("PUT")
 
+	// Archivo y restauración: Solo Admin
+	api.Handle("/customers/{id:[0-9]+}/archive",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("admin")(http.HandlerFunc(handlers.ArchiveCustomer(db))),
+			cfg.JWTSecret,
+		)).Methods("POST")
+	api.Handle("/customers/{id:[0-9]+}/restore",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("admin")(http.HandlerFunc(handlers.RestoreCustomer(db))),
+			cfg.JWTSecret,
+		)).Methods("POST")
+
 	// Eliminación: Solo Admin
 	api.Handle("/customers/{id:[0-9]+}",
 		middleware.JWTMiddleware(
@@ -188,6 +571,48 @@ func SetupRouter(db *pgxpool.Pool, rabbit *rabbitmq.Client, cfg config.Config, l
 			cfg.JWTSecret,
 		)).Methods("DELETE")
 
+	// Lista de precios acordada con el cliente: Solo Admin
+	api.Handle("/customers/{id:[0-9]+}/price-list",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("admin")(http.HandlerFunc(handlers.SetCustomerPriceList(db))),
+			cfg.JWTSecret,
+		)).Methods("PUT")
+
+	// ============================================
+	// PRICE LISTS - Listas de precios, escalas y precio mínimo
+	// ============================================
+	// Lectura y cotización: Todos los autenticados
+	api.Handle("/price-lists",
+		middleware.JWTMiddleware(http.HandlerFunc(handlers.ListPriceLists(db)), cfg.JWTSecret)).Methods("GET")
+	api.Handle("/price-lists/{id:[0-9]+}",
+		middleware.JWTMiddleware(http.HandlerFunc(handlers.GetPriceList(db)), cfg.JWTSecret)).Methods("GET")
+	api.Handle("/price-lists/quote",
+		middleware.JWTMiddleware(http.HandlerFunc(handlers.QuotePrice(db)), cfg.JWTSecret)).Methods("GET")
+	api.Handle("/price-lists/policy",
+		middleware.JWTMiddleware(http.HandlerFunc(handlers.GetPriceFloorPolicy(db)), cfg.JWTSecret)).Methods("GET")
+
+	// Gestión de listas y política de precio mínimo: Solo Admin
+	api.Handle("/price-lists",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("admin")(http.HandlerFunc(handlers.CreatePriceList(db))),
+			cfg.JWTSecret,
+		)).Methods("POST")
+	api.Handle("/price-lists/{id:[0-9]+}",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("admin")(http.HandlerFunc(handlers.UpdatePriceList(db))),
+			cfg.JWTSecret,
+		)).Methods("PUT")
+	api.Handle("/price-lists/{id:[0-9]+}",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("admin")(http.HandlerFunc(handlers.DeletePriceList(db))),
+			cfg.JWTSecret,
+		)).Methods("DELETE")
+	api.Handle("/price-lists/policy",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("admin")(http.HandlerFunc(handlers.SetPriceFloorPolicy(db))),
+			cfg.JWTSecret,
+		)).Methods("PUT")
+
 	// ============================================
 	// SALES ORDERS - Con protección RBAC
 	// ============================================
@@ -207,6 +632,18 @@ func SetupRouter(db *pgxpool.Pool, rabbit *rabbitmq.Client, cfg config.Config, l
 			middleware.RequireRole("vendedor")(http.HandlerFunc(handlers.GetSalesOrderByID(db))),
 			cfg.JWTSecret,
 		)).Methods("GET")
+	// Ciclo de vida (confirmar, preparar, despachar, entregar o cancelar): descuenta, libera o devuelve el stock
+	api.Handle("/sales-orders/{id:[0-9]+}/status",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("vendedor")(http.HandlerFunc(handlers.UpdateSalesOrderStatus(db))),
+			cfg.JWTSecret,
+		)).Methods("PUT")
+	// Series de una línea cargada sin ellas (p. ej. ventas de Mercado Libre): se asignan antes de despachar
+	api.Handle("/sales-orders/{id:[0-9]+}/items/{itemId:[0-9]+}/serials",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("vendedor")(http.HandlerFunc(handlers.AssignSalesOrderSerials(db))),
+			cfg.JWTSecret,
+		)).Methods("PUT")
 
 	// ============================================
 	// PURCHASE ORDERS - Con protección RBAC
@@ -233,6 +670,13 @@ func SetupRouter(db *pgxpool.Pool, rabbit *rabbitmq.Client, cfg config.Config, l
 			cfg.JWTSecret,
 		)).Methods("PUT")
 
+	// Motor de reposición: genera órdenes en borrador para revisar
+	api.Handle("/purchase-orders/reorder",
+		middleware.JWTMiddleware(
+			middleware.RequireRole("repositor")(http.HandlerFunc(handlers.RequestReorderRun(rabbit))),
+			cfg.JWTSecret,
+		)).Methods("POST")
+
 	// ============================================
 	// INTEGRATIONS - OAuth2 y gestión de integraciones
 	// ============================================
@@ -0,0 +1,122 @@
+// Package storage guarda archivos subidos (adjuntos de productos, miniaturas) detrás de una
+// interfaz común, para poder cambiar el disco local por un bucket sin tocar los handlers.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Backends disponibles
+const (
+	BackendLocal = "local"
+)
+
+// Errors returned by storage backends
+var (
+	ErrNotFound   = errors.New("stored object not found")
+	ErrInvalidKey = errors.New("invalid storage key")
+)
+
+// Storage guarda y recupera objetos por clave ("products/12/abc.jpg").
+type Storage interface {
+	// Save escribe el contenido de r bajo key y devuelve los bytes escritos.
+	Save(ctx context.Context, key string, r io.Reader) (int64, error)
+	// Open devuelve el contenido guardado bajo key; el llamador debe cerrarlo.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete borra key; borrar una clave inexistente no es un error.
+	Delete(ctx context.Context, key string) error
+}
+
+// New crea el backend configurado.
+func New(backend, dir string) (Storage, error) {
+	switch backend {
+	case "", BackendLocal:
+		return NewLocal(dir)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", backend)
+	}
+}
+
+// Local guarda los objetos como archivos bajo Root.
+type Local struct {
+	Root string
+}
+
+// NewLocal crea (si hace falta) el directorio raíz y devuelve el backend local.
+func NewLocal(root string) (*Local, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+	return &Local{Root: root}, nil
+}
+
+// path resuelve una clave dentro de Root, rechazando rutas absolutas o que salgan de la raíz.
+func (l *Local) path(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return "", ErrInvalidKey
+	}
+	clean := filepath.Clean(filepath.FromSlash(key))
+	if clean == "." || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(l.Root, clean), nil
+}
+
+// Save escribe a un archivo temporal y lo renombra, para no dejar archivos a medio escribir.
+func (l *Local) Save(_ context.Context, key string, r io.Reader) (int64, error) {
+	dst, err := l.path(key)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return 0, err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".upload-*")
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), dst)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return 0, err
+	}
+	return n, nil
+}
+
+// Open abre el archivo guardado bajo key.
+func (l *Local) Open(_ context.Context, key string) (io.ReadCloser, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// Delete borra el archivo guardado bajo key.
+func (l *Local) Delete(_ context.Context, key string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLocalSaveOpenDelete(t *testing.T) {
+	ctx := context.Background()
+	l, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := l.Save(ctx, "products/1/a.txt", strings.NewReader("hola"))
+	if err != nil || n != 4 {
+		t.Fatalf("Save = %d, %v", n, err)
+	}
+	f, err := l.Open(ctx, "products/1/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(f)
+	f.Close()
+	if string(got) != "hola" {
+		t.Fatalf("content = %q", got)
+	}
+
+	if err := l.Delete(ctx, "products/1/a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Delete(ctx, "products/1/a.txt"); err != nil {
+		t.Fatalf("deleting a missing key: %v", err)
+	}
+	if _, err := l.Open(ctx, "products/1/a.txt"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Open after delete = %v, want ErrNotFound", err)
+	}
+}
+
+func TestLocalRejectsKeysOutsideRoot(t *testing.T) {
+	l, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"", "/etc/passwd", "../x", "a/../../x", "..", `a\b`} {
+		if _, err := l.Save(context.Background(), key, strings.NewReader("x")); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Save(%q) = %v, want ErrInvalidKey", key, err)
+		}
+	}
+}
+
+func TestWriteThumbnail(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 1024, 512))
+	for y := 0; y < 512; y++ {
+		for x := 0; x < 1024; x++ {
+			src.Set(x, y, color.NRGBA{R: 200, A: 0xff})
+		}
+	}
+	var in bytes.Buffer
+	if err := png.Encode(&in, src); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := WriteThumbnail(&out, bytes.NewReader(in.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	cfg, format, err := image.DecodeConfig(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != "jpeg" || cfg.Width != ThumbnailSize || cfg.Height != ThumbnailSize/2 {
+		t.Fatalf("thumbnail = %s %dx%d", format, cfg.Width, cfg.Height)
+	}
+}
+
+func TestThumbnailKeepsSmallImages(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 40, 30))
+	if b := Thumbnail(src, ThumbnailSize).Bounds(); b.Dx() != 40 || b.Dy() != 30 {
+		t.Fatalf("thumbnail size = %v", b)
+	}
+}
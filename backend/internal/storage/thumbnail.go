@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+
+	// Decoders registrados para image.Decode
+	_ "image/gif"
+	_ "image/png"
+)
+
+// ThumbnailSize es el lado máximo, en píxeles, de las miniaturas.
+const ThumbnailSize = 256
+
+// MaxImagePixels limita el tamaño de las imágenes que se decodifican para generar miniaturas.
+const MaxImagePixels = 40_000_000
+
+// ErrImageTooLarge is returned when an image exceeds MaxImagePixels.
+var ErrImageTooLarge = errors.New("image too large")
+
+// WriteThumbnail decodifica una imagen (JPEG, PNG o GIF) y escribe una miniatura JPEG que entra en
+// un cuadrado de ThumbnailSize píxeles, conservando la proporción. Las imágenes chicas no se agrandan.
+func WriteThumbnail(w io.Writer, r io.ReadSeeker) error {
+	cfg, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return err
+	}
+	if cfg.Width*cfg.Height > MaxImagePixels {
+		return ErrImageTooLarge
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	src, _, err := image.Decode(r)
+	if err != nil {
+		return err
+	}
+	return jpeg.Encode(w, Thumbnail(src, ThumbnailSize), &jpeg.Options{Quality: 85})
+}
+
+// Thumbnail reduce src para que su lado mayor sea a lo sumo size, promediando los píxeles de
+// origen que caen en cada píxel de destino. La transparencia se aplana sobre fondo blanco.
+func Thumbnail(src image.Image, size int) *image.RGBA {
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	dw, dh := sw, sh
+	if sw > size || sh > size {
+		if sw >= sh {
+			dw, dh = size, max(1, sh*size/sw)
+		} else {
+			dw, dh = max(1, sw*size/sh), size
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := b.Min.Y+y*sh/dh, b.Min.Y+(y+1)*sh/dh
+		y1 = max(y1, y0+1)
+		for x := 0; x < dw; x++ {
+			x0, x1 := b.Min.X+x*sw/dw, b.Min.X+(x+1)*sw/dw
+			x1 = max(x1, x0+1)
+
+			var r, g, bl, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					c := color.NRGBA64Model.Convert(src.At(sx, sy)).(color.NRGBA64)
+					// Mezcla con blanco según el alfa
+					a := uint64(c.A)
+					r += (uint64(c.R)*a + 0xffff*(0xffff-a)) / 0xffff
+					g += (uint64(c.G)*a + 0xffff*(0xffff-a)) / 0xffff
+					bl += (uint64(c.B)*a + 0xffff*(0xffff-a)) / 0xffff
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8(r / n >> 8),
+				G: uint8(g / n >> 8),
+				B: uint8(bl / n >> 8),
+				A: 0xff,
+			})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,27 @@
+package inventory
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"stock-in-order/backend/internal/models"
+)
+
+// Reporte de stock inmovilizado y cada uno de sus productos.
+type (
+	DeadStockReport = models.DeadStockReport
+	DeadStockItem   = models.DeadStockItem
+)
+
+// Parámetros por defecto y estados del reporte de stock inmovilizado.
+const (
+	DefaultDeadStockDays        = models.DefaultDeadStockDays
+	DefaultDeadStockMaxTurnover = models.DefaultDeadStockMaxTurnover
+	DeadStockDead               = models.DeadStockDead
+	DeadStockSlow               = models.DeadStockSlow
+)
+
+// DeadStock arma el mismo reporte de stock inmovilizado que el endpoint del backend.
+func DeadStock(db *pgxpool.Pool, userID int64, days int, maxTurnover float64) (*DeadStockReport, error) {
+	m := &models.DeadStockModel{DB: db}
+	return m.Get(userID, models.DeadStockOptions{Days: days, MaxTurnover: maxTurnover})
+}
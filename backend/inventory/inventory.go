@@ -0,0 +1,41 @@
+// Package inventory expone al worker las operaciones de stock del backend, para que los procesos en
+// segundo plano usen la misma implementación que la API en lugar de una copia del SQL. Es el único
+// paquete del backend fuera de internal: sólo envuelve los modelos.
+package inventory
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"stock-in-order/backend/internal/models"
+)
+
+// Querier es la interfaz común de *pgxpool.Pool y pgx.Tx.
+type Querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// DefaultWarehouse devuelve el depósito por defecto del usuario, creándolo (o marcando como tal su
+// depósito PRINCIPAL) si todavía no tiene uno.
+func DefaultWarehouse(ctx context.Context, q Querier, userID int64) (int64, error) {
+	return models.DefaultWarehouse(ctx, q, userID)
+}
+
+// Filas y columnas de una importación de productos ya validada por el backend.
+type (
+	ImportRow     = models.ImportRow
+	ImportColumns = models.ImportColumns
+)
+
+// ErrProductArchived indica que la fila es de un producto archivado.
+var ErrProductArchived = models.ErrProductArchived
+
+// ImportProduct crea o actualiza el producto de una fila dentro de tx; un producto nuevo entra con
+// su cantidad como saldo inicial en el depósito por defecto. Devuelve si lo creó.
+func ImportProduct(ctx context.Context, tx pgx.Tx, userID int64, cols ImportColumns, row ImportRow) (bool, error) {
+	return models.ImportProduct(ctx, tx, userID, cols, row)
+}
@@ -0,0 +1,42 @@
+package inventory
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"stock-in-order/backend/internal/models"
+)
+
+// Orden de venta y sus líneas, tal como las crea la API.
+type (
+	SalesOrder = models.SalesOrder
+	OrderItem  = models.OrderItem
+)
+
+// Estados con los que se crean las órdenes de otros canales: una despachada descuenta el stock al
+// crearse y una confirmada lo reserva sin vencimiento (hasta que se le asignen las series y se despache).
+const (
+	SalesOrderConfirmed = models.SalesOrderConfirmed
+	SalesOrderShipped   = models.SalesOrderShipped
+)
+
+// CreateSalesOrder crea una orden de venta por el mismo camino que la API: reserva el stock del
+// depósito (el por defecto si WarehouseID es 0) y, si la orden se crea despachada, lo descuenta
+// consumiendo lotes FEFO y capas de costo. Las líneas serializadas sin series sólo pueden ir en una
+// orden que reserva.
+func CreateSalesOrder(db *pgxpool.Pool, order *SalesOrder, items []OrderItem) error {
+	m := &models.SalesOrderModel{DB: db}
+	return m.Create(order, items)
+}
+
+// ExpiredReservations devuelve las órdenes pendientes cuya reserva de stock venció.
+func ExpiredReservations(db *pgxpool.Pool) ([]int64, error) {
+	m := &models.SalesOrderModel{DB: db}
+	return m.ExpiredReservations()
+}
+
+// ExpireReservation cancela una orden vencida liberando su reserva como una cancelación de la API.
+// Devuelve false si la orden ya no estaba pendiente y vencida.
+func ExpireReservation(db *pgxpool.Pool, orderID int64) (bool, error) {
+	m := &models.SalesOrderModel{DB: db}
+	return m.ExpireReservation(orderID)
+}
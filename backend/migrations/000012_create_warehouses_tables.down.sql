@@ -0,0 +1,6 @@
+ALTER TABLE purchase_orders DROP COLUMN IF EXISTS warehouse_id;
+ALTER TABLE sales_orders DROP COLUMN IF EXISTS warehouse_id;
+ALTER TABLE stock_movements DROP COLUMN IF EXISTS warehouse_id;
+DROP TABLE IF EXISTS product_stocks;
+DROP INDEX IF EXISTS idx_warehouses_default;
+DROP TABLE IF EXISTS warehouses;
@@ -0,0 +1,57 @@
+-- Migration: Depósitos y stock por ubicación
+-- products.quantity pasa a ser el total de product_stocks; cada movimiento registra su depósito.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS warehouses (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    code TEXT NOT NULL,
+    address TEXT NOT NULL DEFAULT '',
+    is_default BOOLEAN NOT NULL DEFAULT false,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, code)
+);
+
+-- Un único depósito por defecto por usuario
+CREATE UNIQUE INDEX IF NOT EXISTS idx_warehouses_default ON warehouses(user_id) WHERE is_default;
+
+CREATE TABLE IF NOT EXISTS product_stocks (
+    product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    warehouse_id BIGINT NOT NULL REFERENCES warehouses(id),
+    quantity INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (product_id, warehouse_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_stocks_warehouse_id ON product_stocks(warehouse_id);
+
+ALTER TABLE stock_movements ADD COLUMN IF NOT EXISTS warehouse_id BIGINT REFERENCES warehouses(id);
+ALTER TABLE sales_orders ADD COLUMN IF NOT EXISTS warehouse_id BIGINT REFERENCES warehouses(id);
+ALTER TABLE purchase_orders ADD COLUMN IF NOT EXISTS warehouse_id BIGINT REFERENCES warehouses(id);
+
+-- Backfill: cada usuario recibe un depósito principal con todo su stock actual
+INSERT INTO warehouses (name, code, is_default, user_id)
+SELECT 'Depósito Principal', 'PRINCIPAL', true, u.id
+FROM users u
+WHERE NOT EXISTS (SELECT 1 FROM warehouses w WHERE w.user_id = u.id);
+
+INSERT INTO product_stocks (product_id, warehouse_id, quantity)
+SELECT p.id, w.id, p.quantity
+FROM products p
+JOIN warehouses w ON w.user_id = p.user_id AND w.is_default
+WHERE NOT EXISTS (SELECT 1 FROM product_stocks ps WHERE ps.product_id = p.id);
+
+UPDATE stock_movements sm SET warehouse_id = w.id
+FROM warehouses w
+WHERE w.user_id = sm.user_id AND w.is_default AND sm.warehouse_id IS NULL;
+
+UPDATE sales_orders so SET warehouse_id = w.id
+FROM warehouses w
+WHERE w.user_id = so.user_id AND w.is_default AND so.warehouse_id IS NULL;
+
+UPDATE purchase_orders po SET warehouse_id = w.id
+FROM warehouses w
+WHERE w.user_id = po.user_id AND w.is_default AND po.warehouse_id IS NULL;
+
+COMMIT;
@@ -0,0 +1,30 @@
+-- Migration: Transferencias de stock entre depósitos
+-- Documento draft → in_transit → received; cada tramo genera movimientos TRANSFER_OUT / TRANSFER_IN.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS stock_transfers (
+    id BIGSERIAL PRIMARY KEY,
+    from_warehouse_id BIGINT NOT NULL REFERENCES warehouses(id),
+    to_warehouse_id BIGINT NOT NULL REFERENCES warehouses(id),
+    status TEXT NOT NULL DEFAULT 'draft',
+    notes TEXT NOT NULL DEFAULT '',
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    shipped_at TIMESTAMPTZ,
+    received_at TIMESTAMPTZ,
+    CHECK (from_warehouse_id <> to_warehouse_id),
+    CHECK (status IN ('draft', 'in_transit', 'received', 'cancelled'))
+);
+
+CREATE TABLE IF NOT EXISTS stock_transfer_items (
+    id BIGSERIAL PRIMARY KEY,
+    transfer_id BIGINT NOT NULL REFERENCES stock_transfers(id) ON DELETE CASCADE,
+    product_id BIGINT NOT NULL REFERENCES products(id),
+    quantity INTEGER NOT NULL CHECK (quantity > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_transfers_user_id ON stock_transfers(user_id);
+CREATE INDEX IF NOT EXISTS idx_stock_transfer_items_transfer_id ON stock_transfer_items(transfer_id);
+
+COMMIT;
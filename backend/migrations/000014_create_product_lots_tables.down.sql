@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS stock_transfer_item_lots;
+DROP TABLE IF EXISTS order_item_lots;
+ALTER TABLE purchase_order_items DROP COLUMN IF EXISTS expiry_date;
+ALTER TABLE purchase_order_items DROP COLUMN IF EXISTS lot_number;
+DROP INDEX IF EXISTS idx_product_lots_fefo;
+DROP TABLE IF EXISTS product_lots;
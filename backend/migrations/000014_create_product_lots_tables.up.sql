@@ -0,0 +1,43 @@
+-- Migration: Lotes y vencimientos
+-- Los lotes se reciben con las órdenes de compra y se consumen FEFO (first-expired-first-out).
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS product_lots (
+    id BIGSERIAL PRIMARY KEY,
+    product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    warehouse_id BIGINT NOT NULL REFERENCES warehouses(id),
+    lot_number TEXT NOT NULL,
+    expiry_date DATE,
+    quantity INTEGER NOT NULL DEFAULT 0 CHECK (quantity >= 0),
+    received_quantity INTEGER NOT NULL DEFAULT 0,
+    purchase_order_id BIGINT REFERENCES purchase_orders(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (product_id, warehouse_id, lot_number)
+);
+
+-- Índice para el consumo FEFO de lotes con stock
+CREATE INDEX IF NOT EXISTS idx_product_lots_fefo ON product_lots(product_id, warehouse_id, expiry_date) WHERE quantity > 0;
+
+ALTER TABLE purchase_order_items ADD COLUMN IF NOT EXISTS lot_number TEXT;
+ALTER TABLE purchase_order_items ADD COLUMN IF NOT EXISTS expiry_date DATE;
+
+-- Lotes consumidos por cada línea de venta
+CREATE TABLE IF NOT EXISTS order_item_lots (
+    id BIGSERIAL PRIMARY KEY,
+    order_item_id BIGINT NOT NULL REFERENCES order_items(id) ON DELETE CASCADE,
+    lot_id BIGINT NOT NULL REFERENCES product_lots(id) ON DELETE CASCADE,
+    quantity INTEGER NOT NULL CHECK (quantity > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_item_lots_lot_id ON order_item_lots(lot_id);
+
+-- Lotes despachados por cada línea de transferencia
+CREATE TABLE IF NOT EXISTS stock_transfer_item_lots (
+    id BIGSERIAL PRIMARY KEY,
+    transfer_item_id BIGINT NOT NULL REFERENCES stock_transfer_items(id) ON DELETE CASCADE,
+    lot_id BIGINT NOT NULL REFERENCES product_lots(id) ON DELETE CASCADE,
+    quantity INTEGER NOT NULL CHECK (quantity > 0)
+);
+
+COMMIT;
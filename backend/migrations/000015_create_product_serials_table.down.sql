@@ -0,0 +1,3 @@
+ALTER TABLE purchase_order_items DROP COLUMN IF EXISTS serial_numbers;
+DROP TABLE IF EXISTS product_serials;
+ALTER TABLE products DROP COLUMN IF EXISTS is_serialized;
@@ -0,0 +1,30 @@
+-- Migration: Números de serie para productos de alto valor
+-- Los productos serializados registran cada unidad recibida en una OC y vendida en una OV.
+
+BEGIN;
+
+ALTER TABLE products ADD COLUMN IF NOT EXISTS is_serialized BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS product_serials (
+    id BIGSERIAL PRIMARY KEY,
+    product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    serial_number TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'in_stock',
+    purchase_order_id BIGINT REFERENCES purchase_orders(id) ON DELETE SET NULL,
+    sales_order_id BIGINT REFERENCES sales_orders(id) ON DELETE SET NULL,
+    order_item_id BIGINT REFERENCES order_items(id) ON DELETE SET NULL,
+    received_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    sold_at TIMESTAMPTZ,
+    UNIQUE (product_id, serial_number),
+    CHECK (status IN ('in_stock', 'sold'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_serials_serial_number ON product_serials(serial_number);
+
+-- Números de serie informados por ítem de OC (antes o durante la recepción)
+ALTER TABLE purchase_order_items ADD COLUMN IF NOT EXISTS serial_numbers TEXT[] NOT NULL DEFAULT '{}';
+
+-- Productos de alto valor del seed
+UPDATE products SET is_serialized = true WHERE sku IN ('GPU-001', 'CPU-001');
+
+COMMIT;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_products_ml_variation;
+DROP INDEX IF EXISTS idx_products_parent_id;
+ALTER TABLE products DROP COLUMN IF EXISTS ml_variation_id;
+ALTER TABLE products DROP COLUMN IF EXISTS attributes;
+ALTER TABLE products DROP COLUMN IF EXISTS parent_id;
@@ -0,0 +1,15 @@
+-- Migration: Variantes de producto (talle/color) bajo un producto padre
+-- Cada variante es un producto con su propio SKU, stock y stock_minimo.
+
+BEGIN;
+
+ALTER TABLE products ADD COLUMN IF NOT EXISTS parent_id BIGINT REFERENCES products(id);
+ALTER TABLE products ADD COLUMN IF NOT EXISTS attributes JSONB NOT NULL DEFAULT '{}'::jsonb;
+ALTER TABLE products ADD COLUMN IF NOT EXISTS ml_variation_id BIGINT;
+
+CREATE INDEX IF NOT EXISTS idx_products_parent_id ON products(parent_id);
+
+-- Una variación de Mercado Libre se mapea a una única variante por usuario
+CREATE UNIQUE INDEX IF NOT EXISTS idx_products_ml_variation ON products(user_id, ml_variation_id) WHERE ml_variation_id IS NOT NULL;
+
+COMMIT;
@@ -0,0 +1,21 @@
+ALTER TABLE purchase_order_items DROP COLUMN IF EXISTS unit_factor;
+ALTER TABLE purchase_order_items DROP COLUMN IF EXISTS unit_quantity;
+ALTER TABLE purchase_order_items DROP COLUMN IF EXISTS unit;
+ALTER TABLE order_items DROP COLUMN IF EXISTS unit_factor;
+ALTER TABLE order_items DROP COLUMN IF EXISTS unit_quantity;
+ALTER TABLE order_items DROP COLUMN IF EXISTS unit;
+
+ALTER TABLE stock_transfer_item_lots ALTER COLUMN quantity TYPE INTEGER USING ROUND(quantity);
+ALTER TABLE stock_transfer_items ALTER COLUMN quantity TYPE INTEGER USING ROUND(quantity);
+ALTER TABLE purchase_order_items ALTER COLUMN quantity TYPE INTEGER USING ROUND(quantity);
+ALTER TABLE order_item_lots ALTER COLUMN quantity TYPE INTEGER USING ROUND(quantity);
+ALTER TABLE order_items ALTER COLUMN quantity TYPE INTEGER USING ROUND(quantity);
+ALTER TABLE stock_movements ALTER COLUMN quantity_change TYPE INTEGER USING ROUND(quantity_change);
+ALTER TABLE product_lots ALTER COLUMN received_quantity TYPE INTEGER USING ROUND(received_quantity);
+ALTER TABLE product_lots ALTER COLUMN quantity TYPE INTEGER USING ROUND(quantity);
+ALTER TABLE product_stocks ALTER COLUMN quantity TYPE INTEGER USING ROUND(quantity);
+ALTER TABLE products ALTER COLUMN stock_minimo TYPE INTEGER USING ROUND(stock_minimo);
+ALTER TABLE products ALTER COLUMN quantity TYPE INTEGER USING ROUND(quantity);
+
+DROP TABLE IF EXISTS product_units;
+ALTER TABLE products DROP COLUMN IF EXISTS base_unit;
@@ -0,0 +1,48 @@
+-- Migration: Unidades de medida y conversiones (caja x12, kg, metros)
+-- El stock se guarda y se mueve siempre en la unidad base del producto.
+-- Las cantidades pasan a NUMERIC para admitir unidades fraccionarias.
+
+BEGIN;
+
+-- Unidad base del producto (en la que se expresa quantity)
+ALTER TABLE products ADD COLUMN IF NOT EXISTS base_unit VARCHAR(20) NOT NULL DEFAULT 'unidad';
+
+-- Unidades alternativas: factor = cantidad de unidades base que contiene una unidad
+CREATE TABLE IF NOT EXISTS product_units (
+    id BIGSERIAL PRIMARY KEY,
+    product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    name VARCHAR(20) NOT NULL,
+    factor NUMERIC(18, 6) NOT NULL CHECK (factor > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (product_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_units_product_id ON product_units(product_id);
+
+-- Cantidades fraccionarias
+ALTER TABLE products ALTER COLUMN quantity TYPE NUMERIC(18, 3);
+ALTER TABLE products ALTER COLUMN stock_minimo TYPE NUMERIC(18, 3);
+ALTER TABLE product_stocks ALTER COLUMN quantity TYPE NUMERIC(18, 3);
+ALTER TABLE product_lots ALTER COLUMN quantity TYPE NUMERIC(18, 3);
+ALTER TABLE product_lots ALTER COLUMN received_quantity TYPE NUMERIC(18, 3);
+ALTER TABLE stock_movements ALTER COLUMN quantity_change TYPE NUMERIC(18, 3);
+ALTER TABLE order_items ALTER COLUMN quantity TYPE NUMERIC(18, 3);
+ALTER TABLE order_item_lots ALTER COLUMN quantity TYPE NUMERIC(18, 3);
+ALTER TABLE purchase_order_items ALTER COLUMN quantity TYPE NUMERIC(18, 3);
+ALTER TABLE stock_transfer_items ALTER COLUMN quantity TYPE NUMERIC(18, 3);
+ALTER TABLE stock_transfer_item_lots ALTER COLUMN quantity TYPE NUMERIC(18, 3);
+
+-- Unidad en la que se cargó la línea (para los documentos); quantity queda en unidad base
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS unit VARCHAR(20) NOT NULL DEFAULT 'unidad';
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS unit_quantity NUMERIC(18, 3);
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS unit_factor NUMERIC(18, 6) NOT NULL DEFAULT 1;
+UPDATE order_items SET unit_quantity = quantity WHERE unit_quantity IS NULL;
+ALTER TABLE order_items ALTER COLUMN unit_quantity SET NOT NULL;
+
+ALTER TABLE purchase_order_items ADD COLUMN IF NOT EXISTS unit VARCHAR(20) NOT NULL DEFAULT 'unidad';
+ALTER TABLE purchase_order_items ADD COLUMN IF NOT EXISTS unit_quantity NUMERIC(18, 3);
+ALTER TABLE purchase_order_items ADD COLUMN IF NOT EXISTS unit_factor NUMERIC(18, 6) NOT NULL DEFAULT 1;
+UPDATE purchase_order_items SET unit_quantity = quantity WHERE unit_quantity IS NULL;
+ALTER TABLE purchase_order_items ALTER COLUMN unit_quantity SET NOT NULL;
+
+COMMIT;
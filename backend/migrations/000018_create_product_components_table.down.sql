@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS product_components;
+ALTER TABLE products DROP COLUMN IF EXISTS is_kit;
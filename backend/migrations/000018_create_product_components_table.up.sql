@@ -0,0 +1,21 @@
+-- Migration: Kits / lista de materiales
+-- Un kit no guarda stock propio: su disponibilidad se calcula a partir de sus componentes
+-- y al venderlo se descuenta el stock de cada componente.
+
+BEGIN;
+
+ALTER TABLE products ADD COLUMN IF NOT EXISTS is_kit BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS product_components (
+    id BIGSERIAL PRIMARY KEY,
+    kit_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    component_id BIGINT NOT NULL REFERENCES products(id),
+    quantity NUMERIC(18, 3) NOT NULL CHECK (quantity > 0),
+    UNIQUE (kit_id, component_id),
+    CHECK (kit_id <> component_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_components_kit_id ON product_components(kit_id);
+CREATE INDEX IF NOT EXISTS idx_product_components_component_id ON product_components(component_id);
+
+COMMIT;
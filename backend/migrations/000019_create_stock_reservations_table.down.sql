@@ -0,0 +1,9 @@
+UPDATE product_serials SET status = 'in_stock', sales_order_id = NULL, order_item_id = NULL WHERE status = 'reserved';
+ALTER TABLE product_serials DROP CONSTRAINT IF EXISTS product_serials_status_check;
+ALTER TABLE product_serials ADD CONSTRAINT product_serials_status_check CHECK (status IN ('in_stock', 'sold'));
+
+DROP INDEX IF EXISTS idx_sales_orders_reserved_until;
+DROP TABLE IF EXISTS stock_reservations;
+ALTER TABLE sales_orders DROP COLUMN IF EXISTS reserved_until;
+ALTER TABLE product_stocks DROP CONSTRAINT IF EXISTS product_stocks_reserved_check;
+ALTER TABLE product_stocks DROP COLUMN IF EXISTS reserved;
@@ -0,0 +1,35 @@
+-- Migration: Reservas de stock para órdenes de venta pendientes
+-- Las órdenes pendientes reservan stock (disponible = stock físico - reservado); al completarse la
+-- reserva se convierte en descuento y al cancelarse o vencer se libera.
+-- Las órdenes pendientes anteriores a esta migración ya descontaron stock y no tienen reservas.
+
+BEGIN;
+
+ALTER TABLE product_stocks ADD COLUMN IF NOT EXISTS reserved NUMERIC(18, 3) NOT NULL DEFAULT 0;
+ALTER TABLE product_stocks ADD CONSTRAINT product_stocks_reserved_check CHECK (reserved >= 0 AND reserved <= quantity);
+
+-- Vencimiento de la reserva de una orden pendiente
+ALTER TABLE sales_orders ADD COLUMN IF NOT EXISTS reserved_until TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS stock_reservations (
+    id BIGSERIAL PRIMARY KEY,
+    sales_order_id BIGINT NOT NULL REFERENCES sales_orders(id) ON DELETE CASCADE,
+    order_item_id BIGINT NOT NULL REFERENCES order_items(id) ON DELETE CASCADE,
+    product_id BIGINT NOT NULL REFERENCES products(id),
+    warehouse_id BIGINT NOT NULL REFERENCES warehouses(id),
+    quantity NUMERIC(18, 3) NOT NULL CHECK (quantity > 0),
+    status VARCHAR(20) NOT NULL DEFAULT 'active',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    closed_at TIMESTAMPTZ,
+    CHECK (status IN ('active', 'fulfilled', 'released'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_reservations_sales_order_id ON stock_reservations(sales_order_id);
+CREATE INDEX IF NOT EXISTS idx_stock_reservations_active ON stock_reservations(product_id, warehouse_id) WHERE status = 'active';
+CREATE INDEX IF NOT EXISTS idx_sales_orders_reserved_until ON sales_orders(reserved_until) WHERE status = 'pending';
+
+-- Las series de una orden pendiente quedan reservadas hasta completarla
+ALTER TABLE product_serials DROP CONSTRAINT IF EXISTS product_serials_status_check;
+ALTER TABLE product_serials ADD CONSTRAINT product_serials_status_check CHECK (status IN ('in_stock', 'reserved', 'sold'));
+
+COMMIT;
@@ -0,0 +1,47 @@
+-- Migration: Sesiones de conteo físico (inventario cíclico)
+-- Al abrir la sesión se congela el stock esperado de cada producto en el depósito; los conteos
+-- pueden cargarse en varias pasadas y al cerrar se registran las diferencias como movimientos CYCLE_COUNT.
+-- Las diferencias que superan el umbral de la sesión quedan pendientes de aprobación de un admin.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS cycle_counts (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    warehouse_id BIGINT NOT NULL REFERENCES warehouses(id),
+    status VARCHAR(20) NOT NULL DEFAULT 'open',
+    variance_threshold_pct NUMERIC(7, 3) NOT NULL DEFAULT 5 CHECK (variance_threshold_pct >= 0),
+    notes TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    closed_at TIMESTAMPTZ,
+    approved_by BIGINT REFERENCES users(id),
+    approved_at TIMESTAMPTZ,
+    CHECK (status IN ('open', 'pending_approval', 'closed'))
+);
+
+CREATE TABLE IF NOT EXISTS cycle_count_lines (
+    id BIGSERIAL PRIMARY KEY,
+    cycle_count_id BIGINT NOT NULL REFERENCES cycle_counts(id) ON DELETE CASCADE,
+    product_id BIGINT NOT NULL REFERENCES products(id),
+    expected_quantity NUMERIC(18, 3) NOT NULL,
+    counted_quantity NUMERIC(18, 3) CHECK (counted_quantity >= 0),
+    passes INT NOT NULL DEFAULT 0,
+    counted_at TIMESTAMPTZ,
+    UNIQUE (cycle_count_id, product_id)
+);
+
+-- Registro de cada pasada de conteo; la línea guarda la última cantidad contada
+CREATE TABLE IF NOT EXISTS cycle_count_entries (
+    id BIGSERIAL PRIMARY KEY,
+    line_id BIGINT NOT NULL REFERENCES cycle_count_lines(id) ON DELETE CASCADE,
+    quantity NUMERIC(18, 3) NOT NULL CHECK (quantity >= 0),
+    user_id BIGINT NOT NULL REFERENCES users(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_cycle_counts_user_id ON cycle_counts(user_id);
+CREATE INDEX IF NOT EXISTS idx_cycle_counts_status ON cycle_counts(status);
+CREATE INDEX IF NOT EXISTS idx_cycle_count_lines_cycle_count_id ON cycle_count_lines(cycle_count_id);
+CREATE INDEX IF NOT EXISTS idx_cycle_count_entries_line_id ON cycle_count_entries(line_id);
+
+COMMIT;
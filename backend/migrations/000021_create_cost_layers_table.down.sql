@@ -0,0 +1,5 @@
+ALTER TABLE order_items DROP COLUMN IF EXISTS cogs;
+DROP TABLE IF EXISTS cost_entries;
+DROP TABLE IF EXISTS cost_layers;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_costing_method_check;
+ALTER TABLE users DROP COLUMN IF EXISTS costing_method;
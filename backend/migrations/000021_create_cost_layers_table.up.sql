@@ -0,0 +1,42 @@
+-- Migration: Valorización de inventario (FIFO / costo promedio ponderado)
+-- Cada recepción de compra crea una capa de costo; las salidas consumen capas en orden FIFO y
+-- cost_entries registra el valor de cada entrada y salida, de modo que el valor a una fecha es su suma.
+-- El stock anterior a esta migración (y los ingresos sin costo, como ajustes) queda sin valorizar.
+
+BEGIN;
+
+-- Método de costeo de la cuenta: fifo | wac (promedio ponderado)
+ALTER TABLE users ADD COLUMN IF NOT EXISTS costing_method VARCHAR(10) NOT NULL DEFAULT 'fifo';
+ALTER TABLE users ADD CONSTRAINT users_costing_method_check CHECK (costing_method IN ('fifo', 'wac'));
+
+CREATE TABLE IF NOT EXISTS cost_layers (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    purchase_order_id BIGINT REFERENCES purchase_orders(id) ON DELETE SET NULL,
+    unit_cost NUMERIC(18, 6) NOT NULL CHECK (unit_cost >= 0),
+    quantity NUMERIC(18, 3) NOT NULL CHECK (quantity > 0),
+    remaining NUMERIC(18, 3) NOT NULL CHECK (remaining >= 0),
+    received_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS cost_entries (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    quantity NUMERIC(18, 3) NOT NULL,
+    value NUMERIC(18, 4) NOT NULL,
+    reason TEXT NOT NULL,
+    reference_id TEXT,
+    order_item_id BIGINT REFERENCES order_items(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Costo de la mercadería vendida de cada línea de venta
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS cogs NUMERIC(18, 4);
+
+CREATE INDEX IF NOT EXISTS idx_cost_layers_product_open ON cost_layers(product_id, received_at, id) WHERE remaining > 0;
+CREATE INDEX IF NOT EXISTS idx_cost_entries_product_created ON cost_entries(product_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_cost_entries_user_created ON cost_entries(user_id, created_at);
+
+COMMIT;
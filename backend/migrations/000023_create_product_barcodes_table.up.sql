@@ -0,0 +1,18 @@
+-- Migration: Códigos de barras de productos (EAN-13, UPC-A, Code128)
+-- Un producto puede tener varios códigos; cada código es único dentro de la cuenta.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS product_barcodes (
+    id BIGSERIAL PRIMARY KEY,
+    product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code TEXT NOT NULL,
+    symbology TEXT NOT NULL CHECK (symbology IN ('ean13', 'upca', 'code128')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, code)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_barcodes_product_id ON product_barcodes(product_id);
+
+COMMIT;
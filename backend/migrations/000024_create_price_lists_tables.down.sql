@@ -0,0 +1,8 @@
+ALTER TABLE order_items DROP COLUMN IF EXISTS below_floor;
+ALTER TABLE order_items DROP COLUMN IF EXISTS price_list_id;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_price_floor_policy_check;
+ALTER TABLE users DROP COLUMN IF EXISTS price_floor_policy;
+ALTER TABLE products DROP COLUMN IF EXISTS price_floor;
+ALTER TABLE customers DROP COLUMN IF EXISTS price_list_id;
+DROP TABLE IF EXISTS price_list_items;
+DROP TABLE IF EXISTS price_lists;
@@ -0,0 +1,44 @@
+-- Migration: Listas de precios y precios por cliente
+-- Cada lista tiene vigencia opcional y precios por producto con escalas de cantidad (en unidad base).
+-- El precio de una línea de venta sale de la lista del cliente o de la lista por defecto de la cuenta.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS price_lists (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    valid_from DATE,
+    valid_to DATE,
+    is_default BOOLEAN NOT NULL DEFAULT false,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, name),
+    CHECK (valid_to IS NULL OR valid_from IS NULL OR valid_to >= valid_from)
+);
+
+-- Una única lista por defecto por usuario
+CREATE UNIQUE INDEX IF NOT EXISTS idx_price_lists_default ON price_lists(user_id) WHERE is_default;
+
+-- Precio por unidad base a partir de min_quantity unidades base
+CREATE TABLE IF NOT EXISTS price_list_items (
+    id BIGSERIAL PRIMARY KEY,
+    price_list_id BIGINT NOT NULL REFERENCES price_lists(id) ON DELETE CASCADE,
+    product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    min_quantity NUMERIC(18,3) NOT NULL DEFAULT 0 CHECK (min_quantity >= 0),
+    unit_price NUMERIC(12,2) NOT NULL CHECK (unit_price >= 0),
+    UNIQUE (price_list_id, product_id, min_quantity)
+);
+
+CREATE INDEX IF NOT EXISTS idx_price_list_items_product_id ON price_list_items(product_id);
+
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS price_list_id BIGINT REFERENCES price_lists(id);
+
+-- Precio mínimo por unidad base y qué hacer con las líneas por debajo: rechazarlas o marcarlas
+ALTER TABLE products ADD COLUMN IF NOT EXISTS price_floor NUMERIC(12,2);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS price_floor_policy TEXT NOT NULL DEFAULT 'reject';
+ALTER TABLE users ADD CONSTRAINT users_price_floor_policy_check CHECK (price_floor_policy IN ('reject', 'flag'));
+
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS price_list_id BIGINT REFERENCES price_lists(id);
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS below_floor BOOLEAN NOT NULL DEFAULT false;
+
+COMMIT;
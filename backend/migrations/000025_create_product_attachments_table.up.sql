@@ -0,0 +1,27 @@
+-- Migration: Adjuntos de productos (imágenes y documentos)
+-- Los archivos viven en el storage configurado (STORAGE_BACKEND); acá sólo se guardan sus claves.
+-- Las imágenes tienen miniatura y a lo sumo una es la principal del producto.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS product_attachments (
+    id BIGSERIAL PRIMARY KEY,
+    product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('image', 'spec_sheet', 'safety_data_sheet', 'document')),
+    file_name TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size_bytes BIGINT NOT NULL CHECK (size_bytes >= 0),
+    storage_key TEXT NOT NULL UNIQUE,
+    thumbnail_key TEXT,
+    is_primary BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (NOT is_primary OR kind = 'image')
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_attachments_product_id ON product_attachments(product_id);
+
+-- Una única imagen principal por producto
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_attachments_primary ON product_attachments(product_id) WHERE is_primary;
+
+COMMIT;
@@ -0,0 +1,3 @@
+ALTER TABLE suppliers DROP COLUMN IF EXISTS archived_at;
+ALTER TABLE customers DROP COLUMN IF EXISTS archived_at;
+ALTER TABLE products DROP COLUMN IF EXISTS archived_at;
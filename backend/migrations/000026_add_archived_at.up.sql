@@ -0,0 +1,15 @@
+-- Migration: Archivo (baja lógica) de productos, clientes y proveedores
+-- Un registro archivado no aparece en los listados ni puede usarse en órdenes nuevas, pero sigue
+-- referenciado por las órdenes históricas, los reportes y el ledger de movimientos.
+
+BEGIN;
+
+ALTER TABLE products ADD COLUMN IF NOT EXISTS archived_at TIMESTAMPTZ;
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS archived_at TIMESTAMPTZ;
+ALTER TABLE suppliers ADD COLUMN IF NOT EXISTS archived_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_products_active ON products(user_id) WHERE archived_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_customers_active ON customers(user_id) WHERE archived_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_suppliers_active ON suppliers(user_id) WHERE archived_at IS NULL;
+
+COMMIT;
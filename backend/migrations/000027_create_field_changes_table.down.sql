@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS users_field_changes ON users;
+DROP TRIGGER IF EXISTS suppliers_field_changes ON suppliers;
+DROP TRIGGER IF EXISTS customers_field_changes ON customers;
+DROP TRIGGER IF EXISTS products_field_changes ON products;
+DROP FUNCTION IF EXISTS record_field_changes();
+DROP TABLE IF EXISTS field_changes;
@@ -0,0 +1,62 @@
+-- Migration: Historial de cambios por campo de los datos maestros
+-- Un trigger en products, customers, suppliers y users guarda cada campo modificado con su valor
+-- anterior y nuevo, sin importar desde dónde se haga el UPDATE. El autor se toma de la variable de
+-- sesión app.actor_id (SET LOCAL desde el backend); sin ella el cambio queda sin autor (sistema).
+-- Argumentos del trigger: '-campo' no se registra, '*campo' se registra sin sus valores.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS field_changes (
+    id BIGSERIAL PRIMARY KEY,
+    entity TEXT NOT NULL,
+    entity_id BIGINT NOT NULL,
+    field TEXT NOT NULL,
+    old_value JSONB,
+    new_value JSONB,
+    changed_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_field_changes_entity ON field_changes(entity, entity_id, changed_at DESC);
+
+CREATE OR REPLACE FUNCTION record_field_changes() RETURNS trigger AS $$
+DECLARE
+    old_row JSONB := to_jsonb(OLD);
+    new_row JSONB := to_jsonb(NEW);
+    actor BIGINT := NULLIF(current_setting('app.actor_id', true), '')::BIGINT;
+    col TEXT;
+BEGIN
+    FOR col IN SELECT jsonb_object_keys(new_row) LOOP
+        CONTINUE WHEN ('-' || col) = ANY (TG_ARGV);
+        CONTINUE WHEN (old_row -> col) IS NOT DISTINCT FROM (new_row -> col);
+
+        IF ('*' || col) = ANY (TG_ARGV) THEN
+            INSERT INTO field_changes (entity, entity_id, field, changed_by)
+            VALUES (TG_TABLE_NAME, NEW.id, col, actor);
+        ELSE
+            INSERT INTO field_changes (entity, entity_id, field, old_value, new_value, changed_by)
+            VALUES (TG_TABLE_NAME, NEW.id, col, old_row -> col, new_row -> col, actor);
+        END IF;
+    END LOOP;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- La cantidad y la marca de alerta de stock ya quedan en stock_movements / las alertas
+DROP TRIGGER IF EXISTS products_field_changes ON products;
+CREATE TRIGGER products_field_changes AFTER UPDATE ON products
+    FOR EACH ROW EXECUTE FUNCTION record_field_changes('-quantity', '-notificado');
+
+DROP TRIGGER IF EXISTS customers_field_changes ON customers;
+CREATE TRIGGER customers_field_changes AFTER UPDATE ON customers
+    FOR EACH ROW EXECUTE FUNCTION record_field_changes();
+
+DROP TRIGGER IF EXISTS suppliers_field_changes ON suppliers;
+CREATE TRIGGER suppliers_field_changes AFTER UPDATE ON suppliers
+    FOR EACH ROW EXECUTE FUNCTION record_field_changes();
+
+DROP TRIGGER IF EXISTS users_field_changes ON users;
+CREATE TRIGGER users_field_changes AFTER UPDATE ON users
+    FOR EACH ROW EXECUTE FUNCTION record_field_changes('*password_hash');
+
+COMMIT;
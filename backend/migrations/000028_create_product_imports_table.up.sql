@@ -0,0 +1,25 @@
+-- Migration: Importaciones masivas de productos
+-- Las importaciones grandes se procesan en el worker: el backend valida el archivo y guarda
+-- las filas ya resueltas en payload; el worker las aplica por tandas y actualiza el progreso.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS product_imports (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    file_name TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'completed', 'failed')),
+    payload JSONB, -- columnas presentes y filas validadas; se borra al terminar
+    total_rows INTEGER NOT NULL CHECK (total_rows >= 0),
+    processed_rows INTEGER NOT NULL DEFAULT 0,
+    created_rows INTEGER NOT NULL DEFAULT 0,
+    updated_rows INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    started_at TIMESTAMPTZ,
+    finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_imports_user_id ON product_imports(user_id);
+
+COMMIT;
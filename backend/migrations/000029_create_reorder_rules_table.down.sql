@@ -0,0 +1,2 @@
+ALTER TABLE purchase_orders DROP COLUMN IF EXISTS auto_generated;
+DROP TABLE IF EXISTS reorder_rules;
@@ -0,0 +1,25 @@
+-- Migration: Reglas de reposición por producto
+-- Un job programado arma órdenes de compra en borrador (status 'draft'), agrupadas por proveedor,
+-- para los productos cuyo stock disponible más lo pedido queda en o por debajo del punto de pedido.
+-- Un repositor las revisa y las pasa a 'pending' (enviada) o las cancela.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS reorder_rules (
+    product_id BIGINT PRIMARY KEY REFERENCES products(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reorder_point NUMERIC(18, 3) NOT NULL CHECK (reorder_point >= 0),
+    target_level NUMERIC(18, 3) NOT NULL,
+    preferred_supplier_id BIGINT REFERENCES suppliers(id) ON DELETE SET NULL,
+    min_order_quantity NUMERIC(18, 3) NOT NULL DEFAULT 0 CHECK (min_order_quantity >= 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (target_level > reorder_point)
+);
+
+CREATE INDEX IF NOT EXISTS idx_reorder_rules_user_id ON reorder_rules(user_id);
+
+-- Órdenes armadas por el motor de reposición
+ALTER TABLE purchase_orders ADD COLUMN IF NOT EXISTS auto_generated BOOLEAN NOT NULL DEFAULT false;
+
+COMMIT;
@@ -0,0 +1,8 @@
+DROP TRIGGER IF EXISTS products_field_changes ON products;
+CREATE TRIGGER products_field_changes AFTER UPDATE ON products
+    FOR EACH ROW EXECUTE FUNCTION record_field_changes('-quantity', '-notificado');
+
+DROP INDEX IF EXISTS idx_products_abc_xyz_class;
+ALTER TABLE products DROP COLUMN IF EXISTS classified_at;
+ALTER TABLE products DROP COLUMN IF EXISTS xyz_class;
+ALTER TABLE products DROP COLUMN IF EXISTS abc_class;
@@ -0,0 +1,18 @@
+-- Migration: Clasificación ABC/XYZ de los productos
+-- Un job periódico clasifica cada producto activo por su aporte a la facturación (A/B/C, Pareto)
+-- y por la variabilidad de su demanda semanal (X/Y/Z). NULL = todavía sin clasificar.
+
+BEGIN;
+
+ALTER TABLE products ADD COLUMN IF NOT EXISTS abc_class CHAR(1) CHECK (abc_class IN ('A', 'B', 'C'));
+ALTER TABLE products ADD COLUMN IF NOT EXISTS xyz_class CHAR(1) CHECK (xyz_class IN ('X', 'Y', 'Z'));
+ALTER TABLE products ADD COLUMN IF NOT EXISTS classified_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_products_abc_xyz_class ON products(user_id, abc_class, xyz_class);
+
+-- Los cambios de clase quedan en el historial; la fecha de cada corrida no
+DROP TRIGGER IF EXISTS products_field_changes ON products;
+CREATE TRIGGER products_field_changes AFTER UPDATE ON products
+    FOR EACH ROW EXECUTE FUNCTION record_field_changes('-quantity', '-notificado', '-classified_at');
+
+COMMIT;
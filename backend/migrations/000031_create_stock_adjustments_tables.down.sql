@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS stock_adjustments;
+DROP TABLE IF EXISTS adjustment_reasons;
@@ -0,0 +1,45 @@
+-- Migration: Motivos y aprobación de los ajustes manuales de stock
+-- Cada ajuste manual lleva un motivo del catálogo (mantenido por los admins) que restringe su signo.
+-- Los ajustes que superan el límite de cantidad o de valor configurado quedan pendientes hasta que
+-- un admin los apruebe; recién entonces se registra el movimiento MANUAL_ADJUSTMENT.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS adjustment_reasons (
+    id BIGSERIAL PRIMARY KEY,
+    code VARCHAR(50) NOT NULL UNIQUE,
+    name TEXT NOT NULL,
+    sign VARCHAR(10) NOT NULL CHECK (sign IN ('positive', 'negative', 'any')),
+    active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO adjustment_reasons (code, name, sign) VALUES
+    ('damage', 'Rotura / daño', 'negative'),
+    ('theft', 'Robo / faltante', 'negative'),
+    ('sample', 'Muestra', 'negative'),
+    ('found', 'Sobrante encontrado', 'positive'),
+    ('expired', 'Vencimiento', 'negative')
+ON CONFLICT (code) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS stock_adjustments (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    product_id BIGINT NOT NULL REFERENCES products(id),
+    warehouse_id BIGINT NOT NULL REFERENCES warehouses(id),
+    reason_id BIGINT NOT NULL REFERENCES adjustment_reasons(id),
+    quantity_change NUMERIC(18, 3) NOT NULL CHECK (quantity_change <> 0),
+    unit_cost NUMERIC(18, 4) NOT NULL DEFAULT 0,
+    note TEXT NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL,
+    requested_by BIGINT NOT NULL REFERENCES users(id),
+    requested_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    approved_by BIGINT REFERENCES users(id),
+    approved_at TIMESTAMPTZ,
+    CHECK (status IN ('applied', 'pending', 'approved', 'rejected'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_adjustments_user_id ON stock_adjustments(user_id);
+CREATE INDEX IF NOT EXISTS idx_stock_adjustments_status ON stock_adjustments(status);
+
+COMMIT;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS sales_order_status_changes;
+ALTER TABLE sales_orders DROP CONSTRAINT IF EXISTS sales_orders_status_check;
+UPDATE sales_orders SET status = 'completed' WHERE status IN ('shipped', 'delivered');
+UPDATE sales_orders SET status = 'pending' WHERE status IN ('confirmed', 'picking');
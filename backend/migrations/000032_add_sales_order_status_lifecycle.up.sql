@@ -0,0 +1,30 @@
+-- Migration: Ciclo de vida de las órdenes de venta
+-- pending → confirmed → picking → shipped → delivered, con cancelación hasta el despacho.
+-- Las órdenes pendientes, confirmadas y en preparación reservan stock; al despacharse la reserva se
+-- descuenta. Cancelar una orden despachada devuelve el stock con movimientos SALES_ORDER_CANCEL.
+-- Las órdenes completadas de antes de esta migración ya descontaron stock y pasan a entregadas.
+
+BEGIN;
+
+UPDATE sales_orders SET status = 'delivered' WHERE status = 'completed';
+
+ALTER TABLE sales_orders ADD CONSTRAINT sales_orders_status_check
+    CHECK (status IN ('pending', 'confirmed', 'picking', 'shipped', 'delivered', 'cancelled'));
+
+-- Cada cambio de estado con el usuario que lo hizo (NULL para los procesos del sistema)
+CREATE TABLE IF NOT EXISTS sales_order_status_changes (
+    id BIGSERIAL PRIMARY KEY,
+    sales_order_id BIGINT NOT NULL REFERENCES sales_orders(id) ON DELETE CASCADE,
+    from_status TEXT,
+    to_status TEXT NOT NULL,
+    changed_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_sales_order_status_changes_order ON sales_order_status_changes(sales_order_id, changed_at);
+
+-- Estado inicial de las órdenes existentes
+INSERT INTO sales_order_status_changes (sales_order_id, from_status, to_status, changed_by, changed_at)
+SELECT id, NULL, status, NULL, order_date FROM sales_orders;
+
+COMMIT;
@@ -0,0 +1,16 @@
+UPDATE products p SET quantity = p.quantity - s.quantity
+FROM (
+    SELECT ps.product_id, SUM(ps.quantity) AS quantity
+    FROM product_stocks ps
+    JOIN warehouses w ON w.id = ps.warehouse_id AND w.is_transit
+    GROUP BY ps.product_id
+) s
+WHERE p.id = s.product_id;
+DELETE FROM stock_movements WHERE warehouse_id IN (SELECT id FROM warehouses WHERE is_transit);
+DELETE FROM product_lots WHERE warehouse_id IN (SELECT id FROM warehouses WHERE is_transit);
+DELETE FROM product_stocks WHERE warehouse_id IN (SELECT id FROM warehouses WHERE is_transit);
+DELETE FROM warehouses WHERE is_transit;
+DROP INDEX IF EXISTS idx_warehouses_user_code;
+DROP INDEX IF EXISTS idx_warehouses_transit;
+ALTER TABLE warehouses ADD CONSTRAINT warehouses_user_id_code_key UNIQUE (user_id, code);
+ALTER TABLE warehouses DROP COLUMN IF EXISTS is_transit;
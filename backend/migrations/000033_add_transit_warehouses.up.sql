@@ -0,0 +1,55 @@
+-- Migration: Ubicación de tránsito para las transferencias
+-- La mercadería despachada pasa del depósito origen a una ubicación de tránsito por usuario y de ahí
+-- al destino, así products.quantity no baja mientras está en camino. La ubicación no es un depósito
+-- operable: no aparece en el ABM de depósitos ni puede usarse como origen o destino.
+-- Las transferencias en tránsito de antes de esta migración ya registraron el TRANSFER_OUT del
+-- origen; su mercadería entra ahora en la ubicación de tránsito con los lotes despachados.
+
+BEGIN;
+
+ALTER TABLE warehouses ADD COLUMN IF NOT EXISTS is_transit BOOLEAN NOT NULL DEFAULT false;
+
+-- Una única ubicación de tránsito por usuario; su código no compite con los de los depósitos
+CREATE UNIQUE INDEX IF NOT EXISTS idx_warehouses_transit ON warehouses(user_id) WHERE is_transit;
+ALTER TABLE warehouses DROP CONSTRAINT IF EXISTS warehouses_user_id_code_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_warehouses_user_code ON warehouses(user_id, code) WHERE NOT is_transit;
+
+INSERT INTO warehouses (name, code, is_transit, user_id)
+SELECT DISTINCT 'En tránsito', 'TRANSITO', true, t.user_id
+FROM stock_transfers t
+WHERE t.status = 'in_transit'
+ON CONFLICT DO NOTHING;
+
+CREATE TEMP TABLE transit_items ON COMMIT DROP AS
+SELECT ti.id AS transfer_item_id, ti.transfer_id, ti.product_id, ti.quantity, t.user_id, w.id AS warehouse_id,
+    COALESCE(t.shipped_at, NOW()) AS shipped_at
+FROM stock_transfer_items ti
+JOIN stock_transfers t ON t.id = ti.transfer_id
+JOIN warehouses w ON w.user_id = t.user_id AND w.is_transit
+WHERE t.status = 'in_transit';
+
+UPDATE products p SET quantity = p.quantity + s.quantity
+FROM (SELECT product_id, SUM(quantity) AS quantity FROM transit_items GROUP BY product_id) s
+WHERE p.id = s.product_id;
+
+INSERT INTO product_stocks (product_id, warehouse_id, quantity)
+SELECT product_id, warehouse_id, SUM(quantity)
+FROM transit_items
+GROUP BY product_id, warehouse_id
+ON CONFLICT (product_id, warehouse_id)
+DO UPDATE SET quantity = product_stocks.quantity + EXCLUDED.quantity;
+
+INSERT INTO product_lots (product_id, warehouse_id, lot_number, expiry_date, quantity, received_quantity, purchase_order_id)
+SELECT ti.product_id, ti.warehouse_id, l.lot_number, MIN(l.expiry_date), SUM(til.quantity), 0, MIN(l.purchase_order_id)
+FROM transit_items ti
+JOIN stock_transfer_item_lots til ON til.transfer_item_id = ti.transfer_item_id
+JOIN product_lots l ON l.id = til.lot_id
+GROUP BY ti.product_id, ti.warehouse_id, l.lot_number
+ON CONFLICT (product_id, warehouse_id, lot_number)
+DO UPDATE SET quantity = product_lots.quantity + EXCLUDED.quantity;
+
+INSERT INTO stock_movements (product_id, warehouse_id, quantity_change, reason, reference_id, user_id, created_at)
+SELECT product_id, warehouse_id, quantity, 'TRANSFER_IN', transfer_id::text, user_id, shipped_at
+FROM transit_items;
+
+COMMIT;
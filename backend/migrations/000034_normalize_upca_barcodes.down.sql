@@ -0,0 +1,14 @@
+-- Los UPC-A vuelven a su código de 12 dígitos y los duplicados eliminados se recrean
+UPDATE product_barcodes b
+SET code = u.code, symbology = 'upca'
+FROM product_barcodes_upca u
+WHERE b.id = u.id;
+
+INSERT INTO product_barcodes (id, product_id, user_id, code, symbology, created_at)
+SELECT u.id, u.product_id, u.user_id, u.code, 'upca', u.created_at
+FROM product_barcodes_upca u
+JOIN products p ON p.id = u.product_id
+WHERE NOT EXISTS (SELECT 1 FROM product_barcodes b WHERE b.id = u.id)
+ON CONFLICT (user_id, code) DO NOTHING;
+
+DROP TABLE product_barcodes_upca;
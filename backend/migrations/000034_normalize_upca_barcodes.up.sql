@@ -0,0 +1,38 @@
+-- Migration: Los UPC-A se guardan como su EAN-13 equivalente
+-- Un UPC-A y el mismo código con un 0 adelante son el mismo producto; se unifican en la forma EAN-13
+-- para que no puedan cargarse dos veces ni dejen de encontrarse al escanear. Si la forma EAN-13 ya
+-- está cargada en el mismo producto, el UPC-A es un duplicado y se elimina; si está en otro producto
+-- de la cuenta la migración falla y lista los conflictos para resolverlos a mano.
+-- Los UPC-A originales quedan en product_barcodes_upca para que el down los restaure.
+
+BEGIN;
+
+DO $$
+DECLARE
+    conflicts TEXT;
+BEGIN
+    SELECT string_agg(format('user %s: %s (product %s) / %s (product %s)',
+                             b.user_id, b.code, b.product_id, e.code, e.product_id), '; ')
+    INTO conflicts
+    FROM product_barcodes b
+    JOIN product_barcodes e ON e.user_id = b.user_id AND e.code = '0' || b.code
+    WHERE b.symbology = 'upca' AND e.product_id <> b.product_id;
+
+    IF conflicts IS NOT NULL THEN
+        RAISE EXCEPTION 'UPC-A barcodes whose EAN-13 form belongs to another product: %', conflicts;
+    END IF;
+END $$;
+
+CREATE TABLE product_barcodes_upca AS
+SELECT id, product_id, user_id, code, created_at FROM product_barcodes WHERE symbology = 'upca';
+
+DELETE FROM product_barcodes b
+WHERE b.symbology = 'upca'
+  AND EXISTS (
+      SELECT 1 FROM product_barcodes e
+      WHERE e.user_id = b.user_id AND e.product_id = b.product_id AND e.code = '0' || b.code
+  );
+
+UPDATE product_barcodes SET code = '0' || code, symbology = 'ean13' WHERE symbology = 'upca';
+
+COMMIT;
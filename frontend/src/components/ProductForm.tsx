@@ -36,7 +36,7 @@ export default function ProductForm({ productToEdit, onSuccess }: Props) {
     setError(null)
     try {
       if (productToEdit) {
-        await api.put(`/products/${productToEdit.id}`, { name, sku, quantity, stock_minimo: stockMinimo })
+        await api.put(`/products/${productToEdit.id}`, { name, sku, stock_minimo: stockMinimo })
         toast.success('Producto actualizado correctamente')
       } else {
         await api.post('/products', { name, sku, quantity, stock_minimo: stockMinimo })
@@ -85,7 +85,13 @@ export default function ProductForm({ productToEdit, onSuccess }: Props) {
           onChange={(e) => setQuantity(Number(e.target.value))}
           required
           min={0}
+          disabled={!!productToEdit}
         />
+        {productToEdit && (
+          <p className="mt-1 text-xs text-gray-500">
+            El stock se modifica con un ajuste de stock
+          </p>
+        )}
       </div>
       <div>
         <label className="block text-sm font-medium text-gray-700">Stock Mínimo</label>
@@ -107,7 +107,10 @@ export default function SalesOrdersPage() {
             >
               <option value="">Todos</option>
               <option value="pending">Pendiente</option>
-              <option value="completed">Completado</option>
+              <option value="confirmed">Confirmado</option>
+              <option value="picking">En preparación</option>
+              <option value="shipped">Despachado</option>
+              <option value="delivered">Entregado</option>
               <option value="cancelled">Cancelado</option>
             </select>
           </div>
@@ -75,6 +75,18 @@ func main() {
 	// Crear el job de alertas de stock
 	stockAlertsJob := jobs.NewStockAlertsJob(ch)
 
+	// Crear el job de vencimiento de reservas de stock
+	reservationExpiryJob := jobs.NewReservationExpiryJob(ch)
+
+	// Crear el job de reconciliación del ledger de stock
+	ledgerJob := jobs.NewLedgerReconciliationJob(ch, cfg.LedgerAutoRepair)
+
+	// Crear el job de reposición (órdenes de compra en borrador)
+	reorderJob := jobs.NewReorderJob(ch)
+
+	// Crear el job de clasificación ABC/XYZ de los productos
+	classificationJob := jobs.NewClassificationJob(ch)
+
 	// Programar el job de reportes semanales
 	// Cron expression: "*/5 * * * *" = cada 5 minutos (para testing)
 	// Para producción: "0 9 * * MON" = cada lunes a las 9:00 AM
@@ -98,6 +110,46 @@ func main() {
 	}
 
 	log.Printf("👁️  Job de alertas de stock programado con expresión cron: %s", stockAlertsCron)
+
+	// Programar el job de vencimiento de reservas (cada 15 minutos)
+	reservationExpiryCron := "*/15 * * * *"
+
+	_, err = c.AddFunc(reservationExpiryCron, reservationExpiryJob.Execute)
+	if err != nil {
+		log.Fatalf("❌ Error al agregar job de vencimiento de reservas al scheduler: %v", err)
+	}
+
+	log.Printf("⏳ Job de vencimiento de reservas programado con expresión cron: %s", reservationExpiryCron)
+
+	// Programar el job de reconciliación del ledger (todos los días a las 3 AM)
+	ledgerCron := "0 3 * * *"
+
+	_, err = c.AddFunc(ledgerCron, ledgerJob.Execute)
+	if err != nil {
+		log.Fatalf("❌ Error al agregar job de reconciliación del ledger al scheduler: %v", err)
+	}
+
+	log.Printf("📒 Job de reconciliación del ledger programado con expresión cron: %s", ledgerCron)
+
+	// Programar el job de reposición (todos los días a las 6 AM, con el stock del cierre)
+	reorderCron := "0 6 * * *"
+
+	_, err = c.AddFunc(reorderCron, reorderJob.Execute)
+	if err != nil {
+		log.Fatalf("❌ Error al agregar job de reposición al scheduler: %v", err)
+	}
+
+	log.Printf("🛒 Job de reposición programado con expresión cron: %s", reorderCron)
+
+	// Programar el job de clasificación ABC/XYZ (los lunes a las 4 AM, con la semana anterior cerrada)
+	classificationCron := "0 4 * * MON"
+
+	_, err = c.AddFunc(classificationCron, classificationJob.Execute)
+	if err != nil {
+		log.Fatalf("❌ Error al agregar job de clasificación al scheduler: %v", err)
+	}
+
+	log.Printf("🔤 Job de clasificación ABC/XYZ programado con expresión cron: %s", classificationCron)
 	log.Println("🚀 Scheduler iniciado. Esperando próxima ejecución...")
 
 	// Iniciar el scheduler
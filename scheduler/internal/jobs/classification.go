@@ -0,0 +1,35 @@
+package jobs
+
+import (
+	"log"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// ClassificationJob pide al worker que recalcule la clasificación ABC/XYZ de los productos
+type ClassificationJob struct {
+	alerts *StockAlertsJob
+}
+
+// NewClassificationJob crea una nueva instancia del job
+func NewClassificationJob(ch *amqp.Channel) *ClassificationJob {
+	return &ClassificationJob{
+		alerts: NewStockAlertsJob(ch),
+	}
+}
+
+// Execute se ejecuta cuando el cron dispara la tarea
+func (j *ClassificationJob) Execute() {
+	log.Println("🔤 [SCHEDULER] Ejecutando job de clasificación ABC/XYZ...")
+
+	req := StockAlertRequest{
+		TaskType: "classify_products",
+	}
+
+	if err := j.alerts.publishStockAlert(req); err != nil {
+		log.Printf("❌ Error al publicar tarea de clasificación: %v", err)
+		return
+	}
+
+	log.Println("✅ Tarea de clasificación enviada a la cola")
+}
@@ -0,0 +1,39 @@
+package jobs
+
+import (
+	"log"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// LedgerReconciliationJob pide al worker que compare products.quantity con el ledger de movimientos
+type LedgerReconciliationJob struct {
+	alerts *StockAlertsJob
+	repair bool
+}
+
+// NewLedgerReconciliationJob crea una nueva instancia del job. Con repair el worker registra
+// movimientos OPENING_BALANCE / RECONCILIATION; sin repair sólo informa las diferencias.
+func NewLedgerReconciliationJob(ch *amqp.Channel, repair bool) *LedgerReconciliationJob {
+	return &LedgerReconciliationJob{
+		alerts: NewStockAlertsJob(ch),
+		repair: repair,
+	}
+}
+
+// Execute se ejecuta cuando el cron dispara la tarea
+func (j *LedgerReconciliationJob) Execute() {
+	log.Printf("📒 [SCHEDULER] Ejecutando job de reconciliación del ledger (repair=%t)...", j.repair)
+
+	req := StockAlertRequest{
+		TaskType: "reconcile_stock_ledger",
+		Repair:   j.repair,
+	}
+
+	if err := j.alerts.publishStockAlert(req); err != nil {
+		log.Printf("❌ Error al publicar tarea de reconciliación del ledger: %v", err)
+		return
+	}
+
+	log.Println("✅ Tarea de reconciliación del ledger enviada a la cola")
+}
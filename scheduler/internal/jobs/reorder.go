@@ -0,0 +1,36 @@
+package jobs
+
+import (
+	"log"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// ReorderJob pide al worker que arme órdenes de compra en borrador para los productos que
+// llegaron a su punto de pedido
+type ReorderJob struct {
+	alerts *StockAlertsJob
+}
+
+// NewReorderJob crea una nueva instancia del job
+func NewReorderJob(ch *amqp.Channel) *ReorderJob {
+	return &ReorderJob{
+		alerts: NewStockAlertsJob(ch),
+	}
+}
+
+// Execute se ejecuta cuando el cron dispara la tarea
+func (j *ReorderJob) Execute() {
+	log.Println("🛒 [SCHEDULER] Ejecutando job de reposición...")
+
+	req := StockAlertRequest{
+		TaskType: "draft_reorder_purchase_orders",
+	}
+
+	if err := j.alerts.publishStockAlert(req); err != nil {
+		log.Printf("❌ Error al publicar tarea de reposición: %v", err)
+		return
+	}
+
+	log.Println("✅ Tarea de reposición enviada a la cola")
+}
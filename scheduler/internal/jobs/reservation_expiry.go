@@ -0,0 +1,36 @@
+package jobs
+
+import (
+	"log"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// ReservationExpiryJob pide al worker que libere las reservas de stock de órdenes de venta vencidas
+type ReservationExpiryJob struct {
+	alerts *StockAlertsJob
+}
+
+// NewReservationExpiryJob crea una nueva instancia del job
+func NewReservationExpiryJob(ch *amqp.Channel) *ReservationExpiryJob {
+	return &ReservationExpiryJob{
+		alerts: NewStockAlertsJob(ch),
+	}
+}
+
+// Execute se ejecuta cuando el cron dispara la tarea
+func (j *ReservationExpiryJob) Execute() {
+	log.Println("⏳ [SCHEDULER] Ejecutando job de vencimiento de reservas...")
+
+	// La tarea viaja por la cola de alertas de stock, que atiende el mismo consumer del worker
+	req := StockAlertRequest{
+		TaskType: "release_expired_reservations",
+	}
+
+	if err := j.alerts.publishStockAlert(req); err != nil {
+		log.Printf("❌ Error al publicar tarea de vencimiento de reservas: %v", err)
+		return
+	}
+
+	log.Println("✅ Tarea de vencimiento de reservas enviada a la cola")
+}
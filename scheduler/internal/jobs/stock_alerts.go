@@ -13,6 +13,7 @@ import (
 // StockAlertRequest representa el mensaje que se enviará a la cola de alertas
 type StockAlertRequest struct {
 	TaskType string `json:"task_type"`
+	Repair   bool   `json:"repair,omitempty"` // sólo reconcile_stock_ledger
 }
 
 // StockAlertsJob es el job que chequea alertas de stock bajo cada hora
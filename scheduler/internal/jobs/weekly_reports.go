@@ -50,6 +50,11 @@ func (j *WeeklyReportsJob) Execute() {
 			Email:      "admin@stockinorder.com",
 			ReportType: "suppliers_weekly",
 		},
+		{
+			UserID:     1,
+			Email:      "admin@stockinorder.com",
+			ReportType: "dead_stock_weekly",
+		},
 	}
 
 	// Enviar cada reporte a la cola
@@ -14,8 +14,9 @@ import (
 type ProductAlert struct {
 	ID          int64
 	Name        string
-	Quantity    int
-	StockMinimo int
+	Quantity    float64
+	StockMinimo float64
+	BaseUnit    string
 	UserEmail   string
 }
 
@@ -25,11 +26,13 @@ func CheckStockLevels(db *pgxpool.Pool, emailClient *email.Client) error {
 
 	// Consulta SQL para obtener productos con stock bajo que no han sido notificados
 	query := `
-		SELECT p.id, p.name, p.quantity, p.stock_minimo, u.email
+		SELECT p.id, p.name, p.quantity, p.stock_minimo, p.base_unit, u.email
 		FROM products p
 		JOIN users u ON p.user_id = u.id
 		WHERE p.quantity <= p.stock_minimo 
 		  AND p.notificado = false
+		  AND NOT p.is_kit
+		  AND p.archived_at IS NULL
 		ORDER BY p.quantity ASC
 	`
 
@@ -42,7 +45,7 @@ func CheckStockLevels(db *pgxpool.Pool, emailClient *email.Client) error {
 	var alerts []ProductAlert
 	for rows.Next() {
 		var alert ProductAlert
-		err := rows.Scan(&alert.ID, &alert.Name, &alert.Quantity, &alert.StockMinimo, &alert.UserEmail)
+		err := rows.Scan(&alert.ID, &alert.Name, &alert.Quantity, &alert.StockMinimo, &alert.BaseUnit, &alert.UserEmail)
 		if err != nil {
 			log.Printf("❌ Error al escanear fila: %v", err)
 			continue
@@ -63,11 +66,11 @@ func CheckStockLevels(db *pgxpool.Pool, emailClient *email.Client) error {
 
 	// Procesar cada alerta
 	for _, alert := range alerts {
-		log.Printf("📧 Enviando alerta para producto: %s (Stock: %d/%d) a %s",
-			alert.Name, alert.Quantity, alert.StockMinimo, alert.UserEmail)
+		log.Printf("📧 Enviando alerta para producto: %s (Stock: %g/%g %s) a %s",
+			alert.Name, alert.Quantity, alert.StockMinimo, alert.BaseUnit, alert.UserEmail)
 
 		// Enviar el email de alerta
-		if err := emailClient.SendStockAlertEmail(alert.UserEmail, alert.Name, alert.Quantity, alert.StockMinimo); err != nil {
+		if err := emailClient.SendStockAlertEmail(alert.UserEmail, alert.Name, alert.Quantity, alert.StockMinimo, alert.BaseUnit); err != nil {
 			log.Printf("❌ Error al enviar email para producto ID %d: %v", alert.ID, err)
 			continue
 		}
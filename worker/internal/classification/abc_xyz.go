@@ -0,0 +1,206 @@
+package classification
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Parámetros de la clasificación
+const (
+	lookbackWeeks = 52 // ventana de ventas analizada (semanas completas)
+
+	abcLimitA = 0.80 // A: productos que acumulan el primer 80% de la facturación
+	abcLimitB = 0.95 // B: los que completan el 95%; C: el resto
+
+	xyzLimitX = 0.5 // X: coeficiente de variación de la demanda semanal hasta 0,5
+	xyzLimitY = 1.0 // Y: hasta 1; Z: más variable o sin ventas
+)
+
+// productSales es la facturación y la demanda semanal de un producto en la ventana analizada.
+type productSales struct {
+	ID      int64
+	UserID  int64
+	Revenue float64
+	Weekly  []float64
+}
+
+// ClassifyProducts clasifica los productos activos (de un usuario, o de todos si userID es 0) en
+// A/B/C según su aporte a la facturación de las últimas 52 semanas (Pareto) y en X/Y/Z según la
+// variabilidad de su demanda semanal, y guarda la clase en cada producto.
+func ClassifyProducts(db *pgxpool.Pool, userID int64) error {
+	log.Println("🔍 Clasificando productos (ABC/XYZ)...")
+	ctx := context.Background()
+
+	products, err := loadSales(ctx, db, userID)
+	if err != nil {
+		return fmt.Errorf("error al leer las ventas: %w", err)
+	}
+	if len(products) == 0 {
+		log.Println("✅ No hay productos para clasificar")
+		return nil
+	}
+
+	// Los productos vienen ordenados por usuario: el Pareto se arma por cuenta
+	classified := 0
+	for start := 0; start < len(products); {
+		end := start + 1
+		for end < len(products) && products[end].UserID == products[start].UserID {
+			end++
+		}
+		if err := saveClasses(ctx, db, products[start:end]); err != nil {
+			log.Printf("❌ Error al guardar la clasificación (usuario %d): %v", products[start].UserID, err)
+		} else {
+			classified += end - start
+		}
+		start = end
+	}
+
+	log.Printf("✅ Productos clasificados: %d", classified)
+	return nil
+}
+
+// loadSales lee los productos activos con su facturación y su demanda (en unidad base) de cada una
+// de las últimas lookbackWeeks semanas completas. Las órdenes canceladas no cuentan.
+func loadSales(ctx context.Context, db *pgxpool.Pool, userID int64) ([]productSales, error) {
+	rows, err := db.Query(ctx, `
+		SELECT id, user_id FROM products
+		WHERE archived_at IS NULL AND ($1::bigint = 0 OR user_id = $1)
+		ORDER BY user_id, id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	var products []productSales
+	index := map[int64]int{}
+	for rows.Next() {
+		p := productSales{Weekly: make([]float64, lookbackWeeks)}
+		if err := rows.Scan(&p.ID, &p.UserID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		index[p.ID] = len(products)
+		products = append(products, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = db.Query(ctx, `
+		WITH bounds AS (
+			SELECT date_trunc('week', NOW())::date - $2::int * 7 AS week_from, date_trunc('week', NOW())::date AS week_to
+		)
+		SELECT oi.product_id, (date_trunc('week', so.order_date)::date - b.week_from) / 7,
+			SUM(oi.quantity), SUM(oi.unit_quantity * oi.unit_price)
+		FROM order_items oi
+		JOIN sales_orders so ON so.id = oi.order_id
+		CROSS JOIN bounds b
+		WHERE ($1::bigint = 0 OR so.user_id = $1) AND so.status <> 'cancelled'
+			AND so.order_date >= b.week_from AND so.order_date < b.week_to
+		GROUP BY 1, 2`, userID, lookbackWeeks)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var week int
+		var qty, revenue float64
+		if err := rows.Scan(&id, &week, &qty, &revenue); err != nil {
+			return nil, err
+		}
+		i, ok := index[id]
+		if !ok || week < 0 || week >= lookbackWeeks {
+			continue
+		}
+		products[i].Weekly[week] += qty
+		products[i].Revenue += revenue
+	}
+	return products, rows.Err()
+}
+
+// abcClasses asigna la clase ABC a los productos de una cuenta: ordenados por facturación, son A
+// mientras lo acumulado antes de cada uno no llega al 80% del total, B hasta el 95% y C el resto.
+// Los productos sin facturación son siempre C.
+func abcClasses(products []productSales) map[int64]string {
+	sorted := make([]productSales, len(products))
+	copy(sorted, products)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Revenue > sorted[j].Revenue })
+
+	var total float64
+	for _, p := range sorted {
+		total += p.Revenue
+	}
+
+	classes := make(map[int64]string, len(sorted))
+	var cumulative float64
+	for _, p := range sorted {
+		switch {
+		case p.Revenue <= 0:
+			classes[p.ID] = "C"
+		case cumulative < abcLimitA*total:
+			classes[p.ID] = "A"
+		case cumulative < abcLimitB*total:
+			classes[p.ID] = "B"
+		default:
+			classes[p.ID] = "C"
+		}
+		cumulative += p.Revenue
+	}
+	return classes
+}
+
+// xyzClass clasifica la demanda semanal por su coeficiente de variación (desvío / promedio).
+func xyzClass(weekly []float64) string {
+	var sum float64
+	for _, v := range weekly {
+		sum += v
+	}
+	if sum <= 0 {
+		return "Z"
+	}
+	mean := sum / float64(len(weekly))
+
+	var variance float64
+	for _, v := range weekly {
+		variance += (v - mean) * (v - mean)
+	}
+	cv := math.Sqrt(variance/float64(len(weekly))) / mean
+
+	switch {
+	case cv <= xyzLimitX:
+		return "X"
+	case cv <= xyzLimitY:
+		return "Y"
+	default:
+		return "Z"
+	}
+}
+
+// saveClasses calcula y guarda la clasificación de los productos de una cuenta en una transacción.
+func saveClasses(ctx context.Context, db *pgxpool.Pool, products []productSales) error {
+	abc := abcClasses(products)
+	ids := make([]int64, len(products))
+	abcs := make([]string, len(products))
+	xyzs := make([]string, len(products))
+	for i, p := range products {
+		ids[i] = p.ID
+		abcs[i] = abc[p.ID]
+		xyzs[i] = xyzClass(p.Weekly)
+	}
+
+	return pgx.BeginFunc(ctx, db, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `
+			UPDATE products p
+			SET abc_class = c.abc, xyz_class = c.xyz, classified_at = NOW()
+			FROM unnest($1::bigint[], $2::text[], $3::text[]) AS c(id, abc, xyz)
+			WHERE p.id = c.id`, ids, abcs, xyzs)
+		return err
+	})
+}
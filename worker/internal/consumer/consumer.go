@@ -9,10 +9,15 @@ import (
 	amqp "github.com/rabbitmq/amqp091-go"
 
 	"stock-in-order/worker/internal/alerts"
+	"stock-in-order/worker/internal/classification"
 	"stock-in-order/worker/internal/email"
+	"stock-in-order/worker/internal/imports"
 	"stock-in-order/worker/internal/melisales"
 	"stock-in-order/worker/internal/models"
+	"stock-in-order/worker/internal/reconciliation"
+	"stock-in-order/worker/internal/reorder"
 	"stock-in-order/worker/internal/reports"
+	"stock-in-order/worker/internal/reservations"
 	"stock-in-order/worker/internal/services"
 )
 
@@ -20,12 +25,24 @@ import (
 type ReportRequest struct {
 	UserID     int64  `json:"user_id"`
 	Email      string `json:"email_to"`
-	ReportType string `json:"report_type"` // "products", "customers", "suppliers"
+	ReportType string `json:"report_type"` // "products", "customers", "suppliers", "dead_stock"
+
+	// Parámetros de dead_stock: período en días y rotación mínima (0 / nil = por defecto)
+	Days        int      `json:"days,omitempty"`
+	MaxTurnover *float64 `json:"max_turnover,omitempty"`
 }
 
 // StockAlertRequest representa la estructura del mensaje de alertas de stock
 type StockAlertRequest struct {
-	TaskType string `json:"task_type"` // "check_stock_levels"
+	TaskType string `json:"task_type"` // "check_stock_levels" | "release_expired_reservations" | "reconcile_stock_ledger" | "import_products" | "draft_reorder_purchase_orders" | "classify_products"
+
+	// Parámetros de reconcile_stock_ledger, draft_reorder_purchase_orders y classify_products: UserID 0 = todas las cuentas;
+	// Repair registra los movimientos de corrección
+	UserID int64 `json:"user_id,omitempty"`
+	Repair bool  `json:"repair,omitempty"`
+
+	// Parámetro de import_products: la importación (product_imports) a procesar
+	ImportID int64 `json:"import_id,omitempty"`
 }
 
 // StartConsumer inicia el consumidor que escucha la cola de RabbitMQ
@@ -186,22 +203,34 @@ func StartConsumer(rabbitURL string, db *pgxpool.Pool, emailClient *email.Client
 				continue
 			}
 
-			// Verificar que sea el tipo correcto de tarea
-			if req.TaskType != "check_stock_levels" {
+			// Despachar según el tipo de tarea
+			var taskErr error
+			switch req.TaskType {
+			case "check_stock_levels":
+				taskErr = alerts.CheckStockLevels(db, emailClient)
+			case "release_expired_reservations":
+				taskErr = reservations.ReleaseExpired(db)
+			case "reconcile_stock_ledger":
+				taskErr = reconciliation.ReconcileLedger(db, req.UserID, req.Repair)
+			case "import_products":
+				taskErr = imports.ImportProducts(db, req.ImportID)
+			case "draft_reorder_purchase_orders":
+				taskErr = reorder.DraftPurchaseOrders(db, req.UserID)
+			case "classify_products":
+				taskErr = classification.ClassifyProducts(db, req.UserID)
+			default:
 				log.Printf("⚠️  Tipo de tarea desconocido: %s", req.TaskType)
 				d.Nack(false, false)
 				continue
 			}
-
-			// Procesar el chequeo de stock
-			if err := alerts.CheckStockLevels(db, emailClient); err != nil {
-				log.Printf("❌ Error al chequear niveles de stock: %v", err)
+			if taskErr != nil {
+				log.Printf("❌ Error al procesar tarea %s: %v", req.TaskType, taskErr)
 				d.Nack(false, true) // Reencolar para reintentar
 				continue
 			}
 
 			// Confirmar que el mensaje fue procesado exitosamente
-			log.Printf("✅ Chequeo de stock completado exitosamente")
+			log.Printf("✅ Tarea %s completada exitosamente", req.TaskType)
 			d.Ack(false)
 		}
 	}()
@@ -262,6 +291,12 @@ func processReport(db *pgxpool.Pool, emailClient *email.Client, req ReportReques
 	case "suppliers_weekly":
 		reportBytes, err = reports.GenerateSuppliersReport(db, req.UserID)
 		filename = "reporte_proveedores_semanal.xlsx"
+	case "dead_stock":
+		reportBytes, err = reports.GenerateDeadStockReport(db, req.UserID, req.Days, maxTurnover(req))
+		filename = "reporte_stock_inmovilizado.xlsx"
+	case "dead_stock_weekly":
+		reportBytes, err = reports.GenerateDeadStockReport(db, req.UserID, req.Days, maxTurnover(req))
+		filename = "reporte_stock_inmovilizado_semanal.xlsx"
 	default:
 		return fmt.Errorf("unknown report type: %s", req.ReportType)
 	}
@@ -287,3 +322,11 @@ func processReport(db *pgxpool.Pool, emailClient *email.Client, req ReportReques
 
 	return nil
 }
+
+// maxTurnover devuelve la rotación mínima pedida, o -1 para usar la del reporte por defecto.
+func maxTurnover(req ReportRequest) float64 {
+	if req.MaxTurnover == nil {
+		return -1
+	}
+	return *req.MaxTurnover
+}
@@ -87,9 +87,9 @@ func (c *Client) SendReportEmail(toEmail, toName, reportType string, attachment
 }
 
 // SendStockAlertEmail envía un email de alerta de stock bajo
-func (c *Client) SendStockAlertEmail(toEmail, productName string, currentStock, minStock int) error {
+func (c *Client) SendStockAlertEmail(toEmail, productName string, currentStock, minStock float64, unit string) error {
 	if c.isDisabled {
-		log.Printf("📧 [MODO DEV] Alerta de stock simulada a %s - Producto: %s (%d/%d)", toEmail, productName, currentStock, minStock)
+		log.Printf("📧 [MODO DEV] Alerta de stock simulada a %s - Producto: %s (%g/%g %s)", toEmail, productName, currentStock, minStock, unit)
 		return nil
 	}
 
@@ -135,10 +135,10 @@ func (c *Client) SendStockAlertEmail(toEmail, productName string, currentStock,
             
             <div class="stock-info">
                 <p style="margin: 5px 0; color: #666;">Stock Actual:</p>
-                <div class="stock-current">%d unidades</div>
+                <div class="stock-current">%g %s</div>
                 
                 <p style="margin: 15px 0 5px 0; color: #666;">Tu Stock Mínimo:</p>
-                <div class="stock-min">%d unidades</div>
+                <div class="stock-min">%g %s</div>
             </div>
             
             <div class="alert-box">
@@ -155,7 +155,7 @@ func (c *Client) SendStockAlertEmail(toEmail, productName string, currentStock,
     </div>
 </body>
 </html>
-`, productName, currentStock, minStock)
+`, productName, currentStock, unit, minStock, unit)
 
 	// Crear el mensaje
 	message := mail.NewSingleEmail(from, subject, to, "", htmlContent)
@@ -426,6 +426,87 @@ func getEmailContent(reportType string) (subject string, htmlContent string) {
     </div>
 </body>
 </html>
+`
+	case "dead_stock":
+		subject = "🐢 Tu Reporte de Stock Inmovilizado está Listo"
+		htmlContent = `
+<!DOCTYPE html>
+<html>
+<head>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background: linear-gradient(135deg, #f7971e 0%, #ffd200 100%); color: white; padding: 30px; text-align: center; border-radius: 10px 10px 0 0; }
+        .content { background: #f9f9f9; padding: 30px; border-radius: 0 0 10px 10px; }
+        .footer { text-align: center; margin-top: 20px; color: #666; font-size: 12px; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>🐢 Reporte de Stock Inmovilizado</h1>
+        </div>
+        <div class="content">
+            <p>¡Hola!</p>
+            <p>Tu reporte de <strong>Stock Inmovilizado</strong> ha sido generado exitosamente y está adjunto en este email.</p>
+            <p>El archivo está en formato Excel (.xlsx) y lista los productos sin ventas en el período o con baja rotación, para decidir qué liquidar.</p>
+            <p><strong>¿Qué incluye el reporte?</strong></p>
+            <ul>
+                <li>✅ Stock actual y su valor al costo</li>
+                <li>✅ Unidades vendidas en el período y rotación</li>
+                <li>✅ Fecha de la última venta</li>
+                <li>✅ Fecha de la última recepción</li>
+            </ul>
+            <p>Gracias por usar <strong>Stock in Order</strong>.</p>
+        </div>
+        <div class="footer">
+            <p>Este es un email automático, por favor no responder.</p>
+            <p>Stock in Order &copy; 2025</p>
+        </div>
+    </div>
+</body>
+</html>
+`
+	case "dead_stock_weekly":
+		subject = "🐢 Reporte Semanal de Stock Inmovilizado"
+		htmlContent = `
+<!DOCTYPE html>
+<html>
+<head>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background: linear-gradient(135deg, #f7971e 0%, #ffd200 100%); color: white; padding: 30px; text-align: center; border-radius: 10px 10px 0 0; }
+        .content { background: #f9f9f9; padding: 30px; border-radius: 0 0 10px 10px; }
+        .footer { text-align: center; margin-top: 20px; color: #666; font-size: 12px; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>🐢 Reporte Semanal de Stock Inmovilizado</h1>
+        </div>
+        <div class="content">
+            <p>¡Hola!</p>
+            <p>Tu <strong>reporte semanal de Stock Inmovilizado</strong> ha sido generado automáticamente y está adjunto en este email.</p>
+            <p>El archivo está en formato Excel (.xlsx) y lista los productos sin ventas en el período o con baja rotación, para decidir qué liquidar.</p>
+            <p><strong>¿Qué incluye el reporte?</strong></p>
+            <ul>
+                <li>✅ Stock actual y su valor al costo</li>
+                <li>✅ Unidades vendidas en el período y rotación</li>
+                <li>✅ Fecha de la última venta</li>
+                <li>✅ Fecha de la última recepción</li>
+            </ul>
+            <p>Este reporte se genera automáticamente cada semana.</p>
+            <p>Gracias por usar <strong>Stock in Order</strong>.</p>
+        </div>
+        <div class="footer">
+            <p>Este es un email automático, por favor no responder.</p>
+            <p>Stock in Order &copy; 2025</p>
+        </div>
+    </div>
+</body>
+</html>
 `
 	default:
 		subject = "📊 Tu Reporte está Listo"
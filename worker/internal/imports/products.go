@@ -0,0 +1,140 @@
+package imports
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"stock-in-order/backend/inventory"
+)
+
+// ChunkSize es la cantidad de filas que se importan por transacción. Cada tanda confirmada
+// actualiza el progreso, así que si el worker se reinicia la importación sigue desde ahí.
+const ChunkSize = 200
+
+// Columns indica qué columnas opcionales trajo el archivo (las ausentes no se modifican) y Row es
+// una fila ya validada por el backend, con la categoría resuelta.
+type (
+	Columns = inventory.ImportColumns
+	Row     = inventory.ImportRow
+)
+
+// Payload es el contenido de product_imports.payload.
+type Payload struct {
+	Columns Columns `json:"columns"`
+	Rows    []Row   `json:"rows"`
+}
+
+// ImportProducts procesa una importación de productos encolada por el backend: crea o actualiza
+// por SKU las filas pendientes en tandas de ChunkSize. Un error de datos marca la importación como
+// fallida (las tandas anteriores quedan importadas); sólo se devuelven los errores de infraestructura.
+func ImportProducts(db *pgxpool.Pool, importID int64) error {
+	ctx := context.Background()
+
+	const qStart = `
+		UPDATE product_imports SET status = 'running', started_at = COALESCE(started_at, NOW())
+		WHERE id = $1 AND status IN ('pending', 'running')
+		RETURNING user_id, payload, processed_rows`
+	var (
+		userID    int64
+		raw       []byte
+		processed int
+	)
+	if err := db.QueryRow(ctx, qStart, importID).Scan(&userID, &raw, &processed); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("⚠️  Importación %d inexistente o ya terminada", importID)
+			return nil
+		}
+		return fmt.Errorf("error al iniciar la importación %d: %w", importID, err)
+	}
+
+	var payload Payload
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return markFailed(ctx, db, importID, "payload inválido")
+	}
+	log.Printf("📥 Importando %d productos (importación %d, usuario %d, desde la fila %d)",
+		len(payload.Rows), importID, userID, processed)
+
+	for start := processed; start < len(payload.Rows); start += ChunkSize {
+		end := min(start+ChunkSize, len(payload.Rows))
+		if err := importChunk(ctx, db, importID, userID, payload.Columns, payload.Rows[start:end]); err != nil {
+			var rowErr *rowError
+			if errors.As(err, &rowErr) {
+				log.Printf("❌ Importación %d fallida: %v", importID, err)
+				return markFailed(ctx, db, importID, err.Error())
+			}
+			return fmt.Errorf("error al importar productos: %w", err)
+		}
+		log.Printf("   ... %d/%d filas", end, len(payload.Rows))
+	}
+
+	const qDone = `
+		UPDATE product_imports SET status = 'completed', payload = NULL, finished_at = NOW()
+		WHERE id = $1`
+	if _, err := db.Exec(ctx, qDone, importID); err != nil {
+		return fmt.Errorf("error al finalizar la importación %d: %w", importID, err)
+	}
+	log.Printf("✅ Importación %d completada", importID)
+	return nil
+}
+
+// rowError es un error al importar una fila: los datos cambiaron desde la validación.
+type rowError struct {
+	line int
+	err  error
+}
+
+func (e *rowError) Error() string { return fmt.Sprintf("fila %d: %v", e.line, e.err) }
+
+// errArchived indica que la fila es de un producto archivado después de la validación.
+var errArchived = errors.New("el producto está archivado; restauralo antes de importarlo")
+
+// importChunk importa una tanda de filas y actualiza el progreso en la misma transacción.
+func importChunk(ctx context.Context, db *pgxpool.Pool, importID int64, userID int64, cols Columns, rows []Row) error {
+	return pgx.BeginFunc(ctx, db, func(tx pgx.Tx) error {
+		// Los cambios quedan en el historial a nombre del usuario que importó
+		if _, err := tx.Exec(ctx, `SELECT set_config('app.actor_id', $1, true)`, strconv.FormatInt(userID, 10)); err != nil {
+			return err
+		}
+
+		created, updated := 0, 0
+		for _, row := range rows {
+			isNew, err := inventory.ImportProduct(ctx, tx, userID, cols, row)
+			if errors.Is(err, inventory.ErrProductArchived) {
+				err = errArchived
+			}
+			if err != nil {
+				return &rowError{line: row.Line, err: err}
+			}
+			if isNew {
+				created++
+			} else {
+				updated++
+			}
+		}
+
+		const qProgress = `
+			UPDATE product_imports
+			SET processed_rows = processed_rows + $2, created_rows = created_rows + $3, updated_rows = updated_rows + $4
+			WHERE id = $1`
+		_, err := tx.Exec(ctx, qProgress, importID, len(rows), created, updated)
+		return err
+	})
+}
+
+// markFailed marca la importación como fallida con el motivo.
+func markFailed(ctx context.Context, db *pgxpool.Pool, importID int64, reason string) error {
+	const q = `
+		UPDATE product_imports SET status = 'failed', error = $2, payload = NULL, finished_at = NOW()
+		WHERE id = $1`
+	if _, err := db.Exec(ctx, q, importID, reason); err != nil {
+		return fmt.Errorf("error al marcar la importación %d como fallida: %w", importID, err)
+	}
+	return nil
+}
@@ -2,7 +2,6 @@ package melisales
 
 import (
 	"context"
-	"database/sql"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -12,6 +11,7 @@ import (
 
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	"stock-in-order/backend/inventory"
 	"stock-in-order/worker/internal/models"
 	"stock-in-order/worker/internal/services"
 )
@@ -28,26 +28,6 @@ type MercadoLibreNotification struct {
 	Received      string `json:"received"`
 }
 
-// SalesOrder representa una orden de venta en nuestro sistema
-type SalesOrder struct {
-	ID           int64           `json:"id"`
-	CustomerID   sql.NullInt64   `json:"customer_id"`
-	CustomerName string          `json:"customer_name,omitempty"`
-	OrderDate    time.Time       `json:"order_date"`
-	Status       string          `json:"status"`
-	TotalAmount  sql.NullFloat64 `json:"total_amount"`
-	UserID       int64           `json:"user_id"`
-}
-
-// OrderItem representa un item de la orden
-type OrderItem struct {
-	ID        int64   `json:"id"`
-	OrderID   int64   `json:"order_id"`
-	ProductID int64   `json:"product_id"`
-	Quantity  int     `json:"quantity"`
-	UnitPrice float64 `json:"unit_price"`
-}
-
 // ProcessSale procesa una venta de Mercado Libre
 func ProcessSale(db *pgxpool.Pool, mlService *services.MercadoLibreService, integrationModel *models.IntegrationModel, notificationBody []byte, encryptionKey string) error {
 	// 1. Parsear la notificación
@@ -96,7 +76,7 @@ func ProcessSale(db *pgxpool.Pool, mlService *services.MercadoLibreService, inte
 	}
 
 	// 7. Mapear los items de la orden a productos en nuestro sistema
-	orderItems, err := mapOrderItems(db, mlOrder, integration.UserID)
+	orderItems, needsSerials, err := mapOrderItems(db, mlOrder, integration.UserID)
 	if err != nil {
 		return fmt.Errorf("error mapping order items: %w", err)
 	}
@@ -106,21 +86,26 @@ func ProcessSale(db *pgxpool.Pool, mlService *services.MercadoLibreService, inte
 		return fmt.Errorf("no matching products found for order %d", orderID)
 	}
 
-	// 8. Crear la orden de venta en nuestro sistema
-	salesOrder := &SalesOrder{
-		CustomerID:   sql.NullInt64{Valid: false}, // No tenemos customer ID de Mercado Libre
-		CustomerName: fmt.Sprintf("%s %s (%s)", mlOrder.Buyer.FirstName, mlOrder.Buyer.LastName, mlOrder.Buyer.Nickname),
-		OrderDate:    time.Now(),
-		Status:       "completed",
-		TotalAmount:  sql.NullFloat64{Float64: mlOrder.TotalAmount, Valid: true},
-		UserID:       integration.UserID,
+	// 8. Crear la orden de venta en nuestro sistema, por el mismo camino que las ventas de la API: el
+	// stock se descuenta al crearla (se marca entregada desde el backend). Si tiene productos
+	// serializados queda confirmada con el stock reservado hasta que se asignen las series y se despache.
+	salesOrder := &inventory.SalesOrder{
+		OrderDate:      time.Now(),
+		Status:         inventory.SalesOrderShipped,
+		UserID:         integration.UserID,
+		FlagBelowFloor: true,
 	}
-
-	if err := createSalesOrder(db, salesOrder, orderItems); err != nil {
+	if needsSerials {
+		salesOrder.Status = inventory.SalesOrderConfirmed
+	}
+	if err := inventory.CreateSalesOrder(db, salesOrder, orderItems); err != nil {
 		return fmt.Errorf("error creating sales order: %w", err)
 	}
+	if needsSerials {
+		log.Printf("⚠️  La orden %d tiene productos serializados: queda confirmada hasta que se asignen sus series", salesOrder.ID)
+	}
 
-	log.Printf("✅ Orden de venta creada exitosamente - ID: %d, Total: %.2f", salesOrder.ID, mlOrder.TotalAmount)
+	log.Printf("✅ Orden de venta creada exitosamente - ID: %d, Total: %.2f", salesOrder.ID, salesOrder.TotalAmount.Float64)
 
 	return nil
 }
@@ -154,106 +139,87 @@ func isOrderValid(status string) bool {
 	return false
 }
 
-// mapOrderItems mapea los items de Mercado Libre a productos en nuestro sistema
-func mapOrderItems(db *pgxpool.Pool, mlOrder *services.MLOrder, userID int64) ([]OrderItem, error) {
+// mapOrderItems mapea los items de Mercado Libre a productos en nuestro sistema.
+// needsSerials indica si alguno es serializado (o un kit con componentes serializados): Mercado
+// Libre no informa las series, así que hay que asignarlas antes de despachar la orden.
+func mapOrderItems(db *pgxpool.Pool, mlOrder *services.MLOrder, userID int64) (items []inventory.OrderItem, needsSerials bool, err error) {
 	ctx := context.Background()
-	var items []OrderItem
 
 	for _, mlItem := range mlOrder.OrderItems {
-		// Buscar producto por SKU
-		sku := mlItem.Item.SellerSKU
-		if sku == "" {
-			log.Printf("⚠️  Item sin SKU - ID: %s, Title: %s", mlItem.Item.ID, mlItem.Item.Title)
+		productID, ok := matchProduct(ctx, db, userID, mlItem.Item.VariationID, mlItem.Item.SellerSKU, mlItem.Item.ID, mlItem.Item.Title)
+		if !ok {
 			continue
 		}
 
-		var productID int64
-		query := `SELECT id FROM products WHERE sku = $1 AND user_id = $2`
-		err := db.QueryRow(ctx, query, sku, userID).Scan(&productID)
+		serialized, err := requiresSerials(ctx, db, productID)
 		if err != nil {
-			log.Printf("⚠️  Producto no encontrado - SKU: %s, Error: %v", sku, err)
-			continue
+			return nil, false, err
 		}
+		needsSerials = needsSerials || serialized
 
-		items = append(items, OrderItem{
-			ProductID: productID,
-			Quantity:  mlItem.Quantity,
-			UnitPrice: mlItem.UnitPrice,
+		items = append(items, inventory.OrderItem{
+			ProductID:    productID,
+			UnitQuantity: float64(mlItem.Quantity),
+			UnitPrice:    mlItem.UnitPrice,
+			PriceGiven:   true,
 		})
-
-		log.Printf("✅ Item mapeado - SKU: %s → ProductID: %d, Quantity: %d", sku, productID, mlItem.Quantity)
 	}
 
-	return items, nil
+	return items, needsSerials, nil
 }
 
-// createSalesOrder crea una orden de venta en la base de datos
-func createSalesOrder(db *pgxpool.Pool, order *SalesOrder, items []OrderItem) error {
-	ctx := context.Background()
-	tx, err := db.Begin(ctx)
-	if err != nil {
-		return err
-	}
-	defer func() {
-		if tx != nil {
-			_ = tx.Rollback(ctx)
+// matchProduct busca el producto de un item de Mercado Libre: primero por la variante vinculada a
+// la publicación y si no por SKU. ok es false si no hay un producto que coincida o si está
+// archivado (los productos archivados no pueden usarse en órdenes nuevas).
+func matchProduct(ctx context.Context, db *pgxpool.Pool, userID int64, variationID int64, sku, itemID, title string) (int64, bool) {
+	var productID int64
+	var archived bool
+
+	// Si el item es una variación de la publicación, buscar primero la variante vinculada
+	if variationID != 0 {
+		query := `SELECT id, archived_at IS NOT NULL FROM products WHERE ml_variation_id = $1 AND user_id = $2`
+		if err := db.QueryRow(ctx, query, variationID, userID).Scan(&productID, &archived); err == nil {
+			if archived {
+				log.Printf("⚠️  Producto archivado - VariationID: %d → ProductID: %d, no se descuenta", variationID, productID)
+				return 0, false
+			}
+			log.Printf("✅ Item mapeado - VariationID: %d → ProductID: %d", variationID, productID)
+			return productID, true
 		}
-	}()
-
-	// Insertar el header de la orden
-	const insertOrder = `
-		INSERT INTO sales_orders (customer_id, order_date, status, total_amount, user_id)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, order_date`
-
-	if err := tx.QueryRow(ctx, insertOrder,
-		order.CustomerID, order.OrderDate, order.Status, order.TotalAmount, order.UserID,
-	).Scan(&order.ID, &order.OrderDate); err != nil {
-		return err
 	}
 
-	// Insertar items y actualizar stock
-	const insertItem = `
-		INSERT INTO order_items (order_id, product_id, quantity, unit_price)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id`
-	const updateStock = `
-		UPDATE products SET quantity = quantity - $1
-		WHERE id = $2 AND user_id = $3 AND quantity - $1 >= 0`
-
-	for i := range items {
-		items[i].OrderID = order.ID
-
-		// Insertar item
-		if err := tx.QueryRow(ctx, insertItem,
-			items[i].OrderID, items[i].ProductID, items[i].Quantity, items[i].UnitPrice,
-		).Scan(&items[i].ID); err != nil {
-			return err
-		}
-
-		// Actualizar stock (asegurar que no sea negativo)
-		tag, err := tx.Exec(ctx, updateStock, items[i].Quantity, items[i].ProductID, order.UserID)
-		if err != nil {
-			return err
-		}
-		if tag.RowsAffected() == 0 {
-			return fmt.Errorf("insufficient stock for product %d", items[i].ProductID)
-		}
-
-		// Insertar movimiento de stock
-		const insertMovement = `
-			INSERT INTO stock_movements (product_id, movement_type, quantity, reference_id, user_id)
-			VALUES ($1, 'sale', $2, $3, $4)`
-
-		_, err = tx.Exec(ctx, insertMovement, items[i].ProductID, -items[i].Quantity, order.ID, order.UserID)
-		if err != nil {
-			return err
-		}
+	// Buscar producto por SKU
+	if sku == "" {
+		log.Printf("⚠️  Item sin SKU - ID: %s, Title: %s", itemID, title)
+		return 0, false
 	}
 
-	if err := tx.Commit(ctx); err != nil {
-		return err
+	query := `SELECT id, archived_at IS NOT NULL FROM products WHERE sku = $1 AND user_id = $2`
+	if err := db.QueryRow(ctx, query, sku, userID).Scan(&productID, &archived); err != nil {
+		log.Printf("⚠️  Producto no encontrado - SKU: %s, Error: %v", sku, err)
+		return 0, false
+	}
+	if archived {
+		log.Printf("⚠️  Producto archivado - SKU: %s → ProductID: %d, no se descuenta", sku, productID)
+		return 0, false
 	}
 
-	return nil
+	log.Printf("✅ Item mapeado - SKU: %s → ProductID: %d", sku, productID)
+	return productID, true
+}
+
+// requiresSerials indica si vender el producto exige indicar series: es serializado o es un kit con
+// algún componente serializado.
+func requiresSerials(ctx context.Context, db *pgxpool.Pool, productID int64) (bool, error) {
+	const q = `
+		SELECT p.is_serialized OR EXISTS (
+			SELECT 1 FROM product_components pc
+			JOIN products c ON c.id = pc.component_id
+			WHERE pc.kit_id = p.id AND c.is_serialized
+		)
+		FROM products p
+		WHERE p.id = $1`
+	var serialized bool
+	err := db.QueryRow(ctx, q, productID).Scan(&serialized)
+	return serialized, err
 }
@@ -13,7 +13,7 @@ type Product struct {
 	Name        string    `json:"name"`
 	SKU         string    `json:"sku"`
 	Description *string   `json:"description,omitempty"`
-	Quantity    int       `json:"quantity"`
+	Quantity    float64   `json:"quantity"`
 	UserID      int64     `json:"user_id"`
 	CreatedAt   time.Time `json:"created_at"`
 }
@@ -0,0 +1,316 @@
+package reconciliation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"stock-in-order/backend/inventory"
+)
+
+// Drift es la diferencia entre el stock de un producto en un depósito (product_stocks) y la suma de
+// sus movimientos en ese depósito. WarehouseID es nil para los movimientos sin depósito.
+type Drift struct {
+	ProductID      int64
+	UserID         int64
+	SKU            string
+	WarehouseID    *int64
+	Quantity       float64
+	LedgerQuantity float64
+}
+
+// findDrift devuelve los pares producto-depósito (de un usuario, o de todos si userID es 0) cuyo
+// ledger no cuadra con el stock del depósito.
+func findDrift(ctx context.Context, db *pgxpool.Pool, userID int64) ([]Drift, error) {
+	const q = `
+		WITH ledger AS (
+			SELECT product_id, warehouse_id, SUM(quantity_change) AS quantity
+			FROM stock_movements
+			GROUP BY product_id, warehouse_id
+		)
+		SELECT p.id, p.user_id, p.sku, COALESCE(ps.warehouse_id, l.warehouse_id),
+			COALESCE(ps.quantity, 0), COALESCE(l.quantity, 0)
+		FROM product_stocks ps
+		FULL JOIN ledger l ON l.product_id = ps.product_id AND l.warehouse_id = ps.warehouse_id
+		JOIN products p ON p.id = COALESCE(ps.product_id, l.product_id)
+		WHERE NOT p.is_kit AND ($1::bigint = 0 OR p.user_id = $1)
+			AND COALESCE(ps.quantity, 0) <> COALESCE(l.quantity, 0)
+		ORDER BY p.user_id, p.id, 4 NULLS FIRST`
+
+	rows, err := db.Query(ctx, q, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Drift
+	for rows.Next() {
+		var d Drift
+		if err := rows.Scan(&d.ProductID, &d.UserID, &d.SKU, &d.WarehouseID, &d.Quantity, &d.LedgerQuantity); err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+// findTotalMismatch devuelve los productos cuyo total (products.quantity) no es la suma de su stock
+// por depósito. No se reparan: no hay forma de saber cuál de los dos es el correcto.
+func findTotalMismatch(ctx context.Context, db *pgxpool.Pool, userID int64) ([]Drift, error) {
+	const q = `
+		SELECT p.id, p.user_id, p.sku, p.quantity, COALESCE(SUM(ps.quantity), 0)
+		FROM products p
+		LEFT JOIN product_stocks ps ON ps.product_id = p.id
+		WHERE NOT p.is_kit AND ($1::bigint = 0 OR p.user_id = $1)
+		GROUP BY p.id, p.user_id, p.sku, p.quantity
+		HAVING p.quantity <> COALESCE(SUM(ps.quantity), 0)
+		ORDER BY p.user_id, p.id`
+
+	rows, err := db.Query(ctx, q, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Drift
+	for rows.Next() {
+		var d Drift
+		if err := rows.Scan(&d.ProductID, &d.UserID, &d.SKU, &d.Quantity, &d.LedgerQuantity); err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+// ReconcileLedger compara el stock de cada producto en cada depósito con la suma de sus movimientos
+// en ese depósito e informa las diferencias. Con repair registra en cada depósito el movimiento que
+// iguala su ledger al stock: OPENING_BALANCE (fechado en el alta del producto) si el producto no tenía
+// movimientos y RECONCILIATION si los tenía. Los movimientos sin depósito se asignan antes al depósito
+// por defecto. Con userID 0 revisa todas las cuentas.
+func ReconcileLedger(db *pgxpool.Pool, userID int64, repair bool) error {
+	log.Println("🔍 Comparando el stock por depósito con el ledger de movimientos...")
+	ctx := context.Background()
+
+	mismatches, err := findTotalMismatch(ctx, db, userID)
+	if err != nil {
+		return fmt.Errorf("error al comparar totales con el stock por depósito: %w", err)
+	}
+	for _, m := range mismatches {
+		log.Printf("⚠️  Producto %d (%s, usuario %d): quantity=%g pero sus depósitos suman %g; se revisa a mano",
+			m.ProductID, m.SKU, m.UserID, m.Quantity, m.LedgerQuantity)
+	}
+
+	drifts, err := findDrift(ctx, db, userID)
+	if err != nil {
+		return fmt.Errorf("error al buscar diferencias del ledger: %w", err)
+	}
+	if len(drifts) == 0 {
+		log.Println("✅ El ledger coincide con el stock de todos los depósitos")
+		return nil
+	}
+
+	log.Printf("⚠️  %d depósitos con diferencias entre el stock y el ledger", len(drifts))
+	var products []int64
+	for _, d := range drifts {
+		warehouse := "sin depósito"
+		if d.WarehouseID != nil {
+			warehouse = fmt.Sprintf("depósito %d", *d.WarehouseID)
+		}
+		log.Printf("   - Producto %d (%s, usuario %d), %s: stock=%g ledger=%g diferencia=%g",
+			d.ProductID, d.SKU, d.UserID, warehouse, d.Quantity, d.LedgerQuantity, roundQuantity(d.Quantity-d.LedgerQuantity))
+		if len(products) == 0 || products[len(products)-1] != d.ProductID {
+			products = append(products, d.ProductID)
+		}
+	}
+	if !repair {
+		return nil
+	}
+
+	repaired := 0
+	for _, productID := range products {
+		if err := repairProduct(ctx, db, productID); err != nil {
+			log.Printf("❌ Error al reconciliar producto %d: %v", productID, err)
+			continue
+		}
+		repaired++
+	}
+	log.Printf("✅ Productos reconciliados: %d de %d", repaired, len(products))
+	return nil
+}
+
+// ledgerState es el stock de un producto y la suma de sus movimientos, por depósito.
+type ledgerState struct {
+	Stocks     map[int64]float64 // product_stocks.quantity
+	Ledger     map[int64]float64 // SUM(quantity_change) de los movimientos con depósito
+	Unassigned float64           // SUM(quantity_change) de los movimientos sin depósito
+	Movements  int64             // cantidad total de movimientos del producto
+}
+
+// correction es el movimiento que iguala el ledger de un depósito a su stock.
+type correction struct {
+	WarehouseID int64
+	Quantity    float64
+	Reason      string
+}
+
+// planRepair calcula las correcciones de un producto, una por depósito con diferencia y ordenadas
+// por depósito. Los movimientos sin depósito cuentan para defaultWarehouse, al que se reasignan.
+func planRepair(s ledgerState, defaultWarehouse int64) []correction {
+	ledger := map[int64]float64{}
+	for id, q := range s.Ledger {
+		ledger[id] = q
+	}
+	if s.Unassigned != 0 {
+		ledger[defaultWarehouse] += s.Unassigned
+	}
+
+	warehouses := map[int64]bool{}
+	for id := range s.Stocks {
+		warehouses[id] = true
+	}
+	for id := range ledger {
+		warehouses[id] = true
+	}
+
+	// Sin movimientos previos la diferencia es el saldo inicial del producto
+	reason := "RECONCILIATION"
+	if s.Movements == 0 {
+		reason = "OPENING_BALANCE"
+	}
+
+	var out []correction
+	for id := range warehouses {
+		if drift := roundQuantity(s.Stocks[id] - ledger[id]); drift != 0 {
+			out = append(out, correction{WarehouseID: id, Quantity: drift, Reason: reason})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].WarehouseID < out[j].WarehouseID })
+	return out
+}
+
+// repairProduct recalcula las diferencias con el producto bloqueado y registra en cada depósito el
+// movimiento que la corrige. Sólo escribe el ledger: product_stocks ya refleja el stock real.
+func repairProduct(ctx context.Context, db *pgxpool.Pool, productID int64) error {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if tx != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	var (
+		userID    int64
+		createdAt time.Time
+	)
+	const qProduct = `SELECT user_id, created_at FROM products WHERE id = $1 FOR UPDATE`
+	if err := tx.QueryRow(ctx, qProduct, productID).Scan(&userID, &createdAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	s, unassigned, err := loadLedgerState(ctx, tx, productID)
+	if err != nil {
+		return err
+	}
+
+	// Los movimientos sin depósito son anteriores a los depósitos: pasan al depósito por defecto
+	var defaultWarehouse int64
+	if unassigned > 0 {
+		if defaultWarehouse, err = inventory.DefaultWarehouse(ctx, tx, userID); err != nil {
+			return err
+		}
+		const assign = `UPDATE stock_movements SET warehouse_id = $2 WHERE product_id = $1 AND warehouse_id IS NULL`
+		if _, err := tx.Exec(ctx, assign, productID, defaultWarehouse); err != nil {
+			return err
+		}
+	}
+
+	const insertMovement = `
+		INSERT INTO stock_movements (product_id, warehouse_id, quantity_change, reason, user_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+	for _, c := range planRepair(s, defaultWarehouse) {
+		at := time.Now()
+		if c.Reason == "OPENING_BALANCE" {
+			at = createdAt
+		}
+		if _, err := tx.Exec(ctx, insertMovement, productID, c.WarehouseID, c.Quantity, c.Reason, userID, at); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	tx = nil
+	return nil
+}
+
+// loadLedgerState lee el stock y el ledger por depósito de un producto. Devuelve también cuántos
+// movimientos no tienen depósito.
+func loadLedgerState(ctx context.Context, tx pgx.Tx, productID int64) (ledgerState, int64, error) {
+	s := ledgerState{Stocks: map[int64]float64{}, Ledger: map[int64]float64{}}
+
+	rows, err := tx.Query(ctx, `SELECT warehouse_id, quantity FROM product_stocks WHERE product_id = $1`, productID)
+	if err != nil {
+		return s, 0, err
+	}
+	for rows.Next() {
+		var id int64
+		var q float64
+		if err := rows.Scan(&id, &q); err != nil {
+			rows.Close()
+			return s, 0, err
+		}
+		s.Stocks[id] = q
+	}
+	rows.Close()
+	if rows.Err() != nil {
+		return s, 0, rows.Err()
+	}
+
+	const qLedger = `
+		SELECT warehouse_id, SUM(quantity_change), COUNT(*)
+		FROM stock_movements
+		WHERE product_id = $1
+		GROUP BY warehouse_id`
+	rows, err = tx.Query(ctx, qLedger, productID)
+	if err != nil {
+		return s, 0, err
+	}
+	defer rows.Close()
+
+	var unassigned int64
+	for rows.Next() {
+		var id *int64
+		var q float64
+		var n int64
+		if err := rows.Scan(&id, &q, &n); err != nil {
+			return s, 0, err
+		}
+		s.Movements += n
+		if id == nil {
+			s.Unassigned, unassigned = q, n
+			continue
+		}
+		s.Ledger[*id] = q
+	}
+	return s, unassigned, rows.Err()
+}
+
+// roundQuantity redondea a la precisión de las cantidades (3 decimales).
+func roundQuantity(q float64) float64 {
+	return math.Round(q*1000) / 1000
+}
@@ -0,0 +1,71 @@
+package reconciliation
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPlanRepair(t *testing.T) {
+	cases := []struct {
+		name  string
+		state ledgerState
+		want  []correction
+	}{
+		{
+			name: "each warehouse is reconciled against its own movements",
+			state: ledgerState{
+				Stocks:    map[int64]float64{1: 10, 2: 5},
+				Ledger:    map[int64]float64{1: 10, 2: 8},
+				Movements: 4,
+			},
+			want: []correction{{WarehouseID: 2, Quantity: -3, Reason: "RECONCILIATION"}},
+		},
+		{
+			name: "movements in a warehouse without a stock row",
+			state: ledgerState{
+				Stocks:    map[int64]float64{1: 10},
+				Ledger:    map[int64]float64{1: 7, 3: 2},
+				Movements: 3,
+			},
+			want: []correction{
+				{WarehouseID: 1, Quantity: 3, Reason: "RECONCILIATION"},
+				{WarehouseID: 3, Quantity: -2, Reason: "RECONCILIATION"},
+			},
+		},
+		{
+			name: "movements without a warehouse count for the default one",
+			state: ledgerState{
+				Stocks:     map[int64]float64{1: 10, 2: 4},
+				Ledger:     map[int64]float64{2: 4},
+				Unassigned: 6,
+				Movements:  2,
+			},
+			want: []correction{{WarehouseID: 1, Quantity: 4, Reason: "RECONCILIATION"}},
+		},
+		{
+			name: "without movements the stock is an opening balance",
+			state: ledgerState{
+				Stocks: map[int64]float64{2: 1.5, 1: 3},
+				Ledger: map[int64]float64{},
+			},
+			want: []correction{
+				{WarehouseID: 1, Quantity: 3, Reason: "OPENING_BALANCE"},
+				{WarehouseID: 2, Quantity: 1.5, Reason: "OPENING_BALANCE"},
+			},
+		},
+		{
+			name: "ledger up to date",
+			state: ledgerState{
+				Stocks:    map[int64]float64{1: 2.1},
+				Ledger:    map[int64]float64{1: 2.1000001},
+				Movements: 1,
+			},
+			want: nil,
+		},
+	}
+	for _, c := range cases {
+		if got := planRepair(c.state, 1); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: expected %+v, got %+v", c.name, c.want, got)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package reorder
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"stock-in-order/backend/inventory"
+)
+
+// Need es un producto por reponer según su regla.
+type Need struct {
+	UserID     int64
+	ProductID  int64
+	SKU        string
+	SupplierID *int64
+	BaseUnit   string
+	Position   float64 // disponible + pedido en órdenes abiertas
+	Quantity   float64 // cantidad a pedir, en unidad base
+	UnitCost   float64 // último costo conocido por unidad base (0 si nunca se compró)
+}
+
+// findNeeds devuelve los productos (de un usuario, o de todos si userID es 0) cuya posición de
+// stock (cantidad - reservado + pedido en órdenes borrador o pendientes) está en el punto de pedido
+// o por debajo. Se excluyen los productos archivados y los de proveedores archivados.
+func findNeeds(ctx context.Context, db *pgxpool.Pool, userID int64) ([]Need, error) {
+	const q = `
+		SELECT r.user_id, p.id, p.sku, r.preferred_supplier_id, p.base_unit, r.reorder_point, r.target_level, r.min_order_quantity,
+			p.quantity
+				- (SELECT COALESCE(SUM(ps.reserved), 0) FROM product_stocks ps WHERE ps.product_id = p.id)
+				+ (SELECT COALESCE(SUM(poi.quantity), 0)
+					FROM purchase_order_items poi
+					JOIN purchase_orders po ON po.id = poi.purchase_order_id
+					WHERE poi.product_id = p.id AND po.status IN ('draft', 'pending')),
+			COALESCE((SELECT poi.unit_cost / NULLIF(poi.unit_factor, 0)
+				FROM purchase_order_items poi
+				JOIN purchase_orders po ON po.id = poi.purchase_order_id
+				WHERE poi.product_id = p.id AND po.status <> 'cancelled'
+					AND (r.preferred_supplier_id IS NULL OR po.supplier_id = r.preferred_supplier_id)
+				ORDER BY po.order_date DESC, poi.id DESC
+				LIMIT 1), 0)
+		FROM reorder_rules r
+		JOIN products p ON p.id = r.product_id
+		LEFT JOIN suppliers s ON s.id = r.preferred_supplier_id
+		WHERE ($1::bigint = 0 OR r.user_id = $1)
+			AND p.archived_at IS NULL AND NOT p.is_kit AND s.archived_at IS NULL
+		ORDER BY r.user_id, r.preferred_supplier_id NULLS LAST, p.sku`
+
+	rows, err := db.Query(ctx, q, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Need
+	for rows.Next() {
+		var n Need
+		var reorderPoint, targetLevel, minOrder float64
+		if err := rows.Scan(&n.UserID, &n.ProductID, &n.SKU, &n.SupplierID, &n.BaseUnit, &reorderPoint, &targetLevel, &minOrder,
+			&n.Position, &n.UnitCost); err != nil {
+			return nil, err
+		}
+		n.Position = roundQuantity(n.Position)
+		if n.Quantity = orderQuantity(n.Position, reorderPoint, targetLevel, minOrder); n.Quantity > 0 {
+			out = append(out, n)
+		}
+	}
+	return out, rows.Err()
+}
+
+// orderQuantity es lo que hay que pedir para volver al nivel objetivo (al menos el mínimo de
+// compra), o 0 si la posición todavía está por encima del punto de pedido.
+func orderQuantity(position, reorderPoint, targetLevel, minOrder float64) float64 {
+	if position > reorderPoint {
+		return 0
+	}
+	return roundQuantity(math.Max(targetLevel-position, minOrder))
+}
+
+// DraftPurchaseOrders arma una orden de compra en borrador por usuario y proveedor preferido con
+// los productos a reponer. Como los borradores cuentan como pedido, volver a ejecutarlo no duplica
+// órdenes. Los borradores esperan la revisión de un repositor antes de enviarse.
+func DraftPurchaseOrders(db *pgxpool.Pool, userID int64) error {
+	log.Println("🔍 Buscando productos por debajo del punto de pedido...")
+	ctx := context.Background()
+
+	needs, err := findNeeds(ctx, db, userID)
+	if err != nil {
+		return fmt.Errorf("error al calcular reposiciones: %w", err)
+	}
+	if len(needs) == 0 {
+		log.Println("✅ No hay productos para reponer")
+		return nil
+	}
+
+	// Las necesidades vienen ordenadas por usuario y proveedor: cada tramo es una orden
+	drafted := 0
+	for start := 0; start < len(needs); {
+		end := start + 1
+		for end < len(needs) && needs[end].UserID == needs[start].UserID && sameSupplier(needs[end].SupplierID, needs[start].SupplierID) {
+			end++
+		}
+		orderID, err := createDraft(ctx, db, needs[start:end])
+		if err != nil {
+			log.Printf("❌ Error al crear orden en borrador (usuario %d): %v", needs[start].UserID, err)
+		} else {
+			drafted++
+			log.Printf("   - Orden %d en borrador (usuario %d) con %d productos", orderID, needs[start].UserID, end-start)
+		}
+		start = end
+	}
+
+	log.Printf("✅ Órdenes de compra en borrador creadas: %d", drafted)
+	return nil
+}
+
+func sameSupplier(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// createDraft crea la orden en borrador de un usuario y proveedor, recibida en el depósito por defecto.
+func createDraft(ctx context.Context, db *pgxpool.Pool, needs []Need) (int64, error) {
+	var orderID int64
+	err := pgx.BeginFunc(ctx, db, func(tx pgx.Tx) error {
+		warehouseID, err := inventory.DefaultWarehouse(ctx, tx, needs[0].UserID)
+		if err != nil {
+			return err
+		}
+
+		const insertOrder = `
+			INSERT INTO purchase_orders (supplier_id, order_date, status, user_id, warehouse_id, auto_generated)
+			VALUES ($1, NOW(), 'draft', $2, $3, true)
+			RETURNING id`
+		if err := tx.QueryRow(ctx, insertOrder, needs[0].SupplierID, needs[0].UserID, warehouseID).Scan(&orderID); err != nil {
+			return err
+		}
+
+		const insertItem = `
+			INSERT INTO purchase_order_items (purchase_order_id, product_id, quantity, unit_cost, unit, unit_quantity, unit_factor)
+			VALUES ($1, $2, $3, $4, $5, $3, 1)`
+		for _, n := range needs {
+			unitCost := math.Round(n.UnitCost*100) / 100
+			if _, err := tx.Exec(ctx, insertItem, orderID, n.ProductID, n.Quantity, unitCost, n.BaseUnit); err != nil {
+				return fmt.Errorf("producto %s: %w", n.SKU, err)
+			}
+		}
+		return nil
+	})
+	return orderID, err
+}
+
+// roundQuantity redondea a la precisión de las cantidades (3 decimales).
+func roundQuantity(q float64) float64 {
+	return math.Round(q*1000) / 1000
+}
@@ -0,0 +1,84 @@
+package reports
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/xuri/excelize/v2"
+
+	"stock-in-order/backend/inventory"
+)
+
+// GenerateDeadStockReport genera el reporte Excel de stock inmovilizado y de baja rotación
+// para un usuario con la misma consulta que el endpoint del backend. days <= 0 y maxTurnover < 0
+// usan los valores por defecto.
+func GenerateDeadStockReport(db *pgxpool.Pool, userID int64, days int, maxTurnover float64) ([]byte, error) {
+	if maxTurnover < 0 {
+		maxTurnover = inventory.DefaultDeadStockMaxTurnover
+	}
+
+	report, err := inventory.DeadStock(db, userID, days, maxTurnover)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch dead stock: %w", err)
+	}
+
+	f := excelize.NewFile()
+	defer func() {
+		_ = f.Close()
+	}()
+
+	sheetName := "Stock Inmovilizado"
+	index, err := f.NewSheet(sheetName)
+	if err != nil {
+		return nil, fmt.Errorf("could not create Excel sheet: %w", err)
+	}
+	f.SetActiveSheet(index)
+
+	headers := []string{"ID", "SKU", "Nombre", "Estado", "Cantidad", "Unidad", "Costo Unitario", "Valor", "Vendido en el Período", "Rotación", "Última Venta", "Última Recepción"}
+	for i, header := range headers {
+		cell, _ := excelize.CoordinatesToCellName(i+1, 1)
+		f.SetCellValue(sheetName, cell, header)
+	}
+
+	for rowIndex, it := range report.Products {
+		row := strconv.Itoa(rowIndex + 2)
+		status := "Baja rotación"
+		if it.Status == inventory.DeadStockDead {
+			status = "Sin ventas"
+		}
+		f.SetCellValue(sheetName, "A"+row, it.ProductID)
+		f.SetCellValue(sheetName, "B"+row, it.SKU)
+		f.SetCellValue(sheetName, "C"+row, it.Name)
+		f.SetCellValue(sheetName, "D"+row, status)
+		f.SetCellValue(sheetName, "E"+row, it.OnHand)
+		f.SetCellValue(sheetName, "F"+row, it.BaseUnit)
+		f.SetCellValue(sheetName, "G"+row, it.UnitCost)
+		f.SetCellValue(sheetName, "H"+row, it.Value)
+		f.SetCellValue(sheetName, "I"+row, it.SoldInPeriod)
+		f.SetCellValue(sheetName, "J"+row, it.Turnover)
+		if it.LastSaleDate != nil {
+			f.SetCellValue(sheetName, "K"+row, it.LastSaleDate.Format("2006-01-02"))
+		}
+		if it.LastReceiptDate != nil {
+			f.SetCellValue(sheetName, "L"+row, it.LastReceiptDate.Format("2006-01-02"))
+		}
+	}
+
+	// Fila de total y parámetros del reporte
+	totalRow := strconv.Itoa(len(report.Products) + 2)
+	f.SetCellValue(sheetName, "G"+totalRow, "Total")
+	f.SetCellValue(sheetName, "H"+totalRow, report.TotalValue)
+	f.SetCellValue(sheetName, "N1", "Período (días)")
+	f.SetCellValue(sheetName, "O1", report.Days)
+	f.SetCellValue(sheetName, "N2", "Rotación mínima")
+	f.SetCellValue(sheetName, "O2", report.MaxTurnover)
+
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		return nil, fmt.Errorf("could not write Excel file: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,44 @@
+package reservations
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"stock-in-order/backend/inventory"
+)
+
+// ReleaseExpired cancela las órdenes de venta pendientes cuya reserva venció y devuelve
+// el stock reservado al disponible. Cada orden se procesa en su propia transacción, con la misma
+// liberación que una cancelación desde el backend.
+// Las órdenes que ya descontaron stock (anteriores a las reservas) no se vencen: se cancelan desde
+// el backend, que registra los movimientos que lo devuelven.
+func ReleaseExpired(db *pgxpool.Pool) error {
+	log.Println("🔍 Buscando reservas de stock vencidas...")
+
+	orderIDs, err := inventory.ExpiredReservations(db)
+	if err != nil {
+		return fmt.Errorf("error al buscar órdenes vencidas: %w", err)
+	}
+
+	if len(orderIDs) == 0 {
+		log.Println("✅ No hay reservas vencidas")
+		return nil
+	}
+
+	released := 0
+	for _, id := range orderIDs {
+		ok, err := inventory.ExpireReservation(db, id)
+		if err != nil {
+			log.Printf("❌ Error al liberar la reserva de la orden %d: %v", id, err)
+			continue
+		}
+		if ok {
+			released++
+		}
+	}
+
+	log.Printf("✅ Reservas liberadas: %d de %d órdenes vencidas", released, len(orderIDs))
+	return nil
+}